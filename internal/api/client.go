@@ -7,6 +7,7 @@ import (
 	"io"
 	"main/internal/core"
 	"main/internal/logger"
+	"main/internal/notify"
 	"main/internal/parser"
 	"main/utils/structs"
 	"net/http"
@@ -28,9 +29,6 @@ func GetUrlSong(songUrl string, account *structs.Account) (string, error) {
 	manifest, err := GetInfoFromAdam(songId, account, storefront)
 	if err != nil {
 		logger.Error("\u26A0 Failed to get manifest: %v", err)
-		core.SharedLock.Lock()
-		core.Counter.NotSong++
-		core.SharedLock.Unlock()
 		return "", err
 	}
 	if len(manifest.Relationships.Albums.Data) == 0 {
@@ -74,8 +72,8 @@ func GetUrlArtistName(urlRaw string, account *structs.Account) (string, string,
 func CheckArtist(artistUrl string, account *structs.Account, relationship string) ([]string, error) {
 	storefront, artistId := parser.CheckUrlArtist(artistUrl)
 
-	// 获取目标艺术家的名称（用于过滤参与作品）
-	targetArtistName, _, err := GetUrlArtistName(artistUrl, account)
+	// 获取目标艺术家的名称与 ID（用于过滤参与作品）
+	targetArtistName, targetArtistID, err := GetUrlArtistName(artistUrl, account)
 	if err != nil {
 		return nil, fmt.Errorf("获取目标艺术家名称失败: %w", err)
 	}
@@ -87,7 +85,7 @@ func CheckArtist(artistUrl string, account *structs.Account, relationship string
 	var filteredCount int // 统计被过滤的作品数量
 	var hasMore bool = true
 	for hasMore {
-		apiURL := fmt.Sprintf("https://amp-api.music.apple.com/v1/catalog/%s/artists/%s/%s?limit=100&offset=%d&l=%s", storefront, artistId, relationship, Num, core.Config.Language)
+		apiURL := fmt.Sprintf("https://amp-api.music.apple.com/v1/catalog/%s/artists/%s/%s?limit=100&offset=%d&l=%s&include=artists", storefront, artistId, relationship, Num, core.Config.Language)
 		logger.Debug("[API] 请求艺术家 API: %s", apiURL)
 		logger.Debug("[API] Token长度: %d", len(core.DeveloperToken))
 
@@ -121,16 +119,25 @@ func CheckArtist(artistUrl string, account *structs.Account, relationship string
 				// 严格模式：目标艺术家必须是专辑的第一作者/主艺术家
 				if relationship == "albums" && album.Attributes.ArtistName != "" {
 					albumArtist := album.Attributes.ArtistName
-					// 提取主要艺术家（第一作者）进行比较
-					primaryArtist := core.GetPrimaryArtist(albumArtist)
-					
-					// 只保留主艺术家完全匹配目标艺术家的作品
+					var related []core.Credit
+					for _, a := range album.Relationships.Artists.Data {
+						related = append(related, core.Credit{ID: a.ID, Name: a.Attributes.Name})
+					}
+					// 提取主要艺术家（第一作者）进行比较：有目录艺术家数据时按艺术家 ID 比较，
+					// 避免名称中含有分隔符（如 "Simon & Garfunkel"）或不同语言的名称造成误判
+					primary := core.PrimaryCredit(albumArtist, related)
+					isPrimary := strings.EqualFold(primary.Name, targetArtistName)
+					if primary.ID != "" && targetArtistID != "" {
+						isPrimary = primary.ID == targetArtistID
+					}
+
+					// 只保留主艺术家为目标艺术家的作品
 					// 过滤掉：
 					// 1. 主艺术家是其他人的作品（纯参与作品）
 					// 2. 目标艺术家不是第一作者的合作作品（如 "王加一 & 陈婧霏"）
-					if !strings.EqualFold(primaryArtist, targetArtistName) {
-						logger.Debug("[艺术家过滤] 跳过非主要作品: '%s' (专辑主艺术家: '%s', 目标艺术家: '%s')", 
-							album.Attributes.Name, primaryArtist, targetArtistName)
+					if !isPrimary {
+						logger.Debug("[艺术家过滤] 跳过非主要作品: '%s' (专辑主艺术家: '%s' %s, 目标艺术家: '%s' %s)",
+							album.Attributes.Name, primary.Name, primary.ID, targetArtistName, targetArtistID)
 						filteredCount++
 						continue
 					}
@@ -266,7 +273,36 @@ func CheckArtist(artistUrl string, account *structs.Account, relationship string
 }
 
 // GetMeta retrieves metadata for an album or playlist
+// 语言按 metadata-language.rules 选择；设置了第二语言时额外获取一份该语言的元数据（Secondary）
 func GetMeta(albumId string, account *structs.Account, storefront string) (*structs.AutoGenerated, error) {
+	lang := core.MetadataLanguage(storefront, nil)
+	obj, err := getMeta(albumId, account, storefront, lang)
+	if err != nil {
+		return nil, err
+	}
+	log := logger.With("album_id", albumId, "storefront", storefront, "stage", "meta")
+	// 艺术家规则在获取元数据后才能匹配，命中其他语言时重新获取
+	if artistLang := core.MetadataLanguage(storefront, obj); artistLang != lang {
+		if again, err := getMeta(albumId, account, storefront, artistLang); err == nil {
+			obj, lang = again, artistLang
+		} else {
+			log.Warn("获取 %s 元数据失败，使用 %s: %v", artistLang, lang, err)
+		}
+	}
+	obj.Language = lang
+	if secondary := core.SecondaryLanguage(lang); secondary != "" {
+		if sec, err := getMeta(albumId, account, storefront, secondary); err == nil {
+			sec.Language = secondary
+			obj.Secondary = sec
+		} else {
+			log.Warn("获取第二语言（%s）元数据失败: %v", secondary, err)
+		}
+	}
+	return obj, nil
+}
+
+// getMeta 按指定语言获取专辑或播放列表的元数据（含全部分页曲目）
+func getMeta(albumId string, account *structs.Account, storefront, lang string) (*structs.AutoGenerated, error) {
 	var mtype string
 	var next string
 	if strings.Contains(albumId, "pl.") {
@@ -289,7 +325,7 @@ func GetMeta(albumId string, account *structs.Account, storefront string) (*stru
 	query.Set("fields[albums:albums]", "artistName,artwork,name,releaseDate,url")
 	query.Set("fields[record-labels]", "name")
 	query.Set("extend", "editorialVideo")
-	query.Set("l", core.Config.Language)
+	query.Set("l", lang)
 	req.URL.RawQuery = query.Encode()
 	do, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -297,7 +333,11 @@ func GetMeta(albumId string, account *structs.Account, storefront string) (*stru
 	}
 	defer do.Body.Close()
 	if do.StatusCode != http.StatusOK {
-		logger.Debug("[API] GetMeta 失败: HTTP %s, albumId=%s", do.Status, albumId)
+		logger.With("album_id", albumId, "account", accountName(account), "storefront", storefront, "stage", "meta", "status", do.StatusCode).
+			Debug("[API] GetMeta 失败: HTTP %s, albumId=%s", do.Status, albumId)
+		if do.StatusCode == http.StatusUnauthorized {
+			notify.TokenExpired(accountName(account), fmt.Errorf("获取专辑元数据返回 HTTP %s", do.Status))
+		}
 		return nil, fmt.Errorf("获取专辑元数据失败 (HTTP %s): ID=%s", do.Status, albumId)
 	}
 	obj := new(structs.AutoGenerated)
@@ -311,7 +351,7 @@ func GetMeta(albumId string, account *structs.Account, storefront string) (*stru
 	if len(obj.Data[0].Relationships.Tracks.Next) > 0 {
 		next = obj.Data[0].Relationships.Tracks.Next
 		for {
-			req, err := http.NewRequest("GET", fmt.Sprintf("https://amp-api.music.apple.com/%s&l=%s&include=albums", next, core.Config.Language), nil)
+			req, err := http.NewRequest("GET", fmt.Sprintf("https://amp-api.music.apple.com/%s&l=%s&include=albums", next, lang), nil)
 			if err != nil {
 				return nil, err
 			}
@@ -358,6 +398,14 @@ func GetMeta(albumId string, account *structs.Account, storefront string) (*stru
 	return obj, nil
 }
 
+// accountName 返回用于日志字段的账户名称
+func accountName(account *structs.Account) string {
+	if account == nil {
+		return ""
+	}
+	return account.Name
+}
+
 // GetInfoFromAdam retrieves song data from the API
 func GetInfoFromAdam(trackid string, account *structs.Account, storefront string) (*structs.SongData, error) {
 	request, err := http.NewRequest("GET", fmt.Sprintf("https://amp-api.music.apple.com/v1/catalog/%s/songs/%s", storefront, trackid), nil)
@@ -380,7 +428,11 @@ func GetInfoFromAdam(trackid string, account *structs.Account, storefront string
 	}
 	defer do.Body.Close()
 	if do.StatusCode != http.StatusOK {
-		logger.Debug("[API] GetInfoFromAdam 失败: HTTP %s, trackId=%s", do.Status, trackid)
+		logger.With("track_id", trackid, "account", accountName(account), "storefront", storefront, "stage", "info", "status", do.StatusCode).
+			Debug("[API] GetInfoFromAdam 失败: HTTP %s, trackId=%s", do.Status, trackid)
+		if do.StatusCode == http.StatusUnauthorized {
+			notify.TokenExpired(accountName(account), fmt.Errorf("获取曲目信息返回 HTTP %s", do.Status))
+		}
 		return nil, fmt.Errorf("获取曲目信息失败 (HTTP %s): ID=%s", do.Status, trackid)
 	}
 
@@ -398,6 +450,68 @@ func GetInfoFromAdam(trackid string, account *structs.Account, storefront string
 	return nil, nil
 }
 
+// GetAlbumsByUPC 按 UPC 在指定地区的目录中查找专辑，返回专辑 ID
+func GetAlbumsByUPC(upc string, account *structs.Account, storefront string) ([]string, error) {
+	var obj struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := catalogFilter(account, storefront, "albums", "upc", upc, &obj); err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, d := range obj.Data {
+		ids = append(ids, d.ID)
+	}
+	return ids, nil
+}
+
+// GetSongsByISRC 按 ISRC 在指定地区的目录中查找曲目（包含所属专辑）
+func GetSongsByISRC(isrc string, account *structs.Account, storefront string) ([]structs.SongData, error) {
+	obj := new(structs.ApiResult)
+	if err := catalogFilter(account, storefront, "songs", "isrc", isrc, obj); err != nil {
+		return nil, err
+	}
+	return obj.Data, nil
+}
+
+// catalogFilter 按 filter[key]=value 查询地区目录中的资源，结果解码到 v
+func catalogFilter(account *structs.Account, storefront, kind, key, value string, v interface{}) error {
+	req, err := http.NewRequest("GET", fmt.Sprintf("https://amp-api.music.apple.com/v1/catalog/%s/%s", storefront, kind), nil)
+	if err != nil {
+		return err
+	}
+	query := url.Values{}
+	query.Set("filter["+key+"]", value)
+	if kind == "songs" {
+		query.Set("include", "albums")
+	}
+	query.Set("l", core.Config.Language)
+	req.URL.RawQuery = query.Encode()
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", core.DeveloperToken))
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
+	req.Header.Set("Origin", "https://music.apple.com")
+
+	do, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("按 %s 查询目录失败: %w", strings.ToUpper(key), err)
+	}
+	defer do.Body.Close()
+	if do.StatusCode != http.StatusOK {
+		logger.With("account", accountName(account), "storefront", storefront, "stage", "match", "status", do.StatusCode).
+			Debug("[API] 按 %s=%s 查询 %s 失败: HTTP %s", key, value, kind, do.Status)
+		if do.StatusCode == http.StatusUnauthorized {
+			notify.TokenExpired(accountName(account), fmt.Errorf("查询目录返回 HTTP %s", do.Status))
+		}
+		return fmt.Errorf("按 %s 查询目录失败 (HTTP %s): %s", strings.ToUpper(key), do.Status, value)
+	}
+	if err := json.NewDecoder(do.Body).Decode(v); err != nil {
+		return fmt.Errorf("解析目录查询结果失败: %w", err)
+	}
+	return nil
+}
+
 // GetMVInfoFromAdam retrieves music video data from the API
 func GetMVInfoFromAdam(mvId string, account *structs.Account, storefront string) (*structs.AutoGeneratedMusicVideo, error) {
 	request, err := http.NewRequest("GET", fmt.Sprintf("https://amp-api.music.apple.com/v1/catalog/%s/music-videos/%s", storefront, mvId), nil)
@@ -417,7 +531,8 @@ func GetMVInfoFromAdam(mvId string, account *structs.Account, storefront string)
 	}
 	defer do.Body.Close()
 	if do.StatusCode != http.StatusOK {
-		logger.Debug("[API] GetMVInfoFromAdam 失败: HTTP %s, mvId=%s", do.Status, mvId)
+		logger.With("track_id", mvId, "account", accountName(account), "storefront", storefront, "stage", "info", "status", do.StatusCode).
+			Debug("[API] GetMVInfoFromAdam 失败: HTTP %s, mvId=%s", do.Status, mvId)
 		return nil, fmt.Errorf("获取 MV 信息失败 (HTTP %s): ID=%s", do.Status, mvId)
 	}
 
@@ -0,0 +1,314 @@
+package checksum
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/zeebo/blake3"
+)
+
+// 支持的摘要算法
+const (
+	AlgSHA256 = "sha256"
+	AlgMD5    = "md5"
+	AlgBLAKE3 = "blake3"
+)
+
+// Algorithms 所有支持的算法，查找已有清单时按此顺序
+var Algorithms = []string{AlgSHA256, AlgBLAKE3, AlgMD5}
+
+// ErrNoManifest 目录中没有校验清单
+var ErrNoManifest = errors.New("目录中没有校验清单")
+
+// ManifestName 清单文件名，如 checksums.sha256
+func ManifestName(alg string) string {
+	return "checksums." + alg
+}
+
+// IsAlgorithm 判断算法名称是否有效
+func IsAlgorithm(alg string) bool {
+	for _, a := range Algorithms {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
+
+// newHash 创建摘要计算器
+func newHash(alg string) (hash.Hash, error) {
+	switch alg {
+	case AlgSHA256:
+		return sha256.New(), nil
+	case AlgMD5:
+		return md5.New(), nil
+	case AlgBLAKE3:
+		return blake3.New(), nil
+	}
+	return nil, fmt.Errorf("不支持的校验算法 '%s'（可选: %s）", alg, strings.Join(Algorithms, ", "))
+}
+
+// HashFile 计算文件摘要（十六进制）
+func HashFile(path, alg string) (string, error) {
+	h, err := newHash(alg)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Manifest 专辑目录的校验清单，格式与 sha256sum / md5sum / b3sum 兼容（"摘要  文件名"）
+type Manifest struct {
+	Dir       string
+	Algorithm string
+	Entries   map[string]string // 文件名 -> 摘要
+}
+
+// Path 清单文件路径
+func (m *Manifest) Path() string {
+	return filepath.Join(m.Dir, ManifestName(m.Algorithm))
+}
+
+// Load 读取目录中已有的清单（任意支持的算法），没有清单时返回 ErrNoManifest
+func Load(dir string) (*Manifest, error) {
+	for _, alg := range Algorithms {
+		data, err := os.ReadFile(filepath.Join(dir, ManifestName(alg)))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		m := &Manifest{Dir: dir, Algorithm: alg, Entries: make(map[string]string)}
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for line := 1; scanner.Scan(); line++ {
+			text := scanner.Text()
+			if text == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+			sum, name, ok := strings.Cut(text, "  ")
+			if !ok {
+				// 二进制模式："摘要 *文件名"
+				sum, name, ok = strings.Cut(text, " *")
+			}
+			if !ok || sum == "" || name == "" {
+				return nil, fmt.Errorf("%s 第 %d 行格式无效", ManifestName(alg), line)
+			}
+			m.Entries[name] = strings.ToLower(sum)
+		}
+		return m, nil
+	}
+	return nil, ErrNoManifest
+}
+
+// Save 按文件名排序写入清单（先写临时文件再重命名，避免中断时留下不完整的清单）
+func (m *Manifest) Save() error {
+	names := make([]string, 0, len(m.Entries))
+	for name := range m.Entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s  %s\n", m.Entries[name], name)
+	}
+	f, err := os.CreateTemp(m.Dir, ".checksums-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(b.String())
+	if err == nil {
+		err = f.Chmod(0644) // CreateTemp 默认 0600
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), m.Path())
+}
+
+// covered 判断文件是否需要记录：音频、封面、歌词、动态封面等，排除清单自身与临时文件
+func covered(name string) bool {
+	if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "checksums.") {
+		return false
+	}
+	return !strings.HasSuffix(name, ".part") && !strings.HasSuffix(name, ".corrupt")
+}
+
+// files 列出目录（不含子目录）中需要记录的文件
+func files(dir string) ([]os.DirEntry, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var out []os.DirEntry
+	for _, e := range entries {
+		if e.Type().IsRegular() && covered(e.Name()) {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// Refresh 创建或增量更新目录的清单：新文件与修改时间晚于清单的文件重新计算摘要
+// 已有清单沿用其算法；清单中记录但已不存在的文件保留，以便校验时报告缺失
+func Refresh(dir, alg string) (changed int, err error) {
+	m, err := Load(dir)
+	var since int64
+	switch {
+	case errors.Is(err, ErrNoManifest):
+		if _, err := newHash(alg); err != nil {
+			return 0, err
+		}
+		m = &Manifest{Dir: dir, Algorithm: alg, Entries: make(map[string]string)}
+	case err != nil:
+		return 0, err
+	default:
+		if st, err := os.Stat(m.Path()); err == nil {
+			since = st.ModTime().UnixNano()
+		}
+	}
+
+	entries, err := files(dir)
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if _, ok := m.Entries[name]; ok {
+			info, err := e.Info()
+			if err != nil || info.ModTime().UnixNano() <= since {
+				continue
+			}
+		}
+		sum, err := HashFile(filepath.Join(dir, name), m.Algorithm)
+		if err != nil {
+			return changed, fmt.Errorf("计算 %s 摘要失败: %w", name, err)
+		}
+		if m.Entries[name] != sum {
+			m.Entries[name] = sum
+			changed++
+		}
+	}
+	if changed == 0 {
+		return 0, nil
+	}
+	return changed, m.Save()
+}
+
+// Update 修改专辑目录中的文件后（如升级音质、补充歌词）增量更新清单
+// 只重新计算 paths 中的文件，已删除的文件从清单中移除；目录没有清单时不做任何事
+func Update(dir string, paths ...string) error {
+	m, err := Load(dir)
+	if errors.Is(err, ErrNoManifest) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, p := range paths {
+		name := filepath.Base(p)
+		if !covered(name) {
+			continue
+		}
+		sum, err := HashFile(filepath.Join(dir, name), m.Algorithm)
+		if os.IsNotExist(err) {
+			delete(m.Entries, name)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("计算 %s 摘要失败: %w", name, err)
+		}
+		m.Entries[name] = sum
+	}
+	return m.Save()
+}
+
+// Result 单个目录的清单校验结果
+type Result struct {
+	Dir        string   `json:"dir"`
+	Algorithm  string   `json:"algorithm"`
+	OK         int      `json:"ok"`
+	Mismatched []string `json:"mismatched,omitempty"` // 摘要不一致（位衰减或被修改）
+	Missing    []string `json:"missing,omitempty"`    // 清单中记录但文件不存在
+	Untracked  []string `json:"untracked,omitempty"`  // 文件存在但未记录在清单中
+}
+
+// Clean 目录是否完全一致
+func (r *Result) Clean() bool {
+	return len(r.Mismatched) == 0 && len(r.Missing) == 0 && len(r.Untracked) == 0
+}
+
+// Verify 按清单重新计算目录中文件的摘要
+func Verify(dir string) (*Result, error) {
+	m, err := Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	r := &Result{Dir: dir, Algorithm: m.Algorithm}
+	present := make(map[string]bool)
+	entries, err := files(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		present[e.Name()] = true
+		if _, ok := m.Entries[e.Name()]; !ok {
+			r.Untracked = append(r.Untracked, e.Name())
+		}
+	}
+
+	names := make([]string, 0, len(m.Entries))
+	for name := range m.Entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !present[name] {
+			r.Missing = append(r.Missing, name)
+			continue
+		}
+		sum, err := HashFile(filepath.Join(dir, name), m.Algorithm)
+		if err != nil {
+			return nil, fmt.Errorf("计算 %s 摘要失败: %w", name, err)
+		}
+		if sum != m.Entries[name] {
+			r.Mismatched = append(r.Mismatched, name)
+		} else {
+			r.OK++
+		}
+	}
+	return r, nil
+}
+
+// HasManifest 判断目录中是否有清单
+func HasManifest(dir string) bool {
+	for _, alg := range Algorithms {
+		if _, err := os.Stat(filepath.Join(dir, ManifestName(alg))); err == nil {
+			return true
+		}
+	}
+	return false
+}
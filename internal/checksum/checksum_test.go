@@ -0,0 +1,227 @@
+package checksum
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFile 写入测试文件并设置修改时间
+func writeFile(t *testing.T, dir, name, content string, mtime time.Time) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestHashFile 测试三种算法的已知摘要
+func TestHashFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.txt", "abc", time.Now())
+	tests := map[string]string{
+		AlgSHA256: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		AlgMD5:    "900150983cd24fb0d6963f7d28e17f72",
+		AlgBLAKE3: "6437b3ac38465133ffb63b75273a8db548c558465d79db03fd359c6cd5bd9d85",
+	}
+	for alg, want := range tests {
+		got, err := HashFile(filepath.Join(dir, "a.txt"), alg)
+		if err != nil {
+			t.Fatalf("%s: %v", alg, err)
+		}
+		if got != want {
+			t.Errorf("%s = %s, want %s", alg, got, want)
+		}
+	}
+	if _, err := HashFile(filepath.Join(dir, "a.txt"), "crc32"); err == nil {
+		t.Error("unsupported algorithm should fail")
+	}
+}
+
+// TestRefreshAndVerify 测试创建清单、排除临时文件以及检测位衰减、缺失与未记录文件
+func TestRefreshAndVerify(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-time.Hour)
+	writeFile(t, dir, "01. Track.m4a", "audio-1", old)
+	writeFile(t, dir, "02. Track.m4a", "audio-2", old)
+	writeFile(t, dir, "cover.jpg", "jpeg", old)
+	writeFile(t, dir, "01. Track.lrc", "[00:00.00]", old)
+	writeFile(t, dir, "03. Track.m4a.part", "partial", old)
+	writeFile(t, dir, ".DS_Store", "x", old)
+
+	changed, err := Refresh(dir, AlgSHA256)
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if changed != 4 {
+		t.Errorf("changed = %d, want 4", changed)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "checksums.sha256"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), ".part") || strings.Contains(string(data), ".DS_Store") {
+		t.Errorf("manifest should exclude temporary and hidden files:\n%s", data)
+	}
+
+	r, err := Verify(dir)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !r.Clean() || r.OK != 4 {
+		t.Errorf("fresh manifest should be clean, got %+v", r)
+	}
+
+	// 内容改变但修改时间不变（位衰减）
+	writeFile(t, dir, "01. Track.m4a", "audio-X", old)
+	os.Remove(filepath.Join(dir, "cover.jpg"))
+	writeFile(t, dir, "bonus.pdf", "pdf", old)
+	r, err = Verify(dir)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if len(r.Mismatched) != 1 || r.Mismatched[0] != "01. Track.m4a" {
+		t.Errorf("Mismatched = %v", r.Mismatched)
+	}
+	if len(r.Missing) != 1 || r.Missing[0] != "cover.jpg" {
+		t.Errorf("Missing = %v", r.Missing)
+	}
+	if len(r.Untracked) != 1 || r.Untracked[0] != "bonus.pdf" {
+		t.Errorf("Untracked = %v", r.Untracked)
+	}
+}
+
+// TestRefreshIncremental 测试增量更新：只重新计算新文件与修改时间晚于清单的文件，并沿用已有清单的算法
+func TestRefreshIncremental(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-time.Hour)
+	writeFile(t, dir, "01. Track.m4a", "audio-1", old)
+	writeFile(t, dir, "02. Track.m4a", "audio-2", old)
+	if _, err := Refresh(dir, AlgBLAKE3); err != nil {
+		t.Fatal(err)
+	}
+	manifest := filepath.Join(dir, "checksums.blake3")
+	if err := os.Chtimes(manifest, old.Add(time.Minute), old.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 未修改的文件不重新计算，即使内容不同（修改时间早于清单）
+	writeFile(t, dir, "02. Track.m4a", "audio-2-rot", old)
+	changed, err := Refresh(dir, AlgSHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != 0 {
+		t.Errorf("changed = %d, want 0", changed)
+	}
+
+	// 重新写入标签（修改时间更新）与新增歌词
+	writeFile(t, dir, "01. Track.m4a", "audio-1-tagged", time.Now())
+	writeFile(t, dir, "01. Track.lrc", "lyrics", time.Now())
+	changed, err = Refresh(dir, AlgSHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != 2 {
+		t.Errorf("changed = %d, want 2", changed)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "checksums.sha256")); !os.IsNotExist(err) {
+		t.Error("existing blake3 manifest should be reused")
+	}
+	m, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Algorithm != AlgBLAKE3 || len(m.Entries) != 3 {
+		t.Errorf("manifest = %s with %d entries, want blake3 with 3", m.Algorithm, len(m.Entries))
+	}
+}
+
+// TestUpdate 测试按路径更新清单（升级音质、补充歌词后）
+func TestUpdate(t *testing.T) {
+	dir := t.TempDir()
+	if err := Update(dir, filepath.Join(dir, "a.m4a")); err != nil {
+		t.Fatalf("Update without manifest should be a no-op: %v", err)
+	}
+	if HasManifest(dir) {
+		t.Fatal("Update should not create a manifest")
+	}
+
+	now := time.Now()
+	writeFile(t, dir, "a.m4a", "alac", now)
+	writeFile(t, dir, "b.m4a", "aac", now)
+	if _, err := Refresh(dir, AlgMD5); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, dir, "a.m4a", "alac-hi-res", now)
+	writeFile(t, dir, "a.lrc", "lyrics", now)
+	os.Remove(filepath.Join(dir, "b.m4a"))
+	if err := Update(dir, filepath.Join(dir, "a.m4a"), filepath.Join(dir, "a.lrc"), filepath.Join(dir, "b.m4a")); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	r, err := Verify(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Clean() || r.OK != 2 {
+		t.Errorf("manifest should be clean after Update, got %+v", r)
+	}
+}
+
+// TestLoadBinaryMode 测试读取 sha256sum -b 生成的二进制模式清单
+func TestLoadBinaryMode(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "checksums.md5", "900150983CD24FB0D6963F7D28E17F72 *a b.txt\n", time.Now())
+	m, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if got := m.Entries["a b.txt"]; got != "900150983cd24fb0d6963f7d28e17f72" {
+		t.Errorf("entry = %q", got)
+	}
+
+	writeFile(t, dir, "checksums.md5", "garbage\n", time.Now())
+	if _, err := Load(dir); err == nil {
+		t.Error("malformed manifest should fail")
+	}
+}
+
+// TestVerifyAll 测试遍历音乐库：只校验带清单的目录
+func TestVerifyAll(t *testing.T) {
+	root := t.TempDir()
+	album := filepath.Join(root, "Artist", "Album")
+	other := filepath.Join(root, "Artist", "Other")
+	for _, d := range []string{album, other} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-time.Hour)
+	writeFile(t, album, "01.m4a", "a", old)
+	writeFile(t, other, "01.m4a", "b", old)
+	if _, err := Refresh(album, AlgSHA256); err != nil {
+		t.Fatal(err)
+	}
+
+	dirs, err := AlbumDirs([]string{root}, true)
+	if err != nil || len(dirs) != 2 {
+		t.Fatalf("AlbumDirs(withAudio) = %v, %v", dirs, err)
+	}
+
+	writeFile(t, album, "01.m4a", "rot", old)
+	report, err := VerifyAll([]string{root}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Dirs != 1 || report.Clean != 0 || len(report.Results) != 1 {
+		t.Errorf("report = %+v", report)
+	}
+	if !strings.Contains(report.Text(), "[不一致] 01.m4a") {
+		t.Errorf("text report missing mismatch:\n%s", report.Text())
+	}
+}
@@ -0,0 +1,129 @@
+package checksum
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// audioExts 判断目录是否为专辑目录时识别的音频扩展名
+var audioExts = map[string]bool{".m4a": true, ".mp4": true, ".flac": true}
+
+// AlbumDirs 遍历目录树，返回已有清单的目录；withAudio 为 true 时同时返回含有音频文件但没有清单的目录
+func AlbumDirs(roots []string, withAudio bool) ([]string, error) {
+	var dirs []string
+	seen := make(map[string]bool)
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() || seen[path] {
+				return nil
+			}
+			if HasManifest(path) || (withAudio && hasAudio(path)) {
+				seen[path] = true
+				dirs = append(dirs, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("遍历 %s 失败: %w", root, err)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// hasAudio 判断目录（不含子目录）中是否有音频文件
+func hasAudio(dir string) bool {
+	entries, err := files(dir)
+	if err != nil {
+		return false
+	}
+	for _, e := range entries {
+		if audioExts[strings.ToLower(filepath.Ext(e.Name()))] {
+			return true
+		}
+	}
+	return false
+}
+
+// Report 整个音乐库的清单校验报告，Results 只包含不一致或无法校验的目录
+type Report struct {
+	Started time.Time `json:"started"`
+	Roots   []string  `json:"roots"`
+	Dirs    int       `json:"dirs"`
+	Clean   int       `json:"clean"`
+	Results []*Result `json:"results,omitempty"`
+	Errors  []string  `json:"errors,omitempty"`
+}
+
+// VerifyAll 校验目录树中所有带清单的目录，progress 不为 nil 时每完成一个目录回调一次
+func VerifyAll(roots []string, progress func(done, total int)) (*Report, error) {
+	report := &Report{Started: time.Now(), Roots: roots}
+	dirs, err := AlbumDirs(roots, false)
+	if err != nil {
+		return nil, err
+	}
+	report.Dirs = len(dirs)
+	for i, dir := range dirs {
+		r, err := Verify(dir)
+		switch {
+		case err != nil:
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", dir, err))
+		case r.Clean():
+			report.Clean++
+		default:
+			report.Results = append(report.Results, r)
+		}
+		if progress != nil {
+			progress(i+1, len(dirs))
+		}
+	}
+	return report, nil
+}
+
+// WriteReport 写入报告，扩展名为 .json 时输出 JSON，否则输出文本
+func WriteReport(r *Report, path string) error {
+	var data []byte
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var err error
+		data, err = json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+	} else {
+		data = []byte(r.Text())
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Text 文本格式的报告
+func (r *Report) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "校验时间: %s\n", r.Started.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "校验目录: %s\n", strings.Join(r.Roots, ", "))
+	fmt.Fprintf(&b, "清单: %d | 一致: %d | 不一致: %d | 错误: %d\n", r.Dirs, r.Clean, len(r.Results), len(r.Errors))
+	for _, res := range r.Results {
+		fmt.Fprintf(&b, "\n%s（%s）\n", res.Dir, res.Algorithm)
+		for _, name := range res.Mismatched {
+			fmt.Fprintf(&b, "  [不一致] %s\n", name)
+		}
+		for _, name := range res.Missing {
+			fmt.Fprintf(&b, "  [缺失] %s\n", name)
+		}
+		for _, name := range res.Untracked {
+			fmt.Fprintf(&b, "  [未记录] %s\n", name)
+		}
+	}
+	for _, e := range r.Errors {
+		fmt.Fprintf(&b, "\n[错误] %s\n", e)
+	}
+	return b.String()
+}
@@ -2,12 +2,20 @@ package config
 
 import (
 	"fmt"
+	"main/internal/checksum"
 	"main/internal/constants"
+	"main/internal/hooks"
 	"main/internal/logger"
+	"main/internal/network"
+	"main/internal/notify"
+	"main/internal/quality"
 	"main/utils/structs"
+	"net"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // ValidationError 表示配置验证错误
@@ -90,6 +98,36 @@ func ValidateConfig(cfg *structs.ConfigSet) *ValidationResult {
 	// 10. 验证本地 wrapper 优化配置
 	validateLocalWrapperOptimization(cfg, result)
 
+	// 11. 验证限速配置
+	validateBandwidth(cfg, result)
+
+	// 12. 验证指标服务配置
+	validateMetrics(cfg, result)
+
+	// 13. 验证事件通知配置
+	validateNotifications(cfg, result)
+
+	// 14. 验证下载后处理钩子
+	validatePostHooks(cfg, result)
+
+	// 15. 验证响度分析配置
+	validateLoudness(cfg, result)
+
+	// 16. 验证校验清单配置
+	validateChecksums(cfg, result)
+
+	// 17. 验证单曲 / EP 识别规则
+	validateReleaseRules(cfg, result)
+
+	// 18. 验证音质策略
+	validateQualityPolicy(cfg, result)
+
+	// 19. 验证多音乐库输出
+	validateLibraryOutputs(cfg, result)
+
+	// 20. 验证元数据语言配置
+	validateMetadataLanguage(cfg, result)
+
 	return result
 }
 
@@ -233,6 +271,28 @@ func validateDownloadPerformance(cfg *structs.ConfigSet, result *ValidationResul
 			Message: "网络读取缓冲区大小未设置或无效",
 		})
 	}
+
+	// 验证专辑并行配置
+	if cfg.AlbumConcurrency > 8 {
+		result.Warnings = append(result.Warnings, ValidationError{
+			Field:   "album-concurrency",
+			Message: fmt.Sprintf("同时处理的专辑数过高（%d）可能导致被限流，建议不超过 8", cfg.AlbumConcurrency),
+		})
+	}
+	if cfg.AlbumConcurrency > 1 && cfg.MaxConcurrentTracks == 0 {
+		result.Warnings = append(result.Warnings, ValidationError{
+			Field:   "max-concurrent-tracks",
+			Message: "专辑并行时未设置全局曲目并发上限，总并发数可能达到 专辑数 × 专辑内线程数",
+		})
+	}
+
+	// 验证磁盘空间检查配置
+	if cfg.LowSpaceAction != "pause" && cfg.LowSpaceAction != "abort" {
+		result.Errors = append(result.Errors, ValidationError{
+			Field:   "low-space-action",
+			Message: fmt.Sprintf("无效的处理方式 '%s'，可选值: pause, abort", cfg.LowSpaceAction),
+		})
+	}
 }
 
 // validateWorkRest 验证工作-休息循环配置
@@ -416,6 +476,60 @@ func validateLogging(cfg *structs.ConfigSet, result *ValidationResult) {
 			})
 		}
 	}
+
+	validateLogFormat("logging.format", cfg.Logging.Format, result)
+
+	// 验证日志文件输出配置
+	file := cfg.Logging.File
+	if file.Level != "" {
+		levelValid := false
+		for _, level := range []string{"DEBUG", "INFO", "WARN", "WARNING", "ERROR"} {
+			if strings.EqualFold(file.Level, level) {
+				levelValid = true
+				break
+			}
+		}
+		if !levelValid {
+			result.Warnings = append(result.Warnings, ValidationError{
+				Field:   "logging.file.level",
+				Message: fmt.Sprintf("无效的日志级别 '%s'，将按 info 处理", file.Level),
+			})
+		}
+	}
+	validateLogFormat("logging.file.format", file.Format, result)
+
+	if file.RotateEvery != "" {
+		if d, err := time.ParseDuration(file.RotateEvery); err != nil || d <= 0 {
+			result.Errors = append(result.Errors, ValidationError{
+				Field:   "logging.file.rotate_every",
+				Message: fmt.Sprintf("无效的轮转周期 '%s'（示例: 24h、1h、30m）", file.RotateEvery),
+			})
+		}
+	}
+	if file.MaxSizeMB < 0 || file.MaxBackups < 0 || file.MaxAgeDays < 0 {
+		result.Errors = append(result.Errors, ValidationError{
+			Field:   "logging.file",
+			Message: "max_size_mb、max_backups、max_age_days 不能为负数",
+		})
+	}
+	if file.Path == "" && (file.MaxSizeMB > 0 || file.RotateEvery != "") &&
+		!strings.HasSuffix(cfg.Logging.Output, ".log") {
+		result.Warnings = append(result.Warnings, ValidationError{
+			Field:   "logging.file.path",
+			Message: "已配置日志轮转但未设置日志文件路径，轮转设置不会生效",
+		})
+	}
+}
+
+// validateLogFormat 验证日志格式（text/json）
+func validateLogFormat(field, format string, result *ValidationResult) {
+	if format == "" || strings.EqualFold(format, "text") || strings.EqualFold(format, "json") {
+		return
+	}
+	result.Warnings = append(result.Warnings, ValidationError{
+		Field:   field,
+		Message: fmt.Sprintf("无效的日志格式 '%s'（有效值: text, json），将使用 text", format),
+	})
 }
 
 // validateLocalWrapperOptimization 验证本地 wrapper 优化配置
@@ -499,3 +613,319 @@ func validateLocalWrapperOptimization(cfg *structs.ConfigSet, result *Validation
 		})
 	}
 }
+
+// validateBandwidth 验证限速配置
+func validateBandwidth(cfg *structs.ConfigSet, result *ValidationResult) {
+	rate, err := network.ParseBandwidth(cfg.MaxBandwidth)
+	if err != nil {
+		result.Errors = append(result.Errors, ValidationError{
+			Field:   "max-bandwidth",
+			Message: err.Error(),
+		})
+	} else if rate > 0 && rate < 64*1024 {
+		result.Warnings = append(result.Warnings, ValidationError{
+			Field:   "max-bandwidth",
+			Message: fmt.Sprintf("速率上限过低（%s），下载可能非常缓慢", network.FormatBandwidth(rate)),
+		})
+	}
+
+	for i, w := range cfg.BandwidthSchedule {
+		if _, err := network.ParseBandwidthWindow(w); err != nil {
+			result.Errors = append(result.Errors, ValidationError{
+				Field:   fmt.Sprintf("bandwidth-schedule[%d]", i),
+				Message: err.Error(),
+			})
+		}
+	}
+}
+
+// validateMetrics 验证指标服务监听地址
+func validateMetrics(cfg *structs.ConfigSet, result *ValidationResult) {
+	if cfg.MetricsListen == "" {
+		return
+	}
+	host, port, err := net.SplitHostPort(cfg.MetricsListen)
+	if err != nil || port == "" {
+		result.Errors = append(result.Errors, ValidationError{
+			Field:   "metrics-listen",
+			Message: fmt.Sprintf("无效的监听地址 '%s'（示例: 127.0.0.1:9108 或 :9108）", cfg.MetricsListen),
+		})
+		return
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		result.Warnings = append(result.Warnings, ValidationError{
+			Field:   "metrics-listen",
+			Message: "指标服务监听所有网卡且没有认证，请确认只在可信网络中暴露",
+		})
+	}
+}
+
+// validateNotifications 验证事件通知钩子
+func validateNotifications(cfg *structs.ConfigSet, result *ValidationResult) {
+	if len(cfg.Notifications) == 0 {
+		return
+	}
+	if _, err := notify.New(cfg.Notifications); err != nil {
+		result.Errors = append(result.Errors, ValidationError{
+			Field:   "notifications",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	for i, n := range cfg.Notifications {
+		field := fmt.Sprintf("notifications[%d]", i)
+		var command string
+		switch strings.ToLower(n.Type) {
+		case "webhook":
+			if !strings.HasPrefix(n.URL, "http://") && !strings.HasPrefix(n.URL, "https://") {
+				result.Errors = append(result.Errors, ValidationError{
+					Field:   field + ".url",
+					Message: fmt.Sprintf("webhook 地址必须以 http:// 或 https:// 开头: '%s'", n.URL),
+				})
+			}
+		case "exec":
+			command = n.Command
+		case "notify-send":
+			command = "notify-send"
+			if n.Urgency != "" && n.Urgency != "low" && n.Urgency != "normal" && n.Urgency != "critical" {
+				result.Warnings = append(result.Warnings, ValidationError{
+					Field:   field + ".urgency",
+					Message: fmt.Sprintf("无效的紧急程度 '%s'（可选: low, normal, critical）", n.Urgency),
+				})
+			}
+		}
+		if command != "" {
+			if _, err := exec.LookPath(command); err != nil {
+				result.Warnings = append(result.Warnings, ValidationError{
+					Field:   field,
+					Message: fmt.Sprintf("找不到命令 '%s'，该通知将无法发送", command),
+				})
+			}
+		}
+		if n.TimeoutSec < 0 {
+			result.Errors = append(result.Errors, ValidationError{
+				Field:   field + ".timeout-sec",
+				Message: "超时时间不能为负数",
+			})
+		}
+	}
+}
+
+// validatePostHooks 验证下载后处理钩子
+func validatePostHooks(cfg *structs.ConfigSet, result *ValidationResult) {
+	if len(cfg.PostHooks) == 0 {
+		return
+	}
+	if _, err := hooks.New(cfg.PostHooks); err != nil {
+		result.Errors = append(result.Errors, ValidationError{
+			Field:   "post-hooks",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	for i, h := range cfg.PostHooks {
+		if h.Command == "" {
+			continue
+		}
+		if _, err := exec.LookPath(h.Command); err != nil {
+			result.Warnings = append(result.Warnings, ValidationError{
+				Field:   fmt.Sprintf("post-hooks[%d].command", i),
+				Message: fmt.Sprintf("找不到命令 '%s'，该钩子将执行失败", h.Command),
+			})
+		}
+	}
+}
+
+// validateLoudness 验证响度分析配置
+func validateLoudness(cfg *structs.ConfigSet, result *ValidationResult) {
+	if cfg.Loudness.Threads < 0 {
+		result.Errors = append(result.Errors, ValidationError{
+			Field:   "loudness.threads",
+			Message: "并发数不能为负数",
+		})
+	}
+	if !cfg.Loudness.Enabled {
+		return
+	}
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		result.Warnings = append(result.Warnings, ValidationError{
+			Field:   "loudness.enabled",
+			Message: "找不到 ffmpeg，响度分析将无法进行",
+		})
+	}
+}
+
+// validateChecksums 验证校验清单配置
+func validateChecksums(cfg *structs.ConfigSet, result *ValidationResult) {
+	alg := strings.ToLower(cfg.Checksums.Algorithm)
+	if alg != "" && !checksum.IsAlgorithm(alg) {
+		result.Errors = append(result.Errors, ValidationError{
+			Field:   "checksums.algorithm",
+			Message: fmt.Sprintf("不支持的算法 '%s'（可选: %s）", cfg.Checksums.Algorithm, strings.Join(checksum.Algorithms, ", ")),
+		})
+	}
+}
+
+// validateReleaseRules 验证单曲 / EP 识别规则
+func validateReleaseRules(cfg *structs.ConfigSet, result *ValidationResult) {
+	rules := cfg.ReleaseRules
+	epModes := []string{"album", "single", "collection"}
+	isEPMode := func(mode string) bool {
+		for _, m := range epModes {
+			if strings.EqualFold(mode, m) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if rules.EPMode != "" && !isEPMode(rules.EPMode) {
+		result.Errors = append(result.Errors, ValidationError{
+			Field:   "release-rules.ep-mode",
+			Message: fmt.Sprintf("无效的 EP 处理方式 '%s'（可选: %s）", rules.EPMode, strings.Join(epModes, ", ")),
+		})
+	}
+	if rules.SingleMaxMinutes < 0 || rules.EPMaxTracks < 0 || rules.EPMaxMinutes < 0 {
+		result.Errors = append(result.Errors, ValidationError{
+			Field:   "release-rules",
+			Message: "single-max-minutes、ep-max-tracks、ep-max-minutes 不能为负数（0 表示不限制或不判断）",
+		})
+	}
+	singleMax := rules.SingleMaxTracks
+	if singleMax == 0 {
+		singleMax = 3
+	}
+	if rules.EPMaxTracks > 0 && singleMax >= rules.EPMaxTracks {
+		result.Warnings = append(result.Warnings, ValidationError{
+			Field:   "release-rules.ep-max-tracks",
+			Message: fmt.Sprintf("不大于 single-max-tracks (%d)，按曲目数不会识别出 EP", singleMax),
+		})
+	}
+	singlesName := cfg.VirtualSinglesFolderName
+	if singlesName == "" {
+		singlesName = "Singles"
+	}
+	if strings.EqualFold(rules.EPFolderName, singlesName) {
+		result.Warnings = append(result.Warnings, ValidationError{
+			Field:   "release-rules.ep-folder-name",
+			Message: "与 virtual-singles-folder-name 相同，EP 会与单曲混在同一目录",
+		})
+	}
+	for i, a := range rules.Artists {
+		field := fmt.Sprintf("release-rules.artists[%d]", i)
+		if strings.TrimSpace(a.Artist) == "" {
+			result.Errors = append(result.Errors, ValidationError{
+				Field:   field + ".artist",
+				Message: "艺术家名称或 ID 不能为空",
+			})
+		}
+		if a.Singles != "" && !strings.EqualFold(a.Singles, "virtual") && !strings.EqualFold(a.Singles, "album") {
+			result.Errors = append(result.Errors, ValidationError{
+				Field:   field + ".singles",
+				Message: fmt.Sprintf("无效的单曲处理方式 '%s'（可选: virtual, album）", a.Singles),
+			})
+		}
+		if a.EPs != "" && !isEPMode(a.EPs) {
+			result.Errors = append(result.Errors, ValidationError{
+				Field:   field + ".eps",
+				Message: fmt.Sprintf("无效的 EP 处理方式 '%s'（可选: %s）", a.EPs, strings.Join(epModes, ", ")),
+			})
+		}
+	}
+}
+
+// validateQualityPolicy 验证音质策略
+func validateQualityPolicy(cfg *structs.ConfigSet, result *ValidationResult) {
+	rules, err := quality.ParsePolicy(cfg.QualityPolicy)
+	if err != nil {
+		result.Errors = append(result.Errors, ValidationError{
+			Field:   "quality-policy",
+			Message: err.Error(),
+		})
+		return
+	}
+	// AAC 立体声总是可用，之后的档位不会被使用
+	for i, r := range rules {
+		if r.AlwaysAvailable() && i < len(rules)-1 {
+			result.Warnings = append(result.Warnings, ValidationError{
+				Field:   "quality-policy",
+				Message: fmt.Sprintf("档位 %s 之后的 %d 个档位不会被使用（AAC 立体声总是可用）", r, len(rules)-1-i),
+			})
+			break
+		}
+	}
+}
+
+// validateLibraryOutputs 验证多音乐库输出：每个音乐库只能输出一个档位
+func validateLibraryOutputs(cfg *structs.ConfigSet, result *ValidationResult) {
+	rules, err := quality.ParsePolicy(cfg.LibraryOutputs)
+	if err != nil {
+		result.Errors = append(result.Errors, ValidationError{
+			Field:   "library-outputs",
+			Message: err.Error(),
+		})
+		return
+	}
+	seen := make(map[string]quality.Rule)
+	for _, r := range rules {
+		if prev, ok := seen[r.Library()]; ok {
+			result.Errors = append(result.Errors, ValidationError{
+				Field:   "library-outputs",
+				Message: fmt.Sprintf("档位 %s 与 %s 都保存到 %s 音乐库，每个音乐库只能输出一个档位", prev, r, r.Library()),
+			})
+			continue
+		}
+		seen[r.Library()] = r
+	}
+	if len(rules) > 0 && len(cfg.QualityPolicy) > 0 {
+		result.Warnings = append(result.Warnings, ValidationError{
+			Field:   "quality-policy",
+			Message: "已启用多音乐库模式（library-outputs），音质策略不会被使用",
+		})
+	}
+}
+
+// validateMetadataLanguage 验证元数据语言规则、第二语言写入位置与文件夹语言
+func validateMetadataLanguage(cfg *structs.ConfigSet, result *ValidationResult) {
+	ml := cfg.MetadataLanguage
+	for i, r := range ml.Rules {
+		field := fmt.Sprintf("metadata-language.rules[%d]", i)
+		if strings.TrimSpace(r.Language) == "" {
+			result.Errors = append(result.Errors, ValidationError{
+				Field:   field + ".language",
+				Message: "语言规则必须设置 language",
+			})
+		}
+		if strings.TrimSpace(r.Storefront) == "" && strings.TrimSpace(r.Artist) == "" {
+			result.Warnings = append(result.Warnings, ValidationError{
+				Field:   field,
+				Message: "规则没有设置 storefront 或 artist，总是命中，之后的规则不会被使用",
+			})
+		}
+	}
+	switch strings.ToLower(ml.SecondaryTarget) {
+	case "", "sort", "custom":
+	default:
+		result.Errors = append(result.Errors, ValidationError{
+			Field:   "metadata-language.secondary-target",
+			Message: fmt.Sprintf("无效的第二语言写入位置 '%s'（可选: sort, custom）", ml.SecondaryTarget),
+		})
+	}
+	switch strings.ToLower(ml.Folder) {
+	case "", "primary":
+	case "secondary":
+		if strings.TrimSpace(ml.Secondary) == "" {
+			result.Warnings = append(result.Warnings, ValidationError{
+				Field:   "metadata-language.folder",
+				Message: "未设置第二语言（metadata-language.secondary），文件夹使用主语言",
+			})
+		}
+	default:
+		result.Errors = append(result.Errors, ValidationError{
+			Field:   "metadata-language.folder",
+			Message: fmt.Sprintf("无效的文件夹语言 '%s'（可选: primary, secondary）", ml.Folder),
+		})
+	}
+}
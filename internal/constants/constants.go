@@ -87,6 +87,12 @@ const (
 
 	// CleanupWaitSeconds 清理等待时间（秒）
 	CleanupWaitSeconds = 2
+
+	// StalePartFileAge 清理遗留 .part 临时文件时要求的最短未修改时长（避免误删其他实例正在写入的文件）
+	StalePartFileAge = 10 * time.Minute
+
+	// NotifyFlushTimeout 退出前等待通知送达的最长时间
+	NotifyFlushTimeout = 15 * time.Second
 )
 
 // ==================== 音质参数 ====================
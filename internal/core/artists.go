@@ -0,0 +1,186 @@
+package core
+
+import (
+	"strings"
+
+	"main/utils/structs"
+)
+
+// 合作分隔符（按优先级排序，优先检查英文分隔符，更明确的合作标识）
+var artistSeparators = []string{" & ", " ft. ", " feat. ", " featuring ", " / ", ", ", "、"}
+
+// Credit 一位参与艺术家
+type Credit struct {
+	ID   string // Apple Music 艺术家 ID，名称无法对应目录艺术家时为空
+	Name string
+}
+
+// separatorCut 名称字符串中一处合作分隔符的位置
+type separatorCut struct {
+	start, end int
+	sep        string
+}
+
+// findSeparators 按出现顺序返回名称中的合作分隔符
+// artist-exceptions 与 known（如目录返回的艺术家名称）中的名称整体保留，其中的分隔符不计入
+func findSeparators(artistName string, known []string) []separatorCut {
+	lower := strings.ToLower(artistName)
+	protected := make([]bool, len(lower))
+	for _, name := range append(append([]string(nil), Config.ArtistExceptions...), known...) {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		for from := 0; ; {
+			i := strings.Index(lower[from:], name)
+			if i < 0 {
+				break
+			}
+			for j := from + i; j < from+i+len(name); j++ {
+				protected[j] = true
+			}
+			from += i + len(name)
+		}
+	}
+
+	var cuts []separatorCut
+	for i := 0; i < len(lower); {
+		matched := false
+		for _, sep := range artistSeparators {
+			if !strings.HasPrefix(lower[i:], sep) || isProtected(protected, i, i+len(sep)) {
+				continue
+			}
+			cuts = append(cuts, separatorCut{start: i, end: i + len(sep), sep: sep})
+			i += len(sep)
+			matched = true
+			break
+		}
+		if !matched {
+			i++
+		}
+	}
+	return cuts
+}
+
+func isProtected(protected []bool, start, end int) bool {
+	for i := start; i < end; i++ {
+		if protected[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPrimaryArtist 从艺术家名称字符串中提取主要艺术家
+// 处理合作者情况：如果包含 " & " 或 " ft. " 或 ", " 等，则返回第一个艺术家
+// artist-exceptions 与 known 中的名称不拆分（如 "Simon & Garfunkel"、"Earth, Wind & Fire"）
+// 示例: "Olivia Rodrigo & Joshua Bassett" -> "Olivia Rodrigo"
+//
+//	"好妹妹, 秦昊Jeff, 张小厚" -> "好妹妹"
+func GetPrimaryArtist(artistName string, known ...string) string {
+	cuts := findSeparators(artistName, known)
+	for _, sep := range artistSeparators {
+		for _, c := range cuts {
+			if c.sep == sep {
+				return strings.TrimSpace(artistName[:c.start])
+			}
+		}
+	}
+	return artistName
+}
+
+// SplitArtists 按合作分隔符拆分艺术家名称字符串，artist-exceptions 与 known 中的名称不拆分
+func SplitArtists(artistName string, known ...string) []string {
+	var names []string
+	start := 0
+	for _, c := range findSeparators(artistName, known) {
+		names = appendName(names, artistName[start:c.start])
+		start = c.end
+	}
+	return appendName(names, artistName[start:])
+}
+
+func appendName(names []string, name string) []string {
+	if name = strings.TrimSpace(name); name != "" {
+		names = append(names, name)
+	}
+	return names
+}
+
+// ResolveCredits 将艺术家名称字符串解析为参与艺术家列表
+// related 为目录返回的艺术家（relationships.artists）：其名称不拆分并按名称补全艺术家 ID，
+// 名称字符串中没有出现的目录艺术家追加在最后
+func ResolveCredits(artistName string, related []Credit) []Credit {
+	var credits []Credit
+	used := make(map[int]bool)
+	for _, name := range SplitArtists(artistName, creditNames(related)...) {
+		c := Credit{Name: name}
+		for i, r := range related {
+			if !used[i] && strings.EqualFold(strings.TrimSpace(r.Name), name) {
+				c.ID = r.ID
+				used[i] = true
+				break
+			}
+		}
+		credits = append(credits, c)
+	}
+	for i, r := range related {
+		if !used[i] && r.Name != "" {
+			credits = append(credits, r)
+		}
+	}
+	return credits
+}
+
+// PrimaryCredit 返回主要艺术家：名称取自艺术家名称字符串，ID 按名称对应目录艺术家，
+// 对应不上时（如目录返回的是另一种语言的名称）使用第一个目录艺术家的 ID
+func PrimaryCredit(artistName string, related []Credit) Credit {
+	c := Credit{Name: GetPrimaryArtist(artistName, creditNames(related)...)}
+	for _, r := range related {
+		if strings.EqualFold(strings.TrimSpace(r.Name), c.Name) {
+			c.ID = r.ID
+			return c
+		}
+	}
+	if len(related) > 0 {
+		c.ID = related[0].ID
+	}
+	return c
+}
+
+func creditNames(credits []Credit) []string {
+	names := make([]string, len(credits))
+	for i, c := range credits {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// albumRelated 专辑的目录艺术家
+func albumRelated(meta *structs.AutoGenerated) []Credit {
+	var related []Credit
+	for _, a := range meta.Data[0].Relationships.Artists.Data {
+		related = append(related, Credit{ID: a.ID, Name: a.Attributes.Name})
+	}
+	return related
+}
+
+// AlbumCredits 专辑的参与艺术家
+func AlbumCredits(meta *structs.AutoGenerated) []Credit {
+	return ResolveCredits(meta.Data[0].Attributes.ArtistName, albumRelated(meta))
+}
+
+// TrackCredits 曲目的参与艺术家（index 为曲目在 meta 中的下标）
+func TrackCredits(meta *structs.AutoGenerated, index int) []Credit {
+	track := meta.Data[0].Relationships.Tracks.Data[index]
+	var related []Credit
+	for _, a := range track.Relationships.Artists.Data {
+		related = append(related, Credit{ID: a.ID, Name: a.Attributes.Name})
+	}
+	return ResolveCredits(track.Attributes.ArtistName, related)
+}
+
+// PrimaryArtist 专辑的主要艺术家名称（用于虚拟合辑的文件夹名与专辑艺术家标签）
+func PrimaryArtist(meta *structs.AutoGenerated) string {
+	return PrimaryCredit(meta.Data[0].Attributes.ArtistName, albumRelated(meta)).Name
+}
@@ -0,0 +1,86 @@
+package core
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"main/utils/structs"
+)
+
+// TestSplitArtists 测试拆分合作艺术家，名称中含有分隔符的艺术家整体保留
+func TestSplitArtists(t *testing.T) {
+	defer func(exceptions []string) { Config.ArtistExceptions = exceptions }(Config.ArtistExceptions)
+	Config.ArtistExceptions = []string{"Earth, Wind & Fire"}
+
+	tests := []struct {
+		artistName string
+		known      []string
+		want       []string
+	}{
+		{"Taylor Swift", nil, []string{"Taylor Swift"}},
+		{"Artist A & Artist B", nil, []string{"Artist A", "Artist B"}},
+		{"Primary Artist feat. Second Artist & Third Artist", nil, []string{"Primary Artist", "Second Artist", "Third Artist"}},
+		{"陈婧霏、王加一、韦唯", nil, []string{"陈婧霏", "王加一", "韦唯"}},
+		{"Simon & Garfunkel", []string{"Simon & Garfunkel"}, []string{"Simon & Garfunkel"}},
+		{"simon & garfunkel & Artist C", []string{"Simon & Garfunkel", "Artist C"}, []string{"simon & garfunkel", "Artist C"}},
+		{"Earth, Wind & Fire feat. Artist A", nil, []string{"Earth, Wind & Fire", "Artist A"}},
+		{"", nil, nil},
+	}
+	for _, tt := range tests {
+		if got := SplitArtists(tt.artistName, tt.known...); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitArtists(%q, %v) = %q, want %q", tt.artistName, tt.known, got, tt.want)
+		}
+	}
+
+	if got := GetPrimaryArtist("Earth, Wind & Fire & The Emotions"); got != "Earth, Wind & Fire" {
+		t.Errorf("GetPrimaryArtist with exception = %q", got)
+	}
+	if got := GetPrimaryArtist("Simon & Garfunkel", "Simon & Garfunkel"); got != "Simon & Garfunkel" {
+		t.Errorf("GetPrimaryArtist with known name = %q", got)
+	}
+}
+
+// TestResolveCredits 测试按目录艺术家补全 ID，以及按 ID 识别主要艺术家
+func TestResolveCredits(t *testing.T) {
+	related := []Credit{{ID: "1", Name: "Simon & Garfunkel"}, {ID: "2", Name: "Artist B"}, {ID: "3", Name: "Artist C"}}
+	got := ResolveCredits("Simon & Garfunkel & artist b", related)
+	want := []Credit{{ID: "1", Name: "Simon & Garfunkel"}, {ID: "2", Name: "artist b"}, {ID: "3", Name: "Artist C"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ResolveCredits = %+v, want %+v", got, want)
+	}
+
+	if c := PrimaryCredit("Simon & Garfunkel & Artist B", related); c.ID != "1" || c.Name != "Simon & Garfunkel" {
+		t.Errorf("PrimaryCredit = %+v", c)
+	}
+	// 目录返回其他语言的名称时使用第一个目录艺术家的 ID
+	if c := PrimaryCredit("周杰倫 & 蔡依林", []Credit{{ID: "9", Name: "Jay Chou"}}); c.ID != "9" || c.Name != "周杰倫" {
+		t.Errorf("PrimaryCredit fallback = %+v", c)
+	}
+	if c := PrimaryCredit("Artist A & Artist B", nil); c.ID != "" || c.Name != "Artist A" {
+		t.Errorf("PrimaryCredit without catalog = %+v", c)
+	}
+}
+
+// TestAlbumCredits 测试从专辑与曲目的 relationships.artists 解析参与艺术家
+func TestAlbumCredits(t *testing.T) {
+	var meta structs.AutoGenerated
+	err := json.Unmarshal([]byte(`{"data":[{"id":"100","attributes":{"artistName":"Earth, Wind & Fire & The Emotions"},
+		"relationships":{
+			"artists":{"data":[{"id":"11","attributes":{"name":"Earth, Wind & Fire"}},{"id":"12","attributes":{"name":"The Emotions"}}]},
+			"tracks":{"data":[{"id":"t1","attributes":{"artistName":"Earth, Wind & Fire feat. Guest"},
+				"relationships":{"artists":{"data":[{"id":"11","attributes":{"name":"Earth, Wind & Fire"}}]}}}]}}}]}`), &meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := PrimaryArtist(&meta); got != "Earth, Wind & Fire" {
+		t.Errorf("PrimaryArtist = %q", got)
+	}
+	if got := AlbumCredits(&meta); len(got) != 2 || got[1].ID != "12" {
+		t.Errorf("AlbumCredits = %+v", got)
+	}
+	want := []Credit{{ID: "11", Name: "Earth, Wind & Fire"}, {Name: "Guest"}}
+	if got := TrackCredits(&meta, 0); !reflect.DeepEqual(got, want) {
+		t.Errorf("TrackCredits = %+v, want %+v", got, want)
+	}
+}
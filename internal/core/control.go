@@ -0,0 +1,110 @@
+package core
+
+import (
+	"context"
+	"sync"
+)
+
+// Control 任务运行期间可动态调整的控制状态
+// 由全屏界面的快捷键修改；下载流程在派发新专辑、开始新曲目前检查暂停状态，
+// 批量模式在每个检查点读取工作-休息循环开关
+type Control struct {
+	mu       sync.Mutex
+	paused   bool
+	resumed  chan struct{} // 暂停期间保持打开，恢复时关闭
+	workRest bool
+	changed  chan struct{} // 工作-休息开关变化时关闭并替换，用于广播
+}
+
+// NewControl 创建未暂停、未启用工作-休息循环的控制状态
+func NewControl() *Control {
+	return &Control{changed: make(chan struct{})}
+}
+
+// Pause 暂停：进行中的曲目继续完成，之后不再开始新的曲目和专辑
+func (c *Control) Pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.paused {
+		c.paused = true
+		c.resumed = make(chan struct{})
+	}
+}
+
+// Resume 恢复下载
+func (c *Control) Resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.paused {
+		c.paused = false
+		close(c.resumed)
+	}
+}
+
+// TogglePause 切换暂停状态，返回切换后是否处于暂停
+func (c *Control) TogglePause() bool {
+	if c.Paused() {
+		c.Resume()
+		return false
+	}
+	c.Pause()
+	return true
+}
+
+// Paused 返回当前是否处于暂停
+func (c *Control) Paused() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused
+}
+
+// WaitIfPaused 暂停期间阻塞，直到恢复或 ctx 取消；ctx 取消时返回其错误
+func (c *Control) WaitIfPaused(ctx context.Context) error {
+	c.mu.Lock()
+	if !c.paused {
+		c.mu.Unlock()
+		return nil
+	}
+	resumed := c.resumed
+	c.mu.Unlock()
+
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SetWorkRest 设置工作-休息循环开关
+func (c *Control) SetWorkRest(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.workRest == enabled {
+		return
+	}
+	c.workRest = enabled
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// ToggleWorkRest 切换工作-休息循环开关，返回切换后的状态
+func (c *Control) ToggleWorkRest() bool {
+	enabled := !c.WorkRestEnabled()
+	c.SetWorkRest(enabled)
+	return enabled
+}
+
+// WorkRestEnabled 返回工作-休息循环是否启用
+func (c *Control) WorkRestEnabled() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.workRest
+}
+
+// WorkRestChanged 返回在下一次开关变化时关闭的通道（休息中关闭循环时用于提前结束休息）
+func (c *Control) WorkRestChanged() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.changed
+}
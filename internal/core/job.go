@@ -0,0 +1,444 @@
+package core
+
+import (
+	"fmt"
+	"main/internal/quality"
+	"main/utils/structs"
+	"strings"
+	"sync"
+)
+
+// Options 单次下载任务的运行参数
+// 值类型，可安全复制：派生任务（如单曲链接、艺术家链接展开）通过修改副本实现，不会影响其他任务
+type Options struct {
+	Atmos  bool // 杜比全景声下载模式
+	AAC    bool // AAC 下载模式
+	Song   bool // 单曲下载模式（仅下载链接中 ?i= 指定的曲目）
+	Select bool // 交互式选择曲目
+	Force  bool // 强制下载，覆盖已存在的文件
+
+	AlacMax     int    // ALAC 最大采样率
+	AtmosMax    int    // Atmos 最大码率
+	AacType     string // AAC 类型（aac, aac-lc, aac-binaural, aac-downmix）
+	MvMax       int    // MV 最大分辨率
+	MvAudioType string // MV 音轨类型（atmos, ac3, aac）
+
+	// QualityPolicy 音质策略：按顺序尝试的档位（如 hires<=96000、lossless、aac）
+	// 为空时按 Atmos / AAC / AlacMax 等参数选择；专辑按策略选定档位后，保留选定档位及之后的档位供曲目回退
+	QualityPolicy []string
+
+	// LibraryOutputs 多音乐库模式：每个专辑依次输出的档位（如 alac、atmos），为空时只输出一个档位
+	LibraryOutputs []string
+	// StrictQuality 曲目没有策略中的档位时跳过，不回退到码率最高的档位（多音乐库模式的各个输出）
+	StrictQuality bool
+
+	// MaxTracks 所有专辑合计同时下载的曲目数上限（0 表示不限制），仅在 NewJob 时生效
+	MaxTracks int
+
+	// ArtistFolderFormat 艺术家文件夹命名格式
+	// 艺术家链接展开时会替换其中的 {UrlArtistName}/{ArtistId}，仅作用于该艺术家的专辑
+	ArtistFolderFormat string
+}
+
+// Codec 返回当前下载模式对应的编码名称
+func (o Options) Codec() string {
+	if o.Atmos {
+		return "ATMOS"
+	} else if o.AAC {
+		return "AAC"
+	}
+	return "ALAC"
+}
+
+// Rule 返回当前下载模式对应的主档位（决定保存目录与质量标签）
+func (o Options) Rule() quality.Rule {
+	if o.Atmos {
+		return quality.Rule{Tier: quality.TierAtmos, Max: o.AtmosMax}
+	} else if o.AAC {
+		if o.AacType == "" {
+			return quality.Rule{Tier: quality.TierAAC}
+		}
+		return quality.Rule{Tier: o.AacType}
+	}
+	return quality.Rule{Tier: quality.TierAlac, Max: o.AlacMax}
+}
+
+// Rules 返回为曲目选择档位时按顺序尝试的档位
+// 未设置音质策略时由下载模式推导：杜比全景声模式在没有全景声档位时接受杜比音效
+func (o Options) Rules() []quality.Rule {
+	if len(o.QualityPolicy) > 0 {
+		// 策略在启动时已校验，这里忽略无效项
+		var rules []quality.Rule
+		for _, item := range o.QualityPolicy {
+			if r, err := quality.ParseRule(item); err == nil {
+				rules = append(rules, r)
+			}
+		}
+		return rules
+	}
+	rules := []quality.Rule{o.Rule()}
+	if o.Atmos {
+		rules = append(rules, quality.Rule{Tier: quality.TierAC3})
+	}
+	return rules
+}
+
+// WithPolicyChoice 返回按音质策略选定 rules[index] 后的下载参数
+// 下载模式与上限改为选定档位对应的值，策略保留选定档位及之后的档位
+func (o Options) WithPolicyChoice(rules []quality.Rule, index int) Options {
+	r := rules[index]
+	o.Atmos, o.AAC = false, false
+	switch {
+	case r.IsDolby():
+		o.Atmos = true
+		if r.Max > 0 {
+			o.AtmosMax = r.Max
+		}
+	case r.IsAAC():
+		o.AAC = true
+		o.AacType = r.Tier
+	case r.Tier == quality.TierLossless:
+		o.AlacMax = 48000
+	default:
+		o.AlacMax = r.Max
+		if o.AlacMax == 0 {
+			o.AlacMax = 192000
+		}
+	}
+	o.QualityPolicy = nil
+	for _, rest := range rules[index:] {
+		o.QualityPolicy = append(o.QualityPolicy, rest.String())
+	}
+	return o
+}
+
+// OutputRules 返回多音乐库模式的输出档位（启动时已校验，这里忽略无效项）
+func (o Options) OutputRules() []quality.Rule {
+	var rules []quality.Rule
+	for _, item := range o.LibraryOutputs {
+		if r, err := quality.ParseRule(item); err == nil {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// WithOutput 返回多音乐库模式中输出档位 r 的下载参数：只下载该档位，曲目没有该档位时跳过
+func (o Options) WithOutput(r quality.Rule) Options {
+	o = o.WithPolicyChoice([]quality.Rule{r}, 0)
+	if r.Tier == quality.TierAtmos {
+		// 与杜比全景声下载模式一致，没有全景声档位时接受杜比音效
+		o.QualityPolicy = append(o.QualityPolicy, quality.TierAC3)
+	}
+	o.LibraryOutputs = nil
+	o.StrictQuality = true
+	return o
+}
+
+// jobShared 在同一任务派生出的所有 Job 之间共享的状态
+type jobShared struct {
+	mu         sync.Mutex
+	counter    structs.Counter
+	okDict     map[string][]int // key: 编码/albumId，value: 已完成的曲目序号
+	boards     *BoardSet        // 多专辑并行时的状态面板集合，nil 表示每个专辑独占终端
+	trackSlots chan struct{}    // 全局曲目下载并发令牌，nil 表示不限制
+	control    *Control         // 暂停/继续、工作-休息开关等运行期控制
+	matches    []StorefrontMatch
+	// trackNumbers 曲目的有效编号（key: trackID），确保文件名和标签使用相同的编号
+	trackNumbers map[string]int
+}
+
+// Job 单次下载任务的上下文
+// 取代原先的包级全局变量（Dl_atmos、Alac_max、OkDict、Counter、TrackStatuses 等），
+// 使同一进程内可以同时运行多个互不干扰的任务（服务模式、作为库调用或并行测试）
+type Job struct {
+	Options
+
+	// Board 动态UI使用的曲目状态面板
+	Board *TrackBoard
+
+	shared *jobShared
+}
+
+// NewJob 使用给定的运行参数创建任务上下文
+func NewJob(opts Options) *Job {
+	shared := &jobShared{
+		okDict:       make(map[string][]int),
+		control:      NewControl(),
+		trackNumbers: make(map[string]int),
+	}
+	if opts.MaxTracks > 0 {
+		shared.trackSlots = make(chan struct{}, opts.MaxTracks)
+	}
+	return &Job{
+		Options: opts,
+		Board:   NewTrackBoard(),
+		shared:  shared,
+	}
+}
+
+// WithOptions 派生一个使用不同运行参数的任务
+// 派生任务与原任务共享统计计数、完成记录和状态面板
+func (j *Job) WithOptions(opts Options) *Job {
+	return &Job{
+		Options: opts,
+		Board:   j.Board,
+		shared:  j.shared,
+	}
+}
+
+// WithBoard 派生一个使用独立状态面板的任务（用于多专辑并行）
+func (j *Job) WithBoard(board *TrackBoard) *Job {
+	return &Job{
+		Options: j.Options,
+		Board:   board,
+		shared:  j.shared,
+	}
+}
+
+// UseBoardSet 启用多专辑状态面板集合，之后各专辑不再独占终端，由调用方统一渲染
+// 必须在任务开始下载前调用
+func (j *Job) UseBoardSet(set *BoardSet) {
+	j.shared.mu.Lock()
+	defer j.shared.mu.Unlock()
+	j.shared.boards = set
+}
+
+// BoardSet 返回多专辑状态面板集合，未启用时返回 nil
+func (j *Job) BoardSet() *BoardSet {
+	j.shared.mu.Lock()
+	defer j.shared.mu.Unlock()
+	return j.shared.boards
+}
+
+// Control 返回任务的运行期控制状态（所有派生任务共享）
+func (j *Job) Control() *Control {
+	return j.shared.control
+}
+
+// AcquireTrackSlot 获取一个全局曲目下载令牌，达到 MaxTracks 上限时阻塞
+func (j *Job) AcquireTrackSlot() {
+	if j.shared.trackSlots != nil {
+		j.shared.trackSlots <- struct{}{}
+	}
+}
+
+// ReleaseTrackSlot 归还全局曲目下载令牌
+func (j *Job) ReleaseTrackSlot() {
+	if j.shared.trackSlots != nil {
+		<-j.shared.trackSlots
+	}
+}
+
+// UpdateCounter 在锁保护下修改任务统计
+func (j *Job) UpdateCounter(fn func(c *structs.Counter)) {
+	j.shared.mu.Lock()
+	defer j.shared.mu.Unlock()
+	fn(&j.shared.counter)
+}
+
+// Counter 返回任务统计的快照
+func (j *Job) Counter() structs.Counter {
+	j.shared.mu.Lock()
+	defer j.shared.mu.Unlock()
+	return j.shared.counter
+}
+
+// StorefrontMatch 跨地区匹配记录：专辑在链接地区无法访问时，改为下载其他地区目录中的同一发行
+type StorefrontMatch struct {
+	AlbumID           string `json:"album_id"`
+	AlbumName         string `json:"album_name"`
+	Storefront        string `json:"storefront"`
+	MatchedID         string `json:"matched_id"`
+	MatchedStorefront string `json:"matched_storefront"`
+	By                string `json:"by"`      // upc / isrc
+	Matched           int    `json:"matched"` // 按 ISRC 对应的曲目数
+	Total             int    `json:"total"`
+}
+
+func (m StorefrontMatch) String() string {
+	return fmt.Sprintf("%s (%s/%s) → %s/%s（按 %s 匹配，%d/%d 首曲目按 ISRC 对应）",
+		m.AlbumName, m.Storefront, m.AlbumID, m.MatchedStorefront, m.MatchedID, strings.ToUpper(m.By), m.Matched, m.Total)
+}
+
+// RecordMatch 记录跨地区匹配，批量任务结束时在汇总中列出
+func (j *Job) RecordMatch(m StorefrontMatch) {
+	j.shared.mu.Lock()
+	defer j.shared.mu.Unlock()
+	j.shared.matches = append(j.shared.matches, m)
+}
+
+// Matches 返回本任务（含派生任务）的跨地区匹配记录
+func (j *Job) Matches() []StorefrontMatch {
+	j.shared.mu.Lock()
+	defer j.shared.mu.Unlock()
+	return append([]StorefrontMatch(nil), j.shared.matches...)
+}
+
+// doneKey 完成记录按编码区分，多音乐库模式下同一专辑的各个输出互不影响
+func (j *Job) doneKey(albumId string) string {
+	return j.Codec() + "/" + albumId
+}
+
+// MarkDone 记录专辑中某首曲目已完成（trackNum 为曲目在专辑中的序号，-1 表示跳过的 MV）
+func (j *Job) MarkDone(albumId string, trackNum int) {
+	j.shared.mu.Lock()
+	defer j.shared.mu.Unlock()
+	key := j.doneKey(albumId)
+	j.shared.okDict[key] = append(j.shared.okDict[key], trackNum)
+}
+
+// IsDone 判断专辑中某首曲目是否已完成（按当前下载模式的编码区分）
+func (j *Job) IsDone(albumId string, trackNum int) bool {
+	j.shared.mu.Lock()
+	defer j.shared.mu.Unlock()
+	for _, n := range j.shared.okDict[j.doneKey(albumId)] {
+		if n == trackNum {
+			return true
+		}
+	}
+	return false
+}
+
+// SetEffectiveTrackNumber 设置曲目的有效编号
+// 虚拟Singles的编号在下载时按目标目录分配一次，写入标签时使用同一编号
+func (j *Job) SetEffectiveTrackNumber(trackID string, num int) {
+	j.shared.mu.Lock()
+	defer j.shared.mu.Unlock()
+	j.shared.trackNumbers[trackID] = num
+}
+
+// EffectiveTrackNumber 获取曲目的有效编号，没有设置时返回 -1
+func (j *Job) EffectiveTrackNumber(trackID string) int {
+	j.shared.mu.Lock()
+	defer j.shared.mu.Unlock()
+	if num, ok := j.shared.trackNumbers[trackID]; ok {
+		return num
+	}
+	return -1
+}
+
+// TrackBoard 曲目状态面板，保存当前批次每首曲目的显示状态
+type TrackBoard struct {
+	mu       sync.Mutex
+	title    string // 多专辑并行时显示的标题（专辑名）
+	statuses []TrackStatus
+	skipped  bool // 用户要求跳过该专辑，尚未开始的曲目不再下载
+}
+
+// NewTrackBoard 创建空的曲目状态面板
+func NewTrackBoard() *TrackBoard {
+	return &TrackBoard{}
+}
+
+// SetTitle 设置面板标题
+func (b *TrackBoard) SetTitle(title string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.title = title
+}
+
+// Title 返回面板标题
+func (b *TrackBoard) Title() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.title
+}
+
+// Reset 用新批次的曲目状态替换面板内容
+func (b *TrackBoard) Reset(statuses []TrackStatus) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.statuses = statuses
+}
+
+// Update 在锁保护下修改指定曲目的状态，索引越界时返回 false
+func (b *TrackBoard) Update(index int, fn func(ts *TrackStatus)) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if index < 0 || index >= len(b.statuses) {
+		return false
+	}
+	fn(&b.statuses[index])
+	return true
+}
+
+// Snapshot 返回当前曲目状态的副本
+func (b *TrackBoard) Snapshot() []TrackStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]TrackStatus, len(b.statuses))
+	copy(out, b.statuses)
+	return out
+}
+
+// Skip 标记跳过该专辑
+func (b *TrackBoard) Skip() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.skipped = true
+}
+
+// Skipped 返回该专辑是否已被要求跳过
+func (b *TrackBoard) Skipped() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.skipped
+}
+
+// FailedCount 返回当前批次中最终失败的曲目数
+func (b *TrackBoard) FailedCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := 0
+	for _, ts := range b.statuses {
+		if ts.Failed {
+			n++
+		}
+	}
+	return n
+}
+
+// BoardSet 多专辑并行时正在下载的状态面板集合，按加入顺序显示
+type BoardSet struct {
+	mu     sync.Mutex
+	boards []*TrackBoard
+}
+
+// NewBoardSet 创建空的状态面板集合
+func NewBoardSet() *BoardSet {
+	return &BoardSet{}
+}
+
+// Add 加入一个状态面板（重复加入会被忽略）
+func (s *BoardSet) Add(b *TrackBoard) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.boards {
+		if existing == b {
+			return
+		}
+	}
+	s.boards = append(s.boards, b)
+}
+
+// Remove 移除一个状态面板
+func (s *BoardSet) Remove(b *TrackBoard) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, existing := range s.boards {
+		if existing == b {
+			s.boards = append(s.boards[:i], s.boards[i+1:]...)
+			return
+		}
+	}
+}
+
+// Boards 返回当前状态面板列表的副本
+func (s *BoardSet) Boards() []*TrackBoard {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]*TrackBoard, len(s.boards))
+	copy(out, s.boards)
+	return out
+}
@@ -0,0 +1,263 @@
+package core
+
+import (
+	"main/utils/structs"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestJobIsolation 测试不同任务之间的统计互不干扰
+func TestJobIsolation(t *testing.T) {
+	t.Parallel()
+
+	alac := NewJob(Options{AlacMax: 192000})
+	atmos := NewJob(Options{Atmos: true, AtmosMax: 2768})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			alac.UpdateCounter(func(c *structs.Counter) { c.Total++; c.Success++ })
+		}()
+		go func() {
+			defer wg.Done()
+			atmos.UpdateCounter(func(c *structs.Counter) { c.Total++; c.Error++ })
+		}()
+	}
+	wg.Wait()
+
+	if got := alac.Counter(); got.Total != 50 || got.Success != 50 || got.Error != 0 {
+		t.Errorf("ALAC 任务统计错误: %+v", got)
+	}
+	if got := atmos.Counter(); got.Total != 50 || got.Error != 50 || got.Success != 0 {
+		t.Errorf("Atmos 任务统计错误: %+v", got)
+	}
+
+	alac.MarkDone("album1", 3)
+	if atmos.IsDone("album1", 3) {
+		t.Error("完成记录不应跨任务共享")
+	}
+	if !alac.IsDone("album1", 3) {
+		t.Error("完成记录丢失")
+	}
+
+	alac.SetEffectiveTrackNumber("1440", 7)
+	if n := atmos.EffectiveTrackNumber("1440"); n != -1 {
+		t.Errorf("有效曲目编号不应跨任务共享: %d", n)
+	}
+	if n := alac.WithOptions(alac.Options).EffectiveTrackNumber("1440"); n != 7 {
+		t.Errorf("派生任务应共享有效曲目编号: %d", n)
+	}
+}
+
+// TestJobWithOptions 测试派生任务：参数独立，统计共享
+func TestJobWithOptions(t *testing.T) {
+	t.Parallel()
+
+	job := NewJob(Options{ArtistFolderFormat: "{UrlArtistName}"})
+
+	opts := job.Options
+	opts.Song = true
+	opts.ArtistFolderFormat = "Taylor Swift"
+	derived := job.WithOptions(opts)
+
+	if job.Song || job.ArtistFolderFormat != "{UrlArtistName}" {
+		t.Errorf("派生任务不应修改原任务参数: %+v", job.Options)
+	}
+	if !derived.Song || derived.ArtistFolderFormat != "Taylor Swift" {
+		t.Errorf("派生任务参数错误: %+v", derived.Options)
+	}
+
+	derived.UpdateCounter(func(c *structs.Counter) { c.Total++ })
+	derived.MarkDone("album1", 1)
+	if job.Counter().Total != 1 {
+		t.Errorf("派生任务应共享统计，实际 Total=%d", job.Counter().Total)
+	}
+	if !job.IsDone("album1", 1) {
+		t.Error("派生任务应共享完成记录")
+	}
+
+	derived.RecordMatch(StorefrontMatch{AlbumID: "1", Storefront: "us", MatchedID: "2", MatchedStorefront: "jp", By: "upc"})
+	if m := job.Matches(); len(m) != 1 || m[0].MatchedStorefront != "jp" {
+		t.Errorf("派生任务应共享跨地区匹配记录: %+v", m)
+	}
+	if derived.Board != job.Board {
+		t.Error("派生任务应共享状态面板")
+	}
+}
+
+// TestOptionsCodec 测试编码名称
+func TestOptionsCodec(t *testing.T) {
+	tests := []struct {
+		opts     Options
+		expected string
+	}{
+		{Options{}, "ALAC"},
+		{Options{Atmos: true}, "ATMOS"},
+		{Options{AAC: true}, "AAC"},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.Codec(); got != tt.expected {
+			t.Errorf("Codec() = %q, 期望 %q", got, tt.expected)
+		}
+	}
+}
+
+// TestOptionsPolicyChoice 测试按音质策略选定档位后的下载参数
+func TestOptionsPolicyChoice(t *testing.T) {
+	if rules := (Options{Atmos: true, AtmosMax: 2768}).Rules(); len(rules) != 2 || rules[0].String() != "atmos<=2768" || rules[1].String() != "ac3" {
+		t.Errorf("atmos rules = %v", rules)
+	}
+
+	opts := Options{AlacMax: 192000, AacType: "aac", QualityPolicy: []string{"atmos", "hires<=96000", "lossless", "aac-binaural"}}
+	rules := opts.Rules()
+	if len(rules) != 4 {
+		t.Fatalf("rules = %v", rules)
+	}
+
+	hires := opts.WithPolicyChoice(rules, 1)
+	if hires.Atmos || hires.AAC || hires.AlacMax != 96000 {
+		t.Errorf("hires options = %+v", hires)
+	}
+	if len(hires.QualityPolicy) != 3 || hires.QualityPolicy[0] != "hires<=96000" {
+		t.Errorf("remaining policy = %v, want hires and later tiers", hires.QualityPolicy)
+	}
+	if lossless := opts.WithPolicyChoice(rules, 2); lossless.AlacMax != 48000 || lossless.Rule().String() != "alac<=48000" {
+		t.Errorf("lossless options = %+v", lossless)
+	}
+	if atmos := opts.WithPolicyChoice(rules, 0); !atmos.Atmos || atmos.Codec() != "ATMOS" {
+		t.Errorf("atmos options = %+v", atmos)
+	}
+	if aac := opts.WithPolicyChoice(rules, 3); !aac.AAC || aac.AacType != "aac-binaural" {
+		t.Errorf("aac options = %+v", aac)
+	}
+	if len(opts.QualityPolicy) != 4 {
+		t.Error("original options should not change")
+	}
+}
+
+// TestOptionsWithOutput 测试多音乐库模式各个输出的下载参数与完成记录
+func TestOptionsWithOutput(t *testing.T) {
+	job := NewJob(Options{AlacMax: 192000, AtmosMax: 2768, QualityPolicy: []string{"aac"}, LibraryOutputs: []string{"alac", "atmos", "bogus"}})
+	rules := job.OutputRules()
+	if len(rules) != 2 {
+		t.Fatalf("output rules = %v", rules)
+	}
+
+	alac := job.WithOptions(job.WithOutput(rules[0]))
+	if alac.Atmos || alac.AAC || alac.AlacMax != 192000 || !alac.StrictQuality || alac.LibraryOutputs != nil {
+		t.Errorf("alac output = %+v", alac.Options)
+	}
+	atmos := job.WithOptions(job.WithOutput(rules[1]))
+	if !atmos.Atmos || atmos.Codec() != "ATMOS" {
+		t.Errorf("atmos output = %+v", atmos.Options)
+	}
+	if got := atmos.Rules(); len(got) != 2 || got[1].Tier != "ac3" {
+		t.Errorf("atmos output rules = %v, want atmos then ac3", got)
+	}
+
+	// 完成记录按编码区分：ALAC 输出完成的曲目在全景声输出中仍需下载
+	alac.MarkDone("album1", 1)
+	if atmos.IsDone("album1", 1) {
+		t.Error("完成记录不应跨编码共享")
+	}
+	if !alac.IsDone("album1", 1) || !job.IsDone("album1", 1) {
+		t.Error("同一编码的派生任务应共享完成记录")
+	}
+}
+
+// TestTrackBoard 测试状态面板的更新与快照
+func TestTrackBoard(t *testing.T) {
+	board := NewTrackBoard()
+	board.Reset([]TrackStatus{{Index: 0, Status: "等待中"}, {Index: 1, Status: "等待中"}})
+
+	if !board.Update(1, func(ts *TrackStatus) { ts.Status = "下载完成" }) {
+		t.Fatal("索引 1 应更新成功")
+	}
+	if board.Update(2, func(ts *TrackStatus) {}) {
+		t.Error("越界索引不应更新成功")
+	}
+
+	snapshot := board.Snapshot()
+	if snapshot[1].Status != "下载完成" {
+		t.Errorf("状态未更新: %q", snapshot[1].Status)
+	}
+
+	// 快照是副本，修改不影响面板
+	snapshot[0].Status = "已修改"
+	if board.Snapshot()[0].Status != "等待中" {
+		t.Error("快照修改不应影响面板")
+	}
+}
+
+// TestBoardSet 测试状态面板集合的增删与派生任务共享
+func TestBoardSet(t *testing.T) {
+	set := NewBoardSet()
+	a, b := NewTrackBoard(), NewTrackBoard()
+	set.Add(a)
+	set.Add(b)
+	set.Add(a)
+	if got := set.Boards(); len(got) != 2 || got[0] != a || got[1] != b {
+		t.Fatalf("Boards() = %v, want [a b]", got)
+	}
+	set.Remove(a)
+	if got := set.Boards(); len(got) != 1 || got[0] != b {
+		t.Fatalf("after Remove, Boards() = %v, want [b]", got)
+	}
+
+	job := NewJob(Options{})
+	if job.BoardSet() != nil {
+		t.Fatal("new job should not have a board set")
+	}
+	derived := job.WithBoard(NewTrackBoard())
+	job.UseBoardSet(set)
+	if derived.BoardSet() != set {
+		t.Error("board set should be shared with derived jobs")
+	}
+	if derived.Board == job.Board {
+		t.Error("WithBoard should use the given board")
+	}
+}
+
+// TestTrackSlots 测试全局曲目并发上限在派生任务间共同生效
+func TestTrackSlots(t *testing.T) {
+	job := NewJob(Options{MaxTracks: 2})
+	other := job.WithBoard(NewTrackBoard())
+
+	var active, peak int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		j := job
+		if i%2 == 1 {
+			j = other
+		}
+		go func(j *Job) {
+			defer wg.Done()
+			j.AcquireTrackSlot()
+			defer j.ReleaseTrackSlot()
+			n := atomic.AddInt32(&active, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&active, -1)
+		}(j)
+	}
+	wg.Wait()
+	if peak > 2 {
+		t.Errorf("peak concurrent tracks = %d, want <= 2", peak)
+	}
+
+	// 未设置上限时不阻塞
+	unlimited := NewJob(Options{})
+	for i := 0; i < 100; i++ {
+		unlimited.AcquireTrackSlot()
+	}
+}
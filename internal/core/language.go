@@ -0,0 +1,76 @@
+package core
+
+import (
+	"strings"
+
+	"main/utils/structs"
+)
+
+// 第二语言写入的位置
+const (
+	SecondaryTargetSort   = "sort"   // 排序字段（TitleSort、ArtistSort 等）
+	SecondaryTargetCustom = "custom" // 自定义字段（ALT_TITLE、ALT_ARTIST 等）
+)
+
+// 文件夹与文件名使用的语言
+const (
+	FolderLanguagePrimary   = "primary"
+	FolderLanguageSecondary = "secondary"
+)
+
+// MetadataLanguage 按 metadata-language.rules 选择目录请求的语言（l= 参数）
+// 规则按顺序匹配，第一个命中的规则生效，未命中时使用 language；
+// meta 为 nil 时（获取元数据之前艺术家未知）跳过设置了艺术家的规则
+func MetadataLanguage(storefront string, meta *structs.AutoGenerated) string {
+	for _, r := range Config.MetadataLanguage.Rules {
+		if r.Language == "" {
+			continue
+		}
+		if r.Storefront != "" && !strings.EqualFold(strings.TrimSpace(r.Storefront), storefront) {
+			continue
+		}
+		if r.Artist != "" && (meta == nil || !matchArtist(meta, r.Artist)) {
+			continue
+		}
+		return r.Language
+	}
+	return Config.Language
+}
+
+// SecondaryLanguage 第二语言；未设置或与主语言相同时返回空
+func SecondaryLanguage(primary string) string {
+	lang := strings.TrimSpace(Config.MetadataLanguage.Secondary)
+	if lang == "" || strings.EqualFold(lang, primary) {
+		return ""
+	}
+	return lang
+}
+
+// SecondaryTrack 第二语言元数据中的对应曲目（两份元数据来自同一专辑，按位置对应）
+func SecondaryTrack(meta *structs.AutoGenerated, index int) (structs.TrackData, bool) {
+	if meta.Secondary == nil || len(meta.Secondary.Data) == 0 {
+		return structs.TrackData{}, false
+	}
+	tracks := meta.Secondary.Data[0].Relationships.Tracks.Data
+	if index < 0 || index >= len(tracks) {
+		return structs.TrackData{}, false
+	}
+	return tracks[index], true
+}
+
+// FolderNames 返回文件夹与文件名使用的元数据与曲目
+// metadata-language.folder 为 secondary 且已获取第二语言元数据时使用第二语言，否则原样返回
+func FolderNames(meta *structs.AutoGenerated, track structs.TrackData) (*structs.AutoGenerated, structs.TrackData) {
+	if !strings.EqualFold(Config.MetadataLanguage.Folder, FolderLanguageSecondary) || meta.Secondary == nil || len(meta.Secondary.Data) == 0 {
+		return meta, track
+	}
+	for i, t := range meta.Data[0].Relationships.Tracks.Data {
+		if t.ID == track.ID {
+			if sec, ok := SecondaryTrack(meta, i); ok {
+				return meta.Secondary, sec
+			}
+			break
+		}
+	}
+	return meta.Secondary, track
+}
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"main/utils/structs"
+)
+
+// TestMetadataLanguage 测试按地区与艺术家选择元数据语言
+func TestMetadataLanguage(t *testing.T) {
+	origConfig := Config
+	defer func() { Config = origConfig }()
+
+	Config.Language = "en-US"
+	Config.MetadataLanguage.Rules = []structs.LanguageRule{
+		{Artist: "周杰倫", Language: "zh-TW"},
+		{Storefront: "jp", Language: "ja"},
+		{Storefront: "jp", Artist: "Unused"},
+	}
+	jay := createTestMeta("1", "范特西", "周杰倫", false, 10)
+	other := createTestMeta("2", "Album", "宇多田ヒカル", false, 10)
+
+	tests := []struct {
+		storefront string
+		meta       *structs.AutoGenerated
+		want       string
+	}{
+		{"jp", nil, "ja"}, // 获取元数据之前只按地区匹配
+		{"JP", other, "ja"},
+		{"jp", jay, "zh-TW"}, // 艺术家规则在前，优先命中
+		{"tw", jay, "zh-TW"},
+		{"us", other, "en-US"},
+	}
+	for _, tt := range tests {
+		if got := MetadataLanguage(tt.storefront, tt.meta); got != tt.want {
+			t.Errorf("MetadataLanguage(%q) = %q, want %q", tt.storefront, got, tt.want)
+		}
+	}
+
+	Config.MetadataLanguage.Secondary = "EN-us"
+	if got := SecondaryLanguage("en-US"); got != "" {
+		t.Errorf("secondary equal to primary should be disabled, got %q", got)
+	}
+	if got := SecondaryLanguage("ja"); got != "EN-us" {
+		t.Errorf("SecondaryLanguage = %q", got)
+	}
+}
+
+// TestFolderNames 测试文件夹按配置使用第二语言的名称
+func TestFolderNames(t *testing.T) {
+	origConfig := Config
+	defer func() { Config = origConfig }()
+
+	var meta, secondary structs.AutoGenerated
+	if err := json.Unmarshal([]byte(`{"data":[{"id":"1","attributes":{"name":"ファンタジー","artistName":"宇多田ヒカル"},
+		"relationships":{"tracks":{"data":[{"id":"t1","attributes":{"name":"一曲目"}},{"id":"t2","attributes":{"name":"二曲目"}}]}}}]}`), &meta); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{"data":[{"id":"1","attributes":{"name":"Fantasy","artistName":"Hikaru Utada"},
+		"relationships":{"tracks":{"data":[{"id":"t1","attributes":{"name":"Track One"}},{"id":"t2","attributes":{"name":"Track Two"}}]}}}]}`), &secondary); err != nil {
+		t.Fatal(err)
+	}
+	track := meta.Data[0].Relationships.Tracks.Data[1]
+
+	if names, nameTrack := FolderNames(&meta, track); names != &meta || nameTrack.Attributes.Name != "二曲目" {
+		t.Errorf("without secondary metadata, FolderNames should return the primary names")
+	}
+	meta.Secondary = &secondary
+	if _, nameTrack := FolderNames(&meta, track); nameTrack.Attributes.Name != "二曲目" {
+		t.Errorf("folder language defaults to primary, got %q", nameTrack.Attributes.Name)
+	}
+
+	Config.MetadataLanguage.Folder = FolderLanguageSecondary
+	names, nameTrack := FolderNames(&meta, track)
+	if names.Data[0].Attributes.Name != "Fantasy" || nameTrack.Attributes.Name != "Track Two" {
+		t.Errorf("FolderNames = %q / %q, want secondary names", names.Data[0].Attributes.Name, nameTrack.Attributes.Name)
+	}
+	if sec, ok := SecondaryTrack(&meta, 5); ok {
+		t.Errorf("out of range SecondaryTrack = %+v", sec)
+	}
+}
@@ -0,0 +1,217 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+)
+
+// TaskState 下载队列中任务的状态
+type TaskState int
+
+const (
+	TaskPending TaskState = iota // 等待中
+	TaskRunning                  // 进行中
+	TaskDone                     // 已完成
+	TaskFailed                   // 失败（整个专辑失败或部分曲目失败）
+	TaskSkipped                  // 已跳过
+)
+
+var taskStateNames = []string{"等待中", "进行中", "已完成", "失败", "已跳过"}
+
+// String 返回任务状态的中文名称
+func (s TaskState) String() string {
+	if int(s) < len(taskStateNames) {
+		return taskStateNames[s]
+	}
+	return "未知"
+}
+
+// QueueItem 下载队列中的一个链接
+type QueueItem struct {
+	ID    int    // 队列内唯一编号，重试后保持不变
+	Num   int    // 任务编号（已考虑 --start 跳过的数量）
+	URL   string // 原始链接
+	Job   *Job   // 该链接使用的任务上下文
+	State TaskState
+	Note  string // 失败原因等附加说明
+}
+
+// Title 返回用于显示的标题：开始下载后为专辑标题，之前为链接
+func (it QueueItem) Title() string {
+	if it.Job != nil && it.Job.Board != nil {
+		if title := it.Job.Board.Title(); title != "" {
+			return title
+		}
+	}
+	return it.URL
+}
+
+// TaskQueue 批量下载队列
+// 任务按加入顺序派发；跳过的任务不再派发，重试的任务重新排到队尾
+type TaskQueue struct {
+	mu       sync.Mutex
+	items    []*QueueItem
+	nextID   int
+	requeued chan struct{} // 有任务被重新加入队列时关闭并替换，用于广播
+}
+
+// NewTaskQueue 创建空队列
+func NewTaskQueue() *TaskQueue {
+	return &TaskQueue{requeued: make(chan struct{})}
+}
+
+// Add 将链接加入队尾
+func (q *TaskQueue) Add(num int, url string, job *Job) *QueueItem {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.nextID++
+	item := &QueueItem{ID: q.nextID, Num: num, URL: url, Job: job}
+	q.items = append(q.items, item)
+	return item
+}
+
+// Next 取出下一个等待中的任务并标记为进行中，没有等待中的任务时返回 nil
+func (q *TaskQueue) Next() *QueueItem {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, item := range q.items {
+		if item.State == TaskPending {
+			item.State = TaskRunning
+			return item
+		}
+	}
+	return nil
+}
+
+// Finish 根据下载结果更新任务状态
+// 曲目被跳过时记为已跳过；专辑返回错误或有曲目最终失败时记为失败，可通过 Retry 重新加入队列
+// 返回更新后的任务快照，供调用方在不持锁的情况下读取结果
+func (q *TaskQueue) Finish(item *QueueItem, err error) QueueItem {
+	var board *TrackBoard
+	if item.Job != nil {
+		board = item.Job.Board
+	}
+
+	state, note := TaskDone, ""
+	switch {
+	case board != nil && board.Skipped():
+		state = TaskSkipped
+	case err != nil:
+		state, note = TaskFailed, err.Error()
+	case board != nil && board.FailedCount() > 0:
+		state, note = TaskFailed, fmt.Sprintf("%d 首曲目失败", board.FailedCount())
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	item.State = state
+	item.Note = note
+	return *item
+}
+
+// Skip 跳过任务：等待中的任务不再派发，进行中的专辑不再开始新的曲目
+func (q *TaskQueue) Skip(id int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	item := q.find(id)
+	if item == nil {
+		return false
+	}
+	switch item.State {
+	case TaskPending:
+		item.State = TaskSkipped
+		return true
+	case TaskRunning:
+		if item.Job != nil && item.Job.Board != nil {
+			item.Job.Board.Skip()
+			return true
+		}
+	}
+	return false
+}
+
+// Retry 将失败的任务重新加入队尾
+// 重新下载时已完成的曲目会被识别为本地已存在，实际只会下载失败的曲目
+func (q *TaskQueue) Retry(id int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, item := range q.items {
+		if item.ID != id {
+			continue
+		}
+		if item.State != TaskFailed {
+			return false
+		}
+		item.State = TaskPending
+		item.Note = ""
+		if item.Job != nil {
+			item.Job = item.Job.WithBoard(NewTrackBoard())
+		}
+		q.items = append(append(q.items[:i:i], q.items[i+1:]...), item)
+		close(q.requeued)
+		q.requeued = make(chan struct{})
+		return true
+	}
+	return false
+}
+
+// Requeued 返回在下一次有任务被重新加入队列时关闭的通道
+func (q *TaskQueue) Requeued() <-chan struct{} {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.requeued
+}
+
+// Snapshot 返回所有任务的副本（按队列顺序）
+func (q *TaskQueue) Snapshot() []QueueItem {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := make([]QueueItem, len(q.items))
+	for i, item := range q.items {
+		out[i] = *item
+	}
+	return out
+}
+
+// Len 返回队列中的任务总数
+func (q *TaskQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+// Count 返回处于指定状态的任务数
+func (q *TaskQueue) Count(state TaskState) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, item := range q.items {
+		if item.State == state {
+			n++
+		}
+	}
+	return n
+}
+
+// Finished 返回已结束（完成、失败或跳过）的任务数
+func (q *TaskQueue) Finished() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, item := range q.items {
+		if item.State != TaskPending && item.State != TaskRunning {
+			n++
+		}
+	}
+	return n
+}
+
+// find 按编号查找任务（调用方需持有锁）
+func (q *TaskQueue) find(id int) *QueueItem {
+	for _, item := range q.items {
+		if item.ID == id {
+			return item
+		}
+	}
+	return nil
+}
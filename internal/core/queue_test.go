@@ -0,0 +1,140 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestTaskQueue 测试队列派发、结果记录、跳过与重试
+func TestTaskQueue(t *testing.T) {
+	t.Parallel()
+
+	job := NewJob(Options{})
+	q := NewTaskQueue()
+	a := q.Add(1, "https://music.apple.com/cn/album/a/1", job.WithBoard(NewTrackBoard()))
+	b := q.Add(2, "https://music.apple.com/cn/album/b/2", job.WithBoard(NewTrackBoard()))
+	c := q.Add(3, "https://music.apple.com/cn/album/c/3", job.WithBoard(NewTrackBoard()))
+
+	// 跳过等待中的任务后不再派发
+	if !q.Skip(b.ID) {
+		t.Fatal("等待中的任务应可跳过")
+	}
+	if got := q.Next(); got != a {
+		t.Fatalf("Next = %v, 期望第一个任务", got)
+	}
+	if got := q.Next(); got != c {
+		t.Fatalf("Next = %v, 期望跳过第二个任务", got)
+	}
+	if q.Next() != nil {
+		t.Fatal("队列已无等待中的任务")
+	}
+
+	// 进行中的任务被跳过时标记其状态面板
+	if !q.Skip(c.ID) || !c.Job.Board.Skipped() {
+		t.Error("进行中的任务应标记为跳过")
+	}
+	q.Finish(c, nil)
+
+	// 有曲目失败时记为失败，可以重试
+	a.Job.Board.Reset([]TrackStatus{{Index: 0}, {Index: 1, Failed: true}})
+	q.Finish(a, nil)
+	if a.State != TaskFailed || a.Note == "" {
+		t.Errorf("部分曲目失败应记为失败: %+v", *a)
+	}
+	if q.Retry(c.ID) {
+		t.Error("已跳过的任务不应可重试")
+	}
+
+	requeued := q.Requeued()
+	oldBoard := a.Job.Board
+	if !q.Retry(a.ID) {
+		t.Fatal("失败的任务应可重试")
+	}
+	select {
+	case <-requeued:
+	default:
+		t.Error("重试后应发出通知")
+	}
+	if a.Job.Board == oldBoard {
+		t.Error("重试的任务应使用新的状态面板")
+	}
+	items := q.Snapshot()
+	if items[len(items)-1].ID != a.ID {
+		t.Error("重试的任务应排到队尾")
+	}
+	if got := q.Next(); got != a {
+		t.Fatalf("Next = %v, 期望重试的任务", got)
+	}
+	q.Finish(a, errors.New("网络错误"))
+	if a.State != TaskFailed || a.Note != "网络错误" {
+		t.Errorf("专辑返回错误应记为失败: %+v", *a)
+	}
+
+	if q.Finished() != 3 || q.Count(TaskSkipped) != 2 || q.Count(TaskFailed) != 1 {
+		t.Errorf("统计错误: finished=%d skipped=%d failed=%d",
+			q.Finished(), q.Count(TaskSkipped), q.Count(TaskFailed))
+	}
+}
+
+// TestControlPause 测试暂停期间阻塞、恢复后放行
+func TestControlPause(t *testing.T) {
+	t.Parallel()
+
+	c := NewControl()
+	if err := c.WaitIfPaused(context.Background()); err != nil {
+		t.Fatalf("未暂停时不应阻塞: %v", err)
+	}
+
+	if !c.TogglePause() {
+		t.Fatal("TogglePause 应进入暂停")
+	}
+	released := make(chan struct{})
+	go func() {
+		c.WaitIfPaused(context.Background())
+		close(released)
+	}()
+	select {
+	case <-released:
+		t.Fatal("暂停期间不应放行")
+	case <-time.After(50 * time.Millisecond):
+	}
+	c.Resume()
+	select {
+	case <-released:
+	case <-time.After(time.Second):
+		t.Fatal("恢复后应放行")
+	}
+
+	c.Pause()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.WaitIfPaused(ctx); err == nil {
+		t.Error("context 取消时应返回错误")
+	}
+}
+
+// TestControlWorkRest 测试工作-休息开关变化通知
+func TestControlWorkRest(t *testing.T) {
+	t.Parallel()
+
+	c := NewControl()
+	changed := c.WorkRestChanged()
+	if !c.ToggleWorkRest() || !c.WorkRestEnabled() {
+		t.Fatal("ToggleWorkRest 应开启循环")
+	}
+	select {
+	case <-changed:
+	default:
+		t.Error("开关变化后应发出通知")
+	}
+
+	changed = c.WorkRestChanged()
+	c.SetWorkRest(true)
+	select {
+	case <-changed:
+		t.Error("状态未变化时不应发出通知")
+	default:
+	}
+}
@@ -0,0 +1,245 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"main/internal/logger"
+	"main/utils/structs"
+)
+
+// 发行类型
+const (
+	ReleaseAlbum  = "album"
+	ReleaseSingle = "single"
+	ReleaseEP     = "ep"
+)
+
+// EP 的处理方式（release-rules.ep-mode）
+const (
+	EPModeAlbum      = "album"      // 按普通专辑保存
+	EPModeSingle     = "single"     // 并入虚拟 Singles
+	EPModeCollection = "collection" // 独立的虚拟 "艺术家 - EPs"
+)
+
+// 未配置时的默认名称规则（按元数据语言，未列出的语言使用 default）
+// 未配置 ep-patterns 时单曲规则优先于默认的 EP 名称规则，曲目数不超过单曲阈值的 "- EP" 仍识别为单曲，
+// 与早期版本的判断一致
+var (
+	DefaultSinglePatterns = structs.LanguagePatterns{structs.PatternLanguageDefault: {"- Single", " Single", "单曲"}}
+	DefaultEPPatterns     = structs.LanguagePatterns{structs.PatternLanguageDefault: {"- EP"}}
+)
+
+const defaultSingleMaxTracks = 3
+
+// ReleaseClass 专辑发行类型的判断结果
+type ReleaseClass struct {
+	Type       string // album / single / ep
+	Rule       string // 命中的规则
+	Exception  string // 命中的艺术家例外规则，空表示没有
+	Collection string // 归入的虚拟合辑（如 Singles、EPs），空表示按普通专辑保存
+}
+
+// ClassifyRelease 按 release-rules 判断专辑的发行类型以及是否归入虚拟合辑
+// 判断顺序：IsSingle 字段 → EP 名称规则 → 单曲名称规则 → 单曲曲目数 / 时长阈值 → EP 曲目数 / 时长阈值；
+// 未配置 ep-patterns 时默认的 EP 名称规则排在单曲规则之后
+func ClassifyRelease(meta *structs.AutoGenerated) ReleaseClass {
+	album := meta.Data[0]
+	attrs := album.Attributes
+
+	// 跳过播放列表
+	if strings.Contains(album.ID, "pl.") {
+		return ReleaseClass{Type: ReleaseAlbum, Rule: "播放列表"}
+	}
+
+	var c ReleaseClass
+	c.Type, c.Rule = releaseType(meta)
+
+	rule := artistRule(meta)
+	if rule != nil {
+		c.Exception = rule.Artist
+	}
+	if Config.EnableVirtualSingles {
+		c.Collection = collectionFor(c.Type, rule)
+	}
+	logger.Debug("[虚拟Singles] 专辑 '%s' by '%s': %s（%s）→ %q", attrs.Name, attrs.ArtistName, c.Type, c.Rule, c.Collection)
+	return c
+}
+
+// IsSingleAlbum 判断专辑是否归入虚拟合辑（虚拟 Singles，或 ep-mode 为 collection 时的虚拟 EPs）
+func IsSingleAlbum(meta *structs.AutoGenerated) bool {
+	return ClassifyRelease(meta).Collection != ""
+}
+
+// VirtualFolderName 返回专辑归入的虚拟合辑名称（如 Singles、EPs），用于 "艺术家 - 合辑名" 文件夹与专辑标签
+func VirtualFolderName(meta *structs.AutoGenerated) string {
+	return ClassifyRelease(meta).Collection
+}
+
+// SinglesFolderName 虚拟 Singles 的合辑名称（virtual-singles-folder-name，默认 Singles）
+func SinglesFolderName() string {
+	if Config.VirtualSinglesFolderName != "" {
+		return Config.VirtualSinglesFolderName
+	}
+	return "Singles"
+}
+
+// EPsFolderName 虚拟 EPs 的合辑名称（release-rules.ep-folder-name，默认 EPs）
+func EPsFolderName() string {
+	if Config.ReleaseRules.EPFolderName != "" {
+		return Config.ReleaseRules.EPFolderName
+	}
+	return "EPs"
+}
+
+// releaseType 按规则判断发行类型，返回类型与命中的规则说明
+func releaseType(meta *structs.AutoGenerated) (string, string) {
+	rules := Config.ReleaseRules
+	attrs := meta.Data[0].Attributes
+
+	if attrs.IsSingle {
+		return ReleaseSingle, "IsSingle=true"
+	}
+
+	// 默认的 EP 名称规则排在单曲规则之后，保持早期版本对短 EP 的判断
+	defaultEP := len(rules.EPPatterns) == 0
+	epPatterns := patternsFor(rules.EPPatterns, DefaultEPPatterns, meta.Language)
+	if !defaultEP {
+		if p := matchPattern(attrs.Name, epPatterns); p != "" {
+			return ReleaseEP, fmt.Sprintf("名称包含 %q", p)
+		}
+	}
+	singlePatterns := patternsFor(rules.SinglePatterns, DefaultSinglePatterns, meta.Language)
+	if p := matchPattern(attrs.Name, singlePatterns); p != "" {
+		return ReleaseSingle, fmt.Sprintf("名称包含 %q", p)
+	}
+
+	// 曲目数阈值能覆盖合作艺术家的单曲与未设置 IsSingle 标记的单曲
+	count := attrs.TrackCount
+	minutes := ReleaseMinutes(meta)
+	singleMax := rules.SingleMaxTracks
+	if singleMax == 0 {
+		singleMax = defaultSingleMaxTracks
+	}
+	if count > 0 && count <= singleMax && withinMinutes(minutes, rules.SingleMaxMinutes) {
+		return ReleaseSingle, thresholdRule(count, singleMax, minutes, rules.SingleMaxMinutes)
+	}
+	if defaultEP {
+		if p := matchPattern(attrs.Name, epPatterns); p != "" {
+			return ReleaseEP, fmt.Sprintf("名称包含 %q", p)
+		}
+	}
+	if count > 0 && count <= rules.EPMaxTracks && withinMinutes(minutes, rules.EPMaxMinutes) {
+		return ReleaseEP, thresholdRule(count, rules.EPMaxTracks, minutes, rules.EPMaxMinutes)
+	}
+	return ReleaseAlbum, fmt.Sprintf("没有命中单曲 / EP 规则（曲目数=%d，时长 %.1f 分钟）", count, minutes)
+}
+
+// patternsFor 返回元数据语言对应的名称规则：先按完整语言代码（如 zh-TW），再按主语言（zh），
+// 最后使用 default；配置中没有对应条目时使用默认规则
+func patternsFor(configured, defaults structs.LanguagePatterns, lang string) []string {
+	if p, ok := lookupPatterns(configured, lang); ok {
+		return p
+	}
+	p, _ := lookupPatterns(defaults, lang)
+	return p
+}
+
+func lookupPatterns(patterns structs.LanguagePatterns, lang string) ([]string, bool) {
+	keys := []string{lang}
+	if i := strings.IndexAny(lang, "-_"); i > 0 {
+		keys = append(keys, lang[:i])
+	}
+	keys = append(keys, structs.PatternLanguageDefault)
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		for k, p := range patterns {
+			if strings.EqualFold(k, key) {
+				return p, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// matchPattern 返回名称中包含的第一个规则文本
+func matchPattern(name string, patterns []string) string {
+	for _, p := range patterns {
+		if p != "" && strings.Contains(name, p) {
+			return p
+		}
+	}
+	return ""
+}
+
+// ReleaseMinutes 专辑中已知曲目的总时长（分钟）
+func ReleaseMinutes(meta *structs.AutoGenerated) float64 {
+	var ms int
+	for _, t := range meta.Data[0].Relationships.Tracks.Data {
+		ms += t.Attributes.DurationInMillis
+	}
+	return float64(ms) / 60000
+}
+
+// withinMinutes 时长阈值为 0 表示不限制
+func withinMinutes(minutes float64, limit int) bool {
+	return limit <= 0 || minutes <= float64(limit)
+}
+
+func thresholdRule(count, maxTracks int, minutes float64, maxMinutes int) string {
+	if maxMinutes > 0 {
+		return fmt.Sprintf("曲目数 %d ≤ %d，时长 %.1f ≤ %d 分钟", count, maxTracks, minutes, maxMinutes)
+	}
+	return fmt.Sprintf("曲目数 %d ≤ %d", count, maxTracks)
+}
+
+// artistRule 返回匹配专辑艺术家（全名、主要艺术家或艺术家 ID，不区分大小写）的例外规则
+func artistRule(meta *structs.AutoGenerated) *structs.ReleaseArtistRule {
+	for i := range Config.ReleaseRules.Artists {
+		rule := &Config.ReleaseRules.Artists[i]
+		if matchArtist(meta, rule.Artist) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// matchArtist 规则中的艺术家是否匹配专辑：不区分大小写比较全名、主要艺术家或艺术家 ID
+func matchArtist(meta *structs.AutoGenerated, artist string) bool {
+	album := meta.Data[0]
+	names := []string{album.Attributes.ArtistName, PrimaryArtist(meta)}
+	for _, a := range album.Relationships.Artists.Data {
+		names = append(names, a.ID)
+	}
+	for _, name := range names {
+		if name != "" && strings.EqualFold(strings.TrimSpace(artist), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// collectionFor 按发行类型、ep-mode 与艺术家例外决定归入的虚拟合辑
+func collectionFor(typ string, rule *structs.ReleaseArtistRule) string {
+	switch typ {
+	case ReleaseSingle:
+		if rule != nil && strings.EqualFold(rule.Singles, EPModeAlbum) {
+			return ""
+		}
+		return SinglesFolderName()
+	case ReleaseEP:
+		mode := Config.ReleaseRules.EPMode
+		if rule != nil && rule.EPs != "" {
+			mode = rule.EPs
+		}
+		switch strings.ToLower(mode) {
+		case EPModeSingle:
+			return SinglesFolderName()
+		case EPModeCollection:
+			return EPsFolderName()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,192 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"main/utils/structs"
+
+	"gopkg.in/yaml.v2"
+)
+
+// withTracks 为测试专辑添加指定时长（秒）的曲目
+func withTracks(meta *structs.AutoGenerated, seconds ...int) *structs.AutoGenerated {
+	for _, s := range seconds {
+		var track structs.TrackData
+		track.Attributes.DurationInMillis = s * 1000
+		meta.Data[0].Relationships.Tracks.Data = append(meta.Data[0].Relationships.Tracks.Data, track)
+	}
+	return meta
+}
+
+// withLanguage 设置测试专辑元数据的语言
+func withLanguage(meta *structs.AutoGenerated, lang string) *structs.AutoGenerated {
+	meta.Language = lang
+	return meta
+}
+
+// TestClassifyRelease 测试可配置的单曲 / EP 识别规则
+func TestClassifyRelease(t *testing.T) {
+	origConfig := Config
+	defer func() { Config = origConfig }()
+
+	tests := []struct {
+		name           string
+		rules          structs.ReleaseRulesConfig
+		meta           *structs.AutoGenerated
+		wantType       string
+		wantCollection string
+	}{
+		{
+			name:           "默认规则：EP 按普通专辑保存",
+			meta:           createTestMeta("1", "Short Stories - EP", "Artist", false, 5),
+			wantType:       ReleaseEP,
+			wantCollection: "",
+		},
+		{
+			name:           "默认规则：三曲目以内的 EP 仍识别为单曲（与早期版本一致）",
+			meta:           createTestMeta("1", "Short Stories - EP", "Artist", false, 3),
+			wantType:       ReleaseSingle,
+			wantCollection: "Singles",
+		},
+		{
+			name:           "配置 ep-patterns 后 EP 名称规则优先",
+			rules:          structs.ReleaseRulesConfig{EPPatterns: structs.LanguagePatterns{"default": {"- EP"}}},
+			meta:           createTestMeta("1", "Short Stories - EP", "Artist", false, 3),
+			wantType:       ReleaseEP,
+			wantCollection: "",
+		},
+		{
+			name:           "EP 并入虚拟 Singles",
+			rules:          structs.ReleaseRulesConfig{EPMode: "single"},
+			meta:           createTestMeta("2", "Short Stories - EP", "Artist", false, 5),
+			wantType:       ReleaseEP,
+			wantCollection: "Singles",
+		},
+		{
+			name:           "EP 归入虚拟 EPs",
+			rules:          structs.ReleaseRulesConfig{EPMode: "collection", EPFolderName: "Extended Plays"},
+			meta:           createTestMeta("3", "Short Stories - EP", "Artist", false, 5),
+			wantType:       ReleaseEP,
+			wantCollection: "Extended Plays",
+		},
+		{
+			name:           "自定义单曲名称规则",
+			rules:          structs.ReleaseRulesConfig{SinglePatterns: structs.LanguagePatterns{"ja": {"シングル"}}, SingleMaxTracks: -1},
+			meta:           withLanguage(createTestMeta("4", "夜に駆ける シングル", "YOASOBI", false, 2), "ja-JP"),
+			wantType:       ReleaseSingle,
+			wantCollection: "Singles",
+		},
+		{
+			name:           "单曲名称规则只用于对应语言",
+			rules:          structs.ReleaseRulesConfig{SinglePatterns: structs.LanguagePatterns{"ja": {"シングル"}}, SingleMaxTracks: -1},
+			meta:           withLanguage(createTestMeta("4", "夜に駆ける シングル", "YOASOBI", false, 2), "en-US"),
+			wantType:       ReleaseAlbum,
+			wantCollection: "",
+		},
+		{
+			name:           "不按曲目数判断单曲",
+			rules:          structs.ReleaseRulesConfig{SingleMaxTracks: -1},
+			meta:           createTestMeta("5", "Three Songs", "Artist", false, 3),
+			wantType:       ReleaseAlbum,
+			wantCollection: "",
+		},
+		{
+			name:           "超过单曲时长上限的三曲目发行",
+			rules:          structs.ReleaseRulesConfig{SingleMaxMinutes: 12},
+			meta:           withTracks(createTestMeta("6", "Maxi", "Artist", false, 3), 300, 300, 300),
+			wantType:       ReleaseAlbum,
+			wantCollection: "",
+		},
+		{
+			name:           "按曲目数与时长识别 EP",
+			rules:          structs.ReleaseRulesConfig{SingleMaxMinutes: 12, EPMaxTracks: 6, EPMaxMinutes: 30, EPMode: "collection"},
+			meta:           withTracks(createTestMeta("7", "Maxi", "Artist", false, 3), 300, 300, 300),
+			wantType:       ReleaseEP,
+			wantCollection: "EPs",
+		},
+		{
+			name: "艺术家例外：单曲按普通专辑保存",
+			rules: structs.ReleaseRulesConfig{Artists: []structs.ReleaseArtistRule{
+				{Artist: "taylor swift", Singles: "album"},
+			}},
+			meta:           createTestMeta("8", "Song - Single", "Taylor Swift & Someone", true, 1),
+			wantType:       ReleaseSingle,
+			wantCollection: "",
+		},
+		{
+			name: "艺术家例外：覆盖 ep-mode",
+			rules: structs.ReleaseRulesConfig{EPMode: "album", Artists: []structs.ReleaseArtistRule{
+				{Artist: "Artist", EPs: "collection"},
+			}},
+			meta:           createTestMeta("9", "Short Stories - EP", "Artist", false, 5),
+			wantType:       ReleaseEP,
+			wantCollection: "EPs",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Config.EnableVirtualSingles = true
+			Config.VirtualSinglesFolderName = ""
+			Config.ReleaseRules = tt.rules
+
+			c := ClassifyRelease(tt.meta)
+			if c.Type != tt.wantType || c.Collection != tt.wantCollection {
+				t.Errorf("ClassifyRelease() = %s / %q（%s），期望 %s / %q", c.Type, c.Collection, c.Rule, tt.wantType, tt.wantCollection)
+			}
+			if c.Rule == "" {
+				t.Error("应说明命中的规则")
+			}
+			if len(tt.rules.Artists) > 0 && c.Exception == "" {
+				t.Error("应记录命中的艺术家例外")
+			}
+		})
+	}
+}
+
+// TestClassifyReleaseRule 测试规则说明
+func TestClassifyReleaseRule(t *testing.T) {
+	origConfig := Config
+	defer func() { Config = origConfig }()
+	Config.EnableVirtualSingles = false
+	Config.ReleaseRules = structs.ReleaseRulesConfig{}
+
+	c := ClassifyRelease(createTestMeta("1", "Song - Single", "Artist", false, 1))
+	if c.Type != ReleaseSingle || !strings.Contains(c.Rule, "- Single") {
+		t.Errorf("rule = %s / %s", c.Type, c.Rule)
+	}
+	if c.Collection != "" {
+		t.Error("虚拟合辑未启用时不应归入合辑")
+	}
+}
+
+// TestLanguagePatternsYAML 测试名称规则的两种写法：列表（所有语言）与按语言的映射
+func TestLanguagePatternsYAML(t *testing.T) {
+	var rules structs.ReleaseRulesConfig
+	data := "single-patterns: [\"- Single\"]\nep-patterns:\n  ja: [\"- EP\"]\n  default: [\"EP\"]\n"
+	if err := yaml.Unmarshal([]byte(data), &rules); err != nil {
+		t.Fatal(err)
+	}
+	if got := patternsFor(rules.SinglePatterns, DefaultSinglePatterns, "ja"); !reflect.DeepEqual(got, []string{"- Single"}) {
+		t.Errorf("single-patterns list = %v", got)
+	}
+	if got := patternsFor(rules.EPPatterns, DefaultEPPatterns, "ja-JP"); !reflect.DeepEqual(got, []string{"- EP"}) {
+		t.Errorf("ep-patterns[ja] = %v", got)
+	}
+	if got := patternsFor(rules.EPPatterns, DefaultEPPatterns, "en-US"); !reflect.DeepEqual(got, []string{"EP"}) {
+		t.Errorf("ep-patterns[default] = %v", got)
+	}
+	if got := patternsFor(nil, DefaultSinglePatterns, "zh-Hans-CN"); len(got) != 3 {
+		t.Errorf("default single-patterns = %v", got)
+	}
+
+	// 示例配置中的空列表等同于未配置
+	if err := yaml.Unmarshal([]byte("ep-patterns: []\n"), &rules); err != nil {
+		t.Fatal(err)
+	}
+	if rules.EPPatterns != nil {
+		t.Errorf("empty ep-patterns = %v, want nil", rules.EPPatterns)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"main/internal/config"
 	"main/internal/logger"
+	"main/internal/quality"
 	"main/utils/structs"
 	"os"
 	"regexp"
@@ -19,35 +20,42 @@ import (
 
 var (
 	ForbiddenNames   = regexp.MustCompile(`[/\\<>:"|?*]`)
-	Dl_atmos         bool
-	Dl_aac           bool
-	Dl_select        bool
-	Dl_song          bool
 	Artist_select    bool
 	Dl_singles_only  bool // 仅下载单曲模式（针对艺术家链接）
 	Debug_mode       bool
-	DisableDynamicUI bool // 禁用动态UI的标志，启用后使用纯日志输出
-	ForceDownload    bool // 强制下载模式，覆盖已存在的文件
-	Alac_max         *int
-	Atmos_max        *int
-	Mv_max           *int
-	Mv_audio_type    *string
-	Aac_type         *string
-	StartFrom        int // 从第几个链接开始下载（从1开始计数）
+	DisableDynamicUI bool   // 禁用动态UI的标志，启用后使用纯日志输出
+	EnableTUI        bool   // 启用全屏终端界面（--tui 或配置 enable-tui）
+	MetricsListen    string // 指标服务监听地址（--metrics-listen 或配置 metrics-listen），为空不启用
+	StartFrom        int    // 从第几个链接开始下载（从1开始计数）
+	VerifyReport     string // verify / verify-checksums 命令的报告输出路径
+	VerifyRequeue    string // verify 命令：损坏曲目追加到的任务文件
+	VerifyNoDecode   bool   // verify 命令：跳过 ffmpeg 完整解码
+	ChecksumsUpdate  bool   // verify-checksums 命令：创建或增量更新清单而不是校验
+	RetagDryRun      bool   // retag 命令：只显示标签差异，不写入
+	RetagPreserve    string // retag 命令：保留文件中原值的字段（逗号分隔，与配置 retag-preserve 合并）
+	RetagReport      string // retag 命令的报告输出路径
+	SinglesDryRun    bool   // rebuild-singles 命令：只显示新编号，不重命名
+	DryRun           bool   // 只预检批量任务的可用性、预计大小与目标路径，不下载
+	DryRunReport     string // --dry-run 报告的保存路径
+	SearchType       string // search 命令：搜索类型（songs / albums / artists）
+	SearchLimit      int    // search 命令：返回的结果数
+	SearchFirst      bool   // search 命令：不交互，直接下载第一个结果
+	SearchJSON       bool   // search 命令：以 JSON 输出结果，不下载
 	Config           structs.ConfigSet
-	Counter          structs.Counter
-	OkDict           = make(map[string][]int)
 	ConfigPath       string
 	OutputPath       string
-	SharedLock       sync.Mutex
 	DeveloperToken   string
 	MaxPathLength    int
-	// 虚拟Singles专辑曲目编号管理
-	virtualSinglesTrackNumbers = make(map[string]int) // key: artistName, value: 下一个可用的曲目编号
-	virtualSinglesLock         sync.RWMutex           // 使用 RWMutex 优化读多写少场景
-	// 存储每个track的有效曲目编号（用于确保文件名和标签使用相同的编号）
-	trackEffectiveNumbers = make(map[string]int) // key: trackID, value: 有效的曲目编号
-	trackEffectiveLock    sync.RWMutex           // 使用 RWMutex 优化读多写少场景
+	// import 命令：生成的任务文件路径、未解析项报告的保存路径与模糊匹配的最低置信度
+	ImportOutput   string
+	ImportReport   string
+	ImportMinScore float64
+	// 命令行指定的下载参数，LoadConfig 时与配置文件合并，通过 FlagOptions 获取
+	flagOpts Options
+	// 命令行指定的音质策略（逗号分隔），优先于配置 quality-policy
+	flagQualityPolicy string
+	// 命令行指定的多音乐库输出档位（逗号分隔），优先于配置 library-outputs
+	flagLibraryOutputs string
 )
 
 type TrackStatus struct {
@@ -59,37 +67,72 @@ type TrackStatus struct {
 	Status       string
 	StatusColor  func(a ...interface{}) string
 	LastUpdateNs int64 // 最后更新时间（纳秒），用于防抖
+	Failed       bool  // 曲目最终下载失败（可在全屏界面中重试）
 }
 
-var UiMutex sync.Mutex
-
+// RipLock 动态UI独占终端区域，启用动态UI时同一时间只允许一个专辑进入下载阶段
 var RipLock sync.Mutex
 
-var TrackStatuses []TrackStatus
-
-func InitCounter() structs.Counter {
-	return structs.Counter{}
-}
-
 func InitFlags() {
 	pflag.StringVar(&ConfigPath, "config", "", "指定要使用的配置文件路径 (例如: configs/cn.yaml)")
 	pflag.StringVar(&OutputPath, "output", "", "指定本次任务的唯一输出目录")
 
-	pflag.BoolVar(&Dl_atmos, "atmos", false, "启用杜比全景声下载模式")
-	pflag.BoolVar(&Dl_aac, "aac", false, "启用 AAC 下载模式")
-	pflag.BoolVar(&Dl_select, "select", false, "启用选择性下载模式（可选择要下载的曲目）")
-	pflag.BoolVar(&Dl_song, "song", false, "启用单曲下载模式")
+	pflag.BoolVar(&flagOpts.Atmos, "atmos", false, "启用杜比全景声下载模式")
+	pflag.BoolVar(&flagOpts.AAC, "aac", false, "启用 AAC 下载模式")
+	pflag.BoolVar(&flagOpts.Select, "select", false, "启用选择性下载模式（可选择要下载的曲目）")
+	pflag.BoolVar(&flagOpts.Song, "song", false, "启用单曲下载模式")
 	pflag.BoolVar(&Artist_select, "all-album", false, "下载歌手的所有专辑")
 	pflag.BoolVar(&Dl_singles_only, "singles-only", false, "仅下载艺术家的单曲作品（自动启用虚拟Singles专辑）")
 	pflag.BoolVar(&Debug_mode, "debug", false, "启用调试模式，显示音频质量信息")
 	pflag.BoolVar(&DisableDynamicUI, "no-ui", false, "禁用动态终端UI，回退到纯日志输出模式（用于CI/调试或兼容性）")
-	pflag.BoolVar(&ForceDownload, "cx", false, "强制下载模式，覆盖已存在的文件")
+	pflag.BoolVar(&EnableTUI, "tui", false, "启用全屏终端界面（队列、曲目表、日志面板，支持快捷键暂停/跳过/重试）")
+	pflag.StringVar(&MetricsListen, "metrics-listen", "", "在指定地址暴露 Prometheus 指标（如：127.0.0.1:9108）")
+	pflag.StringVar(&VerifyReport, "verify-report", "", "verify / verify-checksums 命令：报告输出路径（.json 输出 JSON，否则为文本）")
+	pflag.StringVar(&VerifyRequeue, "verify-requeue", "", "verify 命令：将损坏的曲目追加到指定 TXT 任务文件并重命名损坏文件，以便重新下载")
+	pflag.BoolVar(&VerifyNoDecode, "verify-no-decode", false, "verify 命令：跳过 ffmpeg 完整解码，只检查容器结构、标签与时长")
+	pflag.BoolVar(&ChecksumsUpdate, "checksums-update", false, "verify-checksums 命令：为已有音乐库创建或增量更新校验清单，而不是校验")
+	pflag.BoolVar(&RetagDryRun, "retag-dry-run", false, "retag 命令：只显示每个文件的标签差异，不写入")
+	pflag.StringVar(&RetagPreserve, "retag-preserve", "", "retag 命令：保留文件中原值的字段，逗号分隔（如：genre,comment,isrc）")
+	pflag.StringVar(&RetagReport, "retag-report", "", "retag 命令：报告输出路径（.json 输出 JSON，否则为文本）")
+	pflag.BoolVar(&SinglesDryRun, "singles-dry-run", false, "rebuild-singles 命令：只显示按发行日期计算的新编号，不重命名文件")
+	pflag.BoolVar(&DryRun, "dry-run", false, "预检模式：展开批量任务，报告每个专辑的曲目、账户权限、可用音质、预计大小与目标路径，不下载")
+	pflag.StringVar(&DryRunReport, "dry-run-report", "", "--dry-run 报告的保存路径（.json 输出 JSON，否则为文本表格）")
+	pflag.StringVar(&SearchType, "type", "albums", "search 命令：搜索类型（songs / albums / artists）")
+	pflag.IntVar(&SearchLimit, "search-limit", 25, "search 命令：返回的结果数（最多 25）")
+	pflag.BoolVar(&SearchFirst, "first", false, "search 命令：不交互，直接下载第一个结果")
+	pflag.BoolVar(&SearchJSON, "json", false, "search 命令：以 JSON 输出结果（用于脚本），不下载")
+	pflag.StringVar(&ImportOutput, "import-output", "", "import 命令：生成的任务文件路径（默认为第一个导入文件名加 -import.txt）")
+	pflag.StringVar(&ImportReport, "import-report", "", "import 命令：未解析项报告的保存路径（.json 输出 JSON，否则为文本；默认为第一个导入文件名加 -unresolved.txt）")
+	pflag.Float64Var(&ImportMinScore, "import-min-confidence", 0.8, "import 命令：按名称模糊匹配的最低置信度（0-1），低于该值的行视为未解析")
+	pflag.BoolVar(&flagOpts.Force, "cx", false, "强制下载模式，覆盖已存在的文件")
 	pflag.IntVar(&StartFrom, "start", 0, "从 TXT 文件的第几个链接开始下载（从 1 开始计数，例如：--start 44）")
-	Alac_max = pflag.Int("alac-max", 0, "指定 ALAC 下载的最大音质（如：192000, 96000, 48000）")
-	Atmos_max = pflag.Int("atmos-max", 0, "指定 Dolby Atmos 下载的最大音质（如：2768, 2448）")
-	Aac_type = pflag.String("aac-type", "aac", "选择 AAC 类型（可选：aac, aac-binaural, aac-downmix）")
-	Mv_audio_type = pflag.String("mv-audio-type", "atmos", "选择 MV 音轨类型（可选：atmos, ac3, aac）")
-	Mv_max = pflag.Int("mv-max", 1080, "指定 MV 下载的最大分辨率（如：2160, 1080, 720）")
+	pflag.IntVar(&flagOpts.AlacMax, "alac-max", 0, "指定 ALAC 下载的最大音质（如：192000, 96000, 48000）")
+	pflag.IntVar(&flagOpts.AtmosMax, "atmos-max", 0, "指定 Dolby Atmos 下载的最大音质（如：2768, 2448）")
+	pflag.StringVar(&flagQualityPolicy, "quality-policy", "", "音质策略：按顺序尝试的档位，逗号分隔（如：hires<=96000,lossless,aac），指定 --atmos / --aac 时不使用")
+	pflag.StringVar(&flagLibraryOutputs, "library-outputs", "", "多音乐库模式：每个专辑依次输出的档位，逗号分隔（如：alac,atmos），指定 --atmos / --aac 时不使用")
+	pflag.StringVar(&flagOpts.AacType, "aac-type", "aac", "选择 AAC 类型（可选：aac, aac-binaural, aac-downmix）")
+	pflag.StringVar(&flagOpts.MvAudioType, "mv-audio-type", "atmos", "选择 MV 音轨类型（可选：atmos, ac3, aac）")
+	pflag.IntVar(&flagOpts.MvMax, "mv-max", 1080, "指定 MV 下载的最大分辨率（如：2160, 1080, 720）")
+}
+
+// FlagOptions 返回命令行参数与配置文件合并后的下载参数
+// 必须在 LoadConfig 之后调用
+func FlagOptions() Options {
+	opts := flagOpts
+	// 明确指定 --atmos / --aac 时按下载模式选择，不使用音质策略与多音乐库模式
+	if !opts.Atmos && !opts.AAC {
+		opts.QualityPolicy = Config.QualityPolicy
+		if flagQualityPolicy != "" {
+			opts.QualityPolicy = strings.Split(flagQualityPolicy, ",")
+		}
+		opts.LibraryOutputs = Config.LibraryOutputs
+		if flagLibraryOutputs != "" {
+			opts.LibraryOutputs = strings.Split(flagLibraryOutputs, ",")
+		}
+	}
+	opts.ArtistFolderFormat = Config.ArtistFolderFormat
+	opts.MaxTracks = Config.MaxConcurrentTracks
+	return opts
 }
 
 func LoadConfig(configPath string) error {
@@ -158,25 +201,35 @@ func LoadConfig(configPath string) error {
 		}
 	}
 
-	if *Alac_max == 0 {
-		Alac_max = &Config.AlacMax
+	if flagQualityPolicy != "" {
+		if _, err := quality.ParsePolicy(strings.Split(flagQualityPolicy, ",")); err != nil {
+			return fmt.Errorf("--quality-policy 无效: %w", err)
+		}
 	}
-	if *Atmos_max == 0 {
-		Atmos_max = &Config.AtmosMax
+	if flagLibraryOutputs != "" {
+		if _, err := quality.ParsePolicy(strings.Split(flagLibraryOutputs, ",")); err != nil {
+			return fmt.Errorf("--library-outputs 无效: %w", err)
+		}
 	}
-	// 如果命令行中没有指定aac-type（使用默认值），则使用配置文件的值
-	if *Aac_type == "aac" {
-		Aac_type = &Config.AacType
+
+	if flagOpts.AlacMax == 0 {
+		flagOpts.AlacMax = Config.AlacMax
 	}
-	// 如果命令行中指定了aac-type，则更新Config.AacType以保持一致性
-	if *Aac_type != "aac" {
-		Config.AacType = *Aac_type
+	if flagOpts.AtmosMax == 0 {
+		flagOpts.AtmosMax = Config.AtmosMax
 	}
-	if *Mv_audio_type == "atmos" {
-		Mv_audio_type = &Config.MVAudioType
+	// 如果命令行中没有指定aac-type（使用默认值），则使用配置文件的值
+	if flagOpts.AacType == "aac" {
+		flagOpts.AacType = Config.AacType
+	} else {
+		// 如果命令行中指定了aac-type，则更新Config.AacType以保持一致性
+		Config.AacType = flagOpts.AacType
 	}
-	if *Mv_max == 1080 {
-		Mv_max = &Config.MVMax
+	if flagOpts.MvAudioType == "atmos" {
+		flagOpts.MvAudioType = Config.MVAudioType
+	}
+	if flagOpts.MvMax == 1080 {
+		flagOpts.MvMax = Config.MVMax
 		if Config.MVMax > 0 && Config.MVMax != 1080 {
 			if Config.MVMin > 0 && Config.MVMin != Config.MVMax {
 				logger.Info("📌 使用配置文件中的 MV 分辨率范围: %dp ~ %dp", Config.MVMin, Config.MVMax)
@@ -185,7 +238,7 @@ func LoadConfig(configPath string) error {
 			}
 		}
 	} else {
-		logger.Info("📌 使用命令行指定的 MV 分辨率上限: %dp", *Mv_max)
+		logger.Info("📌 使用命令行指定的 MV 分辨率上限: %dp", flagOpts.MvMax)
 	}
 
 	// 设置缓存文件夹默认值
@@ -210,6 +263,32 @@ func LoadConfig(configPath string) error {
 		logger.Info("📌 'batch-size' 设置为负数，已调整为 0（禁用分批，一次性处理）")
 	}
 
+	// 设置专辑并行默认值
+	if Config.AlbumConcurrency <= 0 {
+		Config.AlbumConcurrency = 1
+	}
+	if Config.MaxConcurrentTracks < 0 {
+		Config.MaxConcurrentTracks = 0
+	}
+
+	// 设置磁盘空间检查默认值（未设置时保留 1GB，负数表示关闭）
+	if Config.MinFreeSpaceMB == 0 {
+		Config.MinFreeSpaceMB = 1024
+	}
+	if Config.LowSpaceAction == "" {
+		Config.LowSpaceAction = "pause"
+	}
+
+	// 全屏界面：命令行 --tui 或配置 enable-tui 任一启用即可
+	if Config.EnableTUI {
+		EnableTUI = true
+	}
+
+	// 指标服务：命令行 --metrics-listen 优先于配置 metrics-listen
+	if MetricsListen == "" {
+		MetricsListen = Config.MetricsListen
+	}
+
 	// 设置工作-休息循环默认值
 	if Config.WorkRestEnabled {
 		logger.Debug("[工作-休息] 配置已启用")
@@ -267,115 +346,3 @@ func LimitString(s string) string {
 	}
 	return s
 }
-
-// IsSingleAlbum 判断专辑是否为单曲专辑
-// 判断依据：
-// 1. 专辑的 IsSingle 字段为 true
-// 2. 或者专辑名称包含 "- Single" 或 "单曲"
-// 3. 或者专辑类型为 "single"（ContentRating 字段）
-// 4. 对于艺术家下载场景：如果专辑只有1-3首曲目，也视为单曲（覆盖合作单曲）
-func IsSingleAlbum(meta *structs.AutoGenerated) bool {
-	if !Config.EnableVirtualSingles {
-		return false
-	}
-
-	// 跳过播放列表
-	if strings.Contains(meta.Data[0].ID, "pl.") {
-		return false
-	}
-
-	albumName := meta.Data[0].Attributes.Name
-	albumArtist := meta.Data[0].Attributes.ArtistName
-	trackCount := meta.Data[0].Attributes.TrackCount
-
-	// 检查 IsSingle 字段
-	if meta.Data[0].Attributes.IsSingle {
-		logger.Debug("[虚拟Singles] 专辑 '%s' by '%s' 被识别为单曲 (IsSingle=true)", albumName, albumArtist)
-		return true
-	}
-
-	// 检查专辑名称
-	if strings.Contains(albumName, "- Single") ||
-		strings.Contains(albumName, " Single") ||
-		strings.Contains(albumName, "单曲") {
-		logger.Debug("[虚拟Singles] 专辑 '%s' by '%s' 被识别为单曲 (名称包含标识)", albumName, albumArtist)
-		return true
-	}
-
-	// 检查曲目数量：1-3首曲目的专辑也视为单曲
-	// 这能覆盖合作艺术家的单曲作品（即使它们在专辑艺术家名称中不包含当前下载的艺术家）
-	// 同时也能处理一些不规范的单曲发布（未设置 IsSingle 标记）
-	if trackCount > 0 && trackCount <= 3 {
-		logger.Debug("[虚拟Singles] 专辑 '%s' by '%s' 被识别为单曲 (曲目数=%d)", albumName, albumArtist, trackCount)
-		return true
-	}
-
-	logger.Debug("[虚拟Singles] 专辑 '%s' by '%s' 不是单曲 (IsSingle=%v, 曲目数=%d)",
-		albumName, albumArtist, meta.Data[0].Attributes.IsSingle, trackCount)
-	return false
-}
-
-// GetVirtualSinglesTrackNumber 为虚拟Singles专辑分配曲目编号
-// 每个艺术家的Singles专辑维护独立的曲目编号序列
-func GetVirtualSinglesTrackNumber(artistName string) int {
-	// 使用写锁保证并发安全（性能影响微乎其微，因为该函数本质上总是写操作）
-	virtualSinglesLock.Lock()
-	defer virtualSinglesLock.Unlock()
-
-	// 初始化（如果不存在）
-	if _, exists := virtualSinglesTrackNumbers[artistName]; !exists {
-		virtualSinglesTrackNumbers[artistName] = 1
-	}
-
-	// 获取当前编号并递增
-	trackNum := virtualSinglesTrackNumbers[artistName]
-	virtualSinglesTrackNumbers[artistName]++
-
-	return trackNum
-}
-
-// ResetVirtualSinglesTrackNumber 重置指定艺术家的虚拟Singles专辑曲目编号
-func ResetVirtualSinglesTrackNumber(artistName string) {
-	virtualSinglesLock.Lock()
-	defer virtualSinglesLock.Unlock()
-
-	delete(virtualSinglesTrackNumbers, artistName)
-}
-
-// GetPrimaryArtist 从艺术家名称字符串中提取主要艺术家
-// 处理合作者情况：如果包含 " & " 或 " ft. " 或 ", " 等，则返回第一个艺术家
-// 示例: "Olivia Rodrigo & Joshua Bassett" -> "Olivia Rodrigo"
-//
-//	"好妹妹, 秦昊Jeff, 张小厚" -> "好妹妹"
-func GetPrimaryArtist(artistName string) string {
-	// 处理常见的合作分隔符（按优先级排序）
-	// 优先检查英文分隔符（更明确的合作标识）
-	separators := []string{" & ", " ft. ", " feat. ", " featuring ", " / ", ", ", "、"}
-
-	for _, sep := range separators {
-		if idx := strings.Index(strings.ToLower(artistName), strings.ToLower(sep)); idx != -1 {
-			return strings.TrimSpace(artistName[:idx])
-		}
-	}
-
-	return artistName
-}
-
-// SetTrackEffectiveNumber 设置track的有效曲目编号
-// 用于确保文件名和标签使用相同的编号（避免GetVirtualSinglesTrackNumber被多次调用）
-func SetTrackEffectiveNumber(trackID string, effectiveNum int) {
-	trackEffectiveLock.Lock()
-	defer trackEffectiveLock.Unlock()
-	trackEffectiveNumbers[trackID] = effectiveNum
-}
-
-// GetTrackEffectiveNumber 获取track的有效曲目编号
-// 如果没有设置，返回-1
-func GetTrackEffectiveNumber(trackID string) int {
-	trackEffectiveLock.RLock()
-	defer trackEffectiveLock.RUnlock()
-	if num, exists := trackEffectiveNumbers[trackID]; exists {
-		return num
-	}
-	return -1
-}
@@ -236,32 +236,3 @@ func TestGetPrimaryArtist(t *testing.T) {
 		})
 	}
 }
-
-// TestGetVirtualSinglesTrackNumber 测试虚拟 Singles 曲目编号分配
-func TestGetVirtualSinglesTrackNumber(t *testing.T) {
-	// 使用临时艺术家名以不影响其他测试
-	artist := "Test Artist " + t.Name()
-
-	// 测试连续分配
-	num1 := GetVirtualSinglesTrackNumber(artist)
-	num2 := GetVirtualSinglesTrackNumber(artist)
-	num3 := GetVirtualSinglesTrackNumber(artist)
-
-	if num1 != 1 {
-		t.Errorf("第一个编号应为 1，实际为 %d", num1)
-	}
-	if num2 != 2 {
-		t.Errorf("第二个编号应为 2，实际为 %d", num2)
-	}
-	if num3 != 3 {
-		t.Errorf("第三个编号应为 3，实际为 %d", num3)
-	}
-
-	// 测试不同艺术家独立计数
-	artist2 := "Another Artist " + t.Name()
-	num4 := GetVirtualSinglesTrackNumber(artist2)
-
-	if num4 != 1 {
-		t.Errorf("另一个艺术家的第一个编号应为 1，实际为 %d", num4)
-	}
-}
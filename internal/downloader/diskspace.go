@@ -0,0 +1,237 @@
+package downloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"main/internal/core"
+	"main/internal/logger"
+	"main/internal/notify"
+	"main/internal/utils"
+	"main/utils/structs"
+)
+
+const (
+	// diskGuardPollInterval 空间不足暂停期间重新检查剩余空间的间隔
+	diskGuardPollInterval = 30 * time.Second
+
+	// mvBitrateKbps MV 预估码率（4K 视频 + 多声道音轨）
+	mvBitrateKbps = 20000
+)
+
+// estimateDownloadSize 估算选中曲目下载完成后占用的空间（字节）
+// EstimateFileSize 给出的是文件校验用的保守下限，预检需要上限：
+// 先还原为典型大小，Hi-Res 再按 24bit/192kHz 相对 48kHz 的码率放大
+func estimateDownloadSize(meta *structs.AutoGenerated, selected []int, codec string, isAtmos, isHires bool) uint64 {
+	var total uint64
+	for _, trackNum := range selected {
+		track := meta.Data[0].Relationships.Tracks.Data[trackNum-1]
+		if track.Type == "music-videos" {
+			seconds := uint64(track.Attributes.DurationInMillis / 1000)
+			total += seconds * mvBitrateKbps * 1024 / 8
+			continue
+		}
+		size := uint64(utils.EstimateFileSize(codec, isAtmos, track.Attributes.DurationInMillis)) * 100 / 80
+		if isHires {
+			size *= 4
+		}
+		total += size
+	}
+	return total
+}
+
+// reservedSpace 并发下载的专辑已通过预检、尚未完成时预留的空间（按文件系统标识）
+// 预检时计入其他进行中专辑的预留，避免多个专辑各自通过检查后合计超出剩余空间
+var (
+	reservedSpace   = map[string]uint64{}
+	reservedSpaceMu sync.Mutex
+)
+
+// checkDiskSpace 下载前预检：按文件系统合并各目录的需求（缓存与目标目录可能位于同一磁盘），
+// 要求剩余空间不少于合计需求 + 其他进行中专辑的预留 + 保留空间
+// 通过后为本专辑预留空间，专辑结束时调用返回的 release 释放
+func checkDiskSpace(need map[string]uint64) (release func(), err error) {
+	if core.Config.MinFreeSpaceMB < 0 {
+		return func() {}, nil
+	}
+	reserve := uint64(core.Config.MinFreeSpaceMB) << 20
+
+	type fsNeed struct {
+		dirs []string
+		size uint64
+	}
+	groups := make(map[string]*fsNeed)
+	for dir, size := range need {
+		id, err := utils.FileSystemID(dir)
+		if err != nil {
+			// 无法识别文件系统时按目录单独计算
+			id = filepath.Clean(dir)
+		}
+		g := groups[id]
+		if g == nil {
+			g = &fsNeed{}
+			groups[id] = g
+		}
+		g.dirs = append(g.dirs, dir)
+		g.size += size
+	}
+
+	reservedSpaceMu.Lock()
+	defer reservedSpaceMu.Unlock()
+	for id, g := range groups {
+		dir := strings.Join(g.dirs, ", ")
+		free, err := utils.FreeSpace(g.dirs[0])
+		if err != nil {
+			logger.Debug("[空间检查] 无法获取剩余空间 %s: %v", dir, err)
+			continue
+		}
+		inFlight := reservedSpace[id]
+		logger.Debug("[空间检查] %s: 剩余 %s，预计需要 %s（进行中的专辑预留 %s，另保留 %s）",
+			dir, utils.FormatBytes(free), utils.FormatBytes(g.size), utils.FormatBytes(inFlight), utils.FormatBytes(reserve))
+		if free < g.size+inFlight+reserve {
+			return nil, fmt.Errorf("磁盘空间不足: %s 剩余 %s，预计需要 %s（进行中的专辑预留 %s，另保留 %s）",
+				dir, utils.FormatBytes(free), utils.FormatBytes(g.size), utils.FormatBytes(inFlight), utils.FormatBytes(reserve))
+		}
+	}
+
+	for id, g := range groups {
+		reservedSpace[id] += g.size
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			reservedSpaceMu.Lock()
+			defer reservedSpaceMu.Unlock()
+			for id, g := range groups {
+				if reservedSpace[id] -= g.size; reservedSpace[id] == 0 {
+					delete(reservedSpace, id)
+				}
+			}
+		})
+	}, nil
+}
+
+// diskGuard 下载过程中的剩余空间守护
+// 每首曲目开始下载前检查一次；空间低于 min-free-space-mb 时按 low-space-action 暂停或中止
+type diskGuard struct {
+	dirs    []string
+	reserve uint64
+	abort   bool
+
+	mu     sync.Mutex
+	paused bool
+	err    error
+}
+
+// newDiskGuard 为给定目录创建空间守护，关闭空间检查时返回 nil
+func newDiskGuard(dirs ...string) *diskGuard {
+	if core.Config.MinFreeSpaceMB < 0 {
+		return nil
+	}
+	return &diskGuard{
+		dirs:    dirs,
+		reserve: uint64(core.Config.MinFreeSpaceMB) << 20,
+		abort:   core.Config.LowSpaceAction == "abort",
+	}
+}
+
+// lowest 返回剩余空间不足的目录及其剩余空间，全部充足时返回空字符串
+func (g *diskGuard) lowest() (string, uint64) {
+	for _, dir := range g.dirs {
+		free, err := utils.FreeSpace(dir)
+		if err != nil {
+			continue
+		}
+		if free < g.reserve {
+			return dir, free
+		}
+	}
+	return "", 0
+}
+
+// Wait 在开始下载新曲目前调用
+// 空间充足时立即返回；不足时暂停等待空间恢复，或在 abort 模式下返回错误（之后的调用都会返回同一错误）
+func (g *diskGuard) Wait() error {
+	if g == nil {
+		return nil
+	}
+	for {
+		g.mu.Lock()
+		if g.err != nil {
+			err := g.err
+			g.mu.Unlock()
+			return err
+		}
+
+		dir, free := g.lowest()
+		if dir == "" {
+			if g.paused {
+				g.paused = false
+				logger.Info("✅ 磁盘空间已恢复，继续下载")
+			}
+			g.mu.Unlock()
+			return nil
+		}
+
+		if g.abort {
+			g.err = fmt.Errorf("磁盘空间不足: %s 剩余 %s", dir, utils.FormatBytes(free))
+			logger.Error("❌ %s，低于保留空间 %s，中止当前专辑", g.err, utils.FormatBytes(g.reserve))
+			notify.DiskSpaceLow(dir, fmt.Sprintf("%s，低于保留空间 %s，已中止当前专辑", g.err, utils.FormatBytes(g.reserve)))
+			err := g.err
+			g.mu.Unlock()
+			return err
+		}
+
+		if !g.paused {
+			g.paused = true
+			logger.Warn("⚠️  磁盘空间不足: %s 剩余 %s，低于保留空间 %s，暂停开始新的曲目，释放空间后自动继续",
+				dir, utils.FormatBytes(free), utils.FormatBytes(g.reserve))
+			notify.DiskSpaceLow(dir, fmt.Sprintf("%s 剩余 %s，低于保留空间 %s，下载已暂停，释放空间后自动继续",
+				dir, utils.FormatBytes(free), utils.FormatBytes(g.reserve)))
+		}
+		g.mu.Unlock()
+		time.Sleep(diskGuardPollInterval)
+	}
+}
+
+// Err 返回中止原因，未中止时返回 nil
+func (g *diskGuard) Err() error {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.err
+}
+
+// rollbackAlbum 中止时回滚本次写入目标目录的曲目文件
+// 仅删除本次下载的文件；专辑目录由本次运行创建（created）且只剩封面等附属文件时一并移除，
+// 已存在的目录始终保留
+func rollbackAlbum(albumFolder string, written []string, created bool) {
+	for _, path := range written {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			logger.Warn("回滚删除文件失败 %s: %v", path, err)
+		}
+	}
+	if !created {
+		return
+	}
+
+	entries, err := os.ReadDir(albumFolder)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if e.IsDir() || ext == ".m4a" || ext == ".mp4" || ext == ".lrc" {
+			return
+		}
+	}
+	if err := os.RemoveAll(albumFolder); err != nil {
+		logger.Warn("回滚删除专辑目录失败 %s: %v", albumFolder, err)
+	}
+}
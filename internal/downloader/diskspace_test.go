@@ -0,0 +1,81 @@
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"main/internal/core"
+	"main/internal/utils"
+)
+
+// TestRollbackAlbum 测试中止回滚：只删除本次写入的文件，已存在的专辑目录始终保留
+func TestRollbackAlbum(t *testing.T) {
+	existing := filepath.Join(t.TempDir(), "Existing")
+	if err := os.MkdirAll(existing, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"cover.jpg", "01. Old.m4a", "02. New.m4a"} {
+		if err := os.WriteFile(filepath.Join(existing, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rollbackAlbum(existing, []string{filepath.Join(existing, "01. Old.m4a"), filepath.Join(existing, "02. New.m4a")}, false)
+	if _, err := os.Stat(filepath.Join(existing, "cover.jpg")); err != nil {
+		t.Errorf("existing album folder should be kept: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(existing, "02. New.m4a")); !os.IsNotExist(err) {
+		t.Errorf("written track should be removed: %v", err)
+	}
+
+	created := filepath.Join(t.TempDir(), "Created")
+	if err := os.MkdirAll(created, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(created, "cover.jpg"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rollbackAlbum(created, nil, true)
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Errorf("album folder created by this run should be removed: %v", err)
+	}
+}
+
+// TestCheckDiskSpaceSharedFileSystem 测试空间预检：同一文件系统上的目录需求合并计算，
+// 进行中专辑的预留在释放前计入后续预检
+func TestCheckDiskSpaceSharedFileSystem(t *testing.T) {
+	defer func(mb int) { core.Config.MinFreeSpaceMB = mb }(core.Config.MinFreeSpaceMB)
+	core.Config.MinFreeSpaceMB = 0
+
+	root := t.TempDir()
+	cache, final := filepath.Join(root, "cache"), filepath.Join(root, "final")
+	free, err := utils.FreeSpace(root)
+	if err != nil {
+		t.Skipf("FreeSpace unsupported: %v", err)
+	}
+	part := free / 5 * 3
+
+	// 单独都放得下，合计超出剩余空间
+	if _, err := checkDiskSpace(map[string]uint64{cache: part, final: part}); err == nil {
+		t.Error("needs on the same file system should be summed")
+	}
+
+	release, err := checkDiskSpace(map[string]uint64{final: part})
+	if err != nil {
+		t.Fatalf("first album: %v", err)
+	}
+	if _, err := checkDiskSpace(map[string]uint64{cache: part}); err == nil {
+		t.Error("space reserved by an album in flight should be counted")
+	}
+	release()
+	release()
+	releaseNext, err := checkDiskSpace(map[string]uint64{cache: part})
+	if err != nil {
+		t.Errorf("reservation should be released: %v", err)
+	} else {
+		releaseNext()
+	}
+	if len(reservedSpace) != 0 {
+		t.Errorf("reservedSpace = %v, want empty", reservedSpace)
+	}
+}
@@ -2,16 +2,23 @@ package downloader
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"main/internal/api"
+	"main/internal/constants"
 	"main/internal/core"
+	"main/internal/hooks"
 	"main/internal/logger"
 	"main/internal/metadata"
+	"main/internal/metrics"
+	"main/internal/notify"
 	"main/internal/parser"
 	"main/internal/progress"
+	"main/internal/quality"
+	"main/internal/singles"
 	"main/internal/ui"
 	"main/internal/utils"
 	"main/utils/lyrics"
@@ -101,6 +108,30 @@ func GetCacheBasePath(targetPath, albumId string) (string, string, bool) {
 	return cachePath, targetPath, true
 }
 
+// saveAnimatedArtwork 下载动画封面，已存在完整文件时跳过
+func saveAnimatedArtwork(url, outPath string) {
+	if validation, _ := utils.ValidateFile(outPath, 0); validation != nil && validation.Exists && validation.IsValid {
+		return
+	}
+	runFFmpegAtomic(outPath, "-loglevel", "quiet", "-y", "-i", url, "-c", "copy", "-f", "mp4")
+}
+
+// runFFmpegAtomic 运行 ffmpeg 并将输出写入 .part 临时文件，成功后再重命名为 outPath
+// args 中需包含 -f 指定输出格式（临时文件没有可供 ffmpeg 推断格式的扩展名）
+func runFFmpegAtomic(outPath string, args ...string) error {
+	partPath := utils.PartPath(outPath)
+	cmd := exec.Command("ffmpeg", append(args, partPath)...)
+	if err := cmd.Run(); err != nil {
+		os.Remove(partPath)
+		return err
+	}
+	if err := utils.CommitFile(partPath, outPath); err != nil {
+		os.Remove(partPath)
+		return err
+	}
+	return nil
+}
+
 // SafeMoveFile 导出utils包中的SafeMoveFile函数，方便外部调用
 func SafeMoveFile(src, dst string) error {
 	return utils.SafeMoveFile(src, dst)
@@ -126,10 +157,14 @@ func checkAndReEncodeTrack(trackPath string, statusIndex int, notifier *progress
 		notifier.NotifyStatus(statusIndex, "文件损坏, 正在重新编码...", "reencode")
 	}
 
-	tempTrackPath := trackPath + ".fixed.m4a"
+	tempTrackPath := utils.PartPath(strings.TrimSuffix(trackPath, utils.PartSuffix) + ".fixed")
 	defer os.Remove(tempTrackPath)
 
 	encodeArgs := strings.Fields(core.Config.FfmpegEncodeArgs)
+	// 临时文件没有 .m4a 扩展名，未指定输出格式时补充 -f mp4
+	if !utils.Contains(encodeArgs, "-f") {
+		encodeArgs = append(encodeArgs, "-f", "mp4")
+	}
 	cmdEncodeArgs := append([]string{"-i", trackPath}, encodeArgs...)
 	cmdEncodeArgs = append(cmdEncodeArgs, tempTrackPath)
 
@@ -139,20 +174,21 @@ func checkAndReEncodeTrack(trackPath string, statusIndex int, notifier *progress
 	err = encodeCmd.Run()
 
 	if err != nil {
+		metrics.FfmpegRepairs.Inc("failed")
 		return true, fmt.Errorf("重新编码失败: %v, FFMPEG输出: %s", err, encodeStderr.String())
 	}
 
-	if err := os.Remove(trackPath); err != nil {
-		return true, fmt.Errorf("删除损坏的原文件失败: %w", err)
-	}
+	// 重命名覆盖是原子操作，中断时原文件保持不变
 	if err := os.Rename(tempTrackPath, trackPath); err != nil {
+		metrics.FfmpegRepairs.Inc("failed")
 		return true, fmt.Errorf("替换为修复文件失败: %w", err)
 	}
 
+	metrics.FfmpegRepairs.Inc("ok")
 	return true, nil
 }
 
-func downloadTrackWithFallback(track structs.TrackData, meta *structs.AutoGenerated, albumId, storefront, baseSaveFolder, finalSaveFolder, Codec, covPath string, workingAccounts []structs.Account, initialAccountIndex int, statusIndex int, updateStatus func(index int, status string, sColor func(a ...interface{}) string), progressChan chan runv14.ProgressUpdate) (string, error) {
+func downloadTrackWithFallback(job *core.Job, track structs.TrackData, meta *structs.AutoGenerated, albumId, storefront, baseSaveFolder, finalSaveFolder, Codec string, workingAccounts []structs.Account, initialAccountIndex int, statusIndex int, updateStatus func(index int, status string, sColor func(a ...interface{}) string), progressChan chan runv14.ProgressUpdate) (string, error) {
 	maxRetries := 3 // 每个账号最多重试次数
 	var lastError error
 	yellow := color.New(color.FgYellow).SprintFunc()
@@ -167,11 +203,14 @@ func downloadTrackWithFallback(track structs.TrackData, meta *structs.AutoGenera
 		account := &workingAccounts[accountIndex]
 
 		for attempt := 0; attempt <= maxRetries; attempt++ {
-			trackPath, err := downloadTrackSilently(track, meta, albumId, storefront, baseSaveFolder, finalSaveFolder, Codec, covPath, account, progressChan)
+			trackPath, err := downloadTrackSilently(job, track, meta, albumId, storefront, baseSaveFolder, finalSaveFolder, Codec, account, progressChan)
 			if err == nil {
 				return trackPath, nil
 			}
 			lastError = err
+			logger.With("album_id", albumId, "track_id", track.ID, "account", account.Name,
+				"storefront", account.Storefront, "stage", "download", "attempt", attempt+1).
+				Debug("曲目下载失败: %v", err)
 
 			// 检测连接被拒绝错误
 			if strings.Contains(err.Error(), "connection refused") {
@@ -213,11 +252,11 @@ func downloadTrackWithFallback(track structs.TrackData, meta *structs.AutoGenera
 	return "", fmt.Errorf("所有账户失败: %s", errorMsg)
 }
 
-func downloadTrackSilently(track structs.TrackData, meta *structs.AutoGenerated, albumId, storefront, baseSaveFolder, finalSaveFolder, Codec, covPath string, account *structs.Account, progressChan chan runv14.ProgressUpdate) (string, error) {
+func downloadTrackSilently(job *core.Job, track structs.TrackData, meta *structs.AutoGenerated, albumId, storefront, baseSaveFolder, finalSaveFolder, Codec string, account *structs.Account, progressChan chan runv14.ProgressUpdate) (string, error) {
 	if track.Type == "music-videos" {
 		// 专辑中的MV：下载到专辑目录，使用简化命名（和歌曲一样的命名规则）
 		if !core.Config.DownloadVideos {
-			core.OkDict[albumId] = append(core.OkDict[albumId], -1)
+			job.MarkDone(albumId, -1)
 			return "", nil
 		}
 
@@ -242,23 +281,23 @@ func downloadTrackSilently(track structs.TrackData, meta *structs.AutoGenerated,
 
 		// 使用和歌曲相同的文件夹结构
 		var singerFoldername, albumFoldername string
-		if core.Config.ArtistFolderFormat != "" {
+		if job.ArtistFolderFormat != "" {
 			if strings.Contains(albumId, "pl.") {
 				singerFoldername = strings.NewReplacer(
 					"{ArtistName}", "Apple Music", "{ArtistId}", "", "{UrlArtistName}", "Apple Music",
-				).Replace(core.Config.ArtistFolderFormat)
+				).Replace(job.ArtistFolderFormat)
 			} else if len(meta.Data[0].Relationships.Artists.Data) > 0 {
 				singerFoldername = strings.NewReplacer(
 					"{UrlArtistName}", core.LimitString(meta.Data[0].Attributes.ArtistName),
 					"{ArtistName}", core.LimitString(meta.Data[0].Attributes.ArtistName),
 					"{ArtistId}", meta.Data[0].Relationships.Artists.Data[0].ID,
-				).Replace(core.Config.ArtistFolderFormat)
+				).Replace(job.ArtistFolderFormat)
 			} else {
 				singerFoldername = strings.NewReplacer(
 					"{UrlArtistName}", core.LimitString(meta.Data[0].Attributes.ArtistName),
 					"{ArtistName}", core.LimitString(meta.Data[0].Attributes.ArtistName),
 					"{ArtistId}", "",
-				).Replace(core.Config.ArtistFolderFormat)
+				).Replace(job.ArtistFolderFormat)
 			}
 		}
 
@@ -329,17 +368,18 @@ func downloadTrackSilently(track structs.TrackData, meta *structs.AutoGenerated,
 		}
 
 		// 强制下载模式跳过文件存在性检查
-		if !core.ForceDownload {
-			exists, err := utils.FileExists(checkPath)
-			if err != nil {
-				return "", errors.New("failed to check if MV exists")
+		if !job.Force {
+			validation, err := utils.ValidateFile(checkPath, 0)
+			if validation == nil {
+				return "", fmt.Errorf("failed to check if MV exists: %w", err)
 			}
-			if exists {
-				core.SharedLock.Lock()
-				core.OkDict[albumId] = append(core.OkDict[albumId], trackNum)
-				core.SharedLock.Unlock()
+			if validation.Exists && validation.IsValid {
+				job.MarkDone(albumId, trackNum)
 				return returnPath, nil
 			}
+			if validation.Exists {
+				logger.Warn("已存在的MV不完整，将重新下载: %s", checkPath)
+			}
 		}
 
 		// 下载MV（带加固的错误处理和资源清理）
@@ -361,10 +401,11 @@ func downloadTrackSilently(track structs.TrackData, meta *structs.AutoGenerated,
 			return "", fmt.Errorf("获取MV播放列表失败: %w", err)
 		}
 		if mvm3u8url == "" {
+			notify.TokenExpired(account.Name, errors.New("media-user-token may be wrong or expired"))
 			return "", errors.New("media-user-token may be wrong or expired")
 		}
 
-		videom3u8url, _, err := parser.ExtractVideo(mvm3u8url)
+		videom3u8url, _, err := parser.ExtractVideo(job.Options, mvm3u8url)
 		if err != nil {
 			return "", fmt.Errorf("提取视频流URL失败: %w", err)
 		}
@@ -385,7 +426,7 @@ func downloadTrackSilently(track structs.TrackData, meta *structs.AutoGenerated,
 			}
 		}
 
-		audiom3u8url, err := parser.ExtractMvAudio(mvm3u8url)
+		audiom3u8url, err := parser.ExtractMvAudio(job.Options, mvm3u8url)
 		if err != nil {
 			return "", fmt.Errorf("提取音频流URL失败: %w", err)
 		}
@@ -405,25 +446,23 @@ func downloadTrackSilently(track structs.TrackData, meta *structs.AutoGenerated,
 			}
 		}
 
-		// 合并视频和音频
-		muxCmd := exec.Command("MP4Box", "-quiet", "-add", vidPath, "-add", audPath, "-keep-utc", "-new", mvPath)
+		// 合并视频和音频（写入 .part 临时文件，标签写入完成后由调用方提交为最终文件名）
+		mvPartPath := utils.PartPath(mvPath)
+		muxCmd := exec.Command("MP4Box", "-quiet", "-add", vidPath, "-add", audPath, "-keep-utc", "-new", mvPartPath)
 		if err := muxCmd.Run(); err != nil {
+			os.Remove(mvPartPath)
 			return "", fmt.Errorf("合并视频音频失败: %w", err)
 		}
 
 		// 验证最终文件大小
-		if mvInfo, err := os.Stat(mvPath); err == nil {
+		if mvInfo, err := os.Stat(mvPartPath); err == nil {
 			if mvInfo.Size() < 1024*1024 { // 小于1MB视为异常
-				os.Remove(mvPath) // 删除异常文件
+				os.Remove(mvPartPath) // 删除异常文件
 				return "", fmt.Errorf("合并后的MV文件过小 (%d bytes)，已删除", mvInfo.Size())
 			}
 		}
 
-		core.SharedLock.Lock()
-		core.OkDict[albumId] = append(core.OkDict[albumId], trackNum)
-		core.SharedLock.Unlock()
-
-		return mvPath, nil
+		return mvPartPath, nil
 	}
 
 	manifest, err := api.GetInfoFromAdam(track.ID, account, storefront)
@@ -432,13 +471,13 @@ func downloadTrackSilently(track structs.TrackData, meta *structs.AutoGenerated,
 	}
 
 	if manifest.Attributes.ExtendedAssetUrls.EnhancedHls == "" {
-		if core.Dl_atmos {
+		if job.Atmos {
 			return "", errors.New("atmos unavailable")
 		}
 		// For AAC modes that need specific stream selection, we need to check M3U8
-		if core.Dl_aac && (*core.Aac_type == "aac-binaural" || *core.Aac_type == "aac-downmix") {
+		if job.AAC && (job.AacType == "aac-binaural" || job.AacType == "aac-downmix") {
 			// These AAC types require stream selection, need to check M3U8
-		} else if core.Dl_aac && *core.Aac_type == "aac-lc" {
+		} else if job.AAC && job.AacType == "aac-lc" {
 			// AAC-LC also needs token for decryption
 		}
 	}
@@ -450,67 +489,21 @@ func downloadTrackSilently(track structs.TrackData, meta *structs.AutoGenerated,
 		needCheck = true
 	}
 	var EnhancedHls_m3u8 string
-	if needCheck && !core.Dl_aac {
+	if needCheck && !job.AAC {
 		EnhancedHls_m3u8, _ = parser.CheckM3u8(track.ID, "song", account)
 		if strings.HasSuffix(EnhancedHls_m3u8, ".m3u8") {
 			manifest.Attributes.ExtendedAssetUrls.EnhancedHls = EnhancedHls_m3u8
 		}
 	}
-	var Quality string
-	if strings.Contains(core.Config.SongFileFormat, "Quality") {
-		if core.Dl_atmos {
-			Quality = fmt.Sprintf("%dkbps", *core.Atmos_max-2000)
-		} else if core.Dl_aac && *core.Aac_type == "aac-lc" {
-			Quality = "256kbps"
-		} else if core.Dl_aac {
-			// For other AAC types, try to extract quality from M3U8
-			if manifest.Attributes.ExtendedAssetUrls.EnhancedHls != "" {
-				_, Quality, _, err = parser.ExtractMedia(manifest.Attributes.ExtendedAssetUrls.EnhancedHls, true)
-				if err != nil {
-					Quality = ""
-				}
-			} else {
-				Quality = "AAC"
-			}
-		} else {
-			_, Quality, _, err = parser.ExtractMedia(manifest.Attributes.ExtendedAssetUrls.EnhancedHls, true)
-			if err != nil {
-				Quality = ""
-			}
-		}
-	}
-	// Determine quality tag: RESPECT USER CHOICE FIRST, then fallback to auto-detection
-	// {Tag} variable is specifically for audio quality
-	var Tag_string string
-
-	// Priority 1: Respect user's explicit quality choice
-	if core.Dl_atmos {
-		Tag_string = utils.FormatQualityTag("Dolby Atmos")
-	} else if core.Dl_aac && *core.Aac_type == "aac-binaural" {
-		Tag_string = utils.FormatQualityTag("Aac Binaural")
-	} else if core.Dl_aac && *core.Aac_type == "aac-downmix" {
-		Tag_string = utils.FormatQualityTag("Aac Downmix")
-	} else if core.Dl_aac && *core.Aac_type == "aac-lc" {
-		Tag_string = utils.FormatQualityTag("Aac 256")
-	} else if core.Dl_aac {
-		// Generic AAC mode - check if user wants specific type
-		if *core.Aac_type != "aac" {
-			Tag_string = utils.FormatQualityTag("Aac " + strings.Title(*core.Aac_type))
-		} else {
-			Tag_string = utils.FormatQualityTag("Aac 256")
-		}
-	} else {
-		// Priority 2: Auto-detect best quality when user didn't specify
-		if utils.Contains(track.Attributes.AudioTraits, "hi-res-lossless") {
-			Tag_string = utils.FormatQualityTag("Hi-Res Lossless")
-		} else if utils.Contains(track.Attributes.AudioTraits, "lossless") {
-			Tag_string = utils.FormatQualityTag("Alac")
-		} else if utils.Contains(track.Attributes.AudioTraits, "atmos") {
-			Tag_string = utils.FormatQualityTag("Dolby Atmos")
-		} else {
-			Tag_string = utils.FormatQualityTag("Aac 256")
-		}
+	// {Quality}：固定码率之外取按音质策略选中的档位（选择失败时为空）
+	hls := manifest.Attributes.ExtendedAssetUrls.EnhancedHls
+	var label string
+	if _, preset := presetQuality(job); !preset && hls != "" {
+		_, label, _, _ = parser.ExtractMedia(job.Options, hls, true)
 	}
+	Quality := fileQuality(job, label, hls == "")
+	// {Tag} 音质标签：由下载模式（或音质策略选定的档位）与曲目音频特性决定
+	Tag_string := quality.Tag(job.Rule(), track.Attributes.AudioTraits)
 
 	trackNum := -1
 	for i, t := range meta.Data[0].Relationships.Tracks.Data {
@@ -523,85 +516,206 @@ func downloadTrackSilently(track structs.TrackData, meta *structs.AutoGenerated,
 		return "", errors.New("track not found in metadata")
 	}
 
+	sanitizedSingerFolder, sanitizedAlbumFolder, songNameFormat, isSingle := trackFolders(job, meta, albumId, track, Quality, Codec, Tag_string)
+	effectiveTrackNum := trackNum
+
+	if isSingle {
+		// 已在目录中的单曲沿用原编号，新单曲排在目录中最大编号之后
+		dir := singlesDir(baseSaveFolder, finalSaveFolder, sanitizedSingerFolder, sanitizedAlbumFolder, songNameFormat)
+		num, err := singles.Assign(dir, singlesEntry(meta, track))
+		if err != nil {
+			return "", err
+		}
+		effectiveTrackNum = num
+		// 保存有效曲目编号，供后续WriteMP4Tags使用（确保文件名和标签编号一致）
+		job.SetEffectiveTrackNumber(track.ID, effectiveTrackNum)
+	}
+
+	songName := strings.ReplaceAll(songNameFormat, "{SongNumer}", fmt.Sprintf("%02d", effectiveTrackNum))
+	sanitizedSongName := core.ForbiddenNames.ReplaceAllString(songName, "_")
+	filenameWithExt := fmt.Sprintf("%s.m4a", sanitizedSongName)
+
+	finalArtistDir, finalAlbumDir, finalFilename := utils.EnsureSafePath(baseSaveFolder, sanitizedSingerFolder, sanitizedAlbumFolder, filenameWithExt)
+	var finalSingerFolder string
+	if finalArtistDir != "" {
+		finalSingerFolder = filepath.Join(baseSaveFolder, finalArtistDir)
+	} else {
+		finalSingerFolder = baseSaveFolder
+	}
+	finalAlbumFolder := filepath.Join(finalSingerFolder, finalAlbumDir)
+	if err := os.MkdirAll(finalAlbumFolder, 0755); err != nil {
+		return "", fmt.Errorf("创建专辑目录失败: %w", err)
+	}
+	trackPath := filepath.Join(finalAlbumFolder, finalFilename)
+
+	// 检查文件是否存在：如果使用缓存，检查最终目标路径；否则检查当前路径
+	checkPath := trackPath
+	returnPath := trackPath
+	if finalSaveFolder != baseSaveFolder {
+		// 使用缓存时，检查最终目标路径是否已存在文件
+		var targetSingerFolder string
+		if finalArtistDir != "" {
+			targetSingerFolder = filepath.Join(finalSaveFolder, finalArtistDir)
+		} else {
+			targetSingerFolder = finalSaveFolder
+		}
+		targetAlbumFolder := filepath.Join(targetSingerFolder, finalAlbumDir)
+		checkPath = filepath.Join(targetAlbumFolder, finalFilename)
+		returnPath = checkPath // 如果文件已存在，返回最终目标路径而非缓存路径
+	}
+
+	// 强制下载模式跳过文件存在性检查
+	if !job.Force {
+		validation, err := utils.ValidateFile(checkPath, 0)
+		if validation == nil {
+			return "", fmt.Errorf("failed to check if track exists: %w", err)
+		}
+		if validation.Exists && validation.IsValid {
+			logger.With("album_id", albumId, "track_id", track.ID, "stage", "check").Debug("[文件跳过] 文件已存在: %s", checkPath)
+			job.MarkDone(albumId, trackNum)
+			// 返回特殊标记 "EXISTS:" + 路径，表示文件已存在（不需要转移）
+			return "EXISTS:" + returnPath, nil
+		}
+		if validation.Exists {
+			logger.With("album_id", albumId, "track_id", track.ID, "stage", "check").Warn("已存在的文件不完整，将重新下载: %s", checkPath)
+		}
+	}
+
+	// 下载到 .part 临时文件，全部后处理完成后由调用方提交为最终文件名
+	partPath := utils.PartPath(trackPath)
+	if job.AAC && job.AacType == "aac-lc" {
+		if len(account.MediaUserToken) <= 50 {
+			return "", errors.New("invalid media-user-token")
+		}
+		_, err := runv3.Run(track.ID, partPath, core.DeveloperToken, account.MediaUserToken, false)
+		if err != nil {
+			os.Remove(partPath)
+			return "", fmt.Errorf("failed to dl aac-lc: %w", err)
+		}
+	} else {
+		trackM3u8Url, choice, _, err := parser.SelectMedia(job.Options, manifest.Attributes.ExtendedAssetUrls.EnhancedHls, false)
+		if err != nil {
+			return "", fmt.Errorf("failed to extract info from manifest: %w", err)
+		}
+		// 多音乐库模式只输出选定的档位，不回退到其他档位
+		if choice.Fallback && job.StrictQuality {
+			return "", fmt.Errorf("没有 %s 版本，已跳过", job.Rule())
+		}
+		// 曲目没有专辑选定的档位时按策略回退，在运行输出中说明
+		if choice.Index > 0 || choice.Fallback {
+			logger.With("album_id", albumId, "track_id", track.ID, "stage", "quality").Info("🎚️ %s: %s", track.Attributes.Name, choice.Reason)
+		}
+		err = runv14.Run(track.ID, trackM3u8Url, partPath, account, core.Config, progressChan)
+		if err != nil {
+			os.Remove(partPath)
+			return "", fmt.Errorf("failed to run v14 with account %s: %w", account.Name, err)
+		}
+	}
+
+	// 下载完成后还要写入标签并提交，由调用方在提交成功后标记完成
+	return partPath, nil
+}
+
+// presetQuality 不依赖曲目播放列表即可确定的 {Quality}：文件名不含 {Quality}、杜比全景声与 AAC-LC
+func presetQuality(job *core.Job) (string, bool) {
+	switch {
+	case !strings.Contains(core.Config.SongFileFormat, "Quality"):
+		return "", true
+	case job.Atmos:
+		return fmt.Sprintf("%dkbps", job.AtmosMax-2000), true
+	case job.AAC && job.AacType == "aac-lc":
+		return "256kbps", true
+	}
+	return "", false
+}
+
+// fileQuality 文件名 {Quality} 的取值，下载与 dry-run 共用
+// label 为按音质策略选中的档位（Variant.Label），noHls 表示曲目没有增强播放列表（AAC 记为 "AAC"）
+func fileQuality(job *core.Job, label string, noHls bool) string {
+	if q, ok := presetQuality(job); ok {
+		return q
+	}
+	if job.AAC && noHls {
+		return "AAC"
+	}
+	return label
+}
+
+// trackFolders 返回曲目的艺术家文件夹、专辑文件夹（已替换非法字符）与文件名格式（{SongNumer} 未替换），
+// 以及专辑是否归入虚拟合辑；曲号最后替换，虚拟Singles的编号取决于目标目录中已有的单曲
+func trackFolders(job *core.Job, meta *structs.AutoGenerated, albumId string, track structs.TrackData, Quality, Codec, Tag_string string) (string, string, string, bool) {
+	// 名称按 metadata-language.folder 选择语言，ID 与发行类型仍按原元数据
+	names, nameTrack := core.FolderNames(meta, track)
 	var singerFoldername, albumFoldername string
-	if core.Config.ArtistFolderFormat != "" {
+	if job.ArtistFolderFormat != "" {
 		if strings.Contains(albumId, "pl.") {
 			singerFoldername = strings.NewReplacer(
 				"{ArtistName}", "Apple Music", "{ArtistId}", "", "{UrlArtistName}", "Apple Music",
-			).Replace(core.Config.ArtistFolderFormat)
+			).Replace(job.ArtistFolderFormat)
 		} else if len(meta.Data[0].Relationships.Artists.Data) > 0 {
 			singerFoldername = strings.NewReplacer(
-				"{UrlArtistName}", core.LimitString(meta.Data[0].Attributes.ArtistName),
-				"{ArtistName}", core.LimitString(meta.Data[0].Attributes.ArtistName),
+				"{UrlArtistName}", core.LimitString(names.Data[0].Attributes.ArtistName),
+				"{ArtistName}", core.LimitString(names.Data[0].Attributes.ArtistName),
 				"{ArtistId}", meta.Data[0].Relationships.Artists.Data[0].ID,
-			).Replace(core.Config.ArtistFolderFormat)
+			).Replace(job.ArtistFolderFormat)
 		} else {
 			singerFoldername = strings.NewReplacer(
-				"{UrlArtistName}", core.LimitString(meta.Data[0].Attributes.ArtistName),
-				"{ArtistName}", core.LimitString(meta.Data[0].Attributes.ArtistName),
+				"{UrlArtistName}", core.LimitString(names.Data[0].Attributes.ArtistName),
+				"{ArtistName}", core.LimitString(names.Data[0].Attributes.ArtistName),
 				"{ArtistId}", "",
-			).Replace(core.Config.ArtistFolderFormat)
+			).Replace(job.ArtistFolderFormat)
 		}
 	}
 
 	// 检查是否为虚拟Singles专辑
 	isSingle := core.IsSingleAlbum(meta)
 
-	// 对于虚拟Singles专辑，提前计算有效的曲目编号（在整个函数中复用，避免重复调用）
-	effectiveTrackNum := trackNum
 	var primaryArtist string
 	if isSingle {
 		// 单曲专辑：始终使用主要艺术家（从专辑艺术家名中提取第一个）
 		// 这样 "Alec Benjamin [feat. 陈婧霏]" 会被归类到 "Alec Benjamin - Singles"
 		// "陈婧霏" 会被归类到 "陈婧霏 - Singles"
-		primaryArtist = core.GetPrimaryArtist(meta.Data[0].Attributes.ArtistName)
+		primaryArtist = core.PrimaryArtist(names)
 		logger.Debug("[虚拟Singles] 专辑: '%s', 专辑艺术家: '%s', 主要艺术家: '%s'",
 			meta.Data[0].Attributes.Name,
 			meta.Data[0].Attributes.ArtistName,
 			primaryArtist)
 
-		effectiveTrackNum = core.GetVirtualSinglesTrackNumber(primaryArtist)
-		// 保存有效曲目编号，供后续WriteMP4Tags使用（确保文件名和标签编号一致）
-		core.SetTrackEffectiveNumber(track.ID, effectiveTrackNum)
-
 		// 对于虚拟Singles专辑，艺术家文件夹也应使用主要艺术家
 		// 覆盖之前设置的 singerFoldername（避免使用完整的合作艺术家名）
-		if core.Config.ArtistFolderFormat != "" {
+		if job.ArtistFolderFormat != "" {
 			singerFoldername = strings.NewReplacer(
 				"{UrlArtistName}", core.LimitString(primaryArtist),
 				"{ArtistName}", core.LimitString(primaryArtist),
 				"{ArtistId}", "", // Singles 专辑不需要艺术家ID
-			).Replace(core.Config.ArtistFolderFormat)
+			).Replace(job.ArtistFolderFormat)
 		}
 	}
 
 	if strings.Contains(albumId, "pl.") {
 		albumFoldername = strings.NewReplacer(
-			"{PlaylistName}", core.LimitString(meta.Data[0].Attributes.Name),
+			"{PlaylistName}", core.LimitString(names.Data[0].Attributes.Name),
 			"{PlaylistId}", albumId, "{Quality}", Quality, "{Codec}", Codec, "{Tag}", Tag_string,
 		).Replace(core.Config.PlaylistFolderFormat)
 	} else if isSingle {
-		// 虚拟Singles专辑：使用主要艺术家名（处理合作者情况）
-		singlesFolder := core.Config.VirtualSinglesFolderName
-		if singlesFolder == "" {
-			singlesFolder = "Singles"
-		}
-		// 格式: "Olivia Rodrigo - Singles"
+		// 虚拟合辑（Singles / EPs）：使用主要艺术家名（处理合作者情况）
+		singlesFolder := core.VirtualFolderName(meta)
+		// 格式: "Olivia Rodrigo - Singles" / "Olivia Rodrigo - EPs"
 		albumFoldername = fmt.Sprintf("%s - %s", core.LimitString(primaryArtist), singlesFolder)
 	} else {
 		albumFoldername = strings.NewReplacer(
 			"{ReleaseDate}", meta.Data[0].Attributes.ReleaseDate, "{ReleaseYear}", meta.Data[0].Attributes.ReleaseDate[:4],
-			"{ArtistName}", core.LimitString(meta.Data[0].Attributes.ArtistName), "{AlbumName}", core.LimitString(meta.Data[0].Attributes.Name),
+			"{ArtistName}", core.LimitString(names.Data[0].Attributes.ArtistName), "{AlbumName}", core.LimitString(names.Data[0].Attributes.Name),
 			"{UPC}", meta.Data[0].Attributes.Upc, "{RecordLabel}", meta.Data[0].Attributes.RecordLabel,
 			"{Copyright}", meta.Data[0].Attributes.Copyright, "{AlbumId}", albumId,
 			"{Quality}", Quality, "{Codec}", Codec, "{Tag}", Tag_string,
 		).Replace(core.Config.AlbumFolderFormat)
 	}
 
-	songName := strings.NewReplacer(
+	// 曲号最后替换：虚拟Singles的编号取决于目标目录中已有的单曲
+	songNameFormat := strings.NewReplacer(
 		"{SongId}", track.ID,
-		"{SongNumer}", fmt.Sprintf("%02d", effectiveTrackNum),
-		"{SongName}", core.LimitString(track.Attributes.Name),
+		"{SongName}", core.LimitString(nameTrack.Attributes.Name),
 		"{DiscNumber}", fmt.Sprintf("%0d", track.Attributes.DiscNumber),
 		"{TrackNumber}", fmt.Sprintf("%0d", track.Attributes.TrackNumber),
 		"{Quality}", Quality,
@@ -611,120 +725,112 @@ func downloadTrackSilently(track structs.TrackData, meta *structs.AutoGenerated,
 
 	sanitizedSingerFolder := core.ForbiddenNames.ReplaceAllString(singerFoldername, "_")
 	sanitizedAlbumFolder := core.ForbiddenNames.ReplaceAllString(albumFoldername, "_")
-	sanitizedSongName := core.ForbiddenNames.ReplaceAllString(songName, "_")
-	filenameWithExt := fmt.Sprintf("%s.m4a", sanitizedSongName)
+	return sanitizedSingerFolder, sanitizedAlbumFolder, songNameFormat, isSingle
+}
 
-	finalArtistDir, finalAlbumDir, finalFilename := utils.EnsureSafePath(baseSaveFolder, sanitizedSingerFolder, sanitizedAlbumFolder, filenameWithExt)
-	var finalSingerFolder string
-	if finalArtistDir != "" {
-		finalSingerFolder = filepath.Join(baseSaveFolder, finalArtistDir)
-	} else {
-		finalSingerFolder = baseSaveFolder
-	}
-	finalAlbumFolder := filepath.Join(finalSingerFolder, finalAlbumDir)
-	if err := os.MkdirAll(finalAlbumFolder, 0755); err != nil {
-		return "", fmt.Errorf("创建专辑目录失败: %w", err)
-	}
-	trackPath := filepath.Join(finalAlbumFolder, finalFilename)
+// singlesDir 返回虚拟Singles专辑在目标位置的目录
+// 与曲目路径相同，按下载目录（可能是缓存）计算路径截断；两位曲号的文件名长度相同，按 "99" 计算
+func singlesDir(baseSaveFolder, finalSaveFolder, singerFolder, albumFolder, songNameFormat string) string {
+	fileName := core.ForbiddenNames.ReplaceAllString(strings.ReplaceAll(songNameFormat, "{SongNumer}", "99"), "_") + ".m4a"
+	artistDir, albumDir, _ := utils.EnsureSafePath(baseSaveFolder, singerFolder, albumFolder, fileName)
+	return filepath.Join(finalSaveFolder, artistDir, albumDir)
+}
 
-	// 检查文件是否存在：如果使用缓存，检查最终目标路径；否则检查当前路径
-	checkPath := trackPath
-	returnPath := trackPath
-	if finalSaveFolder != baseSaveFolder {
-		// 使用缓存时，检查最终目标路径是否已存在文件
-		var targetSingerFolder string
-		if finalArtistDir != "" {
-			targetSingerFolder = filepath.Join(finalSaveFolder, finalArtistDir)
-		} else {
-			targetSingerFolder = finalSaveFolder
-		}
-		targetAlbumFolder := filepath.Join(targetSingerFolder, finalAlbumDir)
-		checkPath = filepath.Join(targetAlbumFolder, finalFilename)
-		returnPath = checkPath // 如果文件已存在，返回最终目标路径而非缓存路径
+// singlesEntry 根据曲目元数据生成虚拟Singles目录中的单曲记录
+func singlesEntry(meta *structs.AutoGenerated, track structs.TrackData) singles.Entry {
+	return singles.Entry{
+		ISRC:        track.Attributes.Isrc,
+		AlbumID:     meta.Data[0].ID,
+		Title:       track.Attributes.Name,
+		ReleaseDate: track.Attributes.ReleaseDate,
 	}
+}
 
-	// 强制下载模式跳过文件存在性检查
-	if !core.ForceDownload {
-		exists, err := utils.FileExists(checkPath)
+// trackCover 返回内嵌到曲目的封面路径；虚拟Singles与播放列表（dl-albumcover-for-playlist）为每首曲目单独下载原始封面，
+// 此时 temp 为 true，调用方内嵌后删除
+func trackCover(assets *albumAssets, track structs.TrackData, albumId, folder, covPath string, isSingle bool) (path string, temp bool) {
+	if !core.Config.EmbedCover || track.Type == "music-videos" {
+		return "", false
+	}
+	if isSingle || (strings.Contains(albumId, "pl.") && core.Config.DlAlbumcoverForPlaylist) {
+		path, err := assets.cover(folder, track.ID, track.Attributes.Artwork.URL)
 		if err != nil {
-			return "", errors.New("failed to check if track exists")
-		}
-		if exists {
-			logger.Debug("[文件跳过] 文件已存在: %s", checkPath)
-			core.SharedLock.Lock()
-			core.OkDict[albumId] = append(core.OkDict[albumId], trackNum)
-			core.SharedLock.Unlock()
-			// 返回特殊标记 "EXISTS:" + 路径，表示文件已存在（不需要转移）
-			return "EXISTS:" + returnPath, nil
+			logger.Warn("下载曲目封面失败 %s: %v", track.ID, err)
+			return "", false
 		}
+		return path, true
 	}
+	return covPath, false
+}
 
-	if core.Dl_aac && *core.Aac_type == "aac-lc" {
-		if len(account.MediaUserToken) <= 50 {
-			return "", errors.New("invalid media-user-token")
-		}
-		_, err := runv3.Run(track.ID, trackPath, core.DeveloperToken, account.MediaUserToken, false)
-		if err != nil {
-			return "", fmt.Errorf("failed to dl aac-lc: %w", err)
-		}
-	} else {
-		trackM3u8Url, _, _, err := parser.ExtractMedia(manifest.Attributes.ExtendedAssetUrls.EnhancedHls, false)
-		if err != nil {
-			return "", fmt.Errorf("failed to extract info from manifest: %w", err)
-		}
-		err = runv14.Run(track.ID, trackM3u8Url, trackPath, account, core.Config, progressChan)
-		if err != nil {
-			return "", fmt.Errorf("failed to run v14 with account %s: %w", account.Name, err)
-		}
+func Rip(job *core.Job, albumId string, storefront string, urlArg_i string, urlRaw string, notifier *progress.ProgressNotifier) error {
+	mainAccount, err := core.GetAccountForStorefront(storefront)
+	if err != nil {
+		return err
 	}
 
-	tags := []string{
-		"tool=",
+	meta, err := api.GetMeta(albumId, mainAccount, storefront)
+	if err != nil {
+		return err
 	}
-	var trackCovPath string
-	if core.Config.EmbedCover {
-		// 对于Singles虚拟专辑，每个单曲内嵌其原始封面（嵌入后删除临时文件）
-		if isSingle {
-			// 下载单曲的原始封面作为临时文件
-			trackCovPath, err = metadata.WriteCover(finalAlbumFolder, track.ID, track.Attributes.Artwork.URL)
-			if err == nil {
-				tags = append(tags, fmt.Sprintf("cover=%s", trackCovPath))
-			}
-		} else if strings.Contains(albumId, "pl.") && core.Config.DlAlbumcoverForPlaylist {
-			_, _, safeCoverFilename := utils.EnsureSafePath(baseSaveFolder, finalArtistDir, finalAlbumDir, track.ID+".jpg")
-			trackCovPath, err = metadata.WriteCover(finalAlbumFolder, strings.TrimSuffix(safeCoverFilename, ".jpg"), track.Attributes.Artwork.URL)
-			if err == nil {
-				tags = append(tags, fmt.Sprintf("cover=%s", trackCovPath))
+	job.Board.SetTitle(fmt.Sprintf("%s - %s", meta.Data[0].Attributes.ArtistName, meta.Data[0].Attributes.Name))
+
+	// 版权预检：所有账户都无法访问时，在其他已配置地区中按 UPC / ISRC 查找同一发行并改从该地区下载
+	var accounts []structs.Account
+	prechecked := !core.Debug_mode && len(meta.Data[0].Relationships.Tracks.Data) > 0
+	if prechecked {
+		accounts = precheckAccounts(meta.Data[0].Relationships.Tracks.Data[0].ID, albumId)
+		if len(accounts) == 0 {
+			m, err := resolveStorefront(meta, albumId, storefront)
+			if err != nil {
+				return err
 			}
-		} else {
-			tags = append(tags, fmt.Sprintf("cover=%s", covPath))
+			job.RecordMatch(m.StorefrontMatch)
+			accounts = precheckAccounts(firstMapped(meta, m.tracks), albumId)
+			meta = m.apply(meta)
+			mainAccount, storefront, urlArg_i = m.account, m.MatchedStorefront, m.trackID(urlArg_i)
 		}
 	}
-	tagsString := strings.Join(tags, ":")
-	cmd := exec.Command("MP4Box", "-quiet", "-itags", tagsString, trackPath)
-	_ = cmd.Run()
-	// 删除临时封面文件：播放列表和Singles虚拟专辑都需要删除
-	if trackCovPath != "" && (isSingle || (strings.Contains(albumId, "pl.") && core.Config.DlAlbumcoverForPlaylist)) {
-		_ = os.Remove(trackCovPath)
-	}
 
-	core.OkDict[albumId] = append(core.OkDict[albumId], trackNum)
-	return trackPath, nil
-}
-
-func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, notifier *progress.ProgressNotifier) error {
-	mainAccount, err := core.GetAccountForStorefront(storefront)
-	if err != nil {
-		return err
+	// 多音乐库模式下依次输出每个档位，元数据、曲目选择、歌词与封面在各输出之间共享
+	outputs := outputJobs(job, meta, albumId)
+	if len(outputs) == 0 {
+		logger.With("album_id", albumId, "url", urlRaw, "stage", "plan").Info("⏭️  %s: 没有可输出的音乐库版本", meta.Data[0].Attributes.Name)
+		return nil
+	}
+	assets := newAlbumAssets()
+	if prechecked {
+		assets.workingAccounts(func() []structs.Account { return accounts })
+	}
+	var errs []error
+	for _, out := range outputs {
+		err := ripOutput(out, mainAccount, meta, assets, albumId, storefront, urlArg_i, urlRaw, notifier)
+		if err != nil && len(outputs) == 1 {
+			return err
+		}
+		if err != nil {
+			logger.With("album_id", albumId, "stage", "download").Error("%s 输出失败: %v", out.Codec(), err)
+			errs = append(errs, fmt.Errorf("%s: %w", out.Codec(), err))
+		}
 	}
+	return errors.Join(errs...)
+}
 
+// ripOutput 按任务的下载模式将专辑输出到对应的音乐库
+// 已完成的输出（所有文件已存在）单独跳过，不影响其他输出
+func ripOutput(job *core.Job, mainAccount *structs.Account, meta *structs.AutoGenerated, assets *albumAssets, albumId, storefront, urlArg_i, urlRaw string, notifier *progress.ProgressNotifier) error {
+	var err error
 	var covPath string // 存储专辑封面路径，用于虚拟Singles专辑
 	var isSingle bool  // 标识是否为虚拟Singles专辑
 
-	meta, err := api.GetMeta(albumId, mainAccount, storefront)
-	if err != nil {
-		return err
-	}
+	// 为本专辑派生通知器：继承外部监听器，并将进度写入本专辑的状态面板
+	notifier = notifier.Fork()
+	notifier.AddListener(ui.NewUIProgressListener(job.Board))
+	notifier.AddListener(progress.NewMetricsListener(job.Codec()))
+
+	// 多专辑并行时由调用方统一渲染所有专辑的状态面板；否则本专辑独占动态UI
+	boardSet := job.BoardSet()
+	exclusiveUI := !core.DisableDynamicUI && boardSet == nil
 	var lyricAccount *structs.Account
 	for i := range core.Config.Accounts {
 		acc := &core.Config.Accounts[i]
@@ -749,27 +855,20 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 			firstTrack := meta.Data[0].Relationships.Tracks.Data[0]
 			manifest, err := api.GetInfoFromAdam(firstTrack.ID, mainAccount, storefront)
 			if err == nil && manifest.Attributes.ExtendedAssetUrls.EnhancedHls != "" {
-				_, _, _, _ = parser.ExtractMedia(manifest.Attributes.ExtendedAssetUrls.EnhancedHls, true)
+				_, _, _, _ = parser.ExtractMedia(job.Options, manifest.Attributes.ExtendedAssetUrls.EnhancedHls, true)
 			}
 		}
 		return nil
 	}
 
-	var Codec string
-	if core.Dl_atmos {
-		Codec = "ATMOS"
-	} else if core.Dl_aac {
-		Codec = "AAC"
-	} else {
-		Codec = "ALAC"
-	}
+	Codec := job.Codec()
 
 	var baseSaveFolder string
 	var finalSaveFolder string
 	var usingCache bool
-	if core.Dl_atmos {
+	if job.Atmos {
 		finalSaveFolder = core.Config.AtmosSaveFolder
-	} else if core.Dl_aac {
+	} else if job.AAC {
 		finalSaveFolder = core.Config.AacSaveFolder
 	} else {
 		finalSaveFolder = core.Config.AlacSaveFolder
@@ -786,124 +885,31 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 		}
 	}()
 
-	// 检查是否为虚拟Singles专辑（需要提前检查以正确设置艺术家文件夹）
+	// 检查是否为虚拟Singles专辑
 	isSingle = core.IsSingleAlbum(meta)
 
-	var singerFoldername, albumFoldername string
-	if core.Config.ArtistFolderFormat != "" {
-		if strings.Contains(albumId, "pl.") {
-			singerFoldername = strings.NewReplacer(
-				"{ArtistName}", "Apple Music", "{ArtistId}", "", "{UrlArtistName}", "Apple Music",
-			).Replace(core.Config.ArtistFolderFormat)
-		} else if isSingle {
-			// 对于虚拟Singles专辑，艺术家文件夹也应使用主要艺术家（避免合作艺术家分散）
-			primaryArtist := core.GetPrimaryArtist(meta.Data[0].Attributes.ArtistName)
-			singerFoldername = strings.NewReplacer(
-				"{UrlArtistName}", core.LimitString(primaryArtist),
-				"{ArtistName}", core.LimitString(primaryArtist),
-				"{ArtistId}", "", // Singles 专辑不需要艺术家ID
-			).Replace(core.Config.ArtistFolderFormat)
-		} else if len(meta.Data[0].Relationships.Artists.Data) > 0 {
-			singerFoldername = strings.NewReplacer(
-				"{UrlArtistName}", core.LimitString(meta.Data[0].Attributes.ArtistName),
-				"{ArtistName}", core.LimitString(meta.Data[0].Attributes.ArtistName),
-				"{ArtistId}", meta.Data[0].Relationships.Artists.Data[0].ID,
-			).Replace(core.Config.ArtistFolderFormat)
-		} else {
-			singerFoldername = strings.NewReplacer(
-				"{UrlArtistName}", core.LimitString(meta.Data[0].Attributes.ArtistName),
-				"{ArtistName}", core.LimitString(meta.Data[0].Attributes.ArtistName),
-				"{ArtistId}", "",
-			).Replace(core.Config.ArtistFolderFormat)
-		}
-	}
-
 	var Quality string
 
-	// Pre-detect album quality by checking all tracks' audio traits
-	// This ensures we use the correct tag from the start, avoiding duplicate folders
-	isHires := false
-	isLossless := false
-	hasAtmos := false
-	if !core.Dl_atmos && !core.Dl_aac {
-		for _, track := range meta.Data[0].Relationships.Tracks.Data {
-			if utils.Contains(track.Attributes.AudioTraits, "hi-res-lossless") {
-				isHires = true
-				break
-			}
-			if utils.Contains(track.Attributes.AudioTraits, "lossless") {
-				isLossless = true
-			}
-			if utils.Contains(track.Attributes.AudioTraits, "atmos") {
-				hasAtmos = true
-			}
-		}
+	// 按所有曲目的音频特性预先确定专辑音质标签，避免同一专辑产生多个文件夹
+	var albumTraits []string
+	for _, track := range meta.Data[0].Relationships.Tracks.Data {
+		albumTraits = append(albumTraits, track.Attributes.AudioTraits...)
 	}
+	isHires := utils.Contains(albumTraits, "hi-res-lossless")
+	Album_Tag_string := quality.Tag(job.Rule(), albumTraits)
 
-	// Determine Album Quality Tag: RESPECT USER CHOICE FIRST, then fallback to auto-detection
-	var Album_Tag_string string
-
-	// Priority 1: Respect user's explicit quality choice
-	if core.Dl_atmos {
-		Album_Tag_string = utils.FormatQualityTag("Dolby Atmos")
-	} else if core.Dl_aac && *core.Aac_type == "aac-binaural" {
-		Album_Tag_string = utils.FormatQualityTag("Aac Binaural")
-	} else if core.Dl_aac && *core.Aac_type == "aac-downmix" {
-		Album_Tag_string = utils.FormatQualityTag("Aac Downmix")
-	} else if core.Dl_aac && *core.Aac_type == "aac-lc" {
-		Album_Tag_string = utils.FormatQualityTag("Aac 256")
-	} else if core.Dl_aac {
-		// Generic AAC mode - check if user wants specific type
-		if *core.Aac_type != "aac" {
-			Album_Tag_string = utils.FormatQualityTag("Aac " + strings.Title(*core.Aac_type))
-		} else {
-			Album_Tag_string = utils.FormatQualityTag("Aac 256")
-		}
-	} else {
-		// Priority 2: Auto-detect best quality when user didn't specify download mode
-		if isHires {
-			Album_Tag_string = utils.FormatQualityTag("Hi-Res Lossless")
-		} else if isLossless {
-			Album_Tag_string = utils.FormatQualityTag("Alac")
-		} else if hasAtmos {
-			Album_Tag_string = utils.FormatQualityTag("Dolby Atmos")
-		} else {
-			Album_Tag_string = utils.FormatQualityTag("Aac 256")
-		}
-	}
-
-	if strings.Contains(albumId, "pl.") {
-		albumFoldername = strings.NewReplacer(
-			"{PlaylistName}", core.LimitString(meta.Data[0].Attributes.Name),
-			"{PlaylistId}", albumId, "{Quality}", Quality, "{Codec}", Codec, "{Tag}", Album_Tag_string,
-		).Replace(core.Config.PlaylistFolderFormat)
-	} else if isSingle {
-		// 虚拟Singles专辑：使用主要艺术家名（处理合作者情况）
-		singlesFolder := core.Config.VirtualSinglesFolderName
-		if singlesFolder == "" {
-			singlesFolder = "Singles"
-		}
-		// 提取主要艺术家（处理合作者：取第一个&之前的名字）
-		primaryArtist := core.GetPrimaryArtist(meta.Data[0].Attributes.ArtistName)
-		// 格式: "Olivia Rodrigo - Singles"
-		albumFoldername = fmt.Sprintf("%s - %s", core.LimitString(primaryArtist), singlesFolder)
-	} else {
-		albumFoldername = strings.NewReplacer(
-			"{ReleaseDate}", meta.Data[0].Attributes.ReleaseDate, "{ReleaseYear}", meta.Data[0].Attributes.ReleaseDate[:4],
-			"{ArtistName}", core.LimitString(meta.Data[0].Attributes.ArtistName), "{AlbumName}", core.LimitString(meta.Data[0].Attributes.Name),
-			"{UPC}", meta.Data[0].Attributes.Upc, "{RecordLabel}", meta.Data[0].Attributes.RecordLabel,
-			"{Copyright}", meta.Data[0].Attributes.Copyright, "{AlbumId}", albumId,
-			"{Quality}", Quality, "{Codec}", Codec, "{Tag}", Album_Tag_string,
-		).Replace(core.Config.AlbumFolderFormat)
+	// 艺术家与专辑文件夹与曲目路径使用相同的命名（trackFolders）
+	var firstTrack structs.TrackData
+	if len(meta.Data[0].Relationships.Tracks.Data) > 0 {
+		firstTrack = meta.Data[0].Relationships.Tracks.Data[0]
 	}
-
-	sanitizedSingerFolder := core.ForbiddenNames.ReplaceAllString(singerFoldername, "_")
-	sanitizedAlbumFolder := core.ForbiddenNames.ReplaceAllString(albumFoldername, "_")
+	sanitizedSingerFolder, sanitizedAlbumFolder, _, _ := trackFolders(job, meta, albumId, firstTrack, Quality, Codec, Album_Tag_string)
 
 	var longestFilename string
-	for i := range meta.Data[0].Relationships.Tracks.Data {
-		if len(meta.Data[0].Relationships.Tracks.Data[i].Attributes.Name) > len(longestFilename) {
-			longestFilename = meta.Data[0].Relationships.Tracks.Data[i].Attributes.Name
+	names, _ := core.FolderNames(meta, firstTrack)
+	for i := range names.Data[0].Relationships.Tracks.Data {
+		if len(names.Data[0].Relationships.Tracks.Data[i].Attributes.Name) > len(longestFilename) {
+			longestFilename = names.Data[0].Relationships.Tracks.Data[i].Attributes.Name
 		}
 	}
 	longestFilename = strings.NewReplacer(
@@ -923,16 +929,19 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 		finalSingerFolder = baseSaveFolder
 	}
 	finalAlbumFolder := filepath.Join(finalSingerFolder, finalAlbumDir)
+	// 记录专辑目录是否由本次运行创建，中止回滚时不删除已存在的目录
+	_, statErr := os.Stat(finalAlbumFolder)
+	albumFolderCreated := os.IsNotExist(statErr)
 	if err := os.MkdirAll(finalAlbumFolder, 0755); err != nil {
 		return fmt.Errorf("创建专辑目录失败: %w", err)
 	}
 
-	core.SafePrintf("🎤 歌手: %s\n", meta.Data[0].Attributes.ArtistName)
-	core.SafePrintf("💽 专辑: %s\n", meta.Data[0].Attributes.Name)
+	logger.With("album_id", albumId, "url", urlRaw).Info("🎤 歌手: %s", meta.Data[0].Attributes.ArtistName)
+	logger.With("album_id", albumId, "url", urlRaw).Info("💽 专辑: %s", meta.Data[0].Attributes.Name)
 
 	if core.Config.SaveArtistCover && !(strings.Contains(albumId, "pl.")) {
 		if len(meta.Data[0].Relationships.Artists.Data) > 0 {
-			_, err = metadata.WriteCover(finalSingerFolder, "folder", meta.Data[0].Relationships.Artists.Data[0].Attributes.Artwork.Url)
+			_, err = assets.cover(finalSingerFolder, "folder", meta.Data[0].Relationships.Artists.Data[0].Attributes.Artwork.Url)
 			if err != nil {
 			}
 		}
@@ -940,125 +949,79 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 	// 处理专辑封面
 	if isSingle {
 		// 对于虚拟Singles专辑，不下载统一的封面文件
-		// 每个单曲会在downloadTrackSilently中保存自己独立的封面（文件名与m4a一致）
-		// covPath 保持空值，downloadTrackSilently 会为每个单曲单独下载封面
+		// covPath 保持空值，写入标签时会为每个单曲单独下载封面（trackCover）
 		covPath = ""
 	} else {
 		// 非虚拟Singles专辑，下载统一的专辑封面
 		var err error
-		covPath, err = metadata.WriteCover(finalAlbumFolder, "cover", meta.Data[0].Attributes.Artwork.URL)
+		covPath, err = assets.cover(finalAlbumFolder, "cover", meta.Data[0].Attributes.Artwork.URL)
 		if err != nil {
 		}
 	}
 	if core.Config.SaveAnimatedArtwork && meta.Data[0].Attributes.EditorialVideo.MotionDetailSquare.Video != "" {
-		motionvideoUrlSquare, _, err := parser.ExtractVideo(meta.Data[0].Attributes.EditorialVideo.MotionDetailSquare.Video)
+		motionvideoUrlSquare, _, err := parser.ExtractVideo(job.Options, meta.Data[0].Attributes.EditorialVideo.MotionDetailSquare.Video)
 		if err == nil {
-			exists, _ := utils.FileExists(filepath.Join(finalAlbumFolder, "square_animated_artwork.mp4"))
-			if !exists {
-				cmd := exec.Command("ffmpeg", "-loglevel", "quiet", "-y", "-i", motionvideoUrlSquare, "-c", "copy", filepath.Join(finalAlbumFolder, "square_animated_artwork.mp4"))
-				_ = cmd.Run()
-			}
+			assets.animatedArtwork(motionvideoUrlSquare, filepath.Join(finalAlbumFolder, "square_animated_artwork.mp4"))
 		}
 
 		if core.Config.EmbyAnimatedArtwork {
-			cmd3 := exec.Command("ffmpeg", "-loglevel", "quiet", "-y", "-i", filepath.Join(finalAlbumFolder, "square_animated_artwork.mp4"), "-vf", "scale=440:-1", "-r", "24", "-f", "gif", filepath.Join(finalAlbumFolder, "folder.jpg"))
-			_ = cmd3.Run()
+			runFFmpegAtomic(filepath.Join(finalAlbumFolder, "folder.jpg"), "-loglevel", "quiet", "-y", "-i", filepath.Join(finalAlbumFolder, "square_animated_artwork.mp4"), "-vf", "scale=440:-1", "-r", "24", "-f", "gif")
 		}
 
-		motionvideoUrlTall, _, err := parser.ExtractVideo(meta.Data[0].Attributes.EditorialVideo.MotionDetailTall.Video)
+		motionvideoUrlTall, _, err := parser.ExtractVideo(job.Options, meta.Data[0].Attributes.EditorialVideo.MotionDetailTall.Video)
 		if err == nil {
-			exists, _ := utils.FileExists(filepath.Join(finalAlbumFolder, "tall_animated_artwork.mp4"))
-			if !exists {
-				cmd := exec.Command("ffmpeg", "-loglevel", "quiet", "-y", "-i", motionvideoUrlTall, "-c", "copy", filepath.Join(finalAlbumFolder, "tall_animated_artwork.mp4"))
-				_ = cmd.Run()
-			}
+			assets.animatedArtwork(motionvideoUrlTall, filepath.Join(finalAlbumFolder, "tall_animated_artwork.mp4"))
 		}
 	}
 
-	// SelectTracks可能涉及交互式输入，暂停UI
-	if !core.DisableDynamicUI && core.Dl_select {
-		ui.Suspend()
-	}
-	selected := ui.SelectTracks(meta, storefront, urlArg_i)
-	if !core.DisableDynamicUI && core.Dl_select {
-		ui.Resume()
+	// SelectTracks可能涉及交互式输入，暂停UI；多音乐库模式下只选择一次
+	selected := assets.selectTracks(func() []int {
+		if !core.DisableDynamicUI && job.Select {
+			ui.Suspend()
+			defer ui.Resume()
+		}
+		return ui.SelectTracks(job, meta, storefront, urlArg_i)
+	})
+	selected = availableTracks(job, meta, albumId, selected)
+	if len(selected) == 0 {
+		return nil
 	}
 
-	core.SafePrintln("🔬 正在进行版权预检，请稍候...")
-	var workingAccounts []structs.Account
-	if len(meta.Data[0].Relationships.Tracks.Data) > 0 {
-		firstTrackId := meta.Data[0].Relationships.Tracks.Data[0].ID
-		for _, acc := range core.Config.Accounts {
-			_, err := api.GetInfoFromAdam(firstTrackId, &acc, acc.Storefront)
-			if err == nil {
-				workingAccounts = append(workingAccounts, acc)
-			} else {
-				core.SafePrintf("账户 [%s] 无法访问此专辑 (可能无版权)，本次任务将跳过该账户。\n", acc.Name)
-			}
-		}
-	} else {
+	if len(meta.Data[0].Relationships.Tracks.Data) == 0 {
 		return errors.New("专辑中没有曲目")
 	}
+	workingAccounts := assets.workingAccounts(func() []structs.Account {
+		return precheckAccounts(meta.Data[0].Relationships.Tracks.Data[0].ID, albumId)
+	})
 
 	if len(workingAccounts) == 0 {
 		return errors.New("所有账户均无法访问此专辑，任务中止")
 	}
 
+	// 按选中曲目的音频特性显示专辑音质（与质量标签同源）并选择下载线程数
+	var selectedTraits []string
+	for _, trackIndex := range selected {
+		selectedTraits = append(selectedTraits, meta.Data[0].Relationships.Tracks.Data[trackIndex-1].Attributes.AudioTraits...)
+	}
 	albumQualityType := "AAC"
-	albumQualityString := "AAC"
-
-	// Priority 1: Respect user's explicit quality choice for display
-	if core.Dl_atmos {
+	switch quality.Tag(job.Rule(), selectedTraits) {
+	case quality.TagHiRes:
+		albumQualityType = "Hi-Res Lossless"
+	case quality.TagAlac:
+		albumQualityType = "Lossless"
+	case quality.TagAtmos:
 		albumQualityType = "Dolby Atmos"
-		albumQualityString = "Dolby Atmos"
-	} else if core.Dl_aac && *core.Aac_type == "aac-binaural" {
+	case quality.TagAACBinaural:
 		albumQualityType = "AAC Binaural"
-		albumQualityString = "AAC Binaural"
-	} else if core.Dl_aac && *core.Aac_type == "aac-downmix" {
+	case quality.TagAACDownmix:
 		albumQualityType = "AAC Downmix"
-		albumQualityString = "AAC Downmix"
-	} else if core.Dl_aac && *core.Aac_type == "aac-lc" {
-		albumQualityType = "AAC 256"
-		albumQualityString = "AAC 256"
-	} else if core.Dl_aac {
-		// Generic AAC mode - check if user wants specific type
-		if *core.Aac_type != "aac" {
-			albumQualityType = "AAC " + strings.Title(*core.Aac_type)
-			albumQualityString = "AAC " + strings.Title(*core.Aac_type)
-		} else {
-			albumQualityType = "AAC"
-			albumQualityString = "AAC"
-		}
-	} else {
-		// Priority 2: Auto-detect quality for display when user didn't specify
-		// isHires and isLossless already declared earlier, just reset them
-		isHires = false
-		isLossless = false
-
-		for _, trackIndex := range selected {
-			track := meta.Data[0].Relationships.Tracks.Data[trackIndex-1]
-			if utils.Contains(track.Attributes.AudioTraits, "hi-res-lossless") {
-				isHires = true
-				break
-			}
-			if utils.Contains(track.Attributes.AudioTraits, "lossless") {
-				isLossless = true
-			}
-		}
-
-		if isHires {
-			albumQualityType = "Hi-Res Lossless"
-			albumQualityString = "Hi-Res Lossless"
-			// Update Album_Tag_string for Hi-Res Lossless
-			if !core.Dl_atmos && !core.Dl_aac {
-				Album_Tag_string = utils.FormatQualityTag("Hi-Res Lossless")
-			}
-		} else if isLossless {
-			albumQualityType = "Lossless"
-			albumQualityString = "Lossless"
-			// Alac tag is already set as default for lossless
+	default:
+		if job.AAC && job.AacType == "aac-lc" {
+			albumQualityType = "AAC 256"
 		}
 	}
+	albumQualityString := albumQualityType
+	isHires = albumQualityType == "Hi-Res Lossless"
 
 	var numThreads int
 	switch albumQualityType {
@@ -1089,20 +1052,26 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 
 	yellow := color.New(color.FgYellow).SprintFunc()
 	green := color.New(color.FgGreen).SprintFunc()
-	core.SafePrintf("%s %s | %s | %s | %s\n",
+	logger.Info("%s %s | %s | %s | %s",
 		green("📡 音源:"),
 		green(albumQualityString),
 		green(fmt.Sprintf("%d 线程", numThreads)),
 		yellow(regionsStr),
 		green(fmt.Sprintf("%d 个账户并行下载", len(workingAccounts))),
 	)
-	core.SafePrintln(strings.Repeat("-", 45))
+	logger.Info("%s", strings.Repeat("-", 45))
 
-	core.RipLock.Lock()
-	defer core.RipLock.Unlock()
+	// 动态UI独占终端区域；禁用动态UI或多专辑统一渲染时允许多个专辑同时下载
+	if exclusiveUI {
+		core.RipLock.Lock()
+		defer core.RipLock.Unlock()
+	}
+	if boardSet != nil {
+		defer boardSet.Remove(job.Board)
+	}
 
 	// 强制下载模式下跳过文件存在性预检
-	if !core.ForceDownload {
+	if !job.Force {
 		// 快速检查所有文件是否已存在（仅文件系统检查，不读取内容）
 		var checkSaveFolder string
 		if usingCache {
@@ -1111,93 +1080,30 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 			checkSaveFolder = baseSaveFolder
 		}
 
-		// 构建所有需要检查的文件路径
+		// 构建所有需要检查的文件路径（与下载时使用相同的命名）
+		// {Quality} 取决于各曲目的播放列表时无法预先确定文件名，交由逐曲目检查
 		type trackFileInfo struct {
 			trackNum int
 			filePath string
 			duration int
 		}
 		var filesToCheck []trackFileInfo
-
-		for _, trackNum := range selected {
-			track := meta.Data[0].Relationships.Tracks.Data[trackNum-1]
-
-			// 快速构建文件路径
-			var singerFoldername, albumFoldername string
-			if core.Config.ArtistFolderFormat != "" {
-				if strings.Contains(albumId, "pl.") {
-					singerFoldername = strings.NewReplacer(
-						"{ArtistName}", "Apple Music", "{ArtistId}", "", "{UrlArtistName}", "Apple Music",
-					).Replace(core.Config.ArtistFolderFormat)
-				} else if isSingle {
-					// 对于虚拟Singles专辑，艺术家文件夹也应使用主要艺术家
-					primaryArtist := core.GetPrimaryArtist(meta.Data[0].Attributes.ArtistName)
-					singerFoldername = strings.NewReplacer(
-						"{UrlArtistName}", core.LimitString(primaryArtist),
-						"{ArtistName}", core.LimitString(primaryArtist),
-						"{ArtistId}", "",
-					).Replace(core.Config.ArtistFolderFormat)
-				} else if len(meta.Data[0].Relationships.Artists.Data) > 0 {
-					singerFoldername = strings.NewReplacer(
-						"{UrlArtistName}", core.LimitString(meta.Data[0].Attributes.ArtistName),
-						"{ArtistName}", core.LimitString(meta.Data[0].Attributes.ArtistName),
-						"{ArtistId}", meta.Data[0].Relationships.Artists.Data[0].ID,
-					).Replace(core.Config.ArtistFolderFormat)
-				}
-			}
-
-			if strings.Contains(albumId, "pl.") {
-				albumFoldername = strings.NewReplacer(
-					"{PlaylistName}", core.LimitString(meta.Data[0].Attributes.Name),
-					"{PlaylistId}", albumId,
-				).Replace(core.Config.PlaylistFolderFormat)
-			} else if isSingle {
-				// 虚拟Singles专辑：使用主要艺术家名（处理合作者情况）
-				singlesFolder := core.Config.VirtualSinglesFolderName
-				if singlesFolder == "" {
-					singlesFolder = "Singles"
-				}
-				// 提取主要艺术家（处理合作者：取第一个&之前的名字）
-				primaryArtist := core.GetPrimaryArtist(meta.Data[0].Attributes.ArtistName)
-				// 格式: "Olivia Rodrigo - Singles"
-				albumFoldername = fmt.Sprintf("%s - %s", core.LimitString(primaryArtist), singlesFolder)
-			} else {
-				albumFoldername = strings.NewReplacer(
-					"{ArtistName}", core.LimitString(meta.Data[0].Attributes.ArtistName),
-					"{AlbumName}", core.LimitString(meta.Data[0].Attributes.Name),
-					"{AlbumId}", albumId,
-				).Replace(core.Config.AlbumFolderFormat)
-			}
-
-			songName := strings.NewReplacer(
-				"{SongNumer}", fmt.Sprintf("%02d", trackNum),
-				"{SongName}", core.LimitString(track.Attributes.Name),
-			).Replace(core.Config.SongFileFormat)
-
-			sanitizedSingerFolder := core.ForbiddenNames.ReplaceAllString(singerFoldername, "_")
-			sanitizedAlbumFolder := core.ForbiddenNames.ReplaceAllString(albumFoldername, "_")
-			sanitizedSongName := core.ForbiddenNames.ReplaceAllString(songName, "_")
-			filenameWithExt := fmt.Sprintf("%s.m4a", sanitizedSongName)
-
-			checkArtistDir, checkAlbumDir, checkFilename := utils.EnsureSafePath(checkSaveFolder, sanitizedSingerFolder, sanitizedAlbumFolder, filenameWithExt)
-			var checkSingerFolder string
-			if checkArtistDir != "" {
-				checkSingerFolder = filepath.Join(checkSaveFolder, checkArtistDir)
-			} else {
-				checkSingerFolder = checkSaveFolder
+		presetQualityString, preset := presetQuality(job)
+		if preset {
+			for _, trackNum := range selected {
+				track := meta.Data[0].Relationships.Tracks.Data[trackNum-1]
+				// 虚拟Singles目录中尚没有的单曲路径为空，对应的文件不存在
+				checkFilePath, _ := plannedPath(job, meta, albumId, track, checkSaveFolder, presetQualityString)
+				filesToCheck = append(filesToCheck, trackFileInfo{
+					trackNum: trackNum,
+					filePath: checkFilePath,
+					duration: track.Attributes.DurationInMillis,
+				})
 			}
-			checkAlbumFolder := filepath.Join(checkSingerFolder, checkAlbumDir)
-			checkFilePath := filepath.Join(checkAlbumFolder, checkFilename)
-
-			filesToCheck = append(filesToCheck, trackFileInfo{
-				trackNum: trackNum,
-				filePath: checkFilePath,
-				duration: track.Attributes.DurationInMillis,
-			})
 		}
 
 		// 使用并发批量校验或串行校验
-		allFilesExist := true
+		allFilesExist := preset
 		if core.Config.FileValidation.ConcurrentCheckEnabled && len(filesToCheck) > 1 {
 			// 并发批量校验
 			logger.Debug("[文件校验] 使用并发模式检查 %d 个文件 (worker数: %d)",
@@ -1211,7 +1117,7 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 			// 估算最小文件大小
 			var minSize int64
 			if core.Config.FileValidation.SizeCheckEnabled && len(filesToCheck) > 0 {
-				minSize = utils.EstimateFileSize(Codec, core.Dl_atmos, filesToCheck[0].duration)
+				minSize = utils.EstimateFileSize(Codec, job.Atmos, filesToCheck[0].duration)
 				logger.Debug("[文件校验] 最小文件大小: %d 字节 (~%.1f MB)", minSize, float64(minSize)/(1024*1024))
 			}
 
@@ -1227,7 +1133,7 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 			for _, info := range filesToCheck {
 				var minSize int64
 				if core.Config.FileValidation.SizeCheckEnabled {
-					minSize = utils.EstimateFileSize(Codec, core.Dl_atmos, info.duration)
+					minSize = utils.EstimateFileSize(Codec, job.Atmos, info.duration)
 				}
 
 				validation, _ := utils.ValidateFile(info.filePath, minSize)
@@ -1241,15 +1147,19 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 		// 如果所有文件都已存在，直接跳过
 		if allFilesExist && len(selected) > 0 {
 			green := color.New(color.FgGreen).SprintFunc()
-			core.SafePrintln(green("✅ 所有文件已存在，任务完成！"))
+			if job.StrictQuality {
+				logger.With("album_id", albumId, "stage", "check").Info("%s", green(fmt.Sprintf("✅ %s 音乐库中所有文件已存在，跳过该输出", Codec)))
+			} else {
+				logger.With("album_id", albumId, "stage", "check").Info("%s", green("✅ 所有文件已存在，任务完成！"))
+			}
 			// 标记所有文件为已完成
 			for _, trackNum := range selected {
-				core.SharedLock.Lock()
-				core.OkDict[albumId] = append(core.OkDict[albumId], trackNum)
-				core.Counter.Total++
-				core.Counter.Success++
-				core.SharedLock.Unlock()
+				job.MarkDone(albumId, trackNum)
 			}
+			job.UpdateCounter(func(c *structs.Counter) {
+				c.Total += len(selected)
+				c.Success += len(selected)
+			})
 
 			// 清理可能存在的缓存目录（避免后续转移流程）
 			if usingCache {
@@ -1264,27 +1174,80 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 			return nil
 		}
 	} else {
-		core.SafePrintf("💪 强制下载模式：将覆盖已存在的文件\n")
+		logger.Info("💪 强制下载模式：将覆盖已存在的文件")
+	}
+
+	// 专辑在目标位置的目录（使用缓存时 finalAlbumFolder 位于缓存中）
+	destAlbumFolder := finalAlbumFolder
+	if usingCache {
+		if rel, err := filepath.Rel(baseSaveFolder, finalAlbumFolder); err == nil {
+			destAlbumFolder = filepath.Join(finalSaveFolder, rel)
+		}
+		_, statErr := os.Stat(destAlbumFolder)
+		albumFolderCreated = os.IsNotExist(statErr)
+	}
+	// 清理该专辑目录中上次运行崩溃或被中断时遗留的 .part 临时文件
+	if removed, _ := utils.CleanupPartFiles(destAlbumFolder, constants.StalePartFileAge); removed > 0 {
+		logger.With("album_id", albumId, "stage", "check").Info("🧹 已清理 %d 个未完成的临时文件（.part）", removed)
+	}
+
+	// 下载后处理：记录曲目结果，曲目落盘与专辑转移完成后执行钩子与响度分析
+	postProcess := newAlbumPost(job, meta, albumId, storefront, urlRaw, Codec, destAlbumFolder, baseSaveFolder, finalSaveFolder, usingCache)
+
+	// 磁盘空间预检：按曲目时长估算本次下载大小，检查缓存与目标文件系统的剩余空间
+	estimatedSize := estimateDownloadSize(meta, selected, Codec, job.Atmos, isHires)
+	spaceNeeded := map[string]uint64{finalSaveFolder: estimatedSize}
+	if usingCache {
+		cacheSize := estimatedSize
+		if core.Config.BatchSize > 0 && core.Config.BatchSize < len(selected) {
+			// 缓存中的文件每批转移一次，只需容纳一个批次
+			cacheSize = estimatedSize * uint64(core.Config.BatchSize) / uint64(len(selected))
+		}
+		spaceNeeded[baseSaveFolder] = cacheSize
+	}
+	releaseSpace, err := checkDiskSpace(spaceNeeded)
+	if err != nil {
+		if !usingCache {
+			rollbackAlbum(destAlbumFolder, nil, albumFolderCreated)
+		}
+		return err
+	}
+	defer releaseSpace()
+
+	// 下载过程中的剩余空间守护，以及中止时需要回滚的已写入目标目录的文件
+	guard := newDiskGuard(baseSaveFolder, finalSaveFolder)
+	var writtenMu sync.Mutex
+	var writtenFiles []string
+	recordWritten := func(path string) {
+		writtenMu.Lock()
+		writtenFiles = append(writtenFiles, path)
+		writtenMu.Unlock()
 	}
 
 	// 使用批次迭代器进行数据层分批处理
 	batchIterator := structs.NewBatchIterator(selected, core.Config.BatchSize)
 
 	for batch, hasMore := batchIterator.Next(); hasMore; batch, hasMore = batchIterator.Next() {
+		// 专辑已被跳过：不再开始后续批次，已完成的曲目照常转移
+		if job.Board.Skipped() {
+			logger.With("album_id", albumId, "stage", "download").Warn("⏭️  已跳过专辑剩余曲目: %s", job.Board.Title())
+			break
+		}
+
 		// 显示批次开始信息（多批次时）
 		if batch.TotalBatches > 1 {
-			if !core.DisableDynamicUI {
+			if exclusiveUI {
 				ui.Suspend()
 			}
 			cyan := color.New(color.FgCyan).SprintFunc()
-			core.SafePrintf("%s\n", cyan(fmt.Sprintf("📦 批次 %d/%d（%d 首）", batch.BatchNum, batch.TotalBatches, batch.BatchSize)))
-			if !core.DisableDynamicUI {
+			logger.With("album_id", albumId, "stage", "download").Info("%s", cyan(fmt.Sprintf("📦 批次 %d/%d（%d 首）", batch.BatchNum, batch.TotalBatches, batch.BatchSize)))
+			if exclusiveUI {
 				ui.Resume()
 			}
 		}
 
-		// 初始化当前批次的 TrackStatuses
-		core.TrackStatuses = make([]core.TrackStatus, len(batch.Tracks))
+		// 初始化当前批次的曲目状态面板
+		statuses := make([]core.TrackStatus, len(batch.Tracks))
 		for i, trackNum := range batch.Tracks {
 			track := meta.Data[0].Relationships.Tracks.Data[trackNum-1]
 
@@ -1295,7 +1258,7 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 			} else {
 				manifest, err := api.GetInfoFromAdam(track.ID, mainAccount, storefront)
 				if err == nil && manifest.Attributes.ExtendedAssetUrls.EnhancedHls != "" {
-					_, _, quality, err = parser.ExtractMedia(manifest.Attributes.ExtendedAssetUrls.EnhancedHls, false)
+					_, _, quality, err = parser.ExtractMedia(job.Options, manifest.Attributes.ExtendedAssetUrls.EnhancedHls, false)
 					if err != nil {
 						quality = "获取失败"
 					}
@@ -1304,7 +1267,7 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 				}
 			}
 
-			core.TrackStatuses[i] = core.TrackStatus{
+			statuses[i] = core.TrackStatus{
 				Index:       i,
 				TrackNum:    trackNum,
 				TrackTotal:  len(meta.Data[0].Relationships.Tracks.Data),
@@ -1315,41 +1278,77 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 			}
 		}
 
+		job.Board.Reset(statuses)
+		if boardSet != nil {
+			boardSet.Add(job.Board)
+		}
+
 		doneUI := make(chan struct{})
 		// 只有在未禁用动态UI时才启动UI渲染
-		if !core.DisableDynamicUI {
+		if exclusiveUI {
 			// 动态UI期间：将logger输出重定向到stderr，避免干扰光标定位
 			// UI使用stdout输出（带光标移动），logger使用stderr，互不干扰
 			logger.SetOutput(os.Stderr)
-			go ui.RenderUI(doneUI)
+			go ui.RenderUI(job.Board, doneUI)
 		}
 
 		var wg sync.WaitGroup
 		semaphore := make(chan struct{}, numThreads)
+		updateStatus := func(index int, status string, sColor func(a ...interface{}) string) {
+			ui.UpdateStatus(job.Board, index, status, sColor)
+		}
 
 		for i, trackNum := range batch.Tracks {
 			wg.Add(1)
 			go func(trackIndexInMeta int, statusIndex int) {
 				semaphore <- struct{}{}
+				// 专辑内线程数之外，还受所有专辑合计的曲目并发上限约束
+				job.AcquireTrackSlot()
 				defer func() {
+					job.ReleaseTrackSlot()
 					<-semaphore
 					wg.Done()
 				}()
 
 				trackData := meta.Data[0].Relationships.Tracks.Data[trackIndexInMeta-1]
 
-				core.SharedLock.Lock()
-				isDone := utils.IsInArray(core.OkDict[albumId], trackIndexInMeta)
-				core.SharedLock.Unlock()
-
-				if isDone {
+				if job.IsDone(albumId, trackIndexInMeta) {
 					if notifier != nil {
 						notifier.NotifyStatus(statusIndex, "已存在", "skipped")
 					}
-					core.SharedLock.Lock()
-					core.Counter.Total++
-					core.Counter.Success++
-					core.SharedLock.Unlock()
+					job.UpdateCounter(func(c *structs.Counter) {
+						c.Total++
+						c.Success++
+					})
+					return
+				}
+
+				// 全屏界面中按下暂停时，等待恢复后再开始新的曲目
+				if control := job.Control(); control.Paused() {
+					if notifier != nil {
+						notifier.NotifyStatus(statusIndex, "已暂停", "paused")
+					}
+					control.WaitIfPaused(context.Background())
+				}
+
+				// 专辑已被跳过：尚未开始的曲目不再下载
+				if job.Board.Skipped() {
+					if notifier != nil {
+						notifier.NotifyStatus(statusIndex, "已跳过", "skipped")
+					}
+					return
+				}
+
+				// 剩余空间不足时暂停等待，或在 abort 模式下放弃尚未开始的曲目
+				if err := guard.Wait(); err != nil {
+					job.UpdateCounter(func(c *structs.Counter) {
+						c.Total++
+						c.Error++
+					})
+					if notifier != nil {
+						notifier.NotifyError(statusIndex, err)
+					}
+					postProcess.record(trackIndexInMeta, trackData, hooks.TrackFailed, "", err)
 					return
 				}
 
@@ -1389,13 +1388,13 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 								} else {
 									status = fmt.Sprintf("%s 下载中 %d%% (%s)", yellow(accountInfo), p.Percentage, speedStr)
 								}
-								ui.UpdateStatus(statusIndex, status, color.New(color.FgYellow).SprintFunc())
+								ui.UpdateStatus(job.Board, statusIndex, status, color.New(color.FgYellow).SprintFunc())
 							}
 						}()
 						progressChan = ch
 					}
 
-					trackPath, err := downloadTrackWithFallback(trackData, meta, albumId, storefront, baseSaveFolder, finalSaveFolder, Codec, covPath, workingAccounts, statusIndex, statusIndex, ui.UpdateStatus, progressChan)
+					trackPath, err := downloadTrackWithFallback(job, trackData, meta, albumId, storefront, baseSaveFolder, finalSaveFolder, Codec, workingAccounts, statusIndex, statusIndex, updateStatus, progressChan)
 					close(progressChan)
 
 					if err != nil {
@@ -1407,21 +1406,29 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 							errorMsg = errorMsg[:47] + "..."
 						}
 
-						core.SharedLock.Lock()
-						core.Counter.Total++
 						// 检查是否是跳过类型的错误
-						if strings.Contains(err.Error(), "已跳过") {
-							if notifier != nil {
-								notifier.NotifyStatus(statusIndex, errorMsg, "skipped")
-							}
+						isSkipped := strings.Contains(err.Error(), "已跳过")
+						logger.With("album_id", albumId, "track_id", trackData.ID, "stage", "download", "skipped", isSkipped).
+							Debug("曲目最终失败: %v", err)
+						job.UpdateCounter(func(c *structs.Counter) {
+							c.Total++
 							// 跳过不计入错误统计
-						} else {
-							if notifier != nil {
+							if !isSkipped {
+								c.Error++
+							}
+						})
+						if notifier != nil {
+							if isSkipped {
+								notifier.NotifyStatus(statusIndex, errorMsg, "skipped")
+							} else {
 								notifier.NotifyError(statusIndex, fmt.Errorf("下载失败: %s", errorMsg))
 							}
-							core.Counter.Error++
 						}
-						core.SharedLock.Unlock()
+						if isSkipped {
+							postProcess.record(trackIndexInMeta, trackData, hooks.TrackSkipped, "", err)
+						} else {
+							postProcess.record(trackIndexInMeta, trackData, hooks.TrackFailed, "", err)
+						}
 						return
 					}
 
@@ -1438,7 +1445,7 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 
 					// Step 2: Re-encode if necessary (文件已存在则跳过)
 					if !fileAlreadyExists && core.Config.FfmpegFix && trackData.Type != "music-videos" {
-						isAAC := core.Dl_aac && *core.Aac_type == "aac-lc"
+						isAAC := job.AAC && job.AacType == "aac-lc"
 						if !isAAC {
 							var fixErr error
 							wasFixed, fixErr = checkAndReEncodeTrack(trackPath, statusIndex, notifier)
@@ -1452,10 +1459,13 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 					if postDownloadError == nil && !fileAlreadyExists {
 						var finalLrc string
 						if lyricAccount != nil && (core.Config.EmbedLrc || core.Config.SaveLrcFile) && trackData.Type != "music-videos" {
-							lrcStr, lrcErr := lyrics.Get(storefront, trackData.ID, core.Config.LrcType, core.Config.Language, core.Config.LrcFormat, core.DeveloperToken, lyricAccount.MediaUserToken)
+							lrcStr, lrcErr := assets.lyric(trackData.ID, func() (string, error) {
+								return lyrics.Get(storefront, trackData.ID, core.Config.LrcType, core.Config.Language, core.Config.LrcFormat, core.DeveloperToken, lyricAccount.MediaUserToken)
+							})
 							if lrcErr == nil {
 								if core.Config.SaveLrcFile {
-									lrcFilename := fmt.Sprintf("%s.lrc", strings.TrimSuffix(filepath.Base(trackPath), filepath.Ext(filepath.Base(trackPath))))
+									finalName := filepath.Base(strings.TrimSuffix(trackPath, utils.PartSuffix))
+									lrcFilename := fmt.Sprintf("%s.lrc", strings.TrimSuffix(finalName, filepath.Ext(finalName)))
 									_ = metadata.WriteLyrics(filepath.Dir(trackPath), lrcFilename, lrcStr)
 								}
 								if core.Config.EmbedLrc {
@@ -1464,12 +1474,16 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 							}
 						}
 
-						// 使用带自动修复功能的标签写入
-						tagErr := metadata.WriteMP4TagsWithRetry(trackPath, finalLrc, meta, trackIndexInMeta, len(meta.Data[0].Relationships.Tracks.Data))
+						// 标签、自定义原子与内嵌封面一次写入（缺少 ilst 时自动补建）
+						coverPath, tempCover := trackCover(assets, trackData, albumId, filepath.Dir(trackPath), covPath, isSingle)
+						tagErr := metadata.WriteMP4Tags(job, trackPath, finalLrc, coverPath, meta, trackIndexInMeta, len(meta.Data[0].Relationships.Tracks.Data))
+						if tempCover {
+							_ = os.Remove(coverPath)
+						}
 						if tagErr != nil {
-							// AAC文件标签写入失败时不删除文件（因为基本标签已通过MP4Box写入）
-							if core.Dl_aac {
-								logger.Warn("AAC文件标签写入失败，保留文件（已包含基本标签）: %v", tagErr)
+							// AAC文件标签写入失败时不删除文件，保留未写入标签的音频
+							if job.AAC {
+								logger.Warn("AAC文件标签写入失败，保留文件（未写入标签）: %v", tagErr)
 								// 不设置 postDownloadError，继续执行
 							} else {
 								postDownloadError = fmt.Errorf("标签写入失败: %w", tagErr)
@@ -1477,6 +1491,16 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 						}
 					}
 
+					// Step 4: 后处理全部完成，将 .part 临时文件落盘并提交为最终文件名
+					if postDownloadError == nil && !fileAlreadyExists && utils.IsPartFile(trackPath) {
+						finalPath := strings.TrimSuffix(trackPath, utils.PartSuffix)
+						if commitErr := utils.CommitFile(trackPath, finalPath); commitErr != nil {
+							postDownloadError = fmt.Errorf("保存文件失败: %w", commitErr)
+						} else {
+							trackPath = finalPath
+						}
+					}
+
 					// Check if any post-download step failed
 					if postDownloadError != nil {
 						os.Remove(trackPath) // Delete the problematic file
@@ -1499,18 +1523,31 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 							if notifier != nil {
 								notifier.NotifyStatus(statusIndex, "已跳过 (标签失败)", "skipped")
 							}
-							core.SharedLock.Lock()
-							core.Counter.Total++
 							// 不增加 Error 计数，视为跳过而非错误
-							core.SharedLock.Unlock()
+							job.UpdateCounter(func(c *structs.Counter) { c.Total++ })
+							postProcess.record(trackIndexInMeta, trackData, hooks.TrackSkipped, "", postDownloadError)
 							return
 						}
 					}
 
 					// All steps successful
-					core.SharedLock.Lock()
-					core.Counter.Total++
-					core.Counter.Success++
+					// 提交成功后才标记完成：标签写入失败时已删除文件，重试时需要重新下载
+					if !fileAlreadyExists {
+						job.MarkDone(albumId, trackIndexInMeta)
+					}
+					job.UpdateCounter(func(c *structs.Counter) {
+						c.Total++
+						c.Success++
+					})
+					if !usingCache && !fileAlreadyExists {
+						recordWritten(trackPath)
+					}
+					// 曲目级后处理钩子（标签写入并落盘之后）
+					if fileAlreadyExists {
+						postProcess.record(trackIndexInMeta, trackData, hooks.TrackExists, trackPath, nil)
+					} else {
+						postProcess.trackDone(trackIndexInMeta, trackData, trackPath)
+					}
 					if fileAlreadyExists {
 						// 文件已存在，显示特殊状态
 						if notifier != nil {
@@ -1525,7 +1562,6 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 							notifier.NotifyComplete(statusIndex)
 						}
 					}
-					core.SharedLock.Unlock()
 					return // Mission accomplished, exit goroutine
 				}
 			}(trackNum, i)
@@ -1534,13 +1570,21 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 		wg.Wait()
 		close(doneUI)
 		time.Sleep(200 * time.Millisecond)
-		ui.PrintUI(false) // 批次完成后的最后一次打印，非首次更新
+		if boardSet == nil {
+			ui.PrintUI(job.Board, false) // 批次完成后的最后一次打印，非首次更新
+		}
 
 		// UI结束后：恢复logger输出到stdout
-		if !core.DisableDynamicUI {
+		if exclusiveUI {
 			logger.SetOutput(os.Stdout)
 		}
 
+		// 空间不足中止：回滚本次写入目标目录的文件（缓存目录由延迟清理处理）
+		if err := guard.Err(); err != nil {
+			rollbackAlbum(destAlbumFolder, writtenFiles, albumFolderCreated)
+			return err
+		}
+
 		// 如果使用了缓存，批次完成后立即转移文件（多批次且不是最后一批）
 		if usingCache && batch.TotalBatches > 1 && !batch.IsLast {
 			// 检查缓存hash目录中是否有新文件需要转移
@@ -1560,11 +1604,11 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 
 			if hasFilesToMove {
 				// 有新文件，需要转移
-				if !core.DisableDynamicUI {
+				if exclusiveUI {
 					ui.Suspend()
 				}
 				cyan := color.New(color.FgCyan).SprintFunc()
-				core.SafePrintf("%s\n", cyan(fmt.Sprintf("📤 批次 %d/%d: 转移文件...", batch.BatchNum, batch.TotalBatches)))
+				logger.With("album_id", albumId, "stage", "move").Info("%s", cyan(fmt.Sprintf("📤 批次 %d/%d: 转移文件...", batch.BatchNum, batch.TotalBatches)))
 
 				// 递归转移所有文件
 				moveCount := 0
@@ -1596,17 +1640,20 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 							}
 						} else {
 							moveCount++
+							if strings.HasSuffix(cachePath, ".m4a") {
+								recordWritten(targetPath)
+							}
 						}
 					}
 					return nil
 				})
 
 				if batchSkippedCount > 0 {
-					core.SafePrintf("%s\n", color.New(color.FgGreen).SprintFunc()(fmt.Sprintf("✅ 批次 %d/%d: 转移完成（新增 %d，跳过 %d）", batch.BatchNum, batch.TotalBatches, moveCount, batchSkippedCount)))
+					logger.With("album_id", albumId, "stage", "move").Info("%s", color.New(color.FgGreen).SprintFunc()(fmt.Sprintf("✅ 批次 %d/%d: 转移完成（新增 %d，跳过 %d）", batch.BatchNum, batch.TotalBatches, moveCount, batchSkippedCount)))
 				} else {
-					core.SafePrintf("%s\n", color.New(color.FgGreen).SprintFunc()(fmt.Sprintf("✅ 批次 %d/%d: 转移完成（%d 个）", batch.BatchNum, batch.TotalBatches, moveCount)))
+					logger.With("album_id", albumId, "stage", "move").Info("%s", color.New(color.FgGreen).SprintFunc()(fmt.Sprintf("✅ 批次 %d/%d: 转移完成（%d 个）", batch.BatchNum, batch.TotalBatches, moveCount)))
 				}
-				if !core.DisableDynamicUI {
+				if exclusiveUI {
 					ui.Resume()
 				}
 			}
@@ -1614,13 +1661,13 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 
 		// 显示批次完成信息（多批次时）
 		if batch.TotalBatches > 1 && !batch.IsLast {
-			if !core.DisableDynamicUI {
+			if exclusiveUI {
 				ui.Suspend()
 			}
 			green := color.New(color.FgGreen).SprintFunc()
-			core.SafePrintf("%s\n", green(fmt.Sprintf("✅ 批次 %d/%d 完成", batch.BatchNum, batch.TotalBatches)))
+			logger.With("album_id", albumId, "stage", "download").Info("%s", green(fmt.Sprintf("✅ 批次 %d/%d 完成", batch.BatchNum, batch.TotalBatches)))
 			time.Sleep(300 * time.Millisecond)
-			if !core.DisableDynamicUI {
+			if exclusiveUI {
 				ui.Resume()
 			}
 		}
@@ -1639,7 +1686,7 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 			// 缓存目录不存在或无法访问，跳过转移
 			logger.Debug("[文件转移] 缓存目录不存在，跳过转移: %s", cacheHashDir)
 			downloadSuccess = true
-			return nil
+			return postProcess.finish()
 		}
 
 		// 统计需要转移的文件（优化：一次扫描完成统计和检测）
@@ -1700,13 +1747,18 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 					return os.MkdirAll(targetPath, info.Mode())
 				}
 
+				// 未完成的临时文件不转移（缓存目录随后整体清理）
+				if utils.IsPartFile(cachePath) {
+					return nil
+				}
+
 				// 转移文件（SafeMoveFile 内部已检查目标文件存在性）
 				if err := utils.SafeMoveFile(cachePath, targetPath); err != nil {
 					if strings.Contains(err.Error(), "目标文件已存在") {
 						skippedCount++
 						// 静默跳过，不打印警告
 					} else {
-						logger.Warn("警告: 转移文件失败 %s: %v", relPath, err)
+						logger.With("album_id", albumId, "stage", "move").Warn("警告: 转移文件失败 %s: %v", relPath, err)
 					}
 				} else {
 					movedCount++
@@ -1715,7 +1767,7 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 			})
 
 			if moveErr != nil {
-				logger.Warn("警告: 转移文件过程出现错误: %v", moveErr)
+				logger.With("album_id", albumId, "stage", "move").Warn("警告: 转移文件过程出现错误: %v", moveErr)
 			}
 
 			// 显示转移结果（只有实际转移了文件才显示）
@@ -1749,10 +1801,11 @@ func Rip(albumId string, storefront string, urlArg_i string, urlRaw string, noti
 		logger.Info("%s", cyan(fmt.Sprintf("🧹 已清理 %d 个冗余空文件夹", cleanedCount)))
 	}
 
-	return nil
+	// 专辑级后处理：响度分析与钩子（文件已转移到目标目录）
+	return postProcess.finish()
 }
 
-func MvDownloader(adamID string, baseSaveDir, artistDir, albumDir string, storefront string, meta *structs.AutoGenerated, account *structs.Account) (string, string, error) {
+func MvDownloader(job *core.Job, adamID string, baseSaveDir, artistDir, albumDir string, storefront string, meta *structs.AutoGenerated, account *structs.Account) (string, string, error) {
 	MVInfo, err := api.GetMVInfoFromAdam(adamID, account, storefront)
 	if err != nil {
 		return "", "", err
@@ -1812,8 +1865,7 @@ func MvDownloader(adamID string, baseSaveDir, artistDir, albumDir string, storef
 	if err := os.MkdirAll(finalMvFolder, 0755); err != nil {
 		return "", "", fmt.Errorf("创建MV目录失败: %w", err)
 	}
-	exists, _ := utils.FileExists(mvOutPath)
-	if exists {
+	if validation, _ := utils.ValidateFile(mvOutPath, 0); validation != nil && validation.Exists && validation.IsValid {
 		return mvOutPath, "已存在", nil
 	}
 
@@ -1822,24 +1874,25 @@ func MvDownloader(adamID string, baseSaveDir, artistDir, albumDir string, storef
 		return "", "", fmt.Errorf("获取MV播放列表失败: %w", err)
 	}
 	if mvm3u8url == "" {
+		notify.TokenExpired(account.Name, errors.New("media-user-token may be wrong or expired"))
 		return "", "", errors.New("media-user-token may be wrong or expired")
 	}
 
 	vidPath := filepath.Join(finalMvFolder, fmt.Sprintf("%s_vid.mp4", adamID))
 	audPath := filepath.Join(finalMvFolder, fmt.Sprintf("%s_aud.mp4", adamID))
 
-	videom3u8url, resolution, err := parser.ExtractVideo(mvm3u8url)
+	videom3u8url, resolution, err := parser.ExtractVideo(job.Options, mvm3u8url)
 	if err != nil {
 		return "", "", fmt.Errorf("提取视频流URL失败: %w", err)
 	}
 
 	// 显示视频质量信息
 	if resolution != "" {
-		core.SafePrintf("📺 视频质量: %s\n", resolution)
+		logger.Info("📺 视频质量: %s", resolution)
 	}
 
 	// 显示下载开始提示
-	core.SafePrintf("🎥 开始下载MV...\n")
+	logger.Info("🎥 开始下载MV...")
 
 	videokeyAndUrls, err := runv3.Run(adamID, videom3u8url, core.DeveloperToken, account.MediaUserToken, true)
 	if err != nil {
@@ -1850,7 +1903,7 @@ func MvDownloader(adamID string, baseSaveDir, artistDir, albumDir string, storef
 		return "", "", fmt.Errorf("下载或解密视频数据失败: %w", err)
 	}
 
-	audiom3u8url, err := parser.ExtractMvAudio(mvm3u8url)
+	audiom3u8url, err := parser.ExtractMvAudio(job.Options, mvm3u8url)
 	if err != nil {
 		return "", "", fmt.Errorf("提取音频流URL失败: %w", err)
 	}
@@ -1901,14 +1954,20 @@ func MvDownloader(adamID string, baseSaveDir, artistDir, albumDir string, storef
 	}
 
 	tagsString := strings.Join(tags, ":")
-	muxCmd := exec.Command("MP4Box", "-itags", tagsString, "-quiet", "-add", vidPath, "-add", audPath, "-keep-utc", "-new", mvOutPath)
-	if err := muxCmd.Run(); err != nil {
-		return "", "", err
-	}
+	mvPartPath := utils.PartPath(mvOutPath)
+	muxCmd := exec.Command("MP4Box", "-itags", tagsString, "-quiet", "-add", vidPath, "-add", audPath, "-keep-utc", "-new", mvPartPath)
 	defer os.Remove(vidPath)
 	defer os.Remove(audPath)
 	if covPath != "" {
 		defer os.Remove(covPath)
 	}
+	if err := muxCmd.Run(); err != nil {
+		os.Remove(mvPartPath)
+		return "", "", err
+	}
+	if err := utils.CommitFile(mvPartPath, mvOutPath); err != nil {
+		os.Remove(mvPartPath)
+		return "", "", err
+	}
 	return mvOutPath, resolution, nil
 }
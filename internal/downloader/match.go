@@ -0,0 +1,187 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"main/internal/api"
+	"main/internal/core"
+	"main/internal/logger"
+	"main/utils/structs"
+)
+
+// albumMatch 在其他地区目录中找到的同一发行
+type albumMatch struct {
+	core.StorefrontMatch
+	account *structs.Account
+	tracks  map[string]string // key: 链接地区的曲目 ID，value: 目标地区的曲目 ID
+}
+
+// precheckAccounts 版权预检：返回能访问曲目 trackID（通常为专辑第一首曲目）的账户
+func precheckAccounts(trackID, albumId string) []structs.Account {
+	core.SafePrintln("🔬 正在进行版权预检，请稍候...")
+	var accounts []structs.Account
+	for i, r := range accountRights(trackID) {
+		if r.Available {
+			accounts = append(accounts, core.Config.Accounts[i])
+		} else {
+			logger.With("album_id", albumId, "account", r.Name, "storefront", r.Storefront, "stage", "precheck").
+				Warn("账户 [%s] 无法访问此专辑 (可能无版权)，本次任务将跳过该账户。", r.Name)
+			logger.With("album_id", albumId, "account", r.Name, "stage", "precheck").Debug("版权预检失败: %s", r.Error)
+		}
+	}
+	return accounts
+}
+
+// matchStorefront 专辑在链接地区无法访问时，在其他已配置账户的地区中查找同一发行
+// 先按专辑 UPC 查找，没有 UPC 或找不到时按曲目 ISRC 查找所属专辑；曲目按 ISRC 对应，
+// 目标地区的账户能访问对应的曲目时视为匹配成功
+func matchStorefront(meta *structs.AutoGenerated, albumId, storefront string) (*albumMatch, error) {
+	log := logger.With("album_id", albumId, "stage", "match")
+	attrs := meta.Data[0].Attributes
+	seen := map[string]bool{strings.ToLower(storefront): true}
+	for i := range core.Config.Accounts {
+		acc := &core.Config.Accounts[i]
+		sf := strings.ToLower(acc.Storefront)
+		if sf == "" || seen[sf] {
+			continue
+		}
+		seen[sf] = true
+
+		ids, by := candidateAlbums(meta, acc, sf)
+		for _, id := range ids {
+			if id == albumId {
+				continue // 与链接相同的专辑已在版权预检中确认无法访问
+			}
+			target, err := api.GetMeta(id, acc, sf)
+			if err != nil {
+				log.Debug("获取 %s/%s 的专辑信息失败: %v", sf, id, err)
+				continue
+			}
+			tracks := mapTracksByISRC(meta, target)
+			if len(tracks) == 0 {
+				log.Debug("%s/%s 中没有 ISRC 相同的曲目", sf, id)
+				continue
+			}
+			if _, err := api.GetInfoFromAdam(firstMapped(meta, tracks), acc, sf); err != nil {
+				log.Debug("账户 [%s] 无法访问 %s/%s", acc.Name, sf, id)
+				continue
+			}
+			return &albumMatch{
+				StorefrontMatch: core.StorefrontMatch{
+					AlbumID:           albumId,
+					AlbumName:         attrs.Name,
+					Storefront:        strings.ToLower(storefront),
+					MatchedID:         id,
+					MatchedStorefront: sf,
+					By:                by,
+					Matched:           len(tracks),
+					Total:             len(meta.Data[0].Relationships.Tracks.Data),
+				},
+				account: acc,
+				tracks:  tracks,
+			}, nil
+		}
+	}
+	return nil, errors.New("其他已配置的地区中没有找到同一发行")
+}
+
+// candidateAlbums 在地区目录中查找可能是同一发行的专辑，返回专辑 ID 与查找方式（upc / isrc）
+func candidateAlbums(meta *structs.AutoGenerated, account *structs.Account, storefront string) ([]string, string) {
+	if upc := meta.Data[0].Attributes.Upc; upc != "" {
+		if ids, err := api.GetAlbumsByUPC(upc, account, storefront); err == nil && len(ids) > 0 {
+			return ids, "upc"
+		}
+	}
+	for _, t := range meta.Data[0].Relationships.Tracks.Data {
+		if t.Attributes.Isrc == "" {
+			continue
+		}
+		songs, err := api.GetSongsByISRC(t.Attributes.Isrc, account, storefront)
+		if err != nil {
+			return nil, ""
+		}
+		var ids []string
+		seen := make(map[string]bool)
+		for _, s := range songs {
+			for _, a := range s.Relationships.Albums.Data {
+				if !seen[a.ID] {
+					seen[a.ID] = true
+					ids = append(ids, a.ID)
+				}
+			}
+		}
+		return ids, "isrc"
+	}
+	return nil, ""
+}
+
+// mapTracksByISRC 按 ISRC 对应两个专辑的曲目；同一 ISRC 出现多次时按顺序依次对应
+func mapTracksByISRC(src, dst *structs.AutoGenerated) map[string]string {
+	byISRC := make(map[string][]string)
+	for _, t := range dst.Data[0].Relationships.Tracks.Data {
+		if isrc := strings.ToUpper(t.Attributes.Isrc); isrc != "" {
+			byISRC[isrc] = append(byISRC[isrc], t.ID)
+		}
+	}
+	tracks := make(map[string]string)
+	for _, t := range src.Data[0].Relationships.Tracks.Data {
+		isrc := strings.ToUpper(t.Attributes.Isrc)
+		if ids := byISRC[isrc]; isrc != "" && len(ids) > 0 {
+			tracks[t.ID] = ids[0]
+			byISRC[isrc] = ids[1:]
+		}
+	}
+	return tracks
+}
+
+// firstMapped 第一首有对应曲目的目标地区曲目 ID（用于版权预检）
+func firstMapped(meta *structs.AutoGenerated, tracks map[string]string) string {
+	for _, t := range meta.Data[0].Relationships.Tracks.Data {
+		if id, ok := tracks[t.ID]; ok {
+			return id
+		}
+	}
+	return ""
+}
+
+// apply 返回使用目标地区曲目 ID 的元数据副本；专辑与曲目的名称、艺术家等仍使用链接地区的（本地化）元数据
+// 没有对应曲目的 ID 保持不变，下载时按失败处理
+func (m *albumMatch) apply(meta *structs.AutoGenerated) *structs.AutoGenerated {
+	out := *meta
+	out.Data = append(meta.Data[:0:0], meta.Data...)
+	tracks := append([]structs.TrackData(nil), meta.Data[0].Relationships.Tracks.Data...)
+	for i := range tracks {
+		if id, ok := m.tracks[tracks[i].ID]; ok {
+			tracks[i].ID = id
+			tracks[i].Attributes.PlayParams.ID = id
+		}
+	}
+	out.Data[0].Relationships.Tracks.Data = tracks
+	return &out
+}
+
+// trackID 链接中 ?i= 指定的曲目在目标地区的 ID
+func (m *albumMatch) trackID(id string) string {
+	if mapped, ok := m.tracks[id]; ok {
+		return mapped
+	}
+	return id
+}
+
+// resolveStorefront 版权预检没有可用账户时尝试跨地区匹配，失败时返回任务中止的错误
+func resolveStorefront(meta *structs.AutoGenerated, albumId, storefront string) (*albumMatch, error) {
+	if strings.Contains(albumId, "pl.") {
+		return nil, errors.New("所有账户均无法访问此播放列表，任务中止")
+	}
+	m, err := matchStorefront(meta, albumId, storefront)
+	if err != nil {
+		return nil, fmt.Errorf("所有账户均无法访问此专辑，任务中止（跨地区匹配: %w）", err)
+	}
+	logger.With("album_id", albumId, "storefront", m.MatchedStorefront, "stage", "match").Info("🌐 跨地区匹配: %s", m.StorefrontMatch)
+	if missing := m.Total - m.Matched; missing > 0 {
+		logger.With("album_id", albumId, "stage", "match").Warn("%d 首曲目在 %s 中没有 ISRC 相同的曲目，将无法下载", missing, strings.ToUpper(m.MatchedStorefront))
+	}
+	return m, nil
+}
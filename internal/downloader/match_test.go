@@ -0,0 +1,63 @@
+package downloader
+
+import (
+	"encoding/json"
+	"testing"
+
+	"main/utils/structs"
+)
+
+func albumMeta(t *testing.T, s string) *structs.AutoGenerated {
+	t.Helper()
+	var meta structs.AutoGenerated
+	if err := json.Unmarshal([]byte(s), &meta); err != nil {
+		t.Fatal(err)
+	}
+	return &meta
+}
+
+// TestMapTracksByISRC 测试按 ISRC 对应曲目，并在保留本地化元数据的同时替换为目标地区的曲目 ID
+func TestMapTracksByISRC(t *testing.T) {
+	src := albumMeta(t, `{"data":[{"id":"100","attributes":{"name":"本地化专辑名"},"relationships":{"tracks":{"data":[
+		{"id":"1","attributes":{"name":"第一首","isrc":"USAAA0000001"}},
+		{"id":"2","attributes":{"name":"间奏","isrc":"USAAA0000002"}},
+		{"id":"3","attributes":{"name":"间奏 (Reprise)","isrc":"USAAA0000002"}},
+		{"id":"4","attributes":{"name":"地区限定","isrc":"USAAA0000009"}}]}}}]}`)
+	dst := albumMeta(t, `{"data":[{"id":"200","attributes":{"name":"Album"},"relationships":{"tracks":{"data":[
+		{"id":"21","attributes":{"name":"First","isrc":"usaaa0000001"}},
+		{"id":"22","attributes":{"name":"Interlude","isrc":"USAAA0000002"}},
+		{"id":"23","attributes":{"name":"Interlude (Reprise)","isrc":"USAAA0000002"}}]}}}]}`)
+
+	tracks := mapTracksByISRC(src, dst)
+	want := map[string]string{"1": "21", "2": "22", "3": "23"}
+	if len(tracks) != len(want) {
+		t.Fatalf("mapTracksByISRC = %v, want %v", tracks, want)
+	}
+	for k, v := range want {
+		if tracks[k] != v {
+			t.Errorf("track %s → %s, want %s", k, tracks[k], v)
+		}
+	}
+	if got := firstMapped(src, tracks); got != "21" {
+		t.Errorf("firstMapped = %q", got)
+	}
+
+	m := &albumMatch{tracks: tracks}
+	out := m.apply(src)
+	got := out.Data[0].Relationships.Tracks.Data
+	if got[0].ID != "21" || got[0].Attributes.PlayParams.ID != "21" || got[0].Attributes.Name != "第一首" {
+		t.Errorf("applied track = %+v", got[0])
+	}
+	if got[3].ID != "4" {
+		t.Errorf("unmatched track ID = %q, want unchanged", got[3].ID)
+	}
+	if out.Data[0].Attributes.Name != "本地化专辑名" {
+		t.Errorf("album name = %q, want localized name", out.Data[0].Attributes.Name)
+	}
+	if src.Data[0].Relationships.Tracks.Data[0].ID != "1" {
+		t.Error("apply should not modify the original metadata")
+	}
+	if m.trackID("3") != "23" || m.trackID("") != "" {
+		t.Errorf("trackID mapping wrong")
+	}
+}
@@ -0,0 +1,219 @@
+package downloader
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"main/internal/core"
+	"main/internal/logger"
+	"main/internal/metadata"
+	"main/internal/quality"
+	"main/internal/utils"
+	"main/utils/structs"
+)
+
+// albumAssets 同一专辑的多个输出（多音乐库模式）之间共享的获取结果
+// 曲目选择、版权预检、歌词与封面只获取一次，之后的输出直接复用
+type albumAssets struct {
+	mu       sync.Mutex
+	selected []int
+	accounts []structs.Account
+	checked  bool // 版权预检已完成
+	lyrics   map[string]lyricResult
+	covers   map[string]coverData // key: 封面 URL
+	animated map[string]string    // key: 动画封面 URL，value: 已保存的文件路径
+}
+
+type lyricResult struct {
+	lrc string
+	err error
+}
+
+type coverData struct {
+	ext  string
+	data []byte
+}
+
+func newAlbumAssets() *albumAssets {
+	return &albumAssets{
+		lyrics:   make(map[string]lyricResult),
+		covers:   make(map[string]coverData),
+		animated: make(map[string]string),
+	}
+}
+
+// selectTracks 返回选中的曲目序号，只在第一个输出时选择（可能需要交互输入）
+func (a *albumAssets) selectTracks(sel func() []int) []int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.selected == nil {
+		a.selected = sel()
+	}
+	return a.selected
+}
+
+// workingAccounts 返回能访问专辑的账户，版权预检只在第一个输出时进行
+func (a *albumAssets) workingAccounts(check func() []structs.Account) []structs.Account {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.checked {
+		a.accounts = check()
+		a.checked = true
+	}
+	return a.accounts
+}
+
+// lyric 返回曲目歌词，同一曲目只请求一次（失败结果同样复用）
+func (a *albumAssets) lyric(trackID string, fetch func() (string, error)) (string, error) {
+	a.mu.Lock()
+	if r, ok := a.lyrics[trackID]; ok {
+		a.mu.Unlock()
+		return r.lrc, r.err
+	}
+	a.mu.Unlock()
+
+	lrc, err := fetch()
+	a.mu.Lock()
+	a.lyrics[trackID] = lyricResult{lrc: lrc, err: err}
+	a.mu.Unlock()
+	return lrc, err
+}
+
+// cover 将封面保存为 folder/name，同一 URL 只下载一次，之后的输出写入已下载的内容
+func (a *albumAssets) cover(folder, name, url string) (string, error) {
+	a.mu.Lock()
+	c, ok := a.covers[url]
+	a.mu.Unlock()
+	if ok {
+		path := filepath.Join(folder, name+c.ext)
+		partPath := utils.PartPath(path)
+		if err := os.WriteFile(partPath, c.data, 0644); err != nil {
+			return "", err
+		}
+		if err := utils.CommitFile(partPath, path); err != nil {
+			os.Remove(partPath)
+			return "", err
+		}
+		return path, nil
+	}
+
+	path, err := metadata.WriteCover(folder, name, url)
+	if err != nil {
+		return "", err
+	}
+	if data, readErr := os.ReadFile(path); readErr == nil {
+		a.mu.Lock()
+		a.covers[url] = coverData{ext: filepath.Ext(path), data: data}
+		a.mu.Unlock()
+	}
+	return path, nil
+}
+
+// animatedArtwork 保存动画封面，之前的输出已保存时复制文件而不重新下载
+func (a *albumAssets) animatedArtwork(url, outPath string) {
+	a.mu.Lock()
+	prev, ok := a.animated[url]
+	a.mu.Unlock()
+	if ok && prev != outPath {
+		if validation, _ := utils.ValidateFile(prev, 0); validation != nil && validation.Exists && validation.IsValid {
+			if err := copyFileAtomic(prev, outPath); err == nil {
+				return
+			}
+		}
+	}
+	saveAnimatedArtwork(url, outPath)
+	a.mu.Lock()
+	a.animated[url] = outPath
+	a.mu.Unlock()
+}
+
+// copyFileAtomic 复制文件，先写入 .part 临时文件再重命名为 dst
+func copyFileAtomic(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	partPath := utils.PartPath(dst)
+	out, err := os.Create(partPath)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = utils.CommitFile(partPath, dst)
+	}
+	if err != nil {
+		os.Remove(partPath)
+	}
+	return err
+}
+
+// outputJobs 返回专辑需要输出的下载任务
+// 多音乐库模式下每个专辑可用的输出档位对应一个任务，保存到各自的音乐库；
+// 否则按音质策略为专辑选定一个档位（未设置策略时为原任务）
+func outputJobs(job *core.Job, meta *structs.AutoGenerated, albumId string) []*core.Job {
+	var traits [][]string
+	for _, t := range meta.Data[0].Relationships.Tracks.Data {
+		traits = append(traits, t.Attributes.AudioTraits)
+	}
+	albumName := meta.Data[0].Attributes.Name
+	log := logger.With("album_id", albumId, "stage", "quality")
+
+	if outputs := job.OutputRules(); len(outputs) > 0 {
+		var jobs []*core.Job
+		for _, r := range outputs {
+			out := job.WithOptions(job.WithOutput(r))
+			choice := quality.SelectAlbum(out.Rules(), traits)
+			if choice.Fallback {
+				log.Info("🎚️ 多音乐库: %s 没有 %s 版本，跳过该输出", albumName, r)
+				continue
+			}
+			log.Info("🎚️ 多音乐库: %s → %s [%s]（%s）", albumName, r, out.Codec(), choice.Reason)
+			jobs = append(jobs, out)
+		}
+		return jobs
+	}
+
+	// 按音质策略为本专辑选定档位：派生使用对应下载模式的任务，保存目录、质量标签与下载参数都由选定档位决定
+	// 曲目没有选定档位时，下载时按策略中之后的档位回退
+	if len(job.QualityPolicy) > 0 {
+		if rules := job.Rules(); len(rules) > 0 {
+			choice := quality.SelectAlbum(rules, traits)
+			job = job.WithOptions(job.WithPolicyChoice(rules, choice.Index))
+			log.Info("🎚️ 音质策略: %s → %s [%s]（%s）", albumName, choice.Rule, job.Codec(), choice.Reason)
+		}
+	}
+	return []*core.Job{job}
+}
+
+// availableTracks 多音乐库模式下过滤掉没有输出档位的曲目（按音频特性判断，MV 保留）
+func availableTracks(job *core.Job, meta *structs.AutoGenerated, albumId string, selected []int) []int {
+	if !job.StrictQuality {
+		return selected
+	}
+	rules := job.Rules()
+	var out []int
+	for _, trackNum := range selected {
+		track := meta.Data[0].Relationships.Tracks.Data[trackNum-1]
+		if track.Type == "music-videos" {
+			out = append(out, trackNum)
+			continue
+		}
+		for _, r := range rules {
+			if quality.Available(r, track.Attributes.AudioTraits) {
+				out = append(out, trackNum)
+				break
+			}
+		}
+	}
+	if skipped := len(selected) - len(out); skipped > 0 {
+		logger.With("album_id", albumId, "stage", "quality").Info("🎚️ %s: %d 首曲目没有 %s 版本，不输出到该音乐库", job.Codec(), skipped, rules[0])
+	}
+	return out
+}
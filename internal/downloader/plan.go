@@ -0,0 +1,434 @@
+package downloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"main/internal/api"
+	"main/internal/core"
+	"main/internal/parser"
+	"main/internal/quality"
+	"main/internal/singles"
+	"main/internal/ui"
+	"main/internal/utils"
+	"main/utils/structs"
+)
+
+// planWorkers dry-run 中同时获取曲目播放列表的数量
+const planWorkers = 4
+
+// AccountRights 账户能否访问专辑（与下载前的版权预检相同）
+type AccountRights struct {
+	Name       string `json:"name"`
+	Storefront string `json:"storefront"`
+	Available  bool   `json:"available"`
+	Error      string `json:"error,omitempty"`
+}
+
+// TrackPlan dry-run 中一首曲目的可用性
+type TrackPlan struct {
+	Number   int    `json:"number"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	AAC      string `json:"aac,omitempty"`      // 最高 AAC 立体声档位
+	Lossless string `json:"lossless,omitempty"` // 最高 48kHz 以内的 ALAC 档位
+	HiRes    string `json:"hires,omitempty"`    // 最高 Hi-Res ALAC 档位
+	Atmos    string `json:"atmos,omitempty"`    // 最高杜比全景声档位
+	Selected string `json:"selected,omitempty"` // 按下载参数选中的档位
+	Reason   string `json:"reason,omitempty"`   // 档位选择的原因
+	Bytes    uint64 `json:"estimated_bytes"`
+	Path     string `json:"path,omitempty"`
+	Exists   bool   `json:"exists"`
+	Skipped  string `json:"skipped,omitempty"` // 不会下载的原因
+	Error    string `json:"error,omitempty"`
+}
+
+// AlbumPlan dry-run 中一个专辑的一个输出（多音乐库模式下每个音乐库一项）
+type AlbumPlan struct {
+	URL         string                `json:"url"`
+	ID          string                `json:"id,omitempty"`
+	Name        string                `json:"name,omitempty"`
+	Artist      string                `json:"artist,omitempty"`
+	Storefront  string                `json:"storefront,omitempty"`
+	Codec       string                `json:"codec,omitempty"`
+	Tag         string                `json:"tag,omitempty"`
+	Folder      string                `json:"folder,omitempty"`
+	TrackCount  int                   `json:"track_count"` // 目录中的曲目数
+	Listed      int                   `json:"listed"`      // 目录返回的曲目数
+	Missing     int                   `json:"missing"`     // 目录中缺少的曲目数（track_count - listed）
+	Unavailable int                   `json:"unavailable"` // 当前地区无法播放的曲目数
+	Accounts    []AccountRights       `json:"accounts,omitempty"`
+	Match       *core.StorefrontMatch `json:"match,omitempty"` // 所有账户都无法访问时匹配到的其他地区版本
+	Tracks      []TrackPlan           `json:"tracks,omitempty"`
+	Bytes       uint64                `json:"estimated_bytes"` // 选中曲目的预计大小
+	Download    uint64                `json:"download_bytes"`  // 尚不存在的曲目的预计大小
+	Existing    int                   `json:"existing_files"`  // 已存在的曲目数
+	Error       string                `json:"error,omitempty"`
+}
+
+// Plan dry-run 报告
+type Plan struct {
+	Started  time.Time   `json:"started"`
+	Albums   []AlbumPlan `json:"albums"`
+	Tracks   int         `json:"tracks"`
+	Existing int         `json:"existing_files"`
+	Bytes    uint64      `json:"estimated_bytes"`
+	Download uint64      `json:"download_bytes"`
+	Errors   int         `json:"errors"`
+}
+
+// Add 加入专辑并累计总数
+func (p *Plan) Add(albums ...AlbumPlan) {
+	for _, a := range albums {
+		p.Albums = append(p.Albums, a)
+		p.Tracks += len(a.Tracks)
+		p.Existing += a.Existing
+		p.Bytes += a.Bytes
+		p.Download += a.Download
+		if a.Error != "" {
+			p.Errors++
+		}
+	}
+}
+
+// accountRights 检查每个账户能否访问曲目（下载前的版权预检）
+func accountRights(trackID string) []AccountRights {
+	var rights []AccountRights
+	for _, acc := range core.Config.Accounts {
+		r := AccountRights{Name: acc.Name, Storefront: acc.Storefront}
+		if _, err := api.GetInfoFromAdam(trackID, &acc, acc.Storefront); err != nil {
+			r.Error = err.Error()
+		} else {
+			r.Available = true
+		}
+		rights = append(rights, r)
+	}
+	return rights
+}
+
+func anyAvailable(rights []AccountRights) bool {
+	for _, r := range rights {
+		if r.Available {
+			return true
+		}
+	}
+	return false
+}
+
+// trackManifest 曲目的主播放列表档位
+type trackManifest struct {
+	variants []quality.Variant
+	noHls    bool // 没有增强播放列表（只能下载 AAC）
+	err      error
+}
+
+// PlanAlbum 不下载任何文件，按下载参数报告专辑的曲目、账户权限、可用音质、预计大小与目标路径
+// 与 Rip 使用相同的输出选择（音质策略 / 多音乐库）、曲目选择与文件命名
+func PlanAlbum(job *core.Job, albumId, storefront, urlArg_i, urlRaw string) []AlbumPlan {
+	base := AlbumPlan{URL: urlRaw, ID: albumId, Storefront: storefront}
+	mainAccount, err := core.GetAccountForStorefront(storefront)
+	if err != nil {
+		base.Error = err.Error()
+		return []AlbumPlan{base}
+	}
+	meta, err := api.GetMeta(albumId, mainAccount, storefront)
+	if err != nil {
+		base.Error = fmt.Sprintf("获取专辑信息失败: %v", err)
+		return []AlbumPlan{base}
+	}
+	attrs := meta.Data[0].Attributes
+	tracks := meta.Data[0].Relationships.Tracks.Data
+	base.Name, base.Artist = attrs.Name, attrs.ArtistName
+	base.TrackCount, base.Listed = attrs.TrackCount, len(tracks)
+	if attrs.TrackCount > len(tracks) {
+		base.Missing = attrs.TrackCount - len(tracks)
+	}
+	for _, t := range tracks {
+		if t.Type != "music-videos" && t.Attributes.PlayParams.ID == "" {
+			base.Unavailable++
+		}
+	}
+	if len(tracks) == 0 {
+		base.Error = "专辑中没有曲目"
+		return []AlbumPlan{base}
+	}
+	base.Accounts = accountRights(tracks[0].ID)
+	if !anyAvailable(base.Accounts) {
+		m, err := resolveStorefront(meta, albumId, storefront)
+		if err != nil {
+			base.Error = err.Error()
+			return []AlbumPlan{base}
+		}
+		base.Match = &m.StorefrontMatch
+		base.Accounts = accountRights(firstMapped(meta, m.tracks))
+		meta = m.apply(meta)
+		mainAccount, storefront, urlArg_i = m.account, m.MatchedStorefront, m.trackID(urlArg_i)
+	}
+
+	// 交互式选择在 dry-run 中不可用，按全部曲目（单曲链接为指定曲目）报告
+	selectOpts := job.Options
+	selectOpts.Select = false
+	selected := ui.SelectTracks(job.WithOptions(selectOpts), meta, storefront, urlArg_i)
+
+	manifests := fetchManifests(meta, selected, mainAccount, storefront)
+	outputs := outputJobs(job, meta, albumId)
+	if len(outputs) == 0 {
+		base.Error = "没有可输出的音乐库版本"
+		return []AlbumPlan{base}
+	}
+	var plans []AlbumPlan
+	for _, out := range outputs {
+		plans = append(plans, planOutput(out, meta, albumId, base, selected, manifests))
+	}
+	return plans
+}
+
+// fetchManifests 并发获取选中曲目的主播放列表档位（各输出共用）
+func fetchManifests(meta *structs.AutoGenerated, selected []int, account *structs.Account, storefront string) map[int]trackManifest {
+	manifests := make(map[int]trackManifest, len(selected))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, planWorkers)
+	for _, trackNum := range selected {
+		track := meta.Data[0].Relationships.Tracks.Data[trackNum-1]
+		if track.Type == "music-videos" {
+			continue
+		}
+		wg.Add(1)
+		go func(trackNum int, track structs.TrackData) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			var m trackManifest
+			manifest, err := api.GetInfoFromAdam(track.ID, account, storefront)
+			switch {
+			case err != nil:
+				m.err = err
+			case manifest.Attributes.ExtendedAssetUrls.EnhancedHls == "":
+				m.noHls = true
+			default:
+				m.variants, m.err = parser.MediaVariants(manifest.Attributes.ExtendedAssetUrls.EnhancedHls)
+			}
+			mu.Lock()
+			manifests[trackNum] = m
+			mu.Unlock()
+		}(trackNum, track)
+	}
+	wg.Wait()
+	return manifests
+}
+
+// planOutput 计算一个输出的曲目档位、目标路径与预计大小
+func planOutput(job *core.Job, meta *structs.AutoGenerated, albumId string, base AlbumPlan, selected []int, manifests map[int]trackManifest) AlbumPlan {
+	plan := base
+	plan.Codec = job.Codec()
+	plan.Accounts = append([]AccountRights(nil), base.Accounts...)
+	saveFolder := librarySaveFolder(job)
+
+	var albumTraits []string
+	for _, t := range meta.Data[0].Relationships.Tracks.Data {
+		albumTraits = append(albumTraits, t.Attributes.AudioTraits...)
+	}
+	plan.Tag = quality.Tag(job.Rule(), albumTraits)
+
+	for _, trackNum := range selected {
+		track := meta.Data[0].Relationships.Tracks.Data[trackNum-1]
+		tp := TrackPlan{Number: trackNum, ID: track.ID, Name: track.Attributes.Name, Type: track.Type}
+		if track.Type == "music-videos" {
+			if !core.Config.DownloadVideos {
+				tp.Skipped = "未启用 download-videos"
+			} else {
+				tp.Selected = "MV"
+				tp.Bytes = estimateDownloadSize(meta, []int{trackNum}, plan.Codec, job.Atmos, false)
+			}
+			plan.addTrack(tp)
+			continue
+		}
+
+		m := manifests[trackNum]
+		var label string
+		isHires := false
+		switch {
+		case m.err != nil:
+			tp.Error = m.err.Error()
+		case m.noHls && job.Atmos:
+			tp.Skipped = "没有杜比全景声版本"
+		case m.noHls:
+			tp.AAC = "256 kbps"
+			tp.Selected = "AAC 256 kbps"
+		default:
+			tp.AAC = bestLabel(quality.TierAAC, m.variants)
+			tp.Lossless = bestLabel(quality.TierLossless, m.variants)
+			tp.HiRes = bestLabel(quality.TierHiRes, m.variants)
+			tp.Atmos = bestLabel(quality.TierAtmos, m.variants)
+			choice := quality.Select(job.Rules(), m.variants)
+			tp.Reason = choice.Reason
+			if choice.Variant != nil {
+				label = choice.Variant.Label()
+				isHires = quality.Rule{Tier: quality.TierHiRes}.Matches(*choice.Variant)
+				tp.Selected = choice.Variant.Codecs + " " + label
+			}
+			if choice.Fallback && job.StrictQuality {
+				tp.Skipped = fmt.Sprintf("没有 %s 版本", job.Rule())
+				tp.Selected = ""
+			}
+		}
+		if tp.Error == "" && tp.Skipped == "" {
+			tp.Bytes = estimateDownloadSize(meta, []int{trackNum}, plan.Codec, job.Atmos, isHires)
+			tp.Path, plan.Folder = plannedPath(job, meta, albumId, track, saveFolder, fileQuality(job, label, m.noHls))
+			if tp.Path != "" {
+				var minSize int64
+				if core.Config.FileValidation.SizeCheckEnabled {
+					minSize = utils.EstimateFileSize(plan.Codec, job.Atmos, track.Attributes.DurationInMillis)
+				}
+				if validation, _ := utils.ValidateFile(tp.Path, minSize); validation != nil && validation.Exists && validation.IsValid {
+					tp.Exists = true
+				}
+			}
+		}
+		plan.addTrack(tp)
+	}
+	return plan
+}
+
+func (a *AlbumPlan) addTrack(tp TrackPlan) {
+	a.Tracks = append(a.Tracks, tp)
+	a.Bytes += tp.Bytes
+	if tp.Exists {
+		a.Existing++
+	} else {
+		a.Download += tp.Bytes
+	}
+}
+
+// librarySaveFolder 下载模式对应的音乐库目录
+func librarySaveFolder(job *core.Job) string {
+	if job.Atmos {
+		return core.Config.AtmosSaveFolder
+	} else if job.AAC {
+		return core.Config.AacSaveFolder
+	}
+	return core.Config.AlacSaveFolder
+}
+
+// bestLabel 档位中满足 tier 的最高音质，没有时为空
+func bestLabel(tier string, variants []quality.Variant) string {
+	for _, v := range variants {
+		if (quality.Rule{Tier: tier}).Matches(v) {
+			return v.Label()
+		}
+	}
+	return ""
+}
+
+// plannedPath 曲目在音乐库中的目标路径与专辑目录
+// 尚未在虚拟Singles目录中的单曲编号在下载时分配，此时只返回目录
+func plannedPath(job *core.Job, meta *structs.AutoGenerated, albumId string, track structs.TrackData, saveFolder, Quality string) (string, string) {
+	Tag_string := quality.Tag(job.Rule(), track.Attributes.AudioTraits)
+	singerFolder, albumFolder, songNameFormat, isSingle := trackFolders(job, meta, albumId, track, Quality, job.Codec(), Tag_string)
+
+	trackNum := track.Attributes.TrackNumber
+	for i, t := range meta.Data[0].Relationships.Tracks.Data {
+		if t.ID == track.ID {
+			trackNum = i + 1
+			break
+		}
+	}
+	if isSingle {
+		dir := singlesDir(saveFolder, saveFolder, singerFolder, albumFolder, songNameFormat)
+		num, ok := singles.Lookup(dir, singlesEntry(meta, track))
+		if !ok {
+			return "", dir
+		}
+		trackNum = num
+	}
+
+	songName := strings.ReplaceAll(songNameFormat, "{SongNumer}", fmt.Sprintf("%02d", trackNum))
+	filename := core.ForbiddenNames.ReplaceAllString(songName, "_") + ".m4a"
+	artistDir, albumDir, finalFilename := utils.EnsureSafePath(saveFolder, singerFolder, albumFolder, filename)
+	folder := filepath.Join(saveFolder, artistDir, albumDir)
+	return filepath.Join(folder, finalFilename), folder
+}
+
+// WritePlan 保存 dry-run 报告：路径以 .json 结尾时输出 JSON，否则为文本表格
+func WritePlan(p *Plan, path string) error {
+	var data []byte
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var err error
+		data, err = json.MarshalIndent(p, "", "  ")
+		if err != nil {
+			return err
+		}
+	} else {
+		data = []byte(p.Text())
+	}
+	return utils.WriteFileAtomic(path, data)
+}
+
+// Text 文本格式的报告（每个专辑一张曲目表）
+func (p *Plan) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "预检时间: %s\n", p.Started.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "专辑: %d | 曲目: %d | 已存在: %d | 失败: %d | 预计大小: %s | 需要下载: %s\n",
+		len(p.Albums), p.Tracks, p.Existing, p.Errors, utils.FormatBytes(p.Bytes), utils.FormatBytes(p.Download))
+
+	for _, a := range p.Albums {
+		fmt.Fprintf(&b, "\n💽 %s - %s（%s）\n", a.Artist, a.Name, a.URL)
+		if a.Error != "" {
+			fmt.Fprintf(&b, "  ❌ %s\n", a.Error)
+			continue
+		}
+		fmt.Fprintf(&b, "  输出: %s [%s] → %s\n", a.Codec, a.Tag, a.Folder)
+		fmt.Fprintf(&b, "  曲目: 目录 %d | 返回 %d | 缺少 %d | 当前地区不可用 %d\n", a.TrackCount, a.Listed, a.Missing, a.Unavailable)
+		var rights []string
+		for _, r := range a.Accounts {
+			mark := "✔"
+			if !r.Available {
+				mark = "✘"
+			}
+			rights = append(rights, fmt.Sprintf("%s %s(%s)", mark, r.Name, strings.ToUpper(r.Storefront)))
+		}
+		fmt.Fprintf(&b, "  账户: %s\n", strings.Join(rights, "  "))
+		if a.Match != nil {
+			fmt.Fprintf(&b, "  跨地区匹配: %s\n", a.Match)
+		}
+		fmt.Fprintf(&b, "  预计大小: %s | 需要下载: %s | 已存在: %d/%d\n", utils.FormatBytes(a.Bytes), utils.FormatBytes(a.Download), a.Existing, len(a.Tracks))
+
+		w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  #\t曲目\tAAC\tLossless\tHi-Res\tAtmos\t选中\t大小\t状态")
+		for _, t := range a.Tracks {
+			fmt.Fprintf(w, "  %02d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", t.Number, t.Name,
+				orDash(t.AAC), orDash(t.Lossless), orDash(t.HiRes), orDash(t.Atmos), orDash(t.Selected),
+				utils.FormatBytes(t.Bytes), t.status())
+		}
+		w.Flush()
+	}
+	return b.String()
+}
+
+func (t TrackPlan) status() string {
+	switch {
+	case t.Error != "":
+		return "失败: " + t.Error
+	case t.Skipped != "":
+		return "跳过: " + t.Skipped
+	case t.Exists:
+		return "已存在"
+	case t.Path == "" && t.Type != "music-videos":
+		return "新单曲（编号在下载时分配）"
+	}
+	return "待下载"
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
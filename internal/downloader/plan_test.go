@@ -0,0 +1,84 @@
+package downloader
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"main/internal/core"
+)
+
+// TestPlanReport 测试 dry-run 报告的汇总与输出格式
+func TestPlanReport(t *testing.T) {
+	album := AlbumPlan{URL: "https://music.apple.com/us/album/x/1", Name: "Album", Artist: "Artist", Codec: "ALAC", TrackCount: 3, Listed: 2, Missing: 1,
+		Accounts: []AccountRights{{Name: "us", Storefront: "us", Available: true}, {Name: "jp", Storefront: "jp"}}}
+	album.addTrack(TrackPlan{Number: 1, Name: "One", Lossless: "24B-48.0kHz", Selected: "24B-48.0kHz", Bytes: 30 << 20, Path: "/music/01. One.m4a", Exists: true})
+	album.addTrack(TrackPlan{Number: 2, Name: "Two", AAC: "256 kbps", Selected: "256 kbps", Bytes: 8 << 20, Path: "/music/02. Two.m4a"})
+
+	plan := &Plan{Started: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+	plan.Add(album, AlbumPlan{URL: "https://music.apple.com/us/album/y/2", Error: "无效的URL"})
+	if plan.Tracks != 2 || plan.Existing != 1 || plan.Errors != 1 || plan.Bytes != 38<<20 || plan.Download != 8<<20 {
+		t.Errorf("plan totals = %+v", plan)
+	}
+
+	text := plan.Text()
+	for _, want := range []string{"曲目: 2 | 已存在: 1 | 失败: 1", "缺少 1", "✔ us(US)", "✘ jp(JP)", "已存在", "待下载", "❌ 无效的URL"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() missing %q:\n%s", want, text)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if err := WritePlan(plan, path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Plan
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("report is not JSON: %v", err)
+	}
+	if len(got.Albums) != 2 || got.Albums[0].Tracks[1].Name != "Two" || got.Albums[0].Download != 8<<20 {
+		t.Errorf("JSON report = %+v", got)
+	}
+}
+
+// TestFileQuality 测试文件名 {Quality} 的取值与下载时一致
+func TestFileQuality(t *testing.T) {
+	defer func(format string) { core.Config.SongFileFormat = format }(core.Config.SongFileFormat)
+	core.Config.SongFileFormat = "{SongNumer}. {SongName} [{Quality}]"
+
+	alac := core.NewJob(core.Options{})
+	atmos := core.NewJob(core.Options{Atmos: true, AtmosMax: 2768})
+	lc := core.NewJob(core.Options{AAC: true, AacType: "aac-lc"})
+	aac := core.NewJob(core.Options{AAC: true, AacType: "aac"})
+	tests := []struct {
+		name  string
+		job   *core.Job
+		label string
+		noHls bool
+		want  string
+	}{
+		{"alac", alac, "24B-96.0kHz", false, "24B-96.0kHz"},
+		{"alac without hls", alac, "", true, ""},
+		{"atmos", atmos, "768 kbps", false, "768kbps"},
+		{"aac-lc", lc, "256 kbps", false, "256kbps"},
+		{"aac", aac, "256 kbps", false, "256 kbps"},
+		{"aac without hls", aac, "", true, "AAC"},
+	}
+	for _, tt := range tests {
+		if got := fileQuality(tt.job, tt.label, tt.noHls); got != tt.want {
+			t.Errorf("%s: fileQuality = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	core.Config.SongFileFormat = "{SongNumer}. {SongName}"
+	if got := fileQuality(aac, "256 kbps", true); got != "" {
+		t.Errorf("format without {Quality}: got %q", got)
+	}
+}
@@ -0,0 +1,274 @@
+package downloader
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"main/internal/checksum"
+	"main/internal/core"
+	"main/internal/hooks"
+	"main/internal/logger"
+	"main/internal/loudness"
+	"main/internal/metadata"
+	"main/internal/singles"
+	"main/utils/structs"
+)
+
+// albumPost 记录专辑内曲目的处理结果，专辑转移完成后整理虚拟Singles编号、进行响度分析、生成校验清单并执行后处理钩子
+// 不是虚拟Singles、未配置钩子且未启用响度分析与校验清单时为 nil，所有方法均可安全调用
+type albumPost struct {
+	pipeline  *hooks.Pipeline
+	loudness  bool // 专辑完成后分析响度并写入 ReplayGain 标签
+	checksums bool // 专辑完成后生成或更新校验清单
+	singles   bool // 虚拟Singles：插入了更早发行的单曲时按发行日期重新编号
+	album     hooks.Album
+	cacheBase string // 使用缓存时的缓存根目录
+	finalBase string // 目标根目录
+
+	mu         sync.Mutex
+	tracks     map[int]hooks.Track
+	downloaded int
+	failErr    error // 策略为 fail 的钩子失败
+}
+
+// newAlbumPost 为专辑创建后处理记录器；destAlbumFolder 为专辑在目标位置的目录
+func newAlbumPost(job *core.Job, meta *structs.AutoGenerated, albumId, storefront, urlRaw, codec, destAlbumFolder, cacheBase, finalBase string, usingCache bool) *albumPost {
+	pipeline := hooks.Default()
+	// 杜比全景声默认不分析（ffmpeg 无法完整解码 JOC 对象声道）
+	analyze := core.Config.Loudness.Enabled && (!job.Atmos || core.Config.Loudness.IncludeAtmos)
+	checksums := core.Config.Checksums.Enabled
+	isSingle := core.IsSingleAlbum(meta)
+	if !analyze && !checksums && !isSingle && !pipeline.Has(hooks.StageTrack) && !pipeline.Has(hooks.StageAlbum) {
+		return nil
+	}
+	attrs := meta.Data[0].Attributes
+	h := &albumPost{
+		pipeline:  pipeline,
+		loudness:  analyze,
+		checksums: checksums,
+		singles:   isSingle,
+		album: hooks.Album{
+			ID:          albumId,
+			Name:        attrs.Name,
+			Artist:      attrs.ArtistName,
+			URL:         urlRaw,
+			Storefront:  storefront,
+			Codec:       codec,
+			Folder:      destAlbumFolder,
+			ReleaseDate: attrs.ReleaseDate,
+		},
+		finalBase: finalBase,
+		tracks:    make(map[int]hooks.Track),
+	}
+	if usingCache {
+		h.cacheBase = cacheBase
+	}
+	return h
+}
+
+// record 记录曲目结果（已存在、跳过、失败）
+func (h *albumPost) record(trackNum int, track structs.TrackData, status, path string, err error) {
+	if h == nil {
+		return
+	}
+	t := newHookTrack(trackNum, track, status, path)
+	if err != nil {
+		t.Error = err.Error()
+	}
+	h.mu.Lock()
+	h.tracks[trackNum] = t
+	h.mu.Unlock()
+}
+
+// trackDone 记录新下载的曲目并执行 track 阶段钩子（使用缓存时路径位于缓存中）
+func (h *albumPost) trackDone(trackNum int, track structs.TrackData, path string) {
+	if h == nil {
+		return
+	}
+	t := newHookTrack(trackNum, track, hooks.TrackDownloaded, path)
+	h.mu.Lock()
+	h.tracks[trackNum] = t
+	h.downloaded++
+	h.mu.Unlock()
+
+	if err := h.pipeline.RunTrack(h.album, t); err != nil {
+		h.mu.Lock()
+		if h.failErr == nil {
+			h.failErr = err
+		}
+		h.mu.Unlock()
+	}
+}
+
+// finish 专辑转移完成后整理虚拟Singles编号、分析响度、更新校验清单并执行 album 阶段钩子；没有新下载的曲目时不执行
+// 返回策略为 fail 的钩子错误（包括 track 阶段），调用方据此将专辑记为失败
+func (h *albumPost) finish() error {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.failErr != nil {
+		return h.failErr
+	}
+	if h.downloaded == 0 {
+		return nil
+	}
+
+	nums := make([]int, 0, len(h.tracks))
+	for n := range h.tracks {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+	album := h.album
+	for _, n := range nums {
+		t := h.tracks[n]
+		t.Path = h.finalPath(t.Path)
+		album.Tracks = append(album.Tracks, t)
+	}
+	if h.singles {
+		h.renumberSingles(album.Tracks)
+	}
+	if h.loudness {
+		h.applyLoudness(album.Tracks)
+	}
+	// 清单在响度标签写入之后生成，记录的是最终文件内容
+	if h.checksums {
+		h.writeChecksums(album.Tracks)
+	}
+	return h.pipeline.RunAlbum(album)
+}
+
+// renumberSingles 新单曲的发行日期早于目录中已有的单曲时，按发行日期重新编号整个虚拟Singles目录
+// 重命名后同步更新曲目路径；失败只记录警告
+func (h *albumPost) renumberSingles(tracks []hooks.Track) {
+	log := logger.With("album_id", h.album.ID, "stage", "singles")
+	changes, err := singles.Rebuild(h.album.Folder, true, false)
+	if err != nil {
+		log.Warn("整理虚拟Singles编号失败: %v", err)
+	}
+	if len(changes) == 0 {
+		return
+	}
+	moved := make(map[string]string, len(changes))
+	for _, c := range changes {
+		moved[c.From] = c.To
+	}
+	for i := range tracks {
+		if to, ok := moved[tracks[i].Path]; ok {
+			tracks[i].Path = to
+		}
+	}
+	log.Info("🔢 %s 已按发行日期重新编号（%d 首）", filepath.Base(h.album.Folder), len(changes))
+}
+
+// applyLoudness 并发分析专辑内已落盘曲目（含本地已存在的曲目）的响度，写入 ReplayGain 标签
+// 分析或写入失败只记录警告，不影响专辑结果
+func (h *albumPost) applyLoudness(tracks []hooks.Track) {
+	var paths []string
+	for _, t := range tracks {
+		if (t.Status == hooks.TrackDownloaded || t.Status == hooks.TrackExists) && strings.HasSuffix(t.Path, ".m4a") {
+			paths = append(paths, t.Path)
+		}
+	}
+	if len(paths) == 0 {
+		return
+	}
+
+	log := logger.With("album_id", h.album.ID, "stage", "loudness")
+	log.Info("🔊 分析响度（%d 首）...", len(paths))
+	results, errs := loudness.AnalyzeAll(context.Background(), paths, core.Config.Loudness.Threads)
+
+	var ok []loudness.Result
+	for i, err := range errs {
+		if err != nil {
+			log.Warn("响度分析失败 %s: %v", filepath.Base(paths[i]), err)
+			continue
+		}
+		ok = append(ok, results[i])
+	}
+	if len(ok) == 0 {
+		return
+	}
+	albumLoudness, albumPeak := loudness.Album(ok)
+
+	// 已存在的曲目只在增益值变化（如专辑新增曲目改变了专辑增益）时才重写
+	written := 0
+	for i, path := range paths {
+		if errs[i] != nil {
+			continue
+		}
+		gain := loudness.GainFor(results[i], albumLoudness, albumPeak)
+		changed, err := metadata.WriteReplayGain(path, gain.Tags())
+		if err != nil {
+			log.Warn("写入 ReplayGain 标签失败 %s: %v", filepath.Base(path), err)
+			continue
+		}
+		if !changed {
+			continue
+		}
+		log.Debug("[响度] %s: %.1f LUFS，曲目增益 %.2f dB，峰值 %.6f", filepath.Base(path), results[i].Loudness, gain.TrackGain, gain.TrackPeak)
+		written++
+	}
+	log.Info("✅ 响度标签已写入 %d 首（专辑 %.1f LUFS，增益 %.2f dB）", written, albumLoudness, loudness.ReferenceLoudness-albumLoudness)
+}
+
+// writeChecksums 为专辑目录（以及曲目所在的分碟目录）创建或增量更新校验清单
+// 清单覆盖目录中的音频、封面、歌词与动态封面；失败只记录警告
+func (h *albumPost) writeChecksums(tracks []hooks.Track) {
+	dirs := map[string]bool{h.album.Folder: true}
+	for _, t := range tracks {
+		if t.Status == hooks.TrackDownloaded && t.Path != "" {
+			dirs[filepath.Dir(t.Path)] = true
+		}
+	}
+	alg := strings.ToLower(core.Config.Checksums.Algorithm)
+	if alg == "" {
+		alg = checksum.AlgSHA256
+	}
+
+	log := logger.With("album_id", h.album.ID, "stage", "checksums")
+	for dir := range dirs {
+		changed, err := checksum.Refresh(dir, alg)
+		if err != nil {
+			log.Warn("生成校验清单失败 %s: %v", dir, err)
+			continue
+		}
+		if changed > 0 {
+			log.Debug("[校验清单] %s: 更新 %d 个文件", dir, changed)
+		}
+	}
+}
+
+// finalPath 将缓存中的路径映射为转移后的目标路径
+func (h *albumPost) finalPath(path string) string {
+	if h.cacheBase == "" || path == "" {
+		return path
+	}
+	rel, err := filepath.Rel(h.cacheBase, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return filepath.Join(h.finalBase, rel)
+}
+
+// newHookTrack 根据曲目元数据生成钩子的曲目描述
+func newHookTrack(trackNum int, track structs.TrackData, status, path string) hooks.Track {
+	number := track.Attributes.TrackNumber
+	if number == 0 {
+		number = trackNum
+	}
+	return hooks.Track{
+		ID:     track.ID,
+		Number: number,
+		Disc:   track.Attributes.DiscNumber,
+		Name:   track.Attributes.Name,
+		Artist: track.Attributes.ArtistName,
+		ISRC:   track.Attributes.Isrc,
+		Status: status,
+		Path:   path,
+	}
+}
@@ -0,0 +1,314 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"main/internal/logger"
+	"main/utils/structs"
+)
+
+// 执行时机
+const (
+	StageTrack = "track" // 每首新下载的曲目写入标签并落盘后
+	StageAlbum = "album" // 专辑全部曲目完成并从缓存转移到目标目录后
+)
+
+// 失败处理策略
+const (
+	PolicyIgnore = "ignore" // 忽略失败，只记录调试日志
+	PolicyWarn   = "warn"   // 记录警告，继续处理
+	PolicyFail   = "fail"   // 将专辑记为失败
+)
+
+// 曲目状态
+const (
+	TrackDownloaded = "downloaded"
+	TrackExists     = "exists"
+	TrackSkipped    = "skipped"
+	TrackFailed     = "failed"
+)
+
+// defaultTimeout 未配置 timeout-sec 时单次执行的超时
+const defaultTimeout = 5 * time.Minute
+
+// waitDelay 超时终止后等待子进程释放输出管道的时间
+const waitDelay = 5 * time.Second
+
+// outputLimit 失败时错误信息中保留的输出长度
+const outputLimit = 512
+
+// Payload 传递给钩子的 JSON 描述
+type Payload struct {
+	Stage string `json:"stage"`
+	Album Album  `json:"album"`
+	Track *Track `json:"track,omitempty"` // 仅 track 阶段
+}
+
+// Album 专辑描述
+type Album struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	Artist      string  `json:"artist"`
+	URL         string  `json:"url,omitempty"`
+	Storefront  string  `json:"storefront"`
+	Codec       string  `json:"codec"`
+	Folder      string  `json:"folder"` // 专辑在目标位置的目录
+	ReleaseDate string  `json:"release_date,omitempty"`
+	Tracks      []Track `json:"tracks,omitempty"` // 仅 album 阶段
+}
+
+// Track 曲目描述
+type Track struct {
+	ID     string `json:"id"`
+	Number int    `json:"number"`
+	Disc   int    `json:"disc"`
+	Name   string `json:"name"`
+	Artist string `json:"artist"`
+	ISRC   string `json:"isrc,omitempty"`
+	Status string `json:"status"`
+	Path   string `json:"path,omitempty"` // 最终路径（track 阶段使用缓存时为缓存中的路径）
+	Error  string `json:"error,omitempty"`
+}
+
+// Runner 钩子的执行方式
+type Runner interface {
+	Run(ctx context.Context, p Payload, data []byte) error
+}
+
+// hook 一个已配置的钩子
+type hook struct {
+	name    string
+	stage   string
+	runner  Runner
+	timeout time.Duration
+	policy  string
+}
+
+// Pipeline 按配置顺序执行的后处理钩子
+type Pipeline struct {
+	hooks []hook
+}
+
+// New 根据配置创建后处理流水线
+func New(cfgs []structs.PostHookConfig) (*Pipeline, error) {
+	p := &Pipeline{}
+	for i, cfg := range cfgs {
+		h, err := newHook(cfg)
+		if err != nil {
+			name := cfg.Name
+			if name == "" {
+				name = fmt.Sprintf("post-hooks[%d]", i)
+			}
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		p.hooks = append(p.hooks, h)
+	}
+	return p, nil
+}
+
+// newHook 校验并创建单个钩子
+func newHook(cfg structs.PostHookConfig) (hook, error) {
+	h := hook{
+		name:    cfg.Name,
+		stage:   strings.ToLower(cfg.Stage),
+		timeout: defaultTimeout,
+		policy:  strings.ToLower(cfg.OnFailure),
+	}
+	if cfg.TimeoutSec > 0 {
+		h.timeout = time.Duration(cfg.TimeoutSec) * time.Second
+	} else if cfg.TimeoutSec < 0 {
+		return h, errors.New("timeout-sec 不能为负数")
+	}
+	if h.stage != StageTrack && h.stage != StageAlbum {
+		return h, fmt.Errorf("无效的 stage '%s'（可选: track, album）", cfg.Stage)
+	}
+	switch h.policy {
+	case "":
+		h.policy = PolicyWarn
+	case PolicyIgnore, PolicyWarn, PolicyFail:
+	default:
+		return h, fmt.Errorf("无效的 on-failure '%s'（可选: ignore, warn, fail）", cfg.OnFailure)
+	}
+
+	switch strings.ToLower(cfg.Type) {
+	case "", "command":
+		if cfg.Command == "" {
+			return h, errors.New("command 类型需要配置 command")
+		}
+		h.runner = &commandRunner{command: cfg.Command, args: cfg.Args}
+		if h.name == "" {
+			h.name = cfg.Command
+		}
+	case "plugin":
+		if cfg.Plugin == "" {
+			return h, errors.New("plugin 类型需要配置 plugin 路径")
+		}
+		r, err := loadPlugin(cfg.Plugin)
+		if err != nil {
+			return h, err
+		}
+		h.runner = r
+		if h.name == "" {
+			h.name = cfg.Plugin
+		}
+	default:
+		return h, fmt.Errorf("无效的 type '%s'（可选: command, plugin）", cfg.Type)
+	}
+	return h, nil
+}
+
+// Has 判断是否配置了指定阶段的钩子
+func (p *Pipeline) Has(stage string) bool {
+	if p == nil {
+		return false
+	}
+	for _, h := range p.hooks {
+		if h.stage == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// RunTrack 对一首曲目执行 track 阶段的钩子
+func (p *Pipeline) RunTrack(album Album, track Track) error {
+	return p.run(Payload{Stage: StageTrack, Album: album, Track: &track})
+}
+
+// RunAlbum 对专辑执行 album 阶段的钩子
+func (p *Pipeline) RunAlbum(album Album) error {
+	return p.run(Payload{Stage: StageAlbum, Album: album})
+}
+
+// run 按顺序执行阶段内的钩子；策略为 fail 的钩子失败时停止并返回错误
+func (p *Pipeline) run(payload Payload) error {
+	if !p.Has(payload.Stage) {
+		return nil
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("编码钩子输入失败: %w", err)
+	}
+
+	fields := []interface{}{"album_id", payload.Album.ID, "stage", payload.Stage}
+	if payload.Track != nil {
+		fields = append(fields, "track_id", payload.Track.ID)
+	}
+	for _, h := range p.hooks {
+		if h.stage != payload.Stage {
+			continue
+		}
+		log := logger.With(append(fields, "hook", h.name)...)
+
+		start := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+		err := h.runner.Run(ctx, payload, data)
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("执行超时（%s）", h.timeout)
+		}
+		cancel()
+
+		if err == nil {
+			log.Debug("[后处理] %s 完成，用时 %s", h.name, time.Since(start).Round(time.Millisecond))
+			continue
+		}
+		switch h.policy {
+		case PolicyIgnore:
+			log.Debug("[后处理] %s 失败（已忽略）: %v", h.name, err)
+		case PolicyWarn:
+			log.Warn("[后处理] %s 失败: %v", h.name, err)
+		case PolicyFail:
+			log.Error("[后处理] %s 失败: %v", h.name, err)
+			return fmt.Errorf("后处理钩子 %s 失败: %w", h.name, err)
+		}
+	}
+	return nil
+}
+
+// commandRunner 执行外部命令，JSON 描述通过标准输入传递
+// 专辑描述可能超过环境变量的长度限制（E2BIG），因此不放入环境变量
+type commandRunner struct {
+	command string
+	args    []string
+}
+
+// Run 执行命令，非零退出码视为失败
+func (r *commandRunner) Run(ctx context.Context, p Payload, data []byte) error {
+	trackPath := ""
+	if p.Track != nil {
+		trackPath = p.Track.Path
+	}
+	replacer := strings.NewReplacer(
+		"{album_folder}", p.Album.Folder,
+		"{album_id}", p.Album.ID,
+		"{track_path}", trackPath,
+		"{stage}", p.Stage,
+	)
+	args := make([]string, len(r.args))
+	for i, a := range r.args {
+		args[i] = replacer.Replace(a)
+	}
+
+	cmd := exec.CommandContext(ctx, r.command, args...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Env = append(os.Environ(),
+		"AMDL_HOOK_STAGE="+p.Stage,
+		"AMDL_ALBUM_ID="+p.Album.ID,
+		"AMDL_ALBUM_NAME="+p.Album.Name,
+		"AMDL_ALBUM_FOLDER="+p.Album.Folder,
+		"AMDL_TRACK_PATH="+trackPath,
+	)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	cmd.WaitDelay = waitDelay
+	if err := cmd.Run(); err != nil {
+		if msg := tail(strings.TrimSpace(out.String()), outputLimit); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// tail 返回字符串末尾最多 n 字节（按字符边界截断）
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	i := len(s) - n
+	for i < len(s) && !utf8.RuneStart(s[i]) {
+		i++
+	}
+	return "..." + s[i:]
+}
+
+// 全局流水线，未配置钩子时为空
+var global *Pipeline
+
+// Init 根据配置初始化全局流水线
+func Init(cfgs []structs.PostHookConfig) error {
+	p, err := New(cfgs)
+	if err != nil {
+		return err
+	}
+	global = p
+	if len(p.hooks) > 0 {
+		logger.Debug("[后处理] 已配置 %d 个钩子", len(p.hooks))
+	}
+	return nil
+}
+
+// Default 返回全局流水线
+func Default() *Pipeline {
+	return global
+}
@@ -0,0 +1,140 @@
+package hooks
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"main/utils/structs"
+)
+
+// TestCommandHook 测试占位符替换、环境变量与标准输入中的 JSON 描述
+func TestCommandHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sh")
+	}
+	out := filepath.Join(t.TempDir(), "out.txt")
+	p, err := New([]structs.PostHookConfig{{
+		Stage:   "track",
+		Command: "sh",
+		Args:    []string{"-c", `printf '%s|%s|%s|' "$1" "$AMDL_HOOK_STAGE" "$AMDL_ALBUM_ID" > "$0"; cat >> "$0"`, out, "{track_path}"},
+	}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	album := Album{ID: "1440", Name: "Album", Folder: "/music/Artist/Album"}
+	track := Track{ID: "1441", Number: 1, Status: TrackDownloaded, Path: "/music/Artist/Album/01. Song.m4a"}
+	if err := p.RunTrack(album, track); err != nil {
+		t.Fatalf("RunTrack failed: %v", err)
+	}
+	// 未配置 album 阶段的钩子，不应执行
+	os.Remove(out)
+	if err := p.RunAlbum(album); err != nil {
+		t.Fatalf("RunAlbum failed: %v", err)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Fatal("Album stage should not run track hooks")
+	}
+
+	if err := p.RunTrack(album, track); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.SplitN(string(data), "|", 4)
+	if len(parts) != 4 || parts[0] != track.Path || parts[1] != StageTrack || parts[2] != "1440" {
+		t.Fatalf("Unexpected output: %q", data)
+	}
+	var payload Payload
+	if err := json.Unmarshal([]byte(parts[3]), &payload); err != nil {
+		t.Fatalf("Invalid stdin payload %q: %v", parts[3], err)
+	}
+	if payload.Stage != StageTrack || payload.Track == nil || payload.Track.ID != "1441" || payload.Album.Folder != album.Folder {
+		t.Errorf("Unexpected payload: %+v", payload)
+	}
+}
+
+// TestFailurePolicy 测试失败处理策略：只有 fail 返回错误，且包含命令输出
+func TestFailurePolicy(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sh")
+	}
+	for _, policy := range []string{"ignore", "warn", "", "fail"} {
+		p, err := New([]structs.PostHookConfig{{
+			Name:      "check",
+			Stage:     "album",
+			Command:   "sh",
+			Args:      []string{"-c", "echo boom >&2; exit 2"},
+			OnFailure: policy,
+		}})
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		err = p.RunAlbum(Album{ID: "1440"})
+		if policy == "fail" {
+			if err == nil || !strings.Contains(err.Error(), "boom") {
+				t.Errorf("Expected error with output for policy fail, got %v", err)
+			}
+		} else if err != nil {
+			t.Errorf("Policy %q should not return error: %v", policy, err)
+		}
+	}
+}
+
+// TestTimeout 测试超时的钩子被终止
+func TestTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sleep")
+	}
+	p, err := New([]structs.PostHookConfig{{
+		Stage:      "album",
+		Command:    "sleep",
+		Args:       []string{"10"},
+		TimeoutSec: 1,
+		OnFailure:  "fail",
+	}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := p.RunAlbum(Album{ID: "1440"}); err == nil || !strings.Contains(err.Error(), "超时") {
+		t.Errorf("Expected timeout error, got %v", err)
+	}
+}
+
+// TestNewValidation 测试配置错误
+func TestNewValidation(t *testing.T) {
+	cases := []structs.PostHookConfig{
+		{Stage: "track"},
+		{Stage: "disc", Command: "true"},
+		{Stage: "album", Command: "true", OnFailure: "retry"},
+		{Stage: "album", Command: "true", TimeoutSec: -1},
+		{Stage: "album", Type: "script", Command: "true"},
+		{Stage: "album", Type: "plugin"},
+		{Stage: "album", Type: "plugin", Plugin: filepath.Join(t.TempDir(), "missing.so")},
+	}
+	for _, c := range cases {
+		if _, err := New([]structs.PostHookConfig{c}); err == nil {
+			t.Errorf("Expected error for %+v", c)
+		}
+	}
+
+	var nilPipeline *Pipeline
+	if nilPipeline.Has(StageAlbum) || nilPipeline.RunAlbum(Album{}) != nil {
+		t.Error("Nil pipeline should be a no-op")
+	}
+}
+
+// TestTail 测试输出截断保持 UTF-8 字符完整
+func TestTail(t *testing.T) {
+	s := strings.Repeat("错", 10)
+	got := tail(s, 7)
+	if got != "...错错" {
+		t.Errorf("tail = %q", got)
+	}
+}
@@ -0,0 +1,51 @@
+//go:build (linux || darwin) && cgo
+
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"plugin"
+)
+
+// pluginRunner 调用 Go 插件导出的 Hook 函数
+type pluginRunner struct {
+	fn func(context.Context, []byte) error
+}
+
+// loadPlugin 加载插件并查找 Hook 符号
+// 插件需导出 func Hook(ctx context.Context, payload []byte) error，payload 为 JSON 描述
+func loadPlugin(path string) (Runner, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("加载插件失败: %w", err)
+	}
+	sym, err := p.Lookup("Hook")
+	if err != nil {
+		return nil, fmt.Errorf("插件未导出 Hook: %w", err)
+	}
+	fn, ok := sym.(func(context.Context, []byte) error)
+	if !ok {
+		return nil, fmt.Errorf("插件的 Hook 签名应为 func(context.Context, []byte) error，实际为 %T", sym)
+	}
+	return &pluginRunner{fn: fn}, nil
+}
+
+// Run 调用插件；插件应在 ctx 取消后尽快返回
+func (r *pluginRunner) Run(ctx context.Context, p Payload, data []byte) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				done <- fmt.Errorf("插件 panic: %v", v)
+			}
+		}()
+		done <- r.fn(ctx, data)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
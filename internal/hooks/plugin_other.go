@@ -0,0 +1,10 @@
+//go:build !((linux || darwin) && cgo)
+
+package hooks
+
+import "errors"
+
+// loadPlugin 当前平台不支持 Go 插件（需要 Linux/macOS 且启用 cgo）
+func loadPlugin(path string) (Runner, error) {
+	return nil, errors.New("当前平台或构建不支持 Go 插件（需要 Linux/macOS 且启用 cgo）")
+}
@@ -0,0 +1,88 @@
+package importer
+
+import (
+	"main/internal/api"
+	"main/internal/search"
+	"main/utils/structs"
+)
+
+// searchLimit 按名称搜索时比较的候选数
+const searchLimit = 10
+
+// Candidate 目录中的候选曲目或专辑
+type Candidate struct {
+	ID     string `json:"id"`
+	Name   string `json:"name,omitempty"`
+	Artist string `json:"artist,omitempty"`
+	Album  string `json:"album,omitempty"` // 曲目所属专辑
+}
+
+// String 候选的可读描述
+func (c Candidate) String() string {
+	s := c.Name
+	if c.Artist != "" {
+		s = c.Artist + " - " + s
+	}
+	if c.Album != "" && c.Album != c.Name {
+		s += "（" + c.Album + "）"
+	}
+	if s == "" {
+		return c.ID
+	}
+	return s
+}
+
+// Catalog 在地区目录中查找曲目与专辑
+type Catalog interface {
+	AlbumsByUPC(upc string) ([]Candidate, error)
+	SongsByISRC(isrc string) ([]Candidate, error)
+	// Search 按关键词搜索，typ 为 search.TypeSongs 或 search.TypeAlbums
+	Search(term, typ string) ([]Candidate, error)
+}
+
+// apiCatalog 通过 Apple Music API 查询的目录
+type apiCatalog struct {
+	account    *structs.Account
+	storefront string
+}
+
+// NewCatalog 返回使用 account 查询 storefront 地区目录的 Catalog
+func NewCatalog(account *structs.Account, storefront string) Catalog {
+	return &apiCatalog{account: account, storefront: storefront}
+}
+
+func (c *apiCatalog) AlbumsByUPC(upc string) ([]Candidate, error) {
+	ids, err := api.GetAlbumsByUPC(upc, c.account, c.storefront)
+	if err != nil {
+		return nil, err
+	}
+	var out []Candidate
+	for _, id := range ids {
+		out = append(out, Candidate{ID: id})
+	}
+	return out, nil
+}
+
+func (c *apiCatalog) SongsByISRC(isrc string) ([]Candidate, error) {
+	songs, err := api.GetSongsByISRC(isrc, c.account, c.storefront)
+	if err != nil {
+		return nil, err
+	}
+	var out []Candidate
+	for _, s := range songs {
+		out = append(out, Candidate{ID: s.ID, Name: s.Attributes.Name, Artist: s.Attributes.ArtistName, Album: s.Attributes.AlbumName})
+	}
+	return out, nil
+}
+
+func (c *apiCatalog) Search(term, typ string) ([]Candidate, error) {
+	results, err := search.Search(c.storefront, term, typ, searchLimit)
+	if err != nil {
+		return nil, err
+	}
+	var out []Candidate
+	for _, r := range results {
+		out = append(out, Candidate{ID: r.ID, Name: r.Name, Artist: r.Artist, Album: r.Album})
+	}
+	return out, nil
+}
@@ -0,0 +1,278 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"main/internal/core"
+	"main/internal/search"
+	"main/internal/utils"
+)
+
+// 行的解析结果
+const (
+	StatusResolved   = "resolved"
+	StatusUnresolved = "unresolved"
+)
+
+// 解析方式
+const (
+	ByURL    = "url"    // 行中已是 Apple Music 链接
+	ByUPC    = "upc"    // 按专辑 UPC 查找
+	ByISRC   = "isrc"   // 按曲目 ISRC 查找
+	BySearch = "search" // 按艺术家与名称搜索后模糊匹配
+)
+
+// DefaultMinConfidence 模糊匹配的默认最低置信度
+const DefaultMinConfidence = 0.8
+
+// Options 导入参数
+type Options struct {
+	Catalog       Catalog
+	Storefront    string  // 生成链接使用的地区
+	MinConfidence float64 // 模糊匹配低于该置信度时视为未解析
+	Progress      func(done, total int)
+}
+
+// Item 一行的解析结果
+type Item struct {
+	Row        Row        `json:"row"`
+	Status     string     `json:"status"`
+	Link       string     `json:"link,omitempty"` // 写入任务文件的链接
+	By         string     `json:"by,omitempty"`
+	Confidence float64    `json:"confidence"`
+	Match      *Candidate `json:"match,omitempty"` // 未解析时为置信度最高的候选
+	Reason     string     `json:"reason,omitempty"`
+	Duplicate  bool       `json:"duplicate,omitempty"` // 链接与之前的行相同，不重复写入
+}
+
+// Report 导入报告
+type Report struct {
+	Started       time.Time `json:"started"`
+	Sources       []string  `json:"sources"`
+	Storefront    string    `json:"storefront"`
+	MinConfidence float64   `json:"min_confidence"`
+	Rows          int       `json:"rows"`
+	Resolved      int       `json:"resolved"`
+	Unresolved    int       `json:"unresolved"`
+	Fuzzy         int       `json:"fuzzy"` // 按名称模糊匹配解析的行（建议核对）
+	Links         []string  `json:"links"`
+	Items         []Item    `json:"items"`
+}
+
+// Run 读取导入文件并将每一行解析为目录中的专辑或曲目链接
+func Run(ctx context.Context, paths []string, opts Options) (*Report, error) {
+	if opts.MinConfidence <= 0 {
+		opts.MinConfidence = DefaultMinConfidence
+	}
+	r := &Report{Started: time.Now(), Sources: paths, Storefront: opts.Storefront, MinConfidence: opts.MinConfidence}
+	var rows []Row
+	for _, p := range paths {
+		fileRows, err := ReadRows(p)
+		if err != nil {
+			return r, fmt.Errorf("读取 %s 失败: %w", p, err)
+		}
+		rows = append(rows, fileRows...)
+	}
+
+	seen := make(map[string]bool)
+	for i, row := range rows {
+		if err := ctx.Err(); err != nil {
+			return r, err
+		}
+		item := resolve(opts, row)
+		if item.Status == StatusResolved {
+			item.Duplicate = seen[item.Link]
+			seen[item.Link] = true
+		}
+		r.add(item)
+		if opts.Progress != nil {
+			opts.Progress(i+1, len(rows))
+		}
+	}
+	return r, nil
+}
+
+func (r *Report) add(item Item) {
+	r.Rows++
+	r.Items = append(r.Items, item)
+	if item.Status != StatusResolved {
+		r.Unresolved++
+		return
+	}
+	r.Resolved++
+	if item.By == BySearch {
+		r.Fuzzy++
+	}
+	if !item.Duplicate {
+		r.Links = append(r.Links, item.Link)
+	}
+}
+
+// resolve 解析一行：链接原样使用，曲目先按 ISRC、专辑先按 UPC 精确查找，找不到时按名称搜索并模糊匹配
+func resolve(opts Options, row Row) Item {
+	item := Item{Row: row, Status: StatusUnresolved}
+	if row.URL != "" {
+		item.resolved(row.URL, ByURL, 1, nil)
+		return item
+	}
+
+	var reasons []string
+	switch {
+	case row.ISRC != "":
+		if c, err := exact(opts.Catalog.SongsByISRC(row.ISRC)); err != nil {
+			reasons = append(reasons, fmt.Sprintf("ISRC %s: %v", row.ISRC, err))
+		} else {
+			best := bestOf(row, c)
+			item.resolved(link(opts.Storefront, search.TypeSongs, best.ID), ByISRC, 1, best)
+			return item
+		}
+	case !row.IsTrack() && row.UPC != "":
+		if c, err := exact(opts.Catalog.AlbumsByUPC(row.UPC)); err != nil {
+			reasons = append(reasons, fmt.Sprintf("UPC %s: %v", row.UPC, err))
+		} else {
+			item.resolved(link(opts.Storefront, search.TypeAlbums, c[0].ID), ByUPC, 1, &c[0])
+			return item
+		}
+	}
+
+	typ, name := search.TypeAlbums, row.Album
+	if row.IsTrack() {
+		typ, name = search.TypeSongs, row.Track
+	}
+	if name == "" {
+		if len(reasons) == 0 {
+			reasons = append(reasons, "没有可用于查找的曲名、专辑名、ISRC 或 UPC")
+		}
+		item.Reason = strings.Join(reasons, "；")
+		return item
+	}
+
+	candidates, err := opts.Catalog.Search(strings.TrimSpace(core.GetPrimaryArtist(row.Artist)+" "+name), typ)
+	if err != nil {
+		item.Reason = strings.Join(append(reasons, err.Error()), "；")
+		return item
+	}
+	var best *Candidate
+	for i := range candidates {
+		if s := score(row, candidates[i]); best == nil || s > item.Confidence {
+			best, item.Confidence = &candidates[i], s
+		}
+	}
+	switch {
+	case best == nil:
+		item.Reason = strings.Join(append(reasons, "搜索没有结果"), "；")
+	case item.Confidence < opts.MinConfidence:
+		item.Match = best
+		item.Reason = strings.Join(append(reasons, fmt.Sprintf("最佳匹配的置信度 %.2f 低于 %.2f", item.Confidence, opts.MinConfidence)), "；")
+	default:
+		item.resolved(link(opts.Storefront, typ, best.ID), BySearch, item.Confidence, best)
+	}
+	return item
+}
+
+func (item *Item) resolved(link, by string, confidence float64, match *Candidate) {
+	item.Status = StatusResolved
+	item.Link = link
+	item.By = by
+	item.Confidence = confidence
+	item.Match = match
+}
+
+// exact 精确查找的结果，没有结果时返回错误
+func exact(candidates []Candidate, err error) ([]Candidate, error) {
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("目录中没有找到")
+	}
+	return candidates, nil
+}
+
+// bestOf 同一 ISRC 对应多个曲目（如单曲与专辑版本）时，选择与行最接近的一个
+func bestOf(row Row, candidates []Candidate) *Candidate {
+	best, bestScore := &candidates[0], -1.0
+	for i := range candidates {
+		if s := score(row, candidates[i]); s > bestScore {
+			best, bestScore = &candidates[i], s
+		}
+	}
+	return best
+}
+
+// link 下载使用的链接；曲目使用 /song/ 链接，按单曲模式只下载该曲目
+func link(storefront, typ, id string) string {
+	kind := "album"
+	if typ == search.TypeSongs {
+		kind = "song"
+	}
+	return fmt.Sprintf("https://music.apple.com/%s/%s/%s", strings.ToLower(storefront), kind, id)
+}
+
+// WriteTasks 将解析出的链接写入任务文件（与 TXT 文件模式的格式相同，# 开头为注释）
+func WriteTasks(r *Report, path string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# 导入自 %s（%s）\n", strings.Join(r.Sources, ", "), r.Started.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "# 行: %d | 已解析: %d（模糊匹配 %d）| 未解析: %d\n", r.Rows, r.Resolved, r.Fuzzy, r.Unresolved)
+	for _, l := range r.Links {
+		b.WriteString(l + "\n")
+	}
+	return utils.WriteFileAtomic(path, []byte(b.String()))
+}
+
+// WriteReport 写入报告，扩展名为 .json 时输出 JSON（包含所有行），否则输出文本
+func WriteReport(r *Report, path string) error {
+	var data []byte
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var err error
+		data, err = json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+	} else {
+		data = []byte(r.Text())
+	}
+	return utils.WriteFileAtomic(path, data)
+}
+
+// Text 文本格式的报告：未解析的行与按名称模糊匹配的行
+func (r *Report) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "导入时间: %s\n", r.Started.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "文件: %s | 地区: %s\n", strings.Join(r.Sources, ", "), strings.ToUpper(r.Storefront))
+	fmt.Fprintf(&b, "行: %d | 已解析: %d（模糊匹配 %d）| 未解析: %d | 链接: %d\n", r.Rows, r.Resolved, r.Fuzzy, r.Unresolved, len(r.Links))
+	for _, section := range []struct {
+		title string
+		match func(Item) bool
+	}{
+		{"未解析", func(i Item) bool { return i.Status == StatusUnresolved }},
+		{"模糊匹配（请核对）", func(i Item) bool { return i.Status == StatusResolved && i.By == BySearch }},
+	} {
+		first := true
+		for _, item := range r.Items {
+			if !section.match(item) {
+				continue
+			}
+			if first {
+				fmt.Fprintf(&b, "\n== %s ==\n", section.title)
+				first = false
+			}
+			fmt.Fprintf(&b, "\n%s:%d  %s\n", item.Row.Source, item.Row.Line, item.Row)
+			if item.Match != nil {
+				fmt.Fprintf(&b, "  候选: %s（%s，置信度 %.2f）\n", item.Match, item.Match.ID, item.Confidence)
+			}
+			if item.Link != "" {
+				fmt.Fprintf(&b, "  链接: %s\n", item.Link)
+			}
+			if item.Reason != "" {
+				fmt.Fprintf(&b, "  - %s\n", item.Reason)
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,206 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"main/internal/core"
+	"main/internal/search"
+)
+
+// fakeCatalog 从 testdata/catalog.json 读取的目录
+type fakeCatalog struct {
+	Albums []fakeEntry `json:"albums"`
+	Songs  []fakeEntry `json:"songs"`
+}
+
+type fakeEntry struct {
+	Candidate
+	ISRC string `json:"isrc"`
+	UPC  string `json:"upc"`
+}
+
+func loadCatalog(t *testing.T) *fakeCatalog {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "catalog.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := new(fakeCatalog)
+	if err := json.Unmarshal(data, c); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func (c *fakeCatalog) AlbumsByUPC(upc string) ([]Candidate, error) {
+	var out []Candidate
+	for _, e := range c.Albums {
+		if e.UPC == upc {
+			out = append(out, e.Candidate)
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeCatalog) SongsByISRC(isrc string) ([]Candidate, error) {
+	var out []Candidate
+	for _, e := range c.Songs {
+		if e.ISRC == isrc {
+			out = append(out, e.Candidate)
+		}
+	}
+	return out, nil
+}
+
+// Search 返回名称或艺术家中包含关键词任一词的条目
+func (c *fakeCatalog) Search(term, typ string) ([]Candidate, error) {
+	entries := c.Albums
+	if typ == search.TypeSongs {
+		entries = c.Songs
+	}
+	var out []Candidate
+	for _, e := range entries {
+		text := " " + normalize(e.Name+" "+e.Artist) + " "
+		for _, w := range strings.Fields(normalize(term)) {
+			if strings.Contains(text, " "+w+" ") {
+				out = append(out, e.Candidate)
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// TestReadRows 测试按表头、按列数与按内容识别导入文件
+func TestReadRows(t *testing.T) {
+	rows, err := ReadRows(filepath.Join("testdata", "spotify.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("spotify rows = %d, want 4", len(rows))
+	}
+	if r := rows[0]; r.Line != 2 || r.Artist != "Daft Punk, Pharrell Williams, Nile Rodgers" || r.Album != "Random Access Memories" || r.ISRC != "USQX91300108" || r.URL != "" {
+		t.Errorf("spotify row = %+v", r)
+	}
+
+	rows, err = ReadRows(filepath.Join("testdata", "lastfm.tsv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Artist != "Daft Punk" || rows[0].Album != "Random Access Memories" || rows[0].Track != "Instant Crush" {
+		t.Errorf("lastfm rows = %+v", rows)
+	}
+
+	rows, err = ReadRows(filepath.Join("testdata", "list.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("list rows = %+v", rows)
+	}
+	if rows[0].ISRC != "GBUM71903894" || rows[1].UPC != "886443927087" || rows[2].URL == "" {
+		t.Errorf("list rows = %+v", rows[:3])
+	}
+	if r := rows[3]; r.Artist != "Beatles" || r.Track != "Come Together" || r.Line != 6 {
+		t.Errorf("artist - title row = %+v", r)
+	}
+}
+
+// TestRun 测试 ISRC / UPC 精确查找、模糊匹配、重复链接与未解析的行
+func TestRun(t *testing.T) {
+	paths := []string{
+		filepath.Join("testdata", "spotify.csv"),
+		filepath.Join("testdata", "albums.csv"),
+		filepath.Join("testdata", "list.txt"),
+	}
+	r, err := Run(context.Background(), paths, Options{Catalog: loadCatalog(t), Storefront: "US"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		status, by, link string
+	}{
+		{StatusResolved, ByISRC, "https://music.apple.com/us/song/2002"}, // 同一 ISRC 选择专辑版本
+		{StatusResolved, BySearch, "https://music.apple.com/us/song/2004"},
+		{StatusUnresolved, "", ""},
+		{StatusResolved, ByISRC, "https://music.apple.com/us/song/2002"},
+		{StatusResolved, ByUPC, "https://music.apple.com/us/album/1001"},
+		{StatusResolved, BySearch, "https://music.apple.com/us/album/1002"},
+		{StatusResolved, ByISRC, "https://music.apple.com/us/song/2001"},
+		{StatusResolved, ByUPC, "https://music.apple.com/us/album/1002"},
+		{StatusResolved, ByURL, "https://music.apple.com/us/album/example/1234"},
+		{StatusResolved, BySearch, "https://music.apple.com/us/song/2001"},
+	}
+	if len(r.Items) != len(want) {
+		t.Fatalf("items = %d, want %d", len(r.Items), len(want))
+	}
+	for i, w := range want {
+		got := r.Items[i]
+		if got.Status != w.status || got.By != w.by || got.Link != w.link {
+			t.Errorf("item %d (%s) = %s/%s %s (%.2f, %s), want %s/%s %s", i, got.Row, got.Status, got.By, got.Link, got.Confidence, got.Reason, w.status, w.by, w.link)
+		}
+	}
+	if !r.Items[3].Duplicate || !r.Items[7].Duplicate || !r.Items[9].Duplicate {
+		t.Error("repeated links should be marked as duplicates")
+	}
+	if r.Resolved != 9 || r.Unresolved != 1 || r.Fuzzy != 3 || len(r.Links) != 6 {
+		t.Errorf("report = resolved %d, unresolved %d, fuzzy %d, links %d", r.Resolved, r.Unresolved, r.Fuzzy, len(r.Links))
+	}
+
+	dir := t.TempDir()
+	tasks := filepath.Join(dir, "tasks.txt")
+	if err := WriteTasks(r, tasks); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(tasks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var links []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			links = append(links, line)
+		}
+	}
+	if strings.Join(links, " ") != strings.Join(r.Links, " ") {
+		t.Errorf("task file links = %v, want %v", links, r.Links)
+	}
+
+	text := r.Text()
+	if !strings.Contains(text, "Totally Unknown Song") || !strings.Contains(text, "spotify.csv:4") {
+		t.Errorf("report should list the unresolved row:\n%s", text)
+	}
+}
+
+// TestScore 测试模糊匹配的置信度
+func TestScore(t *testing.T) {
+	row := Row{Artist: "Daft Punk, Julian Casablancas", Track: "Instant Crush"}
+	if s := score(row, Candidate{Name: "Instant Crush", Artist: "Daft Punk & Julian Casablancas"}); s < 0.95 {
+		t.Errorf("same song with different artist separators: %.2f", s)
+	}
+	if s := score(row, Candidate{Name: "Instant Crush (Live)", Artist: "Daft Punk"}); s < 0.8 || s >= 1 {
+		t.Errorf("live version: %.2f", s)
+	}
+	if s := score(row, Candidate{Name: "Get Lucky", Artist: "Daft Punk"}); s >= DefaultMinConfidence {
+		t.Errorf("different song should be below the threshold: %.2f", s)
+	}
+	if s := score(Row{Track: "Instant Crush"}, Candidate{Name: "Instant Crush", Artist: "Daft Punk"}); s < 0.89 || s > 0.91 {
+		t.Errorf("title-only match = %.2f, want 0.9", s)
+	}
+
+	defer func(exceptions []string) { core.Config.ArtistExceptions = exceptions }(core.Config.ArtistExceptions)
+	core.Config.ArtistExceptions = []string{"Earth, Wind & Fire"}
+	if s := score(Row{Artist: "Earth, Wind & Fire", Track: "September"}, Candidate{Name: "September", Artist: "Earth"}); s >= 0.9 {
+		t.Errorf("artist exception must not be split into its first name: %.2f", s)
+	}
+	if s := score(Row{Artist: "Simon & Garfunkel", Track: "The Boxer"}, Candidate{Name: "The Boxer", Artist: "Simon & Garfunkel"}); s < 0.99 {
+		t.Errorf("same duo: %.2f", s)
+	}
+}
@@ -0,0 +1,139 @@
+package importer
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"main/internal/core"
+)
+
+var (
+	// versionSuffix 曲名或专辑名末尾的版本说明（如 " - Remastered 2011"、" - Live"）
+	versionSuffix = regexp.MustCompile(`(?i)\s+-\s+.*\b(remaster(ed)?|version|live|edit|mix|mono|stereo|deluxe|edition|single|ep)\b.*$`)
+	bracketed     = regexp.MustCompile(`\([^)]*\)|\[[^\]]*\]`)
+	featuring     = regexp.MustCompile(`(?i)\s+(feat\.?|ft\.?|featuring)\s+.*$`)
+)
+
+// score 候选与导入行的匹配程度（0-1）：曲目按曲名、艺术家与专辑名，专辑按专辑名与艺术家加权
+// 没有艺术家时只按名称匹配，置信度打九折
+func score(row Row, c Candidate) float64 {
+	var total, weight float64
+	add := func(w, v float64) {
+		total += w * v
+		weight += w
+	}
+	if row.IsTrack() {
+		add(0.6, similarity(row.Track, c.Name))
+		if row.Album != "" && c.Album != "" {
+			add(0.1, similarity(row.Album, c.Album))
+		}
+	} else {
+		add(0.65, similarity(row.Album, c.Name))
+	}
+	if row.Artist == "" {
+		return 0.9 * total / weight
+	}
+	add(0.35, artistSimilarity(row.Artist, c.Artist))
+	return total / weight
+}
+
+// similarity 名称相似度；去掉版本说明、括号与 feat. 后相同的名称略低于完全相同
+func similarity(a, b string) float64 {
+	s := ratio(normalize(a), normalize(b))
+	if stripped := 0.95 * ratio(normalize(stripVersion(a)), normalize(stripVersion(b))); stripped > s {
+		s = stripped
+	}
+	return s
+}
+
+// artistSimilarity 艺术家相似度；多位艺术家的写法不同时，第一位艺术家相同视为基本一致
+// 拆分规则与标签写入一致，artist-exceptions 中的名称（如 "Simon & Garfunkel"）不拆分
+func artistSimilarity(a, b string) float64 {
+	s := similarity(a, b)
+	if pa := normalize(firstArtist(a, b)); pa != "" && pa == normalize(firstArtist(b, a)) && s < 0.9 {
+		s = 0.9
+	}
+	return s
+}
+
+// firstArtist 第一位艺术家；另一方的完整名称出现在 name 中时整体保留
+func firstArtist(name, other string) string {
+	if names := core.SplitArtists(name, other); len(names) > 0 {
+		return names[0]
+	}
+	return ""
+}
+
+func stripVersion(s string) string {
+	s = versionSuffix.ReplaceAllString(s, "")
+	s = bracketed.ReplaceAllString(s, "")
+	return featuring.ReplaceAllString(s, "")
+}
+
+// normalize 转为小写，标点与空白合并为单个空格
+func normalize(s string) string {
+	var b strings.Builder
+	space := true
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			space = false
+		} else if !space {
+			b.WriteByte(' ')
+			space = true
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// ratio 两个已规范化字符串的相似度：编辑距离与词重合度（Dice 系数）中较高的一个
+func ratio(a, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	lev := 1 - float64(levenshtein(ra, rb))/float64(max(len(ra), len(rb)))
+	return max(lev, dice(strings.Fields(a), strings.Fields(b)))
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func dice(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	count := make(map[string]int)
+	for _, w := range a {
+		count[w]++
+	}
+	common := 0
+	for _, w := range b {
+		if count[w] > 0 {
+			count[w]--
+			common++
+		}
+	}
+	return 2 * float64(common) / float64(len(a)+len(b))
+}
@@ -0,0 +1,235 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Row 导入文件中的一行
+type Row struct {
+	Source string `json:"source"`
+	Line   int    `json:"line"`
+	Artist string `json:"artist,omitempty"`
+	Album  string `json:"album,omitempty"`
+	Track  string `json:"track,omitempty"`
+	ISRC   string `json:"isrc,omitempty"`
+	UPC    string `json:"upc,omitempty"`
+	URL    string `json:"url,omitempty"` // 已经是 Apple Music 链接时原样写入任务文件
+}
+
+// IsTrack 行是否指向单首曲目（有曲名或 ISRC），否则按专辑查找
+func (r Row) IsTrack() bool {
+	return r.Track != "" || r.ISRC != ""
+}
+
+// String 行的可读描述（艺术家 - 专辑 - 曲名 [ISRC / UPC]）
+func (r Row) String() string {
+	var parts []string
+	for _, s := range []string{r.Artist, r.Album, r.Track} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	s := strings.Join(parts, " - ")
+	for _, id := range []struct{ name, value string }{{"ISRC", r.ISRC}, {"UPC", r.UPC}} {
+		if id.value != "" {
+			s = strings.TrimSpace(fmt.Sprintf("%s [%s %s]", s, id.name, id.value))
+		}
+	}
+	return s
+}
+
+// 列
+const (
+	colArtist = iota
+	colAlbumArtist
+	colAlbum
+	colTrack
+	colISRC
+	colUPC
+	colURL
+)
+
+// headerNames 表头（小写、只保留字母与数字）对应的列，覆盖常见的 Spotify（Exportify）、Last.fm 与通用 CSV 导出
+var headerNames = map[string]int{
+	"artist": colArtist, "artists": colArtist, "artistname": colArtist, "artistnames": colArtist, "performer": colArtist, "trackartist": colArtist,
+	"albumartist": colAlbumArtist, "albumartistname": colAlbumArtist, "albumartistnames": colAlbumArtist,
+	"album": colAlbum, "albumname": colAlbum, "albumtitle": colAlbum, "release": colAlbum, "releasename": colAlbum, "releasetitle": colAlbum,
+	"track": colTrack, "trackname": colTrack, "tracktitle": colTrack, "title": colTrack, "song": colTrack, "songname": colTrack, "songtitle": colTrack, "name": colTrack,
+	"isrc": colISRC, "trackisrc": colISRC,
+	"upc": colUPC, "ean": colUPC, "barcode": colUPC, "albumupc": colUPC,
+	"url": colURL, "link": colURL, "applemusicurl": colURL, "applemusiclink": colURL,
+}
+
+var (
+	isrcPattern = regexp.MustCompile(`^[A-Z]{2}[A-Z0-9]{3}\d{7}$`)
+	upcPattern  = regexp.MustCompile(`^\d{12,14}$`)
+)
+
+// ReadRows 读取导入文件：带表头的 CSV / TSV 按表头识别列；
+// 没有表头时，单列按内容识别（Apple Music 链接、ISRC、UPC 或 "艺术家 - 曲名"），
+// 两列为艺术家、曲名，三列及以上为艺术家、专辑、曲名（Last.fm 导出的顺序）。以 # 开头的行为注释
+func ReadRows(path string) ([]Row, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = delimiter(path, data)
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	r.TrimLeadingSpace = true
+
+	var rows []Row
+	var columns map[int]int // key: 列序号，value: 列
+	first := true
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i := range record {
+			record[i] = strings.TrimSpace(record[i])
+		}
+		if strings.Join(record, "") == "" {
+			continue
+		}
+		line, _ := r.FieldPos(0)
+		if first {
+			first = false
+			if columns = header(record); columns != nil {
+				continue
+			}
+		}
+		row := Row{Source: path, Line: line}
+		if columns != nil {
+			row.fill(record, columns)
+		} else {
+			row.fillPositional(record)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// delimiter 按扩展名与第一行判断分隔符（逗号、制表符或分号）
+func delimiter(path string, data []byte) rune {
+	if strings.EqualFold(filepath.Ext(path), ".tsv") {
+		return '\t'
+	}
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+	comma := rune(',')
+	n := bytes.Count(line, []byte(","))
+	for _, d := range []rune{'\t', ';'} {
+		if c := bytes.Count(line, []byte(string(d))); c > n {
+			comma, n = d, c
+		}
+	}
+	return comma
+}
+
+// header 识别表头，没有可识别的列时返回 nil
+func header(record []string) map[int]int {
+	columns := make(map[int]int)
+	for i, name := range record {
+		if col, ok := headerNames[headerKey(name)]; ok {
+			columns[i] = col
+		}
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+	return columns
+}
+
+func headerKey(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// fill 按表头填充；没有艺术家列时使用专辑艺术家，非 Apple Music 链接（如 Spotify URI）忽略
+func (row *Row) fill(record []string, columns map[int]int) {
+	var albumArtist string
+	for i, value := range record {
+		col, ok := columns[i]
+		if !ok || value == "" {
+			continue
+		}
+		switch col {
+		case colArtist:
+			setOnce(&row.Artist, value)
+		case colAlbumArtist:
+			setOnce(&albumArtist, value)
+		case colAlbum:
+			setOnce(&row.Album, value)
+		case colTrack:
+			setOnce(&row.Track, value)
+		case colISRC:
+			setOnce(&row.ISRC, strings.ToUpper(value))
+		case colUPC:
+			setOnce(&row.UPC, value)
+		case colURL:
+			if isAppleMusicURL(value) {
+				setOnce(&row.URL, value)
+			}
+		}
+	}
+	if row.Artist == "" {
+		row.Artist = albumArtist
+	}
+}
+
+// fillPositional 没有表头时按列数与内容填充
+func (row *Row) fillPositional(record []string) {
+	switch len(record) {
+	case 1:
+		value := record[0]
+		switch {
+		case isAppleMusicURL(value):
+			row.URL = value
+		case isrcPattern.MatchString(strings.ToUpper(value)):
+			row.ISRC = strings.ToUpper(value)
+		case upcPattern.MatchString(value):
+			row.UPC = value
+		default:
+			if artist, track, ok := strings.Cut(value, " - "); ok {
+				row.Artist, row.Track = strings.TrimSpace(artist), strings.TrimSpace(track)
+			} else {
+				row.Track = value
+			}
+		}
+	case 2:
+		row.Artist, row.Track = record[0], record[1]
+	default:
+		row.Artist, row.Album, row.Track = record[0], record[1], record[2]
+	}
+}
+
+func setOnce(dst *string, value string) {
+	if *dst == "" {
+		*dst = value
+	}
+}
+
+func isAppleMusicURL(s string) bool {
+	return strings.HasPrefix(s, "https://") && strings.Contains(s, "music.apple.com/")
+}
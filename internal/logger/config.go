@@ -4,23 +4,49 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"time"
 )
 
 // Config 日志配置
 type Config struct {
-	Level         string `yaml:"level"`          // 日志等级: debug/info/warn/error
-	Output        string `yaml:"output"`         // 输出目标: stdout/stderr/文件路径
-	ShowTimestamp bool   `yaml:"show_timestamp"` // 是否显示时间戳
+	Level         string     `yaml:"level"`          // 控制台日志等级: debug/info/warn/error
+	Output        string     `yaml:"output"`         // 输出目标: stdout/stderr/文件路径
+	Format        string     `yaml:"format"`         // 控制台日志格式: text/json
+	ShowTimestamp bool       `yaml:"show_timestamp"` // 是否显示时间戳
+	ShowFields    bool       `yaml:"show_fields"`    // 控制台文本格式是否显示结构化字段
+	File          FileConfig `yaml:"file"`           // 独立的日志文件输出
 }
 
+// FileConfig 日志文件输出配置，与控制台输出的等级和格式互相独立
+type FileConfig struct {
+	Path        string `yaml:"path"`         // 日志文件路径，为空时不写文件
+	Level       string `yaml:"level"`        // 文件日志等级，默认 debug
+	Format      string `yaml:"format"`       // 文件日志格式: json/text，默认 json
+	MaxSizeMB   int    `yaml:"max_size_mb"`  // 单个文件最大大小（MB），0 表示不按大小轮转
+	RotateEvery string `yaml:"rotate_every"` // 按时间轮转周期（如 24h），为空表示不按时间轮转
+	MaxBackups  int    `yaml:"max_backups"`  // 保留的备份文件数，0 表示不限制
+	MaxAgeDays  int    `yaml:"max_age_days"` // 备份保留天数，0 表示不限制
+}
+
+// 由配置打开的日志文件，程序退出前通过 Close 关闭
+var openedFiles []io.Closer
+
 // InitFromConfig 从配置初始化全局logger
 func InitFromConfig(cfg Config) error {
+	Close()
+	global.ClearSinks()
+
 	// 解析日志等级
 	level := ParseLevel(cfg.Level)
 	SetLevel(level)
 
-	// 设置时间戳显示
+	// 设置时间戳显示与格式
 	SetShowTime(cfg.ShowTimestamp)
+	SetFormat(ParseFormat(cfg.Format))
+	global.mu.Lock()
+	global.console.ShowFields = cfg.ShowFields
+	global.console.PlainText = false
+	global.mu.Unlock()
 
 	// 设置输出目标
 	var output io.Writer
@@ -30,24 +56,86 @@ func InitFromConfig(cfg Config) error {
 	case "stderr":
 		output = os.Stderr
 	default:
-		// 尝试打开文件
-		file, err := os.OpenFile(cfg.Output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		// 输出到文件时按文件配置轮转，并去掉颜色码
+		file, err := OpenRotatingFile(cfg.Output, rotateOptions(cfg.File))
 		if err != nil {
 			return fmt.Errorf("failed to open log file %s: %w", cfg.Output, err)
 		}
+		openedFiles = append(openedFiles, file)
 		output = file
-		// 注意：文件不会自动关闭，需要在程序退出时处理
+		global.mu.Lock()
+		global.console.PlainText = true
+		global.mu.Unlock()
 	}
 	SetOutput(output)
 
+	// 独立的日志文件输出
+	if cfg.File.Path != "" {
+		file, err := OpenRotatingFile(cfg.File.Path, rotateOptions(cfg.File))
+		if err != nil {
+			return fmt.Errorf("failed to open log file %s: %w", cfg.File.Path, err)
+		}
+		openedFiles = append(openedFiles, file)
+		fileLevel := DEBUG
+		if cfg.File.Level != "" {
+			fileLevel = ParseLevel(cfg.File.Level)
+		}
+		fileFormat := FormatJSON
+		if cfg.File.Format != "" {
+			fileFormat = ParseFormat(cfg.File.Format)
+		}
+		AddSink(Sink{
+			Writer:     file,
+			Level:      fileLevel,
+			Format:     fileFormat,
+			ShowTime:   true,
+			ShowFields: true,
+			PlainText:  true,
+			TimeFormat: "2006-01-02 15:04:05",
+		})
+	}
+
 	return nil
 }
 
+// rotateOptions 将文件配置转换为轮转参数（周期格式错误时不按时间轮转，由配置验证提示）
+func rotateOptions(cfg FileConfig) RotateOptions {
+	opts := RotateOptions{
+		MaxSizeMB:  cfg.MaxSizeMB,
+		MaxBackups: cfg.MaxBackups,
+		MaxAgeDays: cfg.MaxAgeDays,
+	}
+	if cfg.RotateEvery != "" {
+		if d, err := time.ParseDuration(cfg.RotateEvery); err == nil && d > 0 {
+			opts.Interval = d
+		}
+	}
+	return opts
+}
+
+// Close 关闭由配置打开的日志文件，程序退出前调用
+func Close() error {
+	global.ClearSinks()
+	var firstErr error
+	for _, f := range openedFiles {
+		// 控制台输出指向该文件时恢复为标准输出，避免写入已关闭的文件
+		if w, ok := f.(io.Writer); ok && Output() == w {
+			SetOutput(os.Stdout)
+		}
+		if err := f.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	openedFiles = nil
+	return firstErr
+}
+
 // DefaultConfig 返回默认配置
 func DefaultConfig() Config {
 	return Config{
 		Level:         "info",
 		Output:        "stdout",
+		Format:        "text",
 		ShowTimestamp: false,
 	}
 }
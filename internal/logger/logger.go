@@ -40,84 +40,131 @@ type Logger interface {
 }
 
 // DefaultLogger 默认日志实现
+// 每条日志会写入控制台输出和所有附加输出（如日志文件），各输出有独立的等级和格式
 type DefaultLogger struct {
-	mu       sync.Mutex
-	level    LogLevel
-	output   io.Writer
-	showTime bool
+	mu      sync.Mutex
+	console Sink   // 控制台输出，SetOutput/SetLevel/SetShowTime 作用于此
+	extra   []Sink // 附加输出，通过 AddSink 添加
 }
 
 // New 创建一个新的日志记录器
 func New() *DefaultLogger {
 	return &DefaultLogger{
-		level:    INFO,
-		output:   os.Stdout,
-		showTime: false, // UI模式下默认不显示时间戳
+		console: Sink{
+			Writer:   os.Stdout,
+			Level:    INFO,
+			ShowTime: false, // UI模式下默认不显示时间戳
+		},
 	}
 }
 
 // log 内部日志方法（带锁保护）
-func (l *DefaultLogger) log(level LogLevel, format string, args ...interface{}) {
-	// 日志等级过滤
-	if level < l.level {
-		return
-	}
-
+func (l *DefaultLogger) log(level LogLevel, fields []Field, format string, args ...interface{}) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	// 构建前缀
-	var prefix string
-	if l.showTime {
-		prefix = fmt.Sprintf("[%s] %s: ",
-			time.Now().Format("15:04:05"),
-			levelNames[level])
+	// 日志等级过滤：没有任何输出需要该等级时不做格式化
+	if !l.enabled(level) {
+		return
 	}
 
-	// 格式化并输出
-	message := fmt.Sprintf(format, args...)
-	fmt.Fprintf(l.output, "%s%s\n", prefix, message)
+	rec := Record{
+		Time:    time.Now(),
+		Level:   level,
+		Message: fmt.Sprintf(format, args...),
+		Fields:  fields,
+	}
+	l.console.write(rec)
+	for i := range l.extra {
+		l.extra[i].write(rec)
+	}
+}
+
+// enabled 判断是否有输出接受该等级（调用方需持有锁）
+func (l *DefaultLogger) enabled(level LogLevel) bool {
+	if l.console.accepts(level) {
+		return true
+	}
+	for i := range l.extra {
+		if l.extra[i].accepts(level) {
+			return true
+		}
+	}
+	return false
 }
 
 // Debug 输出DEBUG级别日志
 func (l *DefaultLogger) Debug(format string, args ...interface{}) {
-	l.log(DEBUG, format, args...)
+	l.log(DEBUG, nil, format, args...)
 }
 
 // Info 输出INFO级别日志
 func (l *DefaultLogger) Info(format string, args ...interface{}) {
-	l.log(INFO, format, args...)
+	l.log(INFO, nil, format, args...)
 }
 
 // Warn 输出WARN级别日志
 func (l *DefaultLogger) Warn(format string, args ...interface{}) {
-	l.log(WARN, format, args...)
+	l.log(WARN, nil, format, args...)
 }
 
 // Error 输出ERROR级别日志
 func (l *DefaultLogger) Error(format string, args ...interface{}) {
-	l.log(ERROR, format, args...)
+	l.log(ERROR, nil, format, args...)
 }
 
-// SetLevel 设置日志等级
+// SetLevel 设置控制台日志等级
 func (l *DefaultLogger) SetLevel(level LogLevel) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	l.level = level
+	l.console.Level = level
 }
 
-// SetOutput 设置输出目标
+// SetOutput 设置控制台输出目标
 func (l *DefaultLogger) SetOutput(w io.Writer) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	l.output = w
+	l.console.Writer = w
+}
+
+// Output 返回当前控制台输出目标
+func (l *DefaultLogger) Output() io.Writer {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.console.Writer
 }
 
-// SetShowTime 设置是否显示时间戳
+// SetShowTime 设置控制台是否显示时间戳
 func (l *DefaultLogger) SetShowTime(show bool) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	l.showTime = show
+	l.console.ShowTime = show
+}
+
+// SetFormat 设置控制台日志格式
+func (l *DefaultLogger) SetFormat(format Format) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.console.Format = format
+}
+
+// AddSink 添加附加输出（如日志文件）
+func (l *DefaultLogger) AddSink(s Sink) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.extra = append(l.extra, s)
+}
+
+// ClearSinks 移除所有附加输出
+func (l *DefaultLogger) ClearSinks() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.extra = nil
+}
+
+// With 返回附带结构化字段的日志记录器，参数为交替的键和值
+func (l *DefaultLogger) With(kv ...interface{}) *Entry {
+	return &Entry{logger: l, fields: makeFields(kv)}
 }
 
 // 全局logger实例
@@ -153,11 +200,32 @@ func SetOutput(w io.Writer) {
 	global.SetOutput(w)
 }
 
+// Output 返回全局输出目标
+func Output() io.Writer {
+	return global.Output()
+}
+
 // SetShowTime 设置全局是否显示时间戳
 func SetShowTime(show bool) {
 	global.SetShowTime(show)
 }
 
+// SetFormat 设置全局控制台日志格式
+func SetFormat(format Format) {
+	global.SetFormat(format)
+}
+
+// AddSink 为全局logger添加附加输出
+func AddSink(s Sink) {
+	global.AddSink(s)
+}
+
+// With 返回附带结构化字段的全局日志记录器
+// 例如 logger.With("album_id", id, "stage", "download").Warn("下载失败: %v", err)
+func With(kv ...interface{}) *Entry {
+	return global.With(kv...)
+}
+
 // ParseLevel 从字符串解析日志等级
 func ParseLevel(s string) LogLevel {
 	switch s {
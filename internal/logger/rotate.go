@@ -0,0 +1,203 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeLayout 轮转备份文件名中的时间格式
+const backupTimeLayout = "20060102-150405"
+
+// RotateOptions 日志文件轮转参数，零值表示不启用对应规则
+type RotateOptions struct {
+	MaxSizeMB  int           // 单个文件最大大小（MB），超过后轮转
+	Interval   time.Duration // 按时间轮转的周期（如 24h），按本地时间对齐
+	MaxBackups int           // 保留的备份文件数量
+	MaxAgeDays int           // 备份文件保留天数
+}
+
+// RotatingFile 支持按大小和时间轮转的日志文件，实现 io.WriteCloser
+// 轮转时当前文件重命名为 名称-时间.扩展名，再按数量和天数清理旧备份
+type RotatingFile struct {
+	mu          sync.Mutex
+	path        string
+	opts        RotateOptions
+	file        *os.File
+	size        int64
+	periodStart time.Time
+	now         func() time.Time
+}
+
+// OpenRotatingFile 打开（或创建）日志文件
+// 已有文件属于更早的时间周期时会先轮转，保证每个周期一个文件
+func OpenRotatingFile(path string, opts RotateOptions) (*RotatingFile, error) {
+	r := &RotatingFile{path: path, opts: opts, now: time.Now}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("创建日志目录失败: %w", err)
+		}
+	}
+	if info, err := os.Stat(path); err == nil && opts.Interval > 0 && info.Size() > 0 {
+		if r.periodOf(info.ModTime()).Before(r.periodOf(r.now())) {
+			if err := r.backup(info.ModTime()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Write 写入日志，必要时先轮转
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+	if r.shouldRotate(len(p)) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close 关闭日志文件
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// open 以追加方式打开当前文件
+func (r *RotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("打开日志文件 %s 失败: %w", r.path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("读取日志文件 %s 失败: %w", r.path, err)
+	}
+	r.file = file
+	r.size = info.Size()
+	r.periodStart = r.periodOf(r.now())
+	return nil
+}
+
+// shouldRotate 判断写入 n 字节前是否需要轮转
+func (r *RotatingFile) shouldRotate(n int) bool {
+	if r.size == 0 {
+		return false
+	}
+	if r.opts.MaxSizeMB > 0 && r.size+int64(n) > int64(r.opts.MaxSizeMB)*1024*1024 {
+		return true
+	}
+	return r.opts.Interval > 0 && r.periodOf(r.now()).After(r.periodStart)
+}
+
+// rotate 关闭当前文件、重命名为备份并打开新文件
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return fmt.Errorf("关闭日志文件失败: %w", err)
+	}
+	r.file = nil
+	if err := r.backup(r.now()); err != nil {
+		return err
+	}
+	return r.open()
+}
+
+// backup 将当前文件重命名为带时间的备份文件并清理旧备份
+func (r *RotatingFile) backup(t time.Time) error {
+	ext := filepath.Ext(r.path)
+	base := strings.TrimSuffix(r.path, ext)
+	name := fmt.Sprintf("%s-%s%s", base, t.Format(backupTimeLayout), ext)
+	// 同一秒内多次轮转时追加序号，避免覆盖
+	for i := 1; ; i++ {
+		if _, err := os.Stat(name); os.IsNotExist(err) {
+			break
+		}
+		name = fmt.Sprintf("%s-%s.%d%s", base, t.Format(backupTimeLayout), i, ext)
+	}
+	if err := os.Rename(r.path, name); err != nil {
+		return fmt.Errorf("轮转日志文件失败: %w", err)
+	}
+	r.prune()
+	return nil
+}
+
+// prune 按数量和天数删除多余的备份文件
+func (r *RotatingFile) prune() {
+	if r.opts.MaxBackups <= 0 && r.opts.MaxAgeDays <= 0 {
+		return
+	}
+	backups := r.backups()
+	// 按修改时间从新到旧
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].ModTime().After(backups[j].ModTime())
+	})
+	cutoff := r.now().AddDate(0, 0, -r.opts.MaxAgeDays)
+	dir := filepath.Dir(r.path)
+	for i, info := range backups {
+		expired := r.opts.MaxAgeDays > 0 && info.ModTime().Before(cutoff)
+		if (r.opts.MaxBackups > 0 && i >= r.opts.MaxBackups) || expired {
+			os.Remove(filepath.Join(dir, info.Name()))
+		}
+	}
+}
+
+// backups 列出当前日志文件的所有备份
+func (r *RotatingFile) backups() []os.FileInfo {
+	ext := filepath.Ext(r.path)
+	prefix := strings.TrimSuffix(filepath.Base(r.path), ext) + "-"
+	entries, err := os.ReadDir(filepath.Dir(r.path))
+	if err != nil {
+		return nil
+	}
+	var out []os.FileInfo
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		if len(stamp) < len(backupTimeLayout) {
+			continue
+		}
+		if _, err := time.ParseInLocation(backupTimeLayout, stamp[:len(backupTimeLayout)], time.Local); err != nil {
+			continue
+		}
+		if info, err := e.Info(); err == nil {
+			out = append(out, info)
+		}
+	}
+	return out
+}
+
+// periodOf 返回 t 所在轮转周期的起点（按本地时间对齐，如每天零点）
+func (r *RotatingFile) periodOf(t time.Time) time.Time {
+	if r.opts.Interval <= 0 {
+		return time.Time{}
+	}
+	_, offset := t.Zone()
+	shift := time.Duration(offset) * time.Second
+	return t.Add(shift).Truncate(r.opts.Interval).Add(-shift)
+}
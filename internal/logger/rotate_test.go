@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRotatingFileSize 测试按大小轮转与备份数量限制
+func TestRotatingFileSize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	r, err := OpenRotatingFile(path, RotateOptions{MaxSizeMB: 1, MaxBackups: 2})
+	if err != nil {
+		t.Fatalf("OpenRotatingFile failed: %v", err)
+	}
+	defer r.Close()
+
+	// 每次轮转使用不同的时间，避免备份文件名相同
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.Local)
+	r.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	chunk := []byte(strings.Repeat("x", 600*1024))
+	for i := 0; i < 5; i++ {
+		if _, err := r.Write(chunk); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	if got := len(r.backups()); got != 2 {
+		t.Errorf("Expected 2 backups, got %d", got)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Current log file missing: %v", err)
+	}
+	if info.Size() != int64(len(chunk)) {
+		t.Errorf("Current log file size = %d, want %d", info.Size(), len(chunk))
+	}
+}
+
+// TestRotatingFileInterval 测试按时间周期轮转
+func TestRotatingFileInterval(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	now := time.Date(2025, 1, 1, 23, 59, 0, 0, time.Local)
+
+	r := &RotatingFile{path: path, opts: RotateOptions{Interval: 24 * time.Hour}, now: func() time.Time { return now }}
+	if err := r.open(); err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	defer r.Close()
+
+	r.Write([]byte("day one\n"))
+	r.Write([]byte("still day one\n"))
+	if len(r.backups()) != 0 {
+		t.Fatal("Should not rotate within the same period")
+	}
+
+	now = now.Add(2 * time.Minute)
+	r.Write([]byte("day two\n"))
+	backups := r.backups()
+	if len(backups) != 1 {
+		t.Fatalf("Expected 1 backup after period change, got %d", len(backups))
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "day two\n" {
+		t.Errorf("Current file = %q, want only new period content", data)
+	}
+}
+
+// TestRotatingFileOpenOldPeriod 测试打开上个周期留下的日志文件时先轮转
+func TestRotatingFileOpenOldPeriod(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	os.Chtimes(path, old, old)
+
+	r, err := OpenRotatingFile(path, RotateOptions{Interval: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("OpenRotatingFile failed: %v", err)
+	}
+	defer r.Close()
+
+	if len(r.backups()) != 1 {
+		t.Error("Old period file should be rotated on open")
+	}
+}
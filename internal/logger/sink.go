@@ -0,0 +1,185 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Format 日志输出格式
+type Format int
+
+const (
+	FormatText Format = iota // 人类可读的文本格式
+	FormatJSON               // 每行一个JSON对象，便于日志系统采集
+)
+
+// ParseFormat 从字符串解析日志格式，无法识别时返回 FormatText
+func ParseFormat(s string) Format {
+	switch strings.ToLower(s) {
+	case "json":
+		return FormatJSON
+	default:
+		return FormatText
+	}
+}
+
+// Field 结构化日志字段
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Record 一条日志记录
+type Record struct {
+	Time    time.Time
+	Level   LogLevel
+	Message string
+	Fields  []Field
+}
+
+// Sink 日志输出目标，每个输出有独立的等级、格式和时间戳设置
+type Sink struct {
+	Writer     io.Writer
+	Level      LogLevel
+	Format     Format
+	ShowTime   bool   // 文本格式是否显示时间戳和等级前缀
+	ShowFields bool   // 文本格式是否在消息后附加 key=value 字段
+	PlainText  bool   // 去掉消息中的终端颜色码（写入文件时使用）
+	TimeFormat string // 文本格式时间戳格式，为空时使用 15:04:05
+}
+
+// ansiRegex 匹配终端颜色码等控制序列
+var ansiRegex = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+// accepts 判断该输出是否接受指定等级
+func (s *Sink) accepts(level LogLevel) bool {
+	return s.Writer != nil && level >= s.Level
+}
+
+// write 按输出格式写入一条记录（调用方需持有logger锁）
+func (s *Sink) write(rec Record) {
+	if !s.accepts(rec.Level) {
+		return
+	}
+	msg := rec.Message
+	if s.PlainText || s.Format == FormatJSON {
+		msg = ansiRegex.ReplaceAllString(msg, "")
+	}
+	if s.Format == FormatJSON {
+		s.Writer.Write(encodeJSON(rec, msg))
+		return
+	}
+	s.Writer.Write([]byte(s.encodeText(rec, msg)))
+}
+
+// encodeText 文本格式：[时间] 等级: 消息 key=value ...
+func (s *Sink) encodeText(rec Record, msg string) string {
+	var b strings.Builder
+	if s.ShowTime {
+		layout := s.TimeFormat
+		if layout == "" {
+			layout = "15:04:05"
+		}
+		fmt.Fprintf(&b, "[%s] %s: ", rec.Time.Format(layout), rec.Level)
+	}
+	b.WriteString(msg)
+	if s.ShowFields {
+		for _, f := range rec.Fields {
+			fmt.Fprintf(&b, " %s=%s", f.Key, quoteValue(f.Value))
+		}
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
+
+// quoteValue 字段值含空格或引号时加引号
+func quoteValue(v interface{}) string {
+	s := fmt.Sprint(v)
+	if s == "" || strings.ContainsAny(s, " \t\"=") {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
+}
+
+// encodeJSON JSON格式：固定字段 time/level/msg，结构化字段平铺在同一层
+func encodeJSON(rec Record, msg string) []byte {
+	var b strings.Builder
+	b.WriteString(`{"time":`)
+	writeJSONValue(&b, rec.Time.Format(time.RFC3339Nano))
+	b.WriteString(`,"level":`)
+	writeJSONValue(&b, strings.ToLower(rec.Level.String()))
+	b.WriteString(`,"msg":`)
+	writeJSONValue(&b, msg)
+	for _, f := range rec.Fields {
+		b.WriteByte(',')
+		writeJSONValue(&b, f.Key)
+		b.WriteByte(':')
+		value := f.Value
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		writeJSONValue(&b, value)
+	}
+	b.WriteString("}\n")
+	return []byte(b.String())
+}
+
+// writeJSONValue 写入JSON值，无法编码时按字符串输出
+func writeJSONValue(b *strings.Builder, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(v))
+	}
+	b.Write(data)
+}
+
+// makeFields 将交替的键值参数转换为字段列表，末尾多余的键值为 nil
+func makeFields(kv []interface{}) []Field {
+	fields := make([]Field, 0, (len(kv)+1)/2)
+	for i := 0; i < len(kv); i += 2 {
+		f := Field{Key: fmt.Sprint(kv[i])}
+		if i+1 < len(kv) {
+			f.Value = kv[i+1]
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// Entry 附带结构化字段的日志记录器
+type Entry struct {
+	logger *DefaultLogger
+	fields []Field
+}
+
+// With 返回追加了字段的新Entry，原Entry不变
+func (e *Entry) With(kv ...interface{}) *Entry {
+	fields := make([]Field, 0, len(e.fields)+(len(kv)+1)/2)
+	fields = append(fields, e.fields...)
+	fields = append(fields, makeFields(kv)...)
+	return &Entry{logger: e.logger, fields: fields}
+}
+
+// Debug 输出带字段的DEBUG级别日志
+func (e *Entry) Debug(format string, args ...interface{}) {
+	e.logger.log(DEBUG, e.fields, format, args...)
+}
+
+// Info 输出带字段的INFO级别日志
+func (e *Entry) Info(format string, args ...interface{}) {
+	e.logger.log(INFO, e.fields, format, args...)
+}
+
+// Warn 输出带字段的WARN级别日志
+func (e *Entry) Warn(format string, args ...interface{}) {
+	e.logger.log(WARN, e.fields, format, args...)
+}
+
+// Error 输出带字段的ERROR级别日志
+func (e *Entry) Error(format string, args ...interface{}) {
+	e.logger.log(ERROR, e.fields, format, args...)
+}
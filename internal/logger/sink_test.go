@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestLoggerWithFields 测试结构化字段在文本格式中的输出
+func TestLoggerWithFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New()
+	logger.SetOutput(buf)
+
+	entry := logger.With("album_id", "1440", "stage", "download")
+	entry.With("track_id", "99").Info("track failed")
+	entry.Info("album done")
+
+	output := buf.String()
+	if strings.Contains(output, "album_id=") {
+		t.Error("Console should hide fields unless ShowFields is set")
+	}
+
+	buf.Reset()
+	logger.console.ShowFields = true
+	entry.With("track_id", "99", "account", "cn main").Info("track failed")
+	output = buf.String()
+	for _, want := range []string{"album_id=1440", "stage=download", "track_id=99", `account="cn main"`} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got %q", want, output)
+		}
+	}
+}
+
+// TestLoggerJSONFormat 测试JSON格式输出
+func TestLoggerJSONFormat(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New()
+	logger.SetOutput(buf)
+	logger.SetFormat(FormatJSON)
+
+	logger.With("album_id", "1440", "attempt", 2).Warn("\033[31mretry\033[0m %s", "now")
+
+	var rec map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("Output is not valid JSON: %v (%q)", err, buf.String())
+	}
+	if rec["level"] != "warn" || rec["msg"] != "retry now" {
+		t.Errorf("Unexpected level/msg: %v", rec)
+	}
+	if rec["album_id"] != "1440" || rec["attempt"] != float64(2) {
+		t.Errorf("Unexpected fields: %v", rec)
+	}
+	if _, ok := rec["time"]; !ok {
+		t.Error("JSON record should contain time")
+	}
+}
+
+// TestLoggerSinks 测试控制台与附加输出使用独立的等级和格式
+func TestLoggerSinks(t *testing.T) {
+	console := &bytes.Buffer{}
+	file := &bytes.Buffer{}
+	logger := New()
+	logger.SetOutput(console)
+	logger.SetLevel(WARN)
+	logger.AddSink(Sink{Writer: file, Level: DEBUG, Format: FormatJSON})
+
+	logger.Debug("debug msg")
+	logger.Warn("warn msg")
+
+	if strings.Contains(console.String(), "debug msg") {
+		t.Error("Console should filter DEBUG")
+	}
+	if !strings.Contains(console.String(), "warn msg") {
+		t.Error("Console should receive WARN")
+	}
+	lines := strings.Split(strings.TrimSpace(file.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("File sink should receive 2 records, got %d", len(lines))
+	}
+
+	logger.ClearSinks()
+	logger.Error("after clear")
+	if strings.Contains(file.String(), "after clear") {
+		t.Error("Cleared sink should not receive logs")
+	}
+}
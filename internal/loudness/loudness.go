@@ -0,0 +1,186 @@
+package loudness
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ReferenceLoudness ReplayGain 2.0 的参考响度（LUFS）
+const ReferenceLoudness = -18.0
+
+// silenceLoudness 低于该响度视为静音，不参与专辑响度计算
+const silenceLoudness = -70.0
+
+// Result 单个曲目的响度分析结果
+type Result struct {
+	Loudness float64       // 综合响度（LUFS）
+	Peak     float64       // 真峰值（线性幅度，1.0 为满刻度）
+	Duration time.Duration // 时长，用于专辑响度加权
+}
+
+// Gain 写入标签的增益与峰值
+type Gain struct {
+	TrackGain float64 // dB
+	TrackPeak float64
+	AlbumGain float64 // dB
+	AlbumPeak float64
+}
+
+var (
+	durationRe = regexp.MustCompile(`Duration:\s*(\d+):(\d+):(\d+(?:\.\d+)?)`)
+	loudnessRe = regexp.MustCompile(`^\s*I:\s*(-?[\d.]+|-inf)\s*LUFS`)
+	peakRe     = regexp.MustCompile(`^\s*Peak:\s*(-?[\d.]+|-inf)\s*dBFS`)
+)
+
+// Analyze 使用 ffmpeg 的 ebur128 滤镜（EBU R128）分析曲目的综合响度与真峰值
+func Analyze(ctx context.Context, path string) (Result, error) {
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-nostdin", "-hide_banner", "-nostats",
+		"-i", path, "-map", "0:a:0", "-af", "ebur128=peak=true", "-f", "null", "-")
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return Result{}, err
+	}
+	if err := cmd.Start(); err != nil {
+		return Result{}, fmt.Errorf("启动 ffmpeg 失败: %w", err)
+	}
+	// 逐帧日志可能很长，只解析需要的行
+	res, parseErr := parseOutput(stderr)
+	io.Copy(io.Discard, stderr)
+	if err := cmd.Wait(); err != nil {
+		return Result{}, fmt.Errorf("ffmpeg 响度分析失败: %w", err)
+	}
+	return res, parseErr
+}
+
+// parseOutput 解析 ffmpeg 输出中的时长与 ebur128 汇总（Summary 之后的 I 与 Peak）
+func parseOutput(r io.Reader) (Result, error) {
+	var res Result
+	var inSummary, haveLoudness, havePeak bool
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if res.Duration == 0 {
+			if m := durationRe.FindStringSubmatch(line); m != nil {
+				h, _ := strconv.Atoi(m[1])
+				min, _ := strconv.Atoi(m[2])
+				sec, _ := strconv.ParseFloat(m[3], 64)
+				res.Duration = time.Duration((float64(h*3600+min*60) + sec) * float64(time.Second))
+			}
+		}
+		if strings.Contains(line, "Summary:") {
+			inSummary = true
+			continue
+		}
+		if !inSummary {
+			continue
+		}
+		if m := loudnessRe.FindStringSubmatch(line); m != nil {
+			res.Loudness = parseLevel(m[1])
+			haveLoudness = true
+		} else if m := peakRe.FindStringSubmatch(line); m != nil {
+			res.Peak = math.Pow(10, parseLevel(m[1])/20)
+			havePeak = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return res, err
+	}
+	if !haveLoudness || !havePeak {
+		return res, errors.New("ffmpeg 输出中没有 ebur128 汇总信息")
+	}
+	return res, nil
+}
+
+// parseLevel 解析 dB/LUFS 数值，-inf 视为静音
+func parseLevel(s string) float64 {
+	if s == "-inf" {
+		return math.Inf(-1)
+	}
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
+
+// AnalyzeAll 并发分析多个曲目，threads <= 0 时使用 CPU 核数
+// 返回的结果与错误与 paths 一一对应
+func AnalyzeAll(ctx context.Context, paths []string, threads int) ([]Result, []error) {
+	results := make([]Result, len(paths))
+	errs := make([]error, len(paths))
+	if threads <= 0 {
+		threads = runtime.NumCPU()
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, threads)
+	for i, path := range paths {
+		wg.Add(1)
+		go func(i int, path string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i], errs[i] = Analyze(ctx, path)
+		}(i, path)
+	}
+	wg.Wait()
+	return results, errs
+}
+
+// Album 计算专辑响度与峰值：按时长加权的能量平均（近似整张专辑连续播放的综合响度），峰值取最大值
+func Album(results []Result) (loudness, peak float64) {
+	var energy, weight float64
+	for _, r := range results {
+		peak = math.Max(peak, r.Peak)
+		if r.Loudness <= silenceLoudness || math.IsInf(r.Loudness, 0) {
+			continue
+		}
+		w := r.Duration.Seconds()
+		if w <= 0 {
+			w = 1
+		}
+		energy += w * math.Pow(10, r.Loudness/10)
+		weight += w
+	}
+	if weight == 0 {
+		return math.Inf(-1), peak
+	}
+	return 10 * math.Log10(energy/weight), peak
+}
+
+// GainFor 根据曲目与专辑的分析结果计算 ReplayGain 2.0 增益（参考 -18 LUFS）
+func GainFor(track Result, albumLoudness, albumPeak float64) Gain {
+	return Gain{
+		TrackGain: gainOf(track.Loudness),
+		TrackPeak: track.Peak,
+		AlbumGain: gainOf(albumLoudness),
+		AlbumPeak: albumPeak,
+	}
+}
+
+// gainOf 静音曲目不调整增益
+func gainOf(loudness float64) float64 {
+	if loudness <= silenceLoudness || math.IsInf(loudness, 0) {
+		return 0
+	}
+	return ReferenceLoudness - loudness
+}
+
+// Tags 返回 ReplayGain 标签（MP4 freeform 原子 com.apple.iTunes 与 Vorbis comment 使用相同的键名）
+func (g Gain) Tags() map[string]string {
+	return map[string]string{
+		"REPLAYGAIN_TRACK_GAIN": fmt.Sprintf("%.2f dB", g.TrackGain),
+		"REPLAYGAIN_TRACK_PEAK": fmt.Sprintf("%.6f", g.TrackPeak),
+		"REPLAYGAIN_ALBUM_GAIN": fmt.Sprintf("%.2f dB", g.AlbumGain),
+		"REPLAYGAIN_ALBUM_PEAK": fmt.Sprintf("%.6f", g.AlbumPeak),
+	}
+}
@@ -0,0 +1,80 @@
+package loudness
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ffmpegOutput ebur128 滤镜的典型输出（逐帧日志中同样包含 I: 字段，应只解析 Summary 之后的值）
+const ffmpegOutput = `Input #0, mov,mp4,m4a,3gp,3g2,mj2, from 'track.m4a':
+  Duration: 00:03:20.50, start: 0.000000, bitrate: 1411 kb/s
+  Stream #0:0[0x1](und): Audio: alac (alac / 0x63616C61), 44100 Hz, stereo, s16p, 1407 kb/s (default)
+[Parsed_ebur128_0 @ 0x5581] t: 0.4   TARGET:-23 LUFS    M: -30.1 S:-120.7     I: -30.1 LUFS       LRA:   0.0 LU  FTPK: -12.0 -12.1 dBFS  TPK: -12.0 -12.1 dBFS
+[Parsed_ebur128_0 @ 0x5581] Summary:
+
+  Integrated loudness:
+    I:         -12.3 LUFS
+    Threshold: -22.5 LUFS
+
+  Loudness range:
+    LRA:         5.1 LU
+    Threshold: -32.4 LUFS
+    LRA low:   -16.0 LUFS
+    LRA high:  -10.9 LUFS
+
+  True peak:
+    Peak:        0.5 dBFS
+`
+
+// TestParseOutput 测试解析时长、综合响度与真峰值
+func TestParseOutput(t *testing.T) {
+	res, err := parseOutput(strings.NewReader(ffmpegOutput))
+	if err != nil {
+		t.Fatalf("parseOutput failed: %v", err)
+	}
+	if res.Loudness != -12.3 {
+		t.Errorf("Loudness = %v, want -12.3", res.Loudness)
+	}
+	if want := math.Pow(10, 0.5/20); math.Abs(res.Peak-want) > 1e-9 {
+		t.Errorf("Peak = %v, want %v", res.Peak, want)
+	}
+	if res.Duration != 200*time.Second+500*time.Millisecond {
+		t.Errorf("Duration = %v", res.Duration)
+	}
+
+	if _, err := parseOutput(strings.NewReader("Duration: 00:00:01.00\n")); err == nil {
+		t.Error("Expected error without summary")
+	}
+}
+
+// TestAlbumGain 测试专辑响度按时长加权、静音曲目不参与计算
+func TestAlbumGain(t *testing.T) {
+	results := []Result{
+		{Loudness: -10, Peak: 0.9, Duration: 3 * time.Minute},
+		{Loudness: -20, Peak: 1.1, Duration: time.Minute},
+		{Loudness: math.Inf(-1), Peak: 0, Duration: time.Minute},
+	}
+	loudness, peak := Album(results)
+	want := 10 * math.Log10((3*math.Pow(10, -1)+math.Pow(10, -2))/4)
+	if math.Abs(loudness-want) > 1e-9 {
+		t.Errorf("Album loudness = %v, want %v", loudness, want)
+	}
+	if peak != 1.1 {
+		t.Errorf("Album peak = %v, want 1.1", peak)
+	}
+
+	g := GainFor(results[0], loudness, peak)
+	if g.TrackGain != -8 {
+		t.Errorf("TrackGain = %v, want -8", g.TrackGain)
+	}
+	if silent := GainFor(results[2], loudness, peak); silent.TrackGain != 0 {
+		t.Errorf("Silent track gain = %v, want 0", silent.TrackGain)
+	}
+
+	tags := g.Tags()
+	if tags["REPLAYGAIN_TRACK_GAIN"] != "-8.00 dB" || tags["REPLAYGAIN_TRACK_PEAK"] != "0.900000" || tags["REPLAYGAIN_ALBUM_PEAK"] != "1.100000" {
+		t.Errorf("Unexpected tags: %v", tags)
+	}
+}
@@ -0,0 +1,133 @@
+package metadata
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"main/internal/utils"
+
+	"github.com/zhaarey/go-mp4tag"
+	"golang.org/x/image/draw"
+)
+
+// coverJPEGQuality 重新编码 JPEG 封面时的质量
+const coverJPEGQuality = 95
+
+// decodeCover 解码 JPEG / PNG 封面，返回图片与格式名（jpeg / png）
+func decodeCover(data []byte) (image.Image, string, error) {
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("解码封面失败: %w", err)
+	}
+	return img, format, nil
+}
+
+// encodeCover 按原格式编码封面
+func encodeCover(img image.Image, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if format == "png" {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: coverJPEGQuality})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("编码封面失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// cropSquare 中心裁剪为正方形，已是正方形时原样返回
+func cropSquare(img image.Image) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w == h {
+		return img
+	}
+	side := min(w, h)
+	x0 := b.Min.X + (w-side)/2
+	y0 := b.Min.Y + (h-side)/2
+	rect := image.Rect(x0, y0, x0+side, y0+side)
+	if sub, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(rect)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Copy(dst, image.Point{}, img, rect, draw.Src, nil)
+	return dst
+}
+
+// downscale 将图片等比缩小到最长边不超过 maxSize（Catmull-Rom 插值），不放大
+func downscale(img image.Image, maxSize int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if maxSize <= 0 || (w <= maxSize && h <= maxSize) {
+		return img
+	}
+	nw, nh := maxSize, maxSize
+	if w > h {
+		nh = max(1, h*maxSize/w)
+	} else if h > w {
+		nw = max(1, w*maxSize/h)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, nw, nh))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// normalizeCoverAspectRatio 标准化封面图片为正方形（1:1比例）
+// 对于非正方形图片，使用中心裁剪方式；无法解码的格式保留原始封面
+// 参数:
+//   - inputPath: 输入图片路径
+//
+// 返回:
+//   - error: 处理过程中的错误
+func normalizeCoverAspectRatio(inputPath string) error {
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return err
+	}
+	img, format, err := decodeCover(data)
+	if err != nil {
+		return nil
+	}
+	squared := cropSquare(img)
+	if squared == img {
+		return nil
+	}
+	// 按文件扩展名决定输出格式（.part 临时文件取去掉后缀后的扩展名）
+	if strings.EqualFold(filepath.Ext(strings.TrimSuffix(inputPath, utils.PartSuffix)), ".png") {
+		format = "png"
+	}
+	out, err := encodeCover(squared, format)
+	if err != nil {
+		return err
+	}
+	// 替换原文件（重命名覆盖是原子操作）
+	return utils.WriteFileAtomic(inputPath, out)
+}
+
+// coverPicture 读取封面并生成内嵌图片；maxSize > 0 时将过大的封面缩小后再内嵌（不影响封面文件本身）
+func coverPicture(path string, maxSize int) (*mp4tag.MP4Picture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if maxSize > 0 {
+		if img, format, err := decodeCover(data); err == nil {
+			if scaled := downscale(img, maxSize); scaled != img {
+				if data, err = encodeCover(scaled, format); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return &mp4tag.MP4Picture{Format: mp4tag.ImageTypeAuto, Data: data}, nil
+}
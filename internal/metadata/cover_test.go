@@ -0,0 +1,144 @@
+package metadata
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testImage 生成左半红、右半蓝的测试图片
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= w/2 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// TestCropSquare 测试中心裁剪
+func TestCropSquare(t *testing.T) {
+	img := testImage(300, 100)
+	sq := cropSquare(img)
+	if b := sq.Bounds(); b.Dx() != 100 || b.Dy() != 100 {
+		t.Fatalf("bounds = %v, want 100x100", b)
+	}
+	// 裁剪区域为 x ∈ [100, 200)，左半仍为红色、右半为蓝色
+	if r, _, _, _ := sq.At(sq.Bounds().Min.X+10, 50).RGBA(); r == 0 {
+		t.Error("left side of crop should be red")
+	}
+	if _, _, b, _ := sq.At(sq.Bounds().Max.X-10, 50).RGBA(); b == 0 {
+		t.Error("right side of crop should be blue")
+	}
+
+	square := testImage(50, 50)
+	if cropSquare(square) != image.Image(square) {
+		t.Error("square image should be returned unchanged")
+	}
+}
+
+// TestDownscale 测试等比缩小且不放大
+func TestDownscale(t *testing.T) {
+	tests := []struct {
+		w, h, max, wantW, wantH int
+	}{
+		{1000, 1000, 600, 600, 600},
+		{1200, 600, 600, 600, 300},
+		{400, 800, 600, 300, 600},
+		{300, 300, 600, 300, 300},
+		{800, 800, 0, 800, 800},
+	}
+	for _, tt := range tests {
+		b := downscale(testImage(tt.w, tt.h), tt.max).Bounds()
+		if b.Dx() != tt.wantW || b.Dy() != tt.wantH {
+			t.Errorf("downscale(%dx%d, %d) = %dx%d, want %dx%d", tt.w, tt.h, tt.max, b.Dx(), b.Dy(), tt.wantW, tt.wantH)
+		}
+	}
+}
+
+// TestNormalizeCoverAspectRatio 测试在不依赖 ffmpeg 的情况下将 JPEG / PNG 封面裁剪为正方形
+func TestNormalizeCoverAspectRatio(t *testing.T) {
+	dir := t.TempDir()
+
+	var jpg bytes.Buffer
+	if err := jpeg.Encode(&jpg, testImage(400, 300), nil); err != nil {
+		t.Fatal(err)
+	}
+	jpgPath := filepath.Join(dir, "cover.jpg.part")
+	os.WriteFile(jpgPath, jpg.Bytes(), 0644)
+
+	var pngBuf bytes.Buffer
+	if err := png.Encode(&pngBuf, testImage(200, 500)); err != nil {
+		t.Fatal(err)
+	}
+	pngPath := filepath.Join(dir, "cover.png")
+	os.WriteFile(pngPath, pngBuf.Bytes(), 0644)
+
+	for path, wantFormat := range map[string]string{jpgPath: "jpeg", pngPath: "png"} {
+		if err := normalizeCoverAspectRatio(path); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg, format, err := image.DecodeConfig(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if cfg.Width != cfg.Height {
+			t.Errorf("%s: %dx%d is not square", path, cfg.Width, cfg.Height)
+		}
+		if format != wantFormat {
+			t.Errorf("%s: format = %s, want %s", path, format, wantFormat)
+		}
+	}
+
+	// 无法解码的文件保留原样
+	other := filepath.Join(dir, "cover.webp")
+	os.WriteFile(other, []byte("not an image"), 0644)
+	if err := normalizeCoverAspectRatio(other); err != nil {
+		t.Errorf("undecodable cover should be kept: %v", err)
+	}
+}
+
+// TestCoverPicture 测试内嵌封面按最大边长缩小
+func TestCoverPicture(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(800, 800), nil); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "cover.jpg")
+	os.WriteFile(path, buf.Bytes(), 0644)
+
+	pic, err := coverPicture(path, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(pic.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width != 200 || cfg.Height != 200 {
+		t.Errorf("embedded cover = %dx%d, want 200x200", cfg.Width, cfg.Height)
+	}
+
+	pic, err = coverPicture(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pic.Data, buf.Bytes()) {
+		t.Error("maxSize 0 should embed the original file")
+	}
+}
@@ -0,0 +1,564 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"main/internal/utils"
+
+	"github.com/zhaarey/go-mp4tag"
+)
+
+// maxBoxInMemory moov / moof / mfra 原子读入内存的上限，正常音频文件远小于该值
+const maxBoxInMemory = 64 << 20
+
+// boxRef 缓冲区中的一个原子
+type boxRef struct {
+	typ  string
+	off  int // 原子在缓冲区中的起始位置（含原子头）
+	size int // 原子总大小（含原子头）
+	hdr  int // 原子头长度（8，或 64 位大小时为 16）
+}
+
+func (b boxRef) end() int { return b.off + b.size }
+
+// children 解析 buf[start:end] 中连续的子原子
+func children(buf []byte, start, end int) ([]boxRef, error) {
+	var out []boxRef
+	for off := start; off < end; {
+		if end-off < 8 {
+			return nil, errors.New("原子头不完整")
+		}
+		size := int(binary.BigEndian.Uint32(buf[off:]))
+		typ := string(buf[off+4 : off+8])
+		hdr := 8
+		switch size {
+		case 0: // 延伸到父原子末尾
+			size = end - off
+		case 1: // 64 位大小
+			if end-off < 16 {
+				return nil, fmt.Errorf("原子 %s 头不完整", typ)
+			}
+			size = int(binary.BigEndian.Uint64(buf[off+8:]))
+			hdr = 16
+		}
+		if size < hdr || size > end-off {
+			return nil, fmt.Errorf("原子 %s 大小无效（%d）", typ, size)
+		}
+		out = append(out, boxRef{typ: typ, off: off, size: size, hdr: hdr})
+		off += size
+	}
+	return out, nil
+}
+
+// findRef 按类型查找第一个原子
+func findRef(refs []boxRef, typ string) *boxRef {
+	for i := range refs {
+		if refs[i].typ == typ {
+			return &refs[i]
+		}
+	}
+	return nil
+}
+
+// findAll 按类型查找所有原子
+func findAll(refs []boxRef, typ string) []boxRef {
+	var out []boxRef
+	for _, r := range refs {
+		if r.typ == typ {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// newBox 生成一个 32 位大小的原子
+func newBox(typ string, payload ...[]byte) []byte {
+	size := 8
+	for _, p := range payload {
+		size += len(p)
+	}
+	b := make([]byte, 8, size)
+	binary.BigEndian.PutUint32(b, uint32(size))
+	copy(b[4:], typ)
+	for _, p := range payload {
+		b = append(b, p...)
+	}
+	return b
+}
+
+// hdlrBox iTunes 元数据使用的 hdlr（handler_type mdir，厂商 appl）
+func hdlrBox() []byte {
+	payload := make([]byte, 25)
+	copy(payload[8:], "mdir")
+	copy(payload[12:], "appl")
+	return newBox("hdlr", payload)
+}
+
+// metaBox 包含 hdlr 与 ilst 的 meta 原子（full box，4 字节 version/flags）
+func metaBox(ilst []byte) []byte {
+	return newBox("meta", []byte{0, 0, 0, 0}, hdlrBox(), ilst)
+}
+
+// setSize 更新原子头中的大小
+func setSize(buf []byte, r boxRef, size int) error {
+	if r.hdr == 16 {
+		binary.BigEndian.PutUint64(buf[r.off+8:], uint64(size))
+		return nil
+	}
+	if size > math.MaxUint32 {
+		return fmt.Errorf("原子 %s 超过 4GB", r.typ)
+	}
+	binary.BigEndian.PutUint32(buf[r.off:], uint32(size))
+	return nil
+}
+
+// addIlst 在 moov（含原子头）中补建缺失的 udta / meta / hdlr，并插入给定的 ilst 原子，返回新的 moov
+// 已有 ilst 时原样返回，added 为 false
+func addIlst(moov, ilst []byte) (out []byte, added bool, err error) {
+	root, err := children(moov, 0, len(moov))
+	if err != nil || len(root) != 1 || root[0].typ != "moov" {
+		return nil, false, errors.New("moov 原子无效")
+	}
+	ancestors := []boxRef{root[0]}
+	kids, err := children(moov, root[0].hdr, root[0].end())
+	if err != nil {
+		return nil, false, err
+	}
+
+	var insertAt int
+	var ins []byte
+	if udta := findRef(kids, "udta"); udta == nil {
+		insertAt, ins = root[0].end(), newBox("udta", metaBox(ilst))
+	} else {
+		ancestors = append(ancestors, *udta)
+		ukids, err := children(moov, udta.off+udta.hdr, udta.end())
+		if err != nil {
+			return nil, false, err
+		}
+		if meta := findRef(ukids, "meta"); meta == nil {
+			insertAt, ins = udta.end(), metaBox(ilst)
+		} else {
+			ancestors = append(ancestors, *meta)
+			start := meta.off + meta.hdr + 4
+			if start > meta.end() {
+				return nil, false, errors.New("meta 原子不完整")
+			}
+			mkids, err := children(moov, start, meta.end())
+			if err != nil {
+				return nil, false, err
+			}
+			switch {
+			case findRef(mkids, "ilst") != nil:
+				return moov, false, nil
+			case findRef(mkids, "hdlr") == nil:
+				insertAt, ins = start, append(hdlrBox(), ilst...)
+			default:
+				insertAt, ins = meta.end(), ilst
+			}
+		}
+	}
+
+	out = make([]byte, 0, len(moov)+len(ins))
+	out = append(out, moov[:insertAt]...)
+	out = append(out, ins...)
+	out = append(out, moov[insertAt:]...)
+	// 祖先原子都从插入位置之前开始，位置不变，只需增大尺寸
+	for _, a := range ancestors {
+		if err := setSize(out, a, a.size+len(ins)); err != nil {
+			return nil, false, err
+		}
+	}
+	return out, true, nil
+}
+
+// shiftFunc 将原文件中位于 moov 之后的绝对偏移平移，返回新偏移
+type shiftFunc func(offset uint64) uint64
+
+// patchChunkOffsets 平移 moov 中各 trak 的 stco / co64 块偏移
+func patchChunkOffsets(moov []byte, shift shiftFunc) error {
+	root, err := children(moov, 0, len(moov))
+	if err != nil {
+		return err
+	}
+	kids, err := children(moov, root[0].hdr, root[0].end())
+	if err != nil {
+		return err
+	}
+	for _, trak := range findAll(kids, "trak") {
+		stbl, err := descend(moov, trak, "mdia", "minf", "stbl")
+		if err != nil || stbl == nil {
+			continue
+		}
+		tables, err := children(moov, stbl.off+stbl.hdr, stbl.end())
+		if err != nil {
+			return err
+		}
+		for _, t := range tables {
+			if t.typ != "stco" && t.typ != "co64" {
+				continue
+			}
+			body := moov[t.off+t.hdr : t.end()]
+			if len(body) < 8 {
+				return fmt.Errorf("%s 原子不完整", t.typ)
+			}
+			count := int(binary.BigEndian.Uint32(body[4:8]))
+			width := 4
+			if t.typ == "co64" {
+				width = 8
+			}
+			if len(body) < 8+count*width {
+				return fmt.Errorf("%s 条目数无效", t.typ)
+			}
+			for i := 0; i < count; i++ {
+				p := body[8+i*width:]
+				if width == 4 {
+					v := shift(uint64(binary.BigEndian.Uint32(p)))
+					if v > math.MaxUint32 {
+						return errors.New("stco 块偏移溢出")
+					}
+					binary.BigEndian.PutUint32(p, uint32(v))
+				} else {
+					binary.BigEndian.PutUint64(p, shift(binary.BigEndian.Uint64(p)))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// descend 沿路径查找嵌套的子原子，不存在时返回 nil
+func descend(buf []byte, from boxRef, path ...string) (*boxRef, error) {
+	cur := from
+	for _, typ := range path {
+		kids, err := children(buf, cur.off+cur.hdr, cur.end())
+		if err != nil {
+			return nil, err
+		}
+		next := findRef(kids, typ)
+		if next == nil {
+			return nil, nil
+		}
+		cur = *next
+	}
+	return &cur, nil
+}
+
+// patchMoof 平移分片中 tfhd 显式记录的 base_data_offset（相对 moof 的偏移不受影响）
+func patchMoof(moof []byte, shift shiftFunc) error {
+	root, err := children(moof, 0, len(moof))
+	if err != nil {
+		return err
+	}
+	kids, err := children(moof, root[0].hdr, root[0].end())
+	if err != nil {
+		return err
+	}
+	for _, traf := range findAll(kids, "traf") {
+		tfhd, err := descend(moof, traf, "tfhd")
+		if err != nil || tfhd == nil {
+			continue
+		}
+		body := moof[tfhd.off+tfhd.hdr : tfhd.end()]
+		if len(body) >= 16 && body[3]&0x01 != 0 {
+			binary.BigEndian.PutUint64(body[8:], shift(binary.BigEndian.Uint64(body[8:])))
+		}
+	}
+	return nil
+}
+
+// patchMfra 平移随机访问索引（tfra）中记录的 moof 绝对偏移
+func patchMfra(mfra []byte, shift shiftFunc) error {
+	root, err := children(mfra, 0, len(mfra))
+	if err != nil {
+		return err
+	}
+	kids, err := children(mfra, root[0].hdr, root[0].end())
+	if err != nil {
+		return err
+	}
+	for _, tfra := range findAll(kids, "tfra") {
+		body := mfra[tfra.off+tfra.hdr : tfra.end()]
+		if len(body) < 16 {
+			continue
+		}
+		width := 4
+		if body[0] == 1 {
+			width = 8
+		}
+		lengths := binary.BigEndian.Uint32(body[8:12])
+		numbers := int((lengths>>4)&3+1) + int((lengths>>2)&3+1) + int(lengths&3+1)
+		count := int(binary.BigEndian.Uint32(body[12:16]))
+		entry := 2*width + numbers
+		if len(body) < 16+count*entry {
+			return errors.New("tfra 条目数无效")
+		}
+		for i := 0; i < count; i++ {
+			p := body[16+i*entry+width:]
+			if width == 4 {
+				v := shift(uint64(binary.BigEndian.Uint32(p)))
+				if v > math.MaxUint32 {
+					return errors.New("tfra 偏移溢出")
+				}
+				binary.BigEndian.PutUint32(p, uint32(v))
+			} else {
+				binary.BigEndian.PutUint64(p, shift(binary.BigEndian.Uint64(p)))
+			}
+		}
+	}
+	return nil
+}
+
+// fileBox 文件中的一个顶层原子
+type fileBox struct {
+	typ       string
+	off, size int64
+}
+
+// topLevelBoxes 读取文件的顶层原子列表
+func topLevelBoxes(f *os.File, fileSize int64) ([]fileBox, error) {
+	var boxes []fileBox
+	header := make([]byte, 16)
+	for off := int64(0); off < fileSize; {
+		if fileSize-off < 8 {
+			return nil, fmt.Errorf("文件末尾有 %d 字节不完整的原子头", fileSize-off)
+		}
+		if _, err := f.ReadAt(header[:8], off); err != nil {
+			return nil, err
+		}
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		typ := string(header[4:8])
+		hdr := int64(8)
+		switch size {
+		case 0:
+			size = fileSize - off
+		case 1:
+			if _, err := f.ReadAt(header[8:16], off+8); err != nil {
+				return nil, err
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			hdr = 16
+		}
+		if size < hdr || off+size > fileSize {
+			return nil, fmt.Errorf("原子 %s 大小无效（文件可能被截断）", typ)
+		}
+		boxes = append(boxes, fileBox{typ: typ, off: off, size: size})
+		off += size
+	}
+	return boxes, nil
+}
+
+// readBox 将顶层原子读入内存
+func readBox(f *os.File, b fileBox) ([]byte, error) {
+	if b.size > maxBoxInMemory {
+		return nil, fmt.Errorf("%s 原子过大（%d 字节）", b.typ, b.size)
+	}
+	buf := make([]byte, b.size)
+	if _, err := f.ReadAt(buf, b.off); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// ensureIlst 确保文件包含 moov.udta.meta.ilst，缺失时在 Go 中直接补建（不依赖 ffmpeg 重新封装）
+// moov 位于媒体数据之前时，同步平移 stco / co64、tfhd 与 tfra 中的绝对偏移；已有 ilst 时不改动文件
+// ilst 为要插入的完整 ilst 原子（可已填好标签），返回是否补建了 ilst
+func ensureIlst(path string, ilst []byte) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	boxes, err := topLevelBoxes(f, st.Size())
+	if err != nil {
+		return false, err
+	}
+
+	moovIndex := -1
+	for i, b := range boxes {
+		if b.typ == "moov" {
+			moovIndex = i
+			break
+		}
+	}
+	if moovIndex < 0 {
+		return false, errors.New("缺少 moov 原子")
+	}
+	moovBox := boxes[moovIndex]
+	moov, err := readBox(f, moovBox)
+	if err != nil {
+		return false, err
+	}
+	newMoov, added, err := addIlst(moov, ilst)
+	if err != nil || !added {
+		return false, err
+	}
+
+	moovEnd := uint64(moovBox.off + moovBox.size)
+	delta := uint64(len(newMoov) - len(moov))
+	shift := func(offset uint64) uint64 {
+		if offset >= moovEnd {
+			return offset + delta
+		}
+		return offset
+	}
+	if err := patchChunkOffsets(newMoov, shift); err != nil {
+		return false, err
+	}
+
+	out, err := utils.CreateAtomic(path)
+	if err != nil {
+		return false, err
+	}
+	defer out.Abort()
+	if _, err := io.Copy(out, io.NewSectionReader(f, 0, moovBox.off)); err != nil {
+		return false, err
+	}
+	if _, err := out.Write(newMoov); err != nil {
+		return false, err
+	}
+	for _, b := range boxes[moovIndex+1:] {
+		if b.typ != "moof" && b.typ != "mfra" {
+			if _, err := io.Copy(out, io.NewSectionReader(f, b.off, b.size)); err != nil {
+				return false, err
+			}
+			continue
+		}
+		buf, err := readBox(f, b)
+		if err != nil {
+			return false, err
+		}
+		if b.typ == "moof" {
+			err = patchMoof(buf, shift)
+		} else {
+			err = patchMfra(buf, shift)
+		}
+		if err != nil {
+			return false, err
+		}
+		if _, err := out.Write(buf); err != nil {
+			return false, err
+		}
+	}
+	f.Close()
+	return true, out.Commit()
+}
+
+// encodeIlst 按 go-mp4tag 的原子格式生成填好标签的 ilst 原子
+// 用于文件缺少 ilst 时一次补建带标签的 ilst，避免先补建空 ilst、再由 go-mp4tag 重写整个文件
+func encodeIlst(t *mp4tag.MP4Tags) []byte {
+	var items [][]byte
+	text := func(name, value string) {
+		if value != "" {
+			items = append(items, newBox(name, dataBox(1, []byte(value))))
+		}
+	}
+	text("\xa9nam", t.Title)
+	text("sonm", t.TitleSort)
+	text("\xa9alb", t.Album)
+	text("soal", t.AlbumSort)
+	text("aART", t.AlbumArtist)
+	text("soaa", t.AlbumArtistSort)
+	text("\xa9ART", t.Artist)
+	text("soar", t.ArtistSort)
+	text("\xa9cmt", t.Comment)
+	text("\xa9wrt", t.Composer)
+	text("soco", t.ComposerSort)
+	text("cprt", t.Copyright)
+	text("\xa9lyr", t.Lyrics)
+	text("\xa9gen", t.CustomGenre)
+	text("desc", t.Description)
+	text("\xa9pub", t.Publisher)
+	text("\xa9con", t.Conductor)
+
+	if t.ItunesAdvisory != mp4tag.ItunesAdvisoryNone {
+		items = append(items, newBox("rtng", dataBox(0x15, []byte{byte(t.ItunesAdvisory)})))
+	}
+	if t.ItunesAlbumID > 0 {
+		items = append(items, newBox("plID", dataBox(0x15, make([]byte, 4), be32(uint32(t.ItunesAlbumID)))))
+	}
+	if t.ItunesArtistID > 0 {
+		items = append(items, newBox("atID", dataBox(0x15, be32(uint32(t.ItunesArtistID)))))
+	}
+	if t.TrackNumber > 0 || t.TrackTotal > 0 {
+		items = append(items, newBox("trkn", dataBox(0, []byte{0, 0}, be16(max(t.TrackNumber, 0)), be16(max(t.TrackTotal, 0)), []byte{0, 0})))
+	}
+	if t.DiscNumber > 0 || t.DiscTotal > 0 {
+		items = append(items, newBox("disk", dataBox(0, []byte{0, 0}, be16(max(t.DiscNumber, 0)), be16(max(t.DiscTotal, 0)))))
+	}
+	if t.BPM > 0 {
+		items = append(items, newBox("tmpo", dataBox(0x15, be16(t.BPM))))
+	}
+	if t.Year > 0 {
+		text("\xa9day", strconv.Itoa(int(t.Year)))
+	} else {
+		text("\xa9day", t.Date)
+	}
+	if t.Genre != mp4tag.GenreNone {
+		items = append(items, newBox("gnre", dataBox(0, []byte{0, byte(t.Genre)})))
+	}
+
+	// freeform 原子：名称统一大写，同名的 OtherCustom 值作为额外的 data 原子
+	keys := make([]string, 0, len(t.Custom))
+	for k := range t.Custom {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		name := strings.ToUpper(k)
+		parts := [][]byte{
+			newBox("mean", make([]byte, 4), []byte("com.apple.iTunes")),
+			newBox("name", make([]byte, 4), []byte(name)),
+			dataBox(1, []byte(t.Custom[k])),
+		}
+		for _, v := range t.OtherCustom[name] {
+			parts = append(parts, dataBox(1, []byte(v)))
+		}
+		items = append(items, newBox("----", parts...))
+	}
+
+	var pics [][]byte
+	for _, pic := range t.Pictures {
+		if len(pic.Data) == 0 {
+			continue
+		}
+		format := uint32(0x0D)
+		if pic.Format == mp4tag.ImageTypePNG || (pic.Format == mp4tag.ImageTypeAuto && bytes.HasPrefix(pic.Data, []byte{0x89, 'P', 'N', 'G'})) {
+			format = 0x0E
+		}
+		pics = append(pics, dataBox(format, pic.Data))
+	}
+	if len(pics) > 0 {
+		items = append(items, newBox("covr", pics...))
+	}
+	return newBox("ilst", items...)
+}
+
+// dataBox 生成 ilst 条目的 data 原子（4 字节数据类型 + 4 字节 locale + 值）
+func dataBox(typ uint32, value ...[]byte) []byte {
+	return newBox("data", append([][]byte{be32(typ), make([]byte, 4)}, value...)...)
+}
+
+func be16(v int16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, uint16(v))
+	return b
+}
+
+func be32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
@@ -0,0 +1,211 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"main/internal/mp4test"
+
+	"github.com/zhaarey/go-mp4tag"
+)
+
+// chunkOffsets 读取文件中第一个 stco 的全部偏移
+func chunkOffsets(t *testing.T, data []byte) []uint32 {
+	t.Helper()
+	top, err := children(data, 0, len(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	moov := findRef(top, "moov")
+	kids, _ := children(data, moov.off+moov.hdr, moov.end())
+	stco, err := descend(data, *findRef(kids, "trak"), "mdia", "minf", "stbl", "stco")
+	if err != nil || stco == nil {
+		t.Fatalf("stco not found: %v", err)
+	}
+	body := data[stco.off+stco.hdr : stco.end()]
+	var out []uint32
+	for i := 0; i < int(binary.BigEndian.Uint32(body[4:8])); i++ {
+		out = append(out, binary.BigEndian.Uint32(body[8+i*4:]))
+	}
+	return out
+}
+
+// TestEnsureIlst 测试为缺少 udta / meta / ilst 的文件补建原子，并平移 stco 块偏移
+func TestEnsureIlst(t *testing.T) {
+	tests := []struct {
+		name string
+		udta []byte
+	}{
+		{"no udta", nil},
+		{"udta without meta", newBox("udta", newBox("name", []byte("x")))},
+		{"meta without ilst", newBox("udta", mp4test.FullBox("meta", 0, 0, hdlrBox()))},
+		{"meta without hdlr", newBox("udta", mp4test.FullBox("meta", 0, 0))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := []byte("chunk-one-chunk-two")
+			data := mp4test.Track(5000, tt.udta, payload[:10], payload[10:])
+			path := filepath.Join(t.TempDir(), "track.m4a")
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if added, err := ensureIlst(path, newBox("ilst")); err != nil || !added {
+				t.Fatalf("ensureIlst = %v, %v", added, err)
+			}
+			fixed, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := children(fixed, 0, len(fixed)); err != nil {
+				t.Fatalf("invalid box structure: %v", err)
+			}
+			// 块偏移仍指向原来的媒体数据
+			offsets := chunkOffsets(t, fixed)
+			if got := string(fixed[offsets[0] : offsets[0]+10]); got != string(payload[:10]) {
+				t.Errorf("chunk 1 = %q, want %q", got, payload[:10])
+			}
+			if got := string(fixed[offsets[1] : offsets[1]+9]); got != string(payload[10:]) {
+				t.Errorf("chunk 2 = %q, want %q", got, payload[10:])
+			}
+
+			mp4, err := mp4tag.Open(path)
+			if err != nil {
+				t.Fatalf("mp4tag.Open failed: %v", err)
+			}
+			err = mp4.Write(&mp4tag.MP4Tags{Title: "Song", Custom: map[string]string{"ISRC": "USABC0000001"}}, []string{})
+			mp4.Close()
+			if err != nil {
+				t.Fatalf("write tags failed: %v", err)
+			}
+			mp4, err = mp4tag.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer mp4.Close()
+			tags, err := mp4.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tags.Title != "Song" || tags.Custom["ISRC"] != "USABC0000001" {
+				t.Errorf("tags = %q / %v", tags.Title, tags.Custom)
+			}
+		})
+	}
+}
+
+// TestEnsureIlstNoop 测试已有 ilst 时不改动文件
+func TestEnsureIlstNoop(t *testing.T) {
+	data := mp4test.Track(5000, newBox("udta", metaBox(newBox("ilst"))), []byte("chunk-one-chunk-two"))
+	path := filepath.Join(t.TempDir(), "track.m4a")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if added, err := ensureIlst(path, newBox("ilst")); err != nil || added {
+		t.Fatalf("ensureIlst = %v, %v", added, err)
+	}
+	after, _ := os.ReadFile(path)
+	if !bytes.Equal(after, data) {
+		t.Error("file with ilst should not be rewritten")
+	}
+}
+
+// TestPatchMoof 测试平移分片中显式的 base_data_offset
+func TestPatchMoof(t *testing.T) {
+	tfhdPayload := append(mp4test.U32(1), make([]byte, 8)...)
+	binary.BigEndian.PutUint64(tfhdPayload[4:], 1000)
+	moof := newBox("moof", newBox("traf", mp4test.FullBox("tfhd", 0, 0x01, tfhdPayload)))
+	shift := func(off uint64) uint64 {
+		if off >= 500 {
+			return off + 42
+		}
+		return off
+	}
+	if err := patchMoof(moof, shift); err != nil {
+		t.Fatal(err)
+	}
+	if got := binary.BigEndian.Uint64(moof[len(moof)-8:]); got != 1042 {
+		t.Errorf("base_data_offset = %d, want 1042", got)
+	}
+}
+
+// TestEncodeIlst 测试一次补建的带标签 ilst 与 go-mp4tag 写入空 ilst 的结果一致
+func TestEncodeIlst(t *testing.T) {
+	png := append([]byte{0x89, 'P', 'N', 'G'}, make([]byte, 12)...)
+	tags := func() *mp4tag.MP4Tags {
+		t := &mp4tag.MP4Tags{
+			Title:          "Song",
+			TitleSort:      "Song",
+			Album:          "Album",
+			AlbumArtist:    "Artist",
+			Artist:         "Artist A & Artist B",
+			Composer:       "Writer",
+			Copyright:      "℗ 2024 Label",
+			Lyrics:         "la la la",
+			CustomGenre:    "Pop",
+			Date:           "2024-05-01",
+			ItunesAdvisory: mp4tag.ItunesAdvisoryExplicit,
+			ItunesAlbumID:  1440000000,
+			ItunesArtistID: 123456,
+			TrackNumber:    3,
+			TrackTotal:     12,
+			DiscNumber:     1,
+			DiscTotal:      2,
+			Custom:         map[string]string{"ISRC": "USABC0000001", "label": "Label"},
+			Pictures:       []*mp4tag.MP4Picture{{Format: mp4tag.ImageTypeAuto, Data: png}},
+		}
+		setMultiValue(t, "ARTISTS", []string{"Artist A", "Artist B"})
+		return t
+	}
+
+	read := func(path string) *mp4tag.MP4Tags {
+		mp4, err := mp4tag.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer mp4.Close()
+		got, err := mp4.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	dir := t.TempDir()
+	data := mp4test.Track(5000, nil, []byte("chunk-one-chunk-two"))
+	native := filepath.Join(dir, "native.m4a")
+	viaLib := filepath.Join(dir, "lib.m4a")
+	for _, p := range []string{native, viaLib} {
+		if err := os.WriteFile(p, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := writeTags(native, tags(), nil); err != nil {
+		t.Fatalf("writeTags failed: %v", err)
+	}
+	if _, err := ensureIlst(viaLib, newBox("ilst")); err != nil {
+		t.Fatal(err)
+	}
+	mp4, err := mp4tag.Open(viaLib)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mp4.Write(tags(), []string{})
+	mp4.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, want := read(native), read(viaLib)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("native ilst tags = %+v\nwant %+v", got, want)
+	}
+	if got.Title != "Song" || got.TrackNumber != 3 || got.DiscTotal != 2 || len(got.Pictures) != 1 {
+		t.Errorf("tags not written: %+v", got)
+	}
+}
@@ -0,0 +1,65 @@
+package metadata
+
+import (
+	"strings"
+
+	"main/utils/structs"
+
+	"github.com/zhaarey/go-mp4tag"
+)
+
+// ReadMP4Tags 读取 MP4 标签
+func ReadMP4Tags(path string) (*mp4tag.MP4Tags, error) {
+	mp4, err := mp4tag.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer mp4.Close()
+	return mp4.Read()
+}
+
+// CustomTag 读取自定义标签（键名不区分大小写）
+func CustomTag(tags *mp4tag.MP4Tags, key string) string {
+	for k, v := range tags.Custom {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
+// CustomValues 读取多值自定义标签的全部值（键名不区分大小写）
+func CustomValues(tags *mp4tag.MP4Tags, key string) []string {
+	var values []string
+	if v := CustomTag(tags, key); v != "" {
+		values = append(values, v)
+	}
+	for k, v := range tags.OtherCustom {
+		if strings.EqualFold(k, key) {
+			values = append(values, v...)
+		}
+	}
+	return values
+}
+
+// MatchTrack 在目录曲目中查找文件对应的曲目：优先按 ISRC 匹配，其次按碟号与曲号匹配
+// 返回曲目下标，找不到时返回 -1
+func MatchTrack(tracks []structs.TrackData, isrc string, disc, number int) int {
+	if isrc != "" {
+		for i := range tracks {
+			if strings.EqualFold(tracks[i].Attributes.Isrc, isrc) {
+				return i
+			}
+		}
+	}
+	if number <= 0 {
+		return -1
+	}
+	for i := range tracks {
+		a := tracks[i].Attributes
+		if a.TrackNumber == number && (disc <= 0 || a.DiscNumber == disc) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,30 @@
+package metadata
+
+import (
+	"testing"
+
+	"main/utils/structs"
+)
+
+// TestMatchTrack 测试按 ISRC 或碟号曲号匹配目录中的曲目
+func TestMatchTrack(t *testing.T) {
+	tracks := make([]structs.TrackData, 3)
+	for i := range tracks {
+		tracks[i].ID = string(rune('a' + i))
+		tracks[i].Attributes.TrackNumber = i%2 + 1
+		tracks[i].Attributes.DiscNumber = i/2 + 1
+		tracks[i].Attributes.Isrc = "USRC1700000" + string(rune('0'+i))
+	}
+	if got := MatchTrack(tracks, "usrc17000002", 1, 1); got != 2 {
+		t.Errorf("ISRC match = %d, want 2", got)
+	}
+	if got := MatchTrack(tracks, "", 1, 2); got != 1 {
+		t.Errorf("Track number match = %d, want 1", got)
+	}
+	if got := MatchTrack(tracks, "", 2, 1); got != 2 {
+		t.Errorf("Disc match = %d, want 2", got)
+	}
+	if got := MatchTrack(tracks, "", 0, 0); got != -1 {
+		t.Errorf("Expected no match, got %d", got)
+	}
+}
@@ -1,14 +1,12 @@
 package metadata
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"math"
 	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strconv"
@@ -16,129 +14,17 @@ import (
 	"time"
 
 	"main/internal/core"
+	"main/internal/logger"
+	"main/internal/quality"
 	"main/internal/utils"
 	"main/utils/structs"
 
 	"github.com/zhaarey/go-mp4tag"
 )
 
-// getQualityString determines the quality tag: RESPECT USER CHOICE FIRST, then fallback to auto-detection
-func getQualityString(audioTraits []string) string {
-	// Priority 1: Respect user's explicit quality choice
-	if core.Dl_atmos {
-		return utils.FormatQualityTag("Dolby Atmos")
-	} else if core.Dl_aac && core.Aac_type != nil && *core.Aac_type == "aac-binaural" {
-		return utils.FormatQualityTag("Aac Binaural")
-	} else if core.Dl_aac && core.Aac_type != nil && *core.Aac_type == "aac-downmix" {
-		return utils.FormatQualityTag("Aac Downmix")
-	} else if core.Dl_aac && core.Aac_type != nil && *core.Aac_type == "aac-lc" {
-		return utils.FormatQualityTag("Aac 256")
-	} else if core.Dl_aac && core.Aac_type != nil {
-		// Generic AAC mode - check if user wants specific type
-		if *core.Aac_type != "aac" {
-			return utils.FormatQualityTag("Aac " + strings.Title(*core.Aac_type))
-		} else {
-			return utils.FormatQualityTag("Aac 256")
-		}
-	}
-
-	// Priority 2: Auto-detect best quality when user didn't specify
-	if utils.Contains(audioTraits, "hi-res-lossless") {
-		return utils.FormatQualityTag("Hi-Res Lossless")
-	} else if utils.Contains(audioTraits, "lossless") {
-		return utils.FormatQualityTag("Alac")
-	} else if utils.Contains(audioTraits, "atmos") {
-		return utils.FormatQualityTag("Dolby Atmos")
-	}
-
-	// Default to AAC
-	return utils.FormatQualityTag("Aac 256")
-}
-
-// normalizeCoverAspectRatio 标准化封面图片为正方形（1:1比例）
-// 对于非正方形图片，使用中心裁剪方式
-// 参数:
-//   - inputPath: 输入图片路径
-//
-// 返回:
-//   - error: 处理过程中的错误
-func normalizeCoverAspectRatio(inputPath string) error {
-	// 检查 ffmpeg 是否存在
-	_, err := exec.LookPath("ffmpeg")
-	if err != nil {
-		// ffmpeg 不存在时，跳过标准化（保留原始封面）
-		return nil
-	}
-
-	// 使用 ffprobe 检查图片尺寸
-	probeCmd := exec.Command("ffprobe", "-v", "error", "-select_streams", "v:0",
-		"-show_entries", "stream=width,height", "-of", "csv=s=x:p=0", inputPath)
-
-	output, err := probeCmd.Output()
-	if err != nil {
-		// 如果 ffprobe 失败，跳过标准化
-		return nil
-	}
-
-	// 解析宽度和高度
-	dimensions := strings.TrimSpace(string(output))
-	parts := strings.Split(dimensions, "x")
-	if len(parts) != 2 {
-		return nil
-	}
-
-	width, err1 := strconv.Atoi(parts[0])
-	height, err2 := strconv.Atoi(parts[1])
-	if err1 != nil || err2 != nil {
-		return nil
-	}
-
-	// 如果已经是正方形，无需处理
-	if width == height {
-		return nil
-	}
-
-	// 创建临时文件
-	tempPath := inputPath + ".normalized.tmp"
-	defer os.Remove(tempPath)
-
-	// 计算裁剪参数（中心裁剪）
-	var cropFilter string
-	if width > height {
-		// 横向图片，裁剪左右
-		cropFilter = fmt.Sprintf("crop=%d:%d:(iw-%d)/2:0", height, height, height)
-	} else {
-		// 纵向图片，裁剪上下
-		cropFilter = fmt.Sprintf("crop=%d:%d:0:(ih-%d)/2", width, width, width)
-	}
-
-	// 使用 ffmpeg 裁剪为正方形
-	cmd := exec.Command("ffmpeg", "-i", inputPath, "-vf", cropFilter,
-		"-q:v", "2", "-y", tempPath)
-
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		// 裁剪失败，保留原始封面
-		return nil
-	}
-
-	// 检查输出文件
-	if _, err := os.Stat(tempPath); err != nil {
-		return nil
-	}
-
-	// 替换原文件
-	if err := os.Remove(inputPath); err != nil {
-		return nil
-	}
-
-	if err := os.Rename(tempPath, inputPath); err != nil {
-		return nil
-	}
-
-	return nil
+// getQualityString 返回 QUALITY 元数据标签，与文件夹、文件名的 {Tag} 同源
+func getQualityString(opts core.Options, audioTraits []string) string {
+	return quality.Tag(opts.Rule(), audioTraits)
 }
 
 func WriteCover(sanAlbumFolder, name string, url string) (string, error) {
@@ -148,13 +34,6 @@ func WriteCover(sanAlbumFolder, name string, url string) (string, error) {
 		ext = ext[strings.LastIndex(ext, ".")+1:]
 		covPath = filepath.Join(sanAlbumFolder, name+"."+ext)
 	}
-	exists, err := utils.FileExists(covPath)
-	if err != nil {
-		return "", err
-	}
-	if exists {
-		_ = os.Remove(covPath)
-	}
 	if core.Config.CoverFormat == "png" {
 		re := regexp.MustCompile(`\{w\}x\{h\}`)
 		parts := re.Split(url, 2)
@@ -178,133 +57,180 @@ func WriteCover(sanAlbumFolder, name string, url string) (string, error) {
 	if do.StatusCode != http.StatusOK {
 		return "", errors.New(do.Status)
 	}
-	f, err := os.Create(covPath)
+	// 先写入 .part 临时文件，处理完成后再替换已存在的封面
+	partPath := utils.PartPath(covPath)
+	f, err := os.Create(partPath)
 	if err != nil {
 		return "", err
 	}
-	defer f.Close()
 	_, err = io.Copy(f, do.Body)
+	f.Close()
 	if err != nil {
+		os.Remove(partPath)
 		return "", err
 	}
 
 	// 标准化封面比例（裁剪为正方形）
 	// 如果启用了封面内嵌，则进行标准化处理
 	if core.Config.EmbedCover {
-		if normalizeErr := normalizeCoverAspectRatio(covPath); normalizeErr != nil {
+		if normalizeErr := normalizeCoverAspectRatio(partPath); normalizeErr != nil {
 			// 标准化失败不影响整体流程，只记录警告
 			// 保留原始封面继续使用
 		}
 	}
 
+	if err := utils.CommitFile(partPath, covPath); err != nil {
+		os.Remove(partPath)
+		return "", err
+	}
+
 	return covPath, nil
 }
 
 func WriteLyrics(sanAlbumFolder, filename string, lrc string) error {
 	lyricspath := filepath.Join(sanAlbumFolder, filename)
-	f, err := os.Create(lyricspath)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-	_, err = f.WriteString(lrc)
-	if err != nil {
-		return err
-	}
-	return nil
+	return utils.WriteFileAtomic(lyricspath, []byte(lrc))
 }
 
-// fixIlstBoxMissing 使用 FFmpeg 重新封装 MP4 文件以添加缺失的 ilst box
-// 参数:
-//   - trackPath: 需要修复的音频文件路径
-//
-// 返回:
-//   - error: 修复过程中的错误，成功返回 nil
-func fixIlstBoxMissing(trackPath string) error {
-	// 检查 ffmpeg 是否存在
-	_, err := exec.LookPath("ffmpeg")
-	if err != nil {
-		return fmt.Errorf("未找到 ffmpeg 命令: %w", err)
+// WriteReplayGain 将 ReplayGain 标签写入 MP4 freeform 原子（----:com.apple.iTunes:*），保留已有标签
+// 文件中已有完全相同的值时不重写文件；返回是否写入
+func WriteReplayGain(trackPath string, tags map[string]string) (bool, error) {
+	if old, err := ReadMP4Tags(trackPath); err == nil {
+		same := true
+		for k, v := range tags {
+			if CustomTag(old, k) != v {
+				same = false
+				break
+			}
+		}
+		if same {
+			return false, nil
+		}
 	}
+	return true, UpdateMP4Tags(trackPath, &mp4tag.MP4Tags{Custom: tags})
+}
 
-	// 创建临时文件路径
-	tempPath := trackPath + ".tmp.m4a"
-
-	// 使用 FFmpeg 重新封装文件，添加元数据容器
-	// -i: 输入文件
-	// -c copy: 复制所有流，不重新编码（快速）
-	// -movflags +faststart: 优化文件结构，将 moov atom 移到文件开头
-	// -f mp4: 强制输出为 MP4 格式
-	cmd := exec.Command("ffmpeg", "-i", trackPath, "-c", "copy", "-movflags", "+faststart", "-f", "mp4", "-y", tempPath)
-
-	// 捕获错误输出
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-
-	// 执行命令
-	if err := cmd.Run(); err != nil {
-		// 清理临时文件
-		os.Remove(tempPath)
-		return fmt.Errorf("FFmpeg 重新封装失败: %w, stderr: %s", err, stderr.String())
+// WriteMP4Tags 一次写入曲目的全部标签、自定义（freeform）原子与内嵌封面
+// 文件缺少 moov.udta.meta.ilst 时在 Go 中直接补建带标签的 ilst；coverPath 为空时不内嵌封面
+func WriteMP4Tags(job *core.Job, trackPath, lrc, coverPath string, meta *structs.AutoGenerated, trackNum, trackTotal int) error {
+	t := BuildMP4Tags(job, lrc, "", meta, trackNum, trackTotal)
+
+	// 内嵌封面替换文件中已有的图片，避免重复写入时累积
+	var delStrings []string
+	if coverPath != "" {
+		pic, err := coverPicture(coverPath, core.Config.EmbedCoverMaxSize)
+		if err != nil {
+			logger.Warn("读取内嵌封面失败 %s: %v", filepath.Base(coverPath), err)
+		} else {
+			t.Pictures = []*mp4tag.MP4Picture{pic}
+			delStrings = append(delStrings, "allpictures")
+		}
 	}
 
-	// 检查临时文件是否创建成功
-	if _, err := os.Stat(tempPath); err != nil {
-		return fmt.Errorf("FFmpeg 输出文件未创建: %w", err)
-	}
+	return writeTags(trackPath, t, delStrings)
+}
 
-	// 替换原文件
-	if err := os.Remove(trackPath); err != nil {
-		os.Remove(tempPath)
-		return fmt.Errorf("删除原文件失败: %w", err)
+// UpdateMP4Tags 将标签合并写入已有文件：空字段保留文件中的原值，封面不变
+// 标签写入 .part 副本后再提交替换原文件，中途失败或被中断时原文件保持不变
+func UpdateMP4Tags(trackPath string, t *mp4tag.MP4Tags) error {
+	partPath := utils.PartPath(trackPath)
+	if err := copyFile(trackPath, partPath); err != nil {
+		os.Remove(partPath)
+		return fmt.Errorf("创建临时副本失败: %w", err)
 	}
-
-	if err := os.Rename(tempPath, trackPath); err != nil {
-		return fmt.Errorf("重命名临时文件失败: %w", err)
+	if err := writeTags(partPath, t, []string{}); err != nil {
+		os.Remove(partPath)
+		return err
+	}
+	if err := utils.CommitFile(partPath, trackPath); err != nil {
+		os.Remove(partPath)
+		return err
 	}
-
 	return nil
 }
 
-// WriteMP4TagsWithRetry 尝试写入 MP4 标签，如果遇到 ilst box 缺失错误则自动修复
-// 参数与 WriteMP4Tags 相同
-// 返回:
-//   - error: 写入或修复过程中的错误
-func WriteMP4TagsWithRetry(trackPath, lrc string, meta *structs.AutoGenerated, trackNum, trackTotal int) error {
-	// 第一次尝试写入标签
-	err := WriteMP4Tags(trackPath, lrc, meta, trackNum, trackTotal)
-
-	// 如果没有错误，直接返回
-	if err == nil {
+// writeTags 在文件上直接合并写入标签，调用方负责文件的原子提交
+// 缺少 ilst 时直接补建已填好标签的 ilst（文件中没有可合并的原有标签），整个文件只重写一次
+func writeTags(path string, t *mp4tag.MP4Tags, delStrings []string) error {
+	added, err := ensureIlst(path, encodeIlst(t))
+	if err != nil {
+		return fmt.Errorf("补建 ilst 原子失败: %w", err)
+	}
+	if added {
 		return nil
 	}
+	mp4, err := mp4tag.Open(path)
+	if err != nil {
+		return err
+	}
+	defer mp4.Close()
+	return mp4.Write(t, replaceMultiValues(mp4, t, delStrings))
+}
 
-	// 检查是否是 ilst box 缺失错误
-	errMsg := err.Error()
-	if !strings.Contains(errMsg, "ilst box not present") && !strings.Contains(errMsg, "ilst") {
-		// 不是 ilst box 问题，直接返回原错误
+// copyFile 将 src 完整拷贝到 dst（覆盖已存在的文件）
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
 		return err
 	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
 
-	// 尝试使用 FFmpeg 修复
-	if fixErr := fixIlstBoxMissing(trackPath); fixErr != nil {
-		return fmt.Errorf("ilst box 修复失败: %w (原错误: %v)", fixErr, err)
+// setMultiValue 设置多值自定义标签：第一个值写入 Custom，其余值写入 OtherCustom（同一 freeform 原子中的多个 data 原子）
+func setMultiValue(t *mp4tag.MP4Tags, key string, values []string) {
+	if len(values) == 0 {
+		return
 	}
+	if t.OtherCustom == nil {
+		t.OtherCustom = make(map[string][]string)
+	}
+	t.Custom[key] = values[0]
+	t.OtherCustom[key] = values[1:]
+}
 
-	// 修复成功后重试写入标签
-	retryErr := WriteMP4Tags(trackPath, lrc, meta, trackNum, trackTotal)
-	if retryErr != nil {
-		return fmt.Errorf("修复后标签写入仍失败: %w", retryErr)
+// replaceMultiValues 合并写入时 OtherCustom 会追加到文件中的原有值之后，重复写入会累积：
+// 写入多值标签时先读出文件中其他的多值标签，清空后整体写入
+func replaceMultiValues(mp4 *mp4tag.MP4, t *mp4tag.MP4Tags, delStrings []string) []string {
+	if len(t.OtherCustom) == 0 {
+		return delStrings
+	}
+	if old, err := mp4.Read(); err == nil {
+		for k, v := range old.OtherCustom {
+			if _, ok := t.OtherCustom[k]; !ok {
+				t.OtherCustom[k] = v
+			}
+		}
 	}
+	return append(delStrings, "allothercustom")
+}
 
-	return nil
+func creditNames(credits []core.Credit) []string {
+	names := make([]string, len(credits))
+	for i, c := range credits {
+		names[i] = c.Name
+	}
+	return names
 }
 
-func WriteMP4Tags(trackPath, lrc string, meta *structs.AutoGenerated, trackNum, trackTotal int) error {
+// BuildMP4Tags 根据目录元数据生成曲目标签（不含封面）；trackNum 为曲目在 meta 中的序号（从 1 开始）
+// quality 为空时按任务参数与音频特性推断音质标签
+func BuildMP4Tags(job *core.Job, lrc, quality string, meta *structs.AutoGenerated, trackNum, trackTotal int) *mp4tag.MP4Tags {
 	index := trackNum - 1
 
 	// Get quality string for metadata embedding
-	qualityString := getQualityString(meta.Data[0].Relationships.Tracks.Data[index].Attributes.AudioTraits)
+	qualityString := quality
+	if qualityString == "" {
+		qualityString = getQualityString(job.Options, meta.Data[0].Relationships.Tracks.Data[index].Attributes.AudioTraits)
+	}
 
 	t := &mp4tag.MP4Tags{
 		Title:      meta.Data[0].Relationships.Tracks.Data[index].Attributes.Name,
@@ -328,6 +254,9 @@ func WriteMP4Tags(trackPath, lrc string, meta *structs.AutoGenerated, trackNum,
 		Lyrics:       lrc,
 	}
 
+	// 多值艺术家标签，便于媒体服务器分别关联每位参与艺术家
+	setMultiValue(t, "ARTISTS", creditNames(core.TrackCredits(meta, index)))
+
 	if meta.Data[0].Attributes.EditorialNotes != nil && meta.Data[0].Attributes.EditorialNotes.Standard != "" {
 		reHTML := regexp.MustCompile("<[^>]*>")
 		textWithoutHTML := reHTML.ReplaceAllString(meta.Data[0].Attributes.EditorialNotes.Standard, "")
@@ -402,14 +331,12 @@ func WriteMP4Tags(trackPath, lrc string, meta *structs.AutoGenerated, trackNum,
 		}
 
 		if isSingle {
-			// 虚拟Singles专辑：使用预先保存的有效曲目编号（避免重复调用GetVirtualSinglesTrackNumber）
+			// 虚拟Singles专辑：使用下载时按目标目录分配的有效曲目编号（确保文件名和标签编号一致）
 			trackID := meta.Data[0].Relationships.Tracks.Data[index].ID
-			virtualTrackNum := core.GetTrackEffectiveNumber(trackID)
+			virtualTrackNum := job.EffectiveTrackNumber(trackID)
 			if virtualTrackNum == -1 {
-				// 如果没有预先保存的编号（兼容旧代码），则生成新编号
-				primaryArtist := core.GetPrimaryArtist(meta.Data[0].Attributes.ArtistName)
-				virtualTrackNum = core.GetVirtualSinglesTrackNumber(primaryArtist)
-				core.SetTrackEffectiveNumber(trackID, virtualTrackNum)
+				// 没有分配编号时（不经过下载流程的调用）使用曲目在单曲专辑中的位置
+				virtualTrackNum = trackNum
 			}
 			if virtualTrackNum <= math.MaxInt16 {
 				t.TrackNumber = int16(virtualTrackNum)
@@ -431,13 +358,10 @@ func WriteMP4Tags(trackPath, lrc string, meta *structs.AutoGenerated, trackNum,
 		// 为专辑名称添加音质标签，确保音乐管理软件能识别不同音质版本
 		// 示例: "Black Codes (From The Underground) [2023 Remaster] Alac"
 		if isSingle {
-			// 虚拟Singles专辑：使用主要艺术家名（处理合作者情况）
-			singlesAlbumName := core.Config.VirtualSinglesFolderName
-			if singlesAlbumName == "" {
-				singlesAlbumName = "Singles"
-			}
+			// 虚拟合辑（Singles / EPs）：使用主要艺术家名（处理合作者情况）
+			singlesAlbumName := core.VirtualFolderName(meta)
 			// 提取主要艺术家（处理合作者：取第一个&之前的名字）
-			primaryArtist := core.GetPrimaryArtist(meta.Data[0].Attributes.ArtistName)
+			primaryArtist := core.PrimaryArtist(meta)
 			// 格式: "Olivia Rodrigo - Singles"
 			fullSinglesAlbumName := fmt.Sprintf("%s - %s", primaryArtist, singlesAlbumName)
 			t.Album = fullSinglesAlbumName
@@ -456,12 +380,14 @@ func WriteMP4Tags(trackPath, lrc string, meta *structs.AutoGenerated, trackNum,
 		}
 		// 对于虚拟Singles专辑，Album Artist 应该使用主要艺术家
 		if isSingle {
-			primaryArtist := core.GetPrimaryArtist(meta.Data[0].Attributes.ArtistName)
+			primaryArtist := core.PrimaryArtist(meta)
 			t.AlbumArtist = primaryArtist
 			t.AlbumArtistSort = primaryArtist
+			setMultiValue(t, "ALBUMARTISTS", []string{primaryArtist})
 		} else {
 			t.AlbumArtist = meta.Data[0].Attributes.ArtistName
 			t.AlbumArtistSort = meta.Data[0].Attributes.ArtistName
+			setMultiValue(t, "ALBUMARTISTS", creditNames(core.AlbumCredits(meta)))
 		}
 	}
 
@@ -473,14 +399,56 @@ func WriteMP4Tags(trackPath, lrc string, meta *structs.AutoGenerated, trackNum,
 		t.ItunesAdvisory = mp4tag.ItunesAdvisoryNone
 	}
 
-	mp4, err := mp4tag.Open(trackPath)
-	if err != nil {
-		return err
-	}
-	defer mp4.Close()
-	err = mp4.Write(t, []string{})
-	if err != nil {
-		return err
+	applySecondaryLanguage(t, meta, index, qualityString)
+	return t
+}
+
+// applySecondaryLanguage 写入第二语言（metadata-language.secondary）的名称：
+// sort 写入排序字段；custom 写入 ALT_TITLE / ALT_ARTIST / ALT_ALBUM / ALT_ALBUMARTIST，只写入与主语言不同的值
+func applySecondaryLanguage(t *mp4tag.MP4Tags, meta *structs.AutoGenerated, index int, qualityString string) {
+	sec, ok := core.SecondaryTrack(meta, index)
+	if !ok {
+		return
+	}
+	var album, albumArtist string
+	isPlaylist := strings.Contains(meta.Data[0].ID, "pl.")
+	switch {
+	case isPlaylist && !core.Config.UseSongInfoForPlaylist:
+		// 播放列表名称不区分语言
+	case isPlaylist:
+		album = sec.Attributes.AlbumName + " " + qualityString
+		if len(sec.Relationships.Albums.Data) > 0 {
+			albumArtist = sec.Relationships.Albums.Data[0].Attributes.ArtistName
+		}
+	case core.IsSingleAlbum(meta):
+		primaryArtist := core.PrimaryArtist(meta.Secondary)
+		album = fmt.Sprintf("%s - %s", primaryArtist, core.VirtualFolderName(meta))
+		albumArtist = primaryArtist
+	default:
+		album = sec.Attributes.AlbumName + " " + qualityString
+		albumArtist = meta.Secondary.Data[0].Attributes.ArtistName
+	}
+
+	if strings.EqualFold(core.Config.MetadataLanguage.SecondaryTarget, core.SecondaryTargetCustom) {
+		alt := func(key, value, primary string) {
+			if value != "" && value != primary {
+				t.Custom[key] = value
+			}
+		}
+		alt("ALT_TITLE", sec.Attributes.Name, t.Title)
+		alt("ALT_ARTIST", sec.Attributes.ArtistName, t.Artist)
+		alt("ALT_ALBUM", album, t.Album)
+		alt("ALT_ALBUMARTIST", albumArtist, t.AlbumArtist)
+		return
+	}
+	setSort := func(field *string, value string) {
+		if value != "" {
+			*field = value
+		}
 	}
-	return nil
+	setSort(&t.TitleSort, sec.Attributes.Name)
+	setSort(&t.ArtistSort, sec.Attributes.ArtistName)
+	setSort(&t.ComposerSort, sec.Attributes.ComposerName)
+	setSort(&t.AlbumSort, album)
+	setSort(&t.AlbumArtistSort, albumArtist)
 }
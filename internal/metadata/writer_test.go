@@ -0,0 +1,145 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"main/internal/core"
+	"main/internal/mp4test"
+	"main/utils/structs"
+
+	"github.com/zhaarey/go-mp4tag"
+)
+
+// TestMultiValueTags 测试多值艺术家标签重复写入时不累积，且保留其他多值标签
+func TestMultiValueTags(t *testing.T) {
+	data := mp4test.Track(5000, nil, []byte("chunk-one-chunk-two"))
+	path := filepath.Join(t.TempDir(), "track.m4a")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	other := &mp4tag.MP4Tags{Custom: map[string]string{}}
+	setMultiValue(other, "GENRES", []string{"Pop", "Rock"})
+	if err := UpdateMP4Tags(path, other); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		tags := &mp4tag.MP4Tags{Custom: map[string]string{}}
+		setMultiValue(tags, "ARTISTS", []string{"Simon & Garfunkel", "Artist B", "Artist C"})
+		if err := UpdateMP4Tags(path, tags); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tags, err := ReadMP4Tags(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := CustomValues(tags, "artists"), []string{"Simon & Garfunkel", "Artist B", "Artist C"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ARTISTS = %v, want %v", got, want)
+	}
+	if got := CustomValues(tags, "GENRES"); len(got) != 2 {
+		t.Errorf("other multi-value tag = %v, want 2 values", got)
+	}
+
+	// 改为单个艺术家时清除原有的其余值
+	single := &mp4tag.MP4Tags{Custom: map[string]string{}}
+	setMultiValue(single, "ARTISTS", []string{"Artist D"})
+	if err := UpdateMP4Tags(path, single); err != nil {
+		t.Fatal(err)
+	}
+	tags, _ = ReadMP4Tags(path)
+	if got := CustomValues(tags, "ARTISTS"); !reflect.DeepEqual(got, []string{"Artist D"}) {
+		t.Errorf("ARTISTS = %v, want [Artist D]", got)
+	}
+}
+
+// TestUpdateMP4TagsAtomic 测试写入失败时原文件保持不变且不留下临时文件
+func TestUpdateMP4TagsAtomic(t *testing.T) {
+	// 已有 ilst 且 ftyp 品牌不受支持：拷贝成功，mp4tag 写入失败
+	data := mp4test.Track(5000, mp4test.Ilst(), []byte("chunk-one-chunk-two"))
+	copy(data[8:12], "XXXX")
+	path := filepath.Join(t.TempDir(), "track.m4a")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateMP4Tags(path, &mp4tag.MP4Tags{Title: "Song"}); err == nil {
+		t.Fatal("expected error for unsupported ftyp")
+	}
+	after, _ := os.ReadFile(path)
+	if !bytes.Equal(after, data) {
+		t.Error("original file should be unchanged after a failed write")
+	}
+	if _, err := os.Stat(path + ".part"); !os.IsNotExist(err) {
+		t.Errorf("temporary file should be removed: %v", err)
+	}
+}
+
+// TestWriteReplayGainUnchanged 测试增益值未变化时不重写文件
+func TestWriteReplayGainUnchanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "track.m4a")
+	if err := os.WriteFile(path, mp4test.Track(5000, nil, []byte("chunk-one-chunk-two")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gain := map[string]string{"REPLAYGAIN_TRACK_GAIN": "-6.50 dB", "REPLAYGAIN_ALBUM_GAIN": "-7.00 dB"}
+	if changed, err := WriteReplayGain(path, gain); err != nil || !changed {
+		t.Fatalf("first write = %v, %v", changed, err)
+	}
+	before, _ := os.ReadFile(path)
+	if changed, err := WriteReplayGain(path, gain); err != nil || changed {
+		t.Fatalf("same gain = %v, %v, want no rewrite", changed, err)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, before) {
+		t.Error("file should not be rewritten when gain is unchanged")
+	}
+
+	gain["REPLAYGAIN_ALBUM_GAIN"] = "-7.20 dB"
+	if changed, err := WriteReplayGain(path, gain); err != nil || !changed {
+		t.Fatalf("changed gain = %v, %v", changed, err)
+	}
+	tags, _ := ReadMP4Tags(path)
+	if got := CustomTag(tags, "REPLAYGAIN_ALBUM_GAIN"); got != "-7.20 dB" {
+		t.Errorf("album gain = %q", got)
+	}
+}
+
+// TestSecondaryLanguageTags 测试第二语言名称写入排序字段或自定义字段
+func TestSecondaryLanguageTags(t *testing.T) {
+	defer func(ml structs.MetadataLanguageConfig) { core.Config.MetadataLanguage = ml }(core.Config.MetadataLanguage)
+
+	var meta, secondary structs.AutoGenerated
+	if err := json.Unmarshal([]byte(`{"data":[{"id":"100","attributes":{"name":"ファンタジー","artistName":"宇多田ヒカル"},
+		"relationships":{"tracks":{"data":[{"id":"t1","attributes":{"name":"一曲目","artistName":"宇多田ヒカル","albumName":"ファンタジー","genreNames":["J-Pop"],"trackNumber":1}}]}}}]}`), &meta); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{"data":[{"id":"100","attributes":{"name":"Fantasy","artistName":"Hikaru Utada"},
+		"relationships":{"tracks":{"data":[{"id":"t1","attributes":{"name":"Track One","artistName":"Hikaru Utada","albumName":"Fantasy","genreNames":["J-Pop"],"trackNumber":1}}]}}}]}`), &secondary); err != nil {
+		t.Fatal(err)
+	}
+	meta.Secondary = &secondary
+	job := core.NewJob(core.Options{})
+
+	tags := BuildMP4Tags(job, "", "Alac", &meta, 1, 1)
+	if tags.Title != "一曲目" || tags.TitleSort != "Track One" || tags.ArtistSort != "Hikaru Utada" {
+		t.Errorf("sort tags: title=%q titleSort=%q artistSort=%q", tags.Title, tags.TitleSort, tags.ArtistSort)
+	}
+	if tags.Album != "ファンタジー Alac" || tags.AlbumSort != "Fantasy Alac" || tags.AlbumArtistSort != "Hikaru Utada" {
+		t.Errorf("album sort tags: album=%q albumSort=%q albumArtistSort=%q", tags.Album, tags.AlbumSort, tags.AlbumArtistSort)
+	}
+
+	core.Config.MetadataLanguage.SecondaryTarget = core.SecondaryTargetCustom
+	tags = BuildMP4Tags(job, "", "Alac", &meta, 1, 1)
+	if tags.TitleSort != "一曲目" || tags.Custom["ALT_TITLE"] != "Track One" || tags.Custom["ALT_ALBUM"] != "Fantasy Alac" {
+		t.Errorf("custom tags: titleSort=%q custom=%v", tags.TitleSort, tags.Custom)
+	}
+
+	// 未获取第二语言元数据时与原有标签一致
+	meta.Secondary = nil
+	if tags = BuildMP4Tags(job, "", "Alac", &meta, 1, 1); tags.TitleSort != "一曲目" || tags.Custom["ALT_TITLE"] != "" {
+		t.Errorf("without secondary: titleSort=%q custom=%v", tags.TitleSort, tags.Custom)
+	}
+}
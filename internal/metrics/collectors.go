@@ -0,0 +1,74 @@
+package metrics
+
+import "time"
+
+// Default 全局指标注册表，由 metrics-listen 配置的监听地址对外暴露
+var Default = NewRegistry()
+
+// 延迟直方图的桶上界（秒），覆盖本地 wrapper 的毫秒级到远程 CDN 的数十秒
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// 工作-休息循环状态
+const (
+	WorkRestDisabled = "disabled"
+	WorkRestWorking  = "working"
+	WorkRestResting  = "resting"
+)
+
+var workRestStates = []string{WorkRestDisabled, WorkRestWorking, WorkRestResting}
+
+var (
+	// Tracks 曲目处理结果，result: downloaded/exists/skipped/failed
+	Tracks = Default.NewCounterVec("amdl_tracks_total",
+		"Tracks processed, by codec and result.", "codec", "result")
+
+	// BytesTransferred 下载的字节数，source: segment(runv3)/chunk(runv14)
+	BytesTransferred = Default.NewCounterVec("amdl_bytes_transferred_total",
+		"Bytes downloaded from media servers.", "source")
+
+	// SegmentDuration 单个分段/分块从发起请求到读取完成的耗时
+	SegmentDuration = Default.NewHistogramVec("amdl_segment_download_seconds",
+		"Latency of segment (runv3) and chunk (runv14) downloads.", latencyBuckets, "source")
+
+	// SegmentErrors 分段/分块下载失败次数
+	SegmentErrors = Default.NewCounterVec("amdl_segment_errors_total",
+		"Failed segment and chunk downloads, by reason.", "source", "reason")
+
+	// FfmpegRepairs ffmpeg 检测到损坏并重新编码的次数，result: ok/failed
+	FfmpegRepairs = Default.NewCounterVec("amdl_ffmpeg_repairs_total",
+		"Tracks re-encoded by ffmpeg after a failed integrity check.", "result")
+
+	// HTTPRequests 经由网络客户端发出的 HTTP 请求，code 为状态码或 error
+	HTTPRequests = Default.NewCounterVec("amdl_http_requests_total",
+		"HTTP requests sent by the API and wrapper clients, by host and status code.", "host", "code")
+
+	// WorkRestState 工作-休息循环状态，当前状态为 1，其余为 0
+	WorkRestState = Default.NewGaugeVec("amdl_work_rest_state",
+		"Work-rest cycle state (1 for the current state).", "state")
+
+	// StartTime 进程启动时间（Unix 秒）
+	StartTime = Default.NewGaugeVec("amdl_start_time_seconds",
+		"Start time of the process since unix epoch in seconds.")
+)
+
+func init() {
+	StartTime.Set(float64(time.Now().Unix()))
+	SetWorkRestState(WorkRestDisabled)
+}
+
+// SetWorkRestState 更新工作-休息循环状态
+func SetWorkRestState(state string) {
+	for _, s := range workRestStates {
+		v := 0.0
+		if s == state {
+			v = 1
+		}
+		WorkRestState.Set(v, s)
+	}
+}
+
+// ObserveSegment 记录一次成功的分段/分块下载
+func ObserveSegment(source string, start time.Time, bytes int64) {
+	SegmentDuration.Observe(time.Since(start).Seconds(), source)
+	BytesTransferred.Add(float64(bytes), source)
+}
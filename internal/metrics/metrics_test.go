@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestRegistryWriteText 测试计数器、仪表和直方图的文本格式输出
+func TestRegistryWriteText(t *testing.T) {
+	r := NewRegistry()
+	tracks := r.NewCounterVec("test_tracks_total", "Tracks.", "codec", "result")
+	state := r.NewGaugeVec("test_state", "State.", "state")
+	latency := r.NewHistogramVec("test_latency_seconds", "Latency.", []float64{1, 0.1}, "source")
+
+	tracks.Inc("alac", "downloaded")
+	tracks.Add(2, "alac", "downloaded")
+	tracks.Add(-1, "alac", "downloaded")
+	tracks.Inc("aac", "fail\"ed")
+	state.Set(1, "working")
+	latency.Observe(0.05, "segment")
+	latency.Observe(0.5, "segment")
+	latency.Observe(5, "segment")
+
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		"# HELP test_tracks_total Tracks.\n# TYPE test_tracks_total counter\n",
+		`test_tracks_total{codec="alac",result="downloaded"} 3`,
+		`test_tracks_total{codec="aac",result="fail\"ed"} 1`,
+		"# TYPE test_state gauge\n",
+		`test_state{state="working"} 1`,
+		"# TYPE test_latency_seconds histogram\n",
+		`test_latency_seconds_bucket{source="segment",le="0.1"} 1`,
+		`test_latency_seconds_bucket{source="segment",le="1"} 2`,
+		`test_latency_seconds_bucket{source="segment",le="+Inf"} 3`,
+		`test_latency_seconds_sum{source="segment"} 5.55`,
+		`test_latency_seconds_count{source="segment"} 3`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Output missing %q\n%s", want, out)
+		}
+	}
+
+	// 指标族按注册顺序输出
+	if strings.Index(out, "test_tracks_total") > strings.Index(out, "test_state") {
+		t.Error("Families should be written in registration order")
+	}
+}
+
+// TestHandler 测试 HTTP 处理器的响应类型与内容
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewGaugeVec("test_up", "Up.").Set(1)
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected Content-Type %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "test_up 1\n") {
+		t.Errorf("Unexpected body:\n%s", rec.Body.String())
+	}
+}
+
+// TestWorkRestState 测试工作-休息状态只有一个为 1
+func TestWorkRestState(t *testing.T) {
+	SetWorkRestState(WorkRestResting)
+	for _, s := range workRestStates {
+		want := 0.0
+		if s == WorkRestResting {
+			want = 1
+		}
+		if got := WorkRestState.Value(s); got != want {
+			t.Errorf("state %s = %v, want %v", s, got, want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"main/internal/logger"
+)
+
+// Handler 返回输出 Prometheus 文本格式的 HTTP 处理器
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := r.WriteText(w); err != nil {
+			logger.Debug("[指标] 输出指标失败: %v", err)
+		}
+	})
+}
+
+// Serve 在 addr 上监听并暴露 /metrics，监听失败时立即返回错误
+// 服务在后台运行直到进程退出
+func Serve(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("监听指标地址 %s 失败: %w", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Default.Handler())
+	mux.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/" {
+			http.NotFound(w, req)
+			return
+		}
+		http.Redirect(w, req, "/metrics", http.StatusFound)
+	})
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Warn("[指标] 指标服务已停止: %v", err)
+		}
+	}()
+
+	logger.Info("📈 指标服务已启动: http://%s/metrics", ln.Addr())
+	return nil
+}
@@ -0,0 +1,65 @@
+// Package mp4test 生成测试用的最小 MP4 文件
+package mp4test
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Box 生成 MP4 原子，负载为 payload 依次拼接
+func Box(typ string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	b := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(b, uint32(8+len(body)))
+	copy(b[4:], typ)
+	return append(b, body...)
+}
+
+// FullBox 生成 full box（version/flags + 负载）
+func FullBox(typ string, version byte, flags uint32, payload ...[]byte) []byte {
+	vf := U32(flags)
+	vf[0] = version
+	return Box(typ, append([][]byte{vf}, payload...)...)
+}
+
+// U32 大端 32 位整数
+func U32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+// Mvhd 生成 version 0 的 mvhd 原子，timescale 为 1000 时 duration 单位为毫秒
+func Mvhd(timescale, duration uint32) []byte {
+	return FullBox("mvhd", 0, 0, make([]byte, 8), U32(timescale), U32(duration), make([]byte, 80))
+}
+
+// Ilst 生成只含空 ilst 的 udta 原子
+func Ilst() []byte {
+	return Box("udta", FullBox("meta", 0, 0, Box("ilst")))
+}
+
+// Track 生成 ftyp + moov + mdat 的最小音频文件
+// moov 在 mdat 之前，stco 依次指向 mdat 中的各个 chunk；udta 为 nil 时不包含 udta 原子
+func Track(durationMs uint32, udta []byte, chunks ...[]byte) []byte {
+	ftyp := Box("ftyp", []byte("M4A "), U32(0), []byte("M4A mp42isom"))
+	mvhd := Mvhd(1000, durationMs)
+
+	build := func(base uint32) []byte {
+		offsets := [][]byte{U32(uint32(len(chunks)))}
+		for _, c := range chunks {
+			offsets = append(offsets, U32(base))
+			base += uint32(len(c))
+		}
+		stbl := Box("stbl", FullBox("stco", 0, 0, offsets...))
+		parts := [][]byte{mvhd, Box("trak", Box("mdia", Box("minf", stbl)))}
+		if udta != nil {
+			parts = append(parts, udta)
+		}
+		return Box("moov", parts...)
+	}
+	// 先计算 moov 大小，再确定 mdat 数据的绝对偏移
+	moov := build(0)
+	moov = build(uint32(len(ftyp) + len(moov) + 8))
+	return bytes.Join([][]byte{ftyp, moov, Box("mdat", chunks...)}, nil)
+}
@@ -0,0 +1,291 @@
+package network
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"main/internal/logger"
+	"main/utils/structs"
+)
+
+const (
+	// LimitUnlimited 时段内不限速
+	LimitUnlimited = "unlimited"
+	// LimitPause 时段内暂停下载
+	LimitPause = "pause"
+
+	// pausePollInterval 暂停时段内检查是否恢复的间隔
+	pausePollInterval = 5 * time.Second
+)
+
+// Bandwidth 全局带宽限制器，所有音频/MV 分段下载共享
+// 未调用 InitializeBandwidth 时为不限速
+var Bandwidth = NewBandwidthLimiter(0, nil)
+
+// BandwidthWindow 一个按时刻生效的限速时段
+type BandwidthWindow struct {
+	Start  int   // 开始时刻（从 00:00 起的分钟数）
+	End    int   // 结束时刻（从 00:00 起的分钟数），小于 Start 表示跨越午夜
+	Rate   int64 // 时段内的速率上限（字节/秒），0 表示不限速
+	Paused bool  // 时段内暂停下载
+
+	label string // 日志中显示的时段描述
+}
+
+// contains 判断某一时刻（分钟数）是否落在时段内，Start == End 表示全天
+func (w BandwidthWindow) contains(minute int) bool {
+	if w.Start == w.End {
+		return true
+	}
+	if w.Start < w.End {
+		return minute >= w.Start && minute < w.End
+	}
+	return minute >= w.Start || minute < w.End
+}
+
+// BandwidthLimiter 令牌桶带宽限制器，支持按时段切换速率或暂停
+type BandwidthLimiter struct {
+	mu          sync.Mutex
+	defaultRate int64 // 不在任何时段内时的速率上限（字节/秒），0 表示不限速
+	windows     []BandwidthWindow
+	now         func() time.Time
+	sleep       func(time.Duration)
+
+	tokens     float64   // 当前可用字节数，可为负（表示欠账，需要等待）
+	lastRefill time.Time // 上次补充令牌的时间
+	lastState  string    // 上次生效的限速状态，用于在切换时输出日志
+}
+
+// NewBandwidthLimiter 创建带宽限制器，rate 为默认速率上限（字节/秒），0 表示不限速
+func NewBandwidthLimiter(rate int64, windows []BandwidthWindow) *BandwidthLimiter {
+	return &BandwidthLimiter{
+		defaultRate: rate,
+		windows:     windows,
+		now:         time.Now,
+		sleep:       time.Sleep,
+	}
+}
+
+// InitializeBandwidth 根据配置初始化全局带宽限制器
+func InitializeBandwidth(config *structs.ConfigSet) error {
+	rate, err := ParseBandwidth(config.MaxBandwidth)
+	if err != nil {
+		return fmt.Errorf("max-bandwidth: %w", err)
+	}
+
+	windows := make([]BandwidthWindow, 0, len(config.BandwidthSchedule))
+	for i, w := range config.BandwidthSchedule {
+		window, err := ParseBandwidthWindow(w)
+		if err != nil {
+			return fmt.Errorf("bandwidth-schedule[%d]: %w", i, err)
+		}
+		windows = append(windows, window)
+	}
+
+	Bandwidth = NewBandwidthLimiter(rate, windows)
+	if rate > 0 || len(windows) > 0 {
+		logger.Debug("[限速] 默认速率: %s, 时段规则: %d 条", FormatBandwidth(rate), len(windows))
+	}
+	return nil
+}
+
+// current 返回当前时刻生效的速率和是否暂停，以及用于日志的状态描述
+// 多个时段重叠时以配置中靠前的为准
+func (l *BandwidthLimiter) current(t time.Time) (int64, bool, string) {
+	minute := t.Hour()*60 + t.Minute()
+	for _, w := range l.windows {
+		if w.contains(minute) {
+			if w.Paused {
+				return 0, true, fmt.Sprintf("时段 %s: 暂停", w.label)
+			}
+			return w.Rate, false, fmt.Sprintf("时段 %s: %s", w.label, FormatBandwidth(w.Rate))
+		}
+	}
+	return l.defaultRate, false, "默认: " + FormatBandwidth(l.defaultRate)
+}
+
+// logTransition 限速状态变化时输出一次日志（调用方需持有锁）
+func (l *BandwidthLimiter) logTransition(state string) {
+	if l.lastState != "" && l.lastState != state {
+		logger.Info("[限速] 切换到%s", state)
+	}
+	l.lastState = state
+}
+
+// WaitForWindow 处于暂停时段时阻塞，直到时段结束
+// 在发起新的下载请求前调用；已经开始的传输由 Wait 在读取时暂停
+func (l *BandwidthLimiter) WaitForWindow() {
+	for {
+		l.mu.Lock()
+		_, paused, state := l.current(l.now())
+		l.logTransition(state)
+		l.mu.Unlock()
+		if !paused {
+			return
+		}
+		l.sleep(pausePollInterval)
+	}
+}
+
+// Wait 消耗 n 字节的额度，超过当前速率时阻塞相应时间
+// 处于暂停时段时先阻塞到时段结束，仍在进行的传输也随之暂停
+func (l *BandwidthLimiter) Wait(n int) {
+	if n <= 0 {
+		return
+	}
+	l.WaitForWindow()
+
+	l.mu.Lock()
+	now := l.now()
+	rate, _, state := l.current(now)
+	l.logTransition(state)
+	if rate <= 0 {
+		l.lastRefill = now
+		l.tokens = 0
+		l.mu.Unlock()
+		return
+	}
+
+	// 补充令牌，桶容量为一秒的额度
+	if !l.lastRefill.IsZero() {
+		l.tokens += now.Sub(l.lastRefill).Seconds() * float64(rate)
+	}
+	l.lastRefill = now
+	if l.tokens > float64(rate) {
+		l.tokens = float64(rate)
+	}
+
+	l.tokens -= float64(n)
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / float64(rate) * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if delay > 0 {
+		l.sleep(delay)
+	}
+}
+
+// limitedReader 读取时按全局带宽限制器限速
+type limitedReader struct {
+	r       io.Reader
+	limiter *BandwidthLimiter
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	n, err := lr.r.Read(p)
+	lr.limiter.Wait(n)
+	return n, err
+}
+
+// LimitReader 返回受全局带宽限制的 Reader
+func LimitReader(r io.Reader) io.Reader {
+	return &limitedReader{r: r, limiter: Bandwidth}
+}
+
+// ParseBandwidth 解析速率字符串，如 "8MB/s"、"512KB/s"、"20Mbps"
+// 空字符串、"0" 或 "unlimited" 表示不限速，返回 0
+func ParseBandwidth(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" || strings.EqualFold(s, LimitUnlimited) {
+		return 0, nil
+	}
+
+	lower := strings.ToLower(s)
+	bits := false
+	switch {
+	case strings.HasSuffix(lower, "bps") && !strings.HasSuffix(lower, "/s"):
+		// 以比特计的速率，如 20Mbps
+		bits = true
+		lower = strings.TrimSuffix(lower, "bps")
+	default:
+		lower = strings.TrimSuffix(lower, "/s")
+		lower = strings.TrimSuffix(lower, "b")
+	}
+
+	// 字节速率按 1024 进位，比特速率按网络惯例 1000 进位
+	base := 1024.0
+	if bits {
+		base = 1000
+	}
+	lower = strings.TrimSuffix(lower, "i")
+	multiplier := 1.0
+	if n := len(lower); n > 0 {
+		switch lower[n-1] {
+		case 'k':
+			multiplier = base
+		case 'm':
+			multiplier = base * base
+		case 'g':
+			multiplier = base * base * base
+		}
+		if multiplier > 1 {
+			lower = lower[:n-1]
+		}
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(lower), 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("无法解析速率 %q（示例: 8MB/s, 512KB/s, 20Mbps）", s)
+	}
+	rate := value * multiplier
+	if bits {
+		rate /= 8
+	}
+	return int64(rate), nil
+}
+
+// FormatBandwidth 将速率格式化为易读字符串
+func FormatBandwidth(rate int64) string {
+	switch {
+	case rate <= 0:
+		return "不限速"
+	case rate >= 1<<20:
+		return fmt.Sprintf("%.1fMB/s", float64(rate)/(1<<20))
+	case rate >= 1<<10:
+		return fmt.Sprintf("%.1fKB/s", float64(rate)/(1<<10))
+	default:
+		return fmt.Sprintf("%dB/s", rate)
+	}
+}
+
+// ParseBandwidthWindow 解析一条时段配置
+func ParseBandwidthWindow(cfg structs.BandwidthWindowConfig) (BandwidthWindow, error) {
+	start, err := parseClock(cfg.Start)
+	if err != nil {
+		return BandwidthWindow{}, fmt.Errorf("start: %w", err)
+	}
+	end, err := parseClock(cfg.End)
+	if err != nil {
+		return BandwidthWindow{}, fmt.Errorf("end: %w", err)
+	}
+
+	window := BandwidthWindow{
+		Start: start,
+		End:   end,
+		label: fmt.Sprintf("%s-%s", strings.TrimSpace(cfg.Start), strings.TrimSpace(cfg.End)),
+	}
+	if strings.EqualFold(strings.TrimSpace(cfg.Limit), LimitPause) {
+		window.Paused = true
+		return window, nil
+	}
+	window.Rate, err = ParseBandwidth(cfg.Limit)
+	if err != nil {
+		return BandwidthWindow{}, fmt.Errorf("limit: %w", err)
+	}
+	return window, nil
+}
+
+// parseClock 解析 "HH:MM" 格式的时刻，返回从 00:00 起的分钟数
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("无法解析时刻 %q（格式 HH:MM）", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,128 @@
+package network
+
+import (
+	"testing"
+	"time"
+
+	"main/utils/structs"
+)
+
+// TestParseBandwidth 测试速率字符串解析
+func TestParseBandwidth(t *testing.T) {
+	cases := []struct {
+		in   string
+		want int64
+	}{
+		{"", 0},
+		{"0", 0},
+		{"unlimited", 0},
+		{"8MB/s", 8 << 20},
+		{"8mb", 8 << 20},
+		{"512KB/s", 512 << 10},
+		{"512KiB/s", 512 << 10},
+		{"1.5M", 3 << 19},
+		{"1GB/s", 1 << 30},
+		{"2048", 2048},
+		{"20Mbps", 2500000},
+	}
+	for _, c := range cases {
+		got, err := ParseBandwidth(c.in)
+		if err != nil {
+			t.Errorf("ParseBandwidth(%q) error: %v", c.in, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("ParseBandwidth(%q) = %d, want %d", c.in, got, c.want)
+		}
+	}
+
+	for _, bad := range []string{"fast", "-1MB/s", "MB/s"} {
+		if _, err := ParseBandwidth(bad); err == nil {
+			t.Errorf("ParseBandwidth(%q) should fail", bad)
+		}
+	}
+}
+
+// TestBandwidthSchedule 测试时段匹配（含跨午夜时段与暂停）
+func TestBandwidthSchedule(t *testing.T) {
+	night, err := ParseBandwidthWindow(structs.BandwidthWindowConfig{Start: "01:00", End: "07:00", Limit: "unlimited"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	evening, err := ParseBandwidthWindow(structs.BandwidthWindowConfig{Start: "22:30", End: "00:30", Limit: "pause"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseBandwidthWindow(structs.BandwidthWindowConfig{Start: "25:00", End: "07:00"}); err == nil {
+		t.Error("invalid start time should fail")
+	}
+
+	l := NewBandwidthLimiter(2<<20, []BandwidthWindow{night, evening})
+	cases := []struct {
+		clock  string
+		rate   int64
+		paused bool
+	}{
+		{"00:59", 2 << 20, false},
+		{"01:00", 0, false},
+		{"06:59", 0, false},
+		{"07:00", 2 << 20, false},
+		{"22:29", 2 << 20, false},
+		{"23:45", 0, true},
+		{"00:15", 0, true},
+		{"00:30", 2 << 20, false},
+	}
+	for _, c := range cases {
+		at, _ := time.Parse("15:04", c.clock)
+		rate, paused, _ := l.current(at)
+		if rate != c.rate || paused != c.paused {
+			t.Errorf("at %s: rate=%d paused=%v, want rate=%d paused=%v", c.clock, rate, paused, c.rate, c.paused)
+		}
+	}
+}
+
+// TestBandwidthLimiterWait 测试令牌桶限速效果
+func TestBandwidthLimiterWait(t *testing.T) {
+	const rate = 100 << 10
+	l := NewBandwidthLimiter(rate, nil)
+
+	start := time.Now()
+	// 首秒额度为 0，传输 0.2 秒的数据量应至少等待约 0.2 秒
+	for i := 0; i < 20; i++ {
+		l.Wait(rate / 100)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("limited transfer took %v, want >= ~200ms", elapsed)
+	}
+
+	unlimited := NewBandwidthLimiter(0, nil)
+	start = time.Now()
+	unlimited.Wait(1 << 30)
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("unlimited Wait took %v", elapsed)
+	}
+}
+
+// TestBandwidthLimiterWaitPaused 测试暂停时段内进行中的读取会阻塞到时段结束
+func TestBandwidthLimiterWaitPaused(t *testing.T) {
+	evening, err := ParseBandwidthWindow(structs.BandwidthWindowConfig{Start: "22:30", End: "00:30", Limit: "pause"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewBandwidthLimiter(0, []BandwidthWindow{evening})
+	clock, _ := time.Parse("15:04", "23:45")
+	var slept time.Duration
+	l.now = func() time.Time { return clock }
+	l.sleep = func(d time.Duration) {
+		slept += d
+		clock = clock.Add(d)
+	}
+
+	l.Wait(1 << 10)
+	if got := clock.Format("15:04"); got != "00:30" {
+		t.Errorf("Wait returned at %s, want 00:30", got)
+	}
+	if slept < 45*time.Minute {
+		t.Errorf("slept %v during pause window, want >= 45m", slept)
+	}
+}
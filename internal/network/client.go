@@ -61,6 +61,11 @@ func InitializeClients(config *structs.ConfigSet) {
 		}
 		logger.Info("🔌 本地 wrapper 优化: 未启用（使用默认配置）")
 	}
+
+	// 统计 API 与 wrapper 请求的数量和状态码（API 调用使用 http.DefaultClient）
+	DefaultClient.Transport = instrument(DefaultClient.Transport)
+	LocalWrapperClient.Transport = instrument(LocalWrapperClient.Transport)
+	http.DefaultClient.Transport = instrument(http.DefaultClient.Transport)
 }
 
 // initializeLocalWrapperClient 初始化本地 wrapper 服务专用客户端（优化配置）
@@ -0,0 +1,35 @@
+package network
+
+import (
+	"net/http"
+	"strconv"
+
+	"main/internal/metrics"
+)
+
+// instrumentedTransport 统计请求数量与状态码的 RoundTripper
+type instrumentedTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip 发送请求并按主机和状态码计数
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	metrics.HTTPRequests.Inc(req.URL.Host, code)
+	return resp, err
+}
+
+// instrument 为 RoundTripper 添加指标统计，已包装过的不重复包装
+func instrument(rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	if _, ok := rt.(*instrumentedTransport); ok {
+		return rt
+	}
+	return &instrumentedTransport{base: rt}
+}
@@ -0,0 +1,160 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"main/utils/structs"
+)
+
+// newHook 根据配置创建钩子
+func newHook(cfg structs.NotificationConfig) (Hook, error) {
+	switch strings.ToLower(cfg.Type) {
+	case "webhook":
+		if cfg.URL == "" {
+			return nil, errors.New("webhook 需要配置 url")
+		}
+		return &webhookHook{url: cfg.URL, headers: cfg.Headers}, nil
+	case "exec":
+		if cfg.Command == "" {
+			return nil, errors.New("exec 需要配置 command")
+		}
+		return &execHook{command: cfg.Command, args: cfg.Args}, nil
+	case "notify-send":
+		return &notifySendHook{urgency: cfg.Urgency}, nil
+	default:
+		return nil, fmt.Errorf("未知的通知类型 '%s'（可选: webhook, exec, notify-send）", cfg.Type)
+	}
+}
+
+// webhookHook 以 JSON 请求体 POST 事件
+type webhookHook struct {
+	url     string
+	headers map[string]string
+}
+
+func (h *webhookHook) Name() string { return "webhook " + h.url }
+
+// Fire 发送事件，非 2xx 响应视为失败
+func (h *webhookHook) Fire(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("编码事件失败: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "apple-music-downloader")
+	for k, v := range h.headers {
+		req.Header.Set(k, os.ExpandEnv(v))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("服务器返回 HTTP %s", resp.Status)
+	}
+	return nil
+}
+
+// execHook 执行外部命令，事件信息通过 AMDL_* 环境变量和标准输入（JSON）传递
+type execHook struct {
+	command string
+	args    []string
+}
+
+func (h *execHook) Name() string { return "exec " + h.command }
+
+// Fire 执行命令，非零退出码视为失败
+func (h *execHook) Fire(ctx context.Context, e Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("编码事件失败: %w", err)
+	}
+	cmd := exec.CommandContext(ctx, h.command, h.args...)
+	cmd.Env = append(os.Environ(), eventEnv(e, payload)...)
+	cmd.Stdin = bytes.NewReader(payload)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// eventEnv 生成传递给 exec 钩子的环境变量
+func eventEnv(e Event, payload []byte) []string {
+	env := []string{
+		"AMDL_EVENT=" + e.Type,
+		"AMDL_TIME=" + e.Time.Format("2006-01-02T15:04:05Z07:00"),
+		"AMDL_TITLE=" + e.Title,
+		"AMDL_MESSAGE=" + e.Message,
+		"AMDL_ACCOUNT=" + e.Account,
+		"AMDL_PATH=" + e.Path,
+		"AMDL_ERROR=" + e.Error,
+		"AMDL_PAYLOAD=" + string(payload),
+	}
+	if a := e.Album; a != nil {
+		env = append(env,
+			"AMDL_ALBUM_ID="+a.ID,
+			"AMDL_ALBUM_TITLE="+a.Title,
+			"AMDL_ALBUM_URL="+a.URL,
+			"AMDL_TRACKS_DOWNLOADED="+strconv.Itoa(a.Tracks.Downloaded),
+			"AMDL_TRACKS_FAILED="+strconv.Itoa(a.Tracks.Failed),
+		)
+	}
+	if b := e.Batch; b != nil {
+		env = append(env,
+			"AMDL_ALBUMS_COMPLETED="+strconv.Itoa(b.AlbumsCompleted),
+			"AMDL_ALBUMS_FAILED="+strconv.Itoa(b.AlbumsFailed),
+			"AMDL_TRACKS_TOTAL="+strconv.Itoa(b.TracksTotal),
+			"AMDL_TRACKS_ERROR="+strconv.Itoa(b.TracksError),
+		)
+	}
+	return env
+}
+
+// notifySendHook 通过 freedesktop notify-send 显示桌面通知
+type notifySendHook struct {
+	urgency string
+}
+
+func (h *notifySendHook) Name() string { return "notify-send" }
+
+// Fire 调用 notify-send；未指定紧急程度时失败类事件使用 critical
+func (h *notifySendHook) Fire(ctx context.Context, e Event) error {
+	urgency := h.urgency
+	if urgency == "" {
+		urgency = "normal"
+		switch e.Type {
+		case EventAlbumFailed, EventTokenExpired, EventDiskSpaceLow:
+			urgency = "critical"
+		}
+	}
+	cmd := exec.CommandContext(ctx, "notify-send",
+		"--app-name=Apple Music Downloader", "--urgency="+urgency, e.Title, e.Message)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
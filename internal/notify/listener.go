@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"sync"
+
+	"main/internal/progress"
+)
+
+// AlbumListener 统计单个专辑的曲目结果，用于专辑完成/失败通知
+type AlbumListener struct {
+	mu    sync.Mutex
+	stats TrackStats
+}
+
+// Watch 派生一个带统计监听器的通知器，供单个专辑使用
+func Watch(notifier *progress.ProgressNotifier) (*progress.ProgressNotifier, *AlbumListener) {
+	l := &AlbumListener{}
+	forked := notifier.Fork()
+	forked.AddListener(l)
+	return forked, l
+}
+
+// Stats 返回当前统计
+func (l *AlbumListener) Stats() TrackStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stats
+}
+
+// OnProgress 处理状态事件：本地已存在、跳过、重编码完成
+func (l *AlbumListener) OnProgress(event progress.ProgressEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	switch event.Stage {
+	case "exists":
+		l.stats.Exists++
+	case "skipped":
+		l.stats.Skipped++
+	case "complete":
+		l.stats.Downloaded++
+	}
+}
+
+// OnComplete 曲目下载完成
+func (l *AlbumListener) OnComplete(trackIndex int) {
+	l.mu.Lock()
+	l.stats.Downloaded++
+	l.mu.Unlock()
+}
+
+// OnError 曲目下载失败
+func (l *AlbumListener) OnError(trackIndex int, err error) {
+	l.mu.Lock()
+	l.stats.Failed++
+	l.mu.Unlock()
+}
@@ -0,0 +1,312 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"main/internal/logger"
+	"main/utils/structs"
+)
+
+// 事件类型（配置 events 中使用的名称）
+const (
+	EventAlbumCompleted = "album-completed"
+	EventAlbumFailed    = "album-failed"
+	EventBatchFinished  = "batch-finished"
+	EventTokenExpired   = "token-expired"
+	EventDiskSpaceLow   = "disk-space-low"
+)
+
+// AllEvents 所有支持的事件
+var AllEvents = []string{EventAlbumCompleted, EventAlbumFailed, EventBatchFinished, EventTokenExpired, EventDiskSpaceLow}
+
+// defaultTimeout 未配置 timeout-sec 时单次通知的超时
+const defaultTimeout = 10 * time.Second
+
+// limitWindow 同一限流键的事件在该时间内只发送一次（如令牌失效、磁盘空间不足）
+const limitWindow = 30 * time.Minute
+
+// Event 通知事件，webhook 的请求体与 exec 的标准输入均为其 JSON 编码
+type Event struct {
+	Type    string     `json:"event"`
+	Time    time.Time  `json:"time"`
+	Title   string     `json:"title"`
+	Message string     `json:"message"`
+	Album   *AlbumInfo `json:"album,omitempty"`
+	Batch   *BatchInfo `json:"batch,omitempty"`
+	Account string     `json:"account,omitempty"`
+	Path    string     `json:"path,omitempty"`
+	Error   string     `json:"error,omitempty"`
+}
+
+// AlbumInfo 专辑事件的附加信息
+type AlbumInfo struct {
+	ID     string     `json:"id,omitempty"`
+	Title  string     `json:"title"`
+	URL    string     `json:"url"`
+	Index  int        `json:"index"` // 在批量任务中的编号
+	Total  int        `json:"total"` // 批量任务总数
+	Tracks TrackStats `json:"tracks"`
+}
+
+// TrackStats 专辑内曲目结果统计
+type TrackStats struct {
+	Downloaded int `json:"downloaded"`
+	Exists     int `json:"exists"`
+	Skipped    int `json:"skipped"`
+	Failed     int `json:"failed"`
+}
+
+// BatchInfo 批量任务结束事件的附加信息
+type BatchInfo struct {
+	AlbumsCompleted int     `json:"albums_completed"`
+	AlbumsFailed    int     `json:"albums_failed"`
+	TracksTotal     int     `json:"tracks_total"`
+	TracksSuccess   int     `json:"tracks_success"`
+	TracksError     int     `json:"tracks_error"`
+	Warnings        int     `json:"warnings"`
+	DurationSec     float64 `json:"duration_sec"`
+	Interrupted     bool    `json:"interrupted,omitempty"` // 收到中断信号提前结束
+}
+
+// Hook 通知钩子
+type Hook interface {
+	Name() string
+	Fire(ctx context.Context, e Event) error
+}
+
+// subscription 钩子及其订阅的事件
+type subscription struct {
+	hook    Hook
+	events  map[string]bool // 为空表示全部事件
+	timeout time.Duration
+}
+
+// Dispatcher 将事件异步分发给订阅的钩子
+type Dispatcher struct {
+	subs  []subscription
+	wg    sync.WaitGroup
+	start time.Time
+
+	mu        sync.Mutex
+	lastSent  map[string]time.Time // 限流键 -> 上次发送时间
+	completed int
+	failed    int
+}
+
+// New 根据配置创建分发器
+func New(cfgs []structs.NotificationConfig) (*Dispatcher, error) {
+	d := &Dispatcher{start: time.Now(), lastSent: make(map[string]time.Time)}
+	for i, cfg := range cfgs {
+		hook, err := newHook(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("notifications[%d]: %w", i, err)
+		}
+		sub := subscription{hook: hook, timeout: defaultTimeout}
+		if cfg.TimeoutSec > 0 {
+			sub.timeout = time.Duration(cfg.TimeoutSec) * time.Second
+		}
+		if len(cfg.Events) > 0 {
+			sub.events = make(map[string]bool)
+			for _, name := range cfg.Events {
+				if !IsEvent(name) {
+					return nil, fmt.Errorf("notifications[%d]: 未知事件 '%s'（可选: %s）", i, name, strings.Join(AllEvents, ", "))
+				}
+				sub.events[name] = true
+			}
+		}
+		d.subs = append(d.subs, sub)
+	}
+	return d, nil
+}
+
+// IsEvent 判断事件名称是否有效
+func IsEvent(name string) bool {
+	for _, e := range AllEvents {
+		if e == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Emit 异步发送事件，发送失败只记录警告
+func (d *Dispatcher) Emit(e Event) {
+	if d == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	d.mu.Lock()
+	switch e.Type {
+	case EventAlbumCompleted:
+		d.completed++
+	case EventAlbumFailed:
+		d.failed++
+	}
+	d.mu.Unlock()
+
+	for _, sub := range d.subs {
+		if sub.events != nil && !sub.events[e.Type] {
+			continue
+		}
+		d.wg.Add(1)
+		go func(sub subscription) {
+			defer d.wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), sub.timeout)
+			defer cancel()
+			if err := sub.hook.Fire(ctx, e); err != nil {
+				logger.With("event", e.Type, "hook", sub.hook.Name()).Warn("[通知] %s 发送失败: %v", sub.hook.Name(), err)
+			}
+		}(sub)
+	}
+}
+
+// EmitLimited 与 Emit 相同，但同一 key 的事件在 30 分钟内只发送一次
+// 用于会反复触发的事件，如多个曲目同时发现令牌失效
+func (d *Dispatcher) EmitLimited(key string, e Event) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	now := time.Now()
+	if last, ok := d.lastSent[key]; ok && now.Sub(last) < limitWindow {
+		d.mu.Unlock()
+		return
+	}
+	d.lastSent[key] = now
+	d.mu.Unlock()
+	d.Emit(e)
+}
+
+// Batch 根据已发送的专辑事件与曲目计数生成批量任务统计
+func (d *Dispatcher) Batch(counter structs.Counter) *BatchInfo {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return &BatchInfo{
+		AlbumsCompleted: d.completed,
+		AlbumsFailed:    d.failed,
+		TracksTotal:     counter.Total,
+		TracksSuccess:   counter.Success,
+		TracksError:     counter.Error,
+		Warnings:        counter.Unavailable + counter.NotSong,
+		DurationSec:     time.Since(d.start).Round(time.Second).Seconds(),
+	}
+}
+
+// Wait 等待已发出的通知完成，最多等待 timeout
+func (d *Dispatcher) Wait(timeout time.Duration) {
+	if d == nil {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		logger.Warn("[通知] 等待通知发送超时，部分通知可能未送达")
+	}
+}
+
+// 全局分发器，未配置通知时为 nil（所有方法均可安全调用）
+var global *Dispatcher
+
+// Init 根据配置初始化全局分发器
+func Init(cfgs []structs.NotificationConfig) error {
+	if len(cfgs) == 0 {
+		global = nil
+		return nil
+	}
+	d, err := New(cfgs)
+	if err != nil {
+		return err
+	}
+	global = d
+	logger.Debug("[通知] 已配置 %d 个通知钩子", len(d.subs))
+	return nil
+}
+
+// Emit 通过全局分发器发送事件
+func Emit(e Event) {
+	global.Emit(e)
+}
+
+// EmitLimited 通过全局分发器发送限流事件
+func EmitLimited(key string, e Event) {
+	global.EmitLimited(key, e)
+}
+
+// BatchFinished 发送批量任务结束事件
+func BatchFinished(counter structs.Counter) {
+	emitBatch(counter, false)
+}
+
+// BatchInterrupted 发送批量任务被中断的结束事件（事件类型同 batch-finished，batch.interrupted 为 true）
+func BatchInterrupted(counter structs.Counter) {
+	emitBatch(counter, true)
+}
+
+func emitBatch(counter structs.Counter, interrupted bool) {
+	batch := global.Batch(counter)
+	if batch == nil {
+		return
+	}
+	batch.Interrupted = interrupted
+	e := Event{
+		Type:  EventBatchFinished,
+		Title: "下载任务已结束",
+		Message: fmt.Sprintf("专辑 完成 %d / 失败 %d，曲目 成功 %d/%d，错误 %d",
+			batch.AlbumsCompleted, batch.AlbumsFailed, batch.TracksSuccess, batch.TracksTotal, batch.TracksError),
+		Batch: batch,
+	}
+	switch {
+	case interrupted:
+		e.Title = "下载任务已中断"
+	case batch.AlbumsFailed > 0 || batch.TracksError > 0:
+		e.Title = "下载任务已结束（有失败）"
+	}
+	Emit(e)
+}
+
+// TokenExpired 发送令牌失效事件（同一账户 30 分钟内只发送一次）
+func TokenExpired(account string, err error) {
+	e := Event{
+		Type:    EventTokenExpired,
+		Title:   "令牌可能已失效",
+		Message: fmt.Sprintf("账户 [%s] 的令牌可能已失效，请更新配置", account),
+		Account: account,
+	}
+	if account == "" {
+		e.Message = "开发者令牌可能已失效"
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	EmitLimited(EventTokenExpired+":"+account, e)
+}
+
+// DiskSpaceLow 发送磁盘空间不足事件（同一目录 30 分钟内只发送一次）
+func DiskSpaceLow(path, message string) {
+	EmitLimited(EventDiskSpaceLow+":"+path, Event{
+		Type:    EventDiskSpaceLow,
+		Title:   "磁盘空间不足",
+		Message: message,
+		Path:    path,
+	})
+}
+
+// Close 等待已发出的通知完成（最多 timeout）
+func Close(timeout time.Duration) {
+	global.Wait(timeout)
+}
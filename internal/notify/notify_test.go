@@ -0,0 +1,216 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"main/internal/progress"
+	"main/utils/structs"
+)
+
+// webhookRecorder 本地 webhook 接收端，记录收到的事件
+type webhookRecorder struct {
+	mu     sync.Mutex
+	events []Event
+	auth   []string
+}
+
+func (r *webhookRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var e Event
+	if err := json.NewDecoder(req.Body).Decode(&e); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.mu.Lock()
+	r.events = append(r.events, e)
+	r.auth = append(r.auth, req.Header.Get("Authorization"))
+	r.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *webhookRecorder) received() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Event(nil), r.events...)
+}
+
+// TestWebhookDelivery 测试 webhook 请求体、请求头和事件过滤
+func TestWebhookDelivery(t *testing.T) {
+	rec := &webhookRecorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	t.Setenv("AMDL_TEST_TOKEN", "secret")
+	d, err := New([]structs.NotificationConfig{{
+		Type:    "webhook",
+		URL:     srv.URL,
+		Events:  []string{EventAlbumFailed, EventBatchFinished},
+		Headers: map[string]string{"Authorization": "Bearer ${AMDL_TEST_TOKEN}"},
+	}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	d.Emit(Event{Type: EventAlbumCompleted, Title: "done"})
+	d.Emit(Event{
+		Type:  EventAlbumFailed,
+		Title: "failed",
+		Album: &AlbumInfo{ID: "1440", Title: "Album", Tracks: TrackStats{Downloaded: 9, Failed: 1}},
+		Error: "1 首曲目失败",
+	})
+	d.Wait(5 * time.Second)
+
+	events := rec.received()
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 delivered event, got %d", len(events))
+	}
+	e := events[0]
+	if e.Type != EventAlbumFailed || e.Album == nil || e.Album.ID != "1440" || e.Album.Tracks.Failed != 1 {
+		t.Errorf("Unexpected payload: %+v", e)
+	}
+	if e.Time.IsZero() {
+		t.Error("Event time should be set")
+	}
+	if rec.auth[0] != "Bearer secret" {
+		t.Errorf("Header not expanded: %q", rec.auth[0])
+	}
+
+	// 未订阅的专辑完成事件也计入批量统计
+	batch := d.Batch(structs.Counter{Total: 10, Success: 9, Error: 1, NotSong: 2})
+	if batch.AlbumsCompleted != 1 || batch.AlbumsFailed != 1 || batch.TracksError != 1 || batch.Warnings != 2 {
+		t.Errorf("Unexpected batch stats: %+v", batch)
+	}
+}
+
+// TestBatchInterrupted 测试中断时发送的批量任务事件，以及 Close 等待事件送达
+func TestBatchInterrupted(t *testing.T) {
+	rec := &webhookRecorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	d, err := New([]structs.NotificationConfig{{Type: "webhook", URL: srv.URL, Events: []string{EventBatchFinished}}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	global = d
+	defer func() { global = nil }()
+
+	BatchInterrupted(structs.Counter{Total: 4, Success: 2})
+	Close(5 * time.Second)
+
+	events := rec.received()
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 delivered event, got %d", len(events))
+	}
+	if e := events[0]; e.Type != EventBatchFinished || e.Batch == nil || !e.Batch.Interrupted || e.Title != "下载任务已中断" {
+		t.Errorf("Unexpected payload: %+v", e)
+	}
+}
+
+// TestWebhookFailure 测试非 2xx 响应返回错误
+func TestWebhookFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	hook := &webhookHook{url: srv.URL}
+	if err := hook.Fire(t.Context(), Event{Type: EventBatchFinished}); err == nil {
+		t.Error("Expected error for HTTP 500")
+	}
+}
+
+// TestEmitLimited 测试限流事件在窗口内只发送一次
+func TestEmitLimited(t *testing.T) {
+	rec := &webhookRecorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	d, err := New([]structs.NotificationConfig{{Type: "webhook", URL: srv.URL}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		d.EmitLimited("token-expired:cn", Event{Type: EventTokenExpired, Account: "cn"})
+	}
+	d.EmitLimited("token-expired:us", Event{Type: EventTokenExpired, Account: "us"})
+	d.Wait(5 * time.Second)
+
+	if got := len(rec.received()); got != 2 {
+		t.Errorf("Expected 2 events (one per key), got %d", got)
+	}
+}
+
+// TestExecHook 测试 exec 钩子的环境变量与标准输入
+func TestExecHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sh")
+	}
+	out := filepath.Join(t.TempDir(), "out.txt")
+	hook := &execHook{
+		command: "sh",
+		args:    []string{"-c", `printf '%s|%s|' "$AMDL_EVENT" "$AMDL_ALBUM_ID" > "$0"; cat >> "$0"`, out},
+	}
+	e := Event{Type: EventAlbumCompleted, Album: &AlbumInfo{ID: "1440", Title: "Album"}}
+	if err := hook.Fire(t.Context(), e); err != nil {
+		t.Fatalf("Fire failed: %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.SplitN(string(data), "|", 3)
+	if len(parts) != 3 || parts[0] != EventAlbumCompleted || parts[1] != "1440" {
+		t.Fatalf("Unexpected env output: %q", data)
+	}
+	var payload Event
+	if err := json.Unmarshal([]byte(parts[2]), &payload); err != nil || payload.Album.Title != "Album" {
+		t.Errorf("Unexpected stdin payload: %q (%v)", parts[2], err)
+	}
+
+	failing := &execHook{command: "sh", args: []string{"-c", "echo boom >&2; exit 3"}}
+	if err := failing.Fire(t.Context(), e); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Expected error with stderr, got %v", err)
+	}
+}
+
+// TestNewValidation 测试配置错误
+func TestNewValidation(t *testing.T) {
+	cases := []structs.NotificationConfig{
+		{Type: "webhook"},
+		{Type: "exec"},
+		{Type: "email"},
+		{Type: "notify-send", Events: []string{"album-done"}},
+	}
+	for _, c := range cases {
+		if _, err := New([]structs.NotificationConfig{c}); err == nil {
+			t.Errorf("Expected error for %+v", c)
+		}
+	}
+}
+
+// TestAlbumListener 测试专辑曲目结果统计
+func TestAlbumListener(t *testing.T) {
+	notifier, stats := Watch(progress.NewNotifier())
+	notifier.NotifyComplete(0)
+	notifier.NotifyStatus(1, "✅ 本地已存在", "exists")
+	notifier.NotifyStatus(2, "已跳过", "skipped")
+	notifier.NotifyStatus(3, "重编码完成", "complete")
+	notifier.NotifyStatus(4, "正在检测...", "check")
+	notifier.NotifyError(5, errors.New("下载失败"))
+
+	want := TrackStats{Downloaded: 2, Exists: 1, Skipped: 1, Failed: 1}
+	if got := stats.Stats(); got != want {
+		t.Errorf("Stats = %+v, want %+v", got, want)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"main/internal/core"
 	"main/internal/logger"
+	"main/internal/quality"
 	"main/utils/structs"
 	"net"
 	"net/http"
@@ -23,7 +24,7 @@ import (
 )
 
 // ExtractMvAudio extracts the best audio stream URL from a music video's master m3u8
-func ExtractMvAudio(c string) (string, error) {
+func ExtractMvAudio(opts core.Options, c string) (string, error) {
 	MediaUrl, err := url.Parse(c)
 	if err != nil {
 		return "", err
@@ -48,9 +49,9 @@ func ExtractMvAudio(c string) (string, error) {
 	audio := from.(*m3u8.MasterPlaylist)
 
 	var audioPriority = []string{"audio-atmos", "audio-ac3", "audio-stereo-256"}
-	if *core.Mv_audio_type == "ac3" {
+	if opts.MvAudioType == "ac3" {
 		audioPriority = []string{"audio-ac3", "audio-stereo-256"}
-	} else if *core.Mv_audio_type == "aac" {
+	} else if opts.MvAudioType == "aac" {
 		audioPriority = []string{"audio-stereo-256"}
 	}
 
@@ -135,33 +136,20 @@ func formatAvailability(available bool, quality string) string {
 }
 
 // ExtractMedia extracts the best media stream URL and quality info from a master m3u8
-func ExtractMedia(b string, more_mode bool) (string, string, string, error) {
-	masterUrl, err := url.Parse(b)
-	if err != nil {
-		return "", "", "", err
-	}
-	resp, err := http.Get(b)
-	if err != nil {
-		return "", "", "", err
+func ExtractMedia(opts core.Options, b string, more_mode bool) (string, string, string, error) {
+	streamUrl, choice, qualityForDisplay, err := SelectMedia(opts, b, more_mode)
+	if err != nil || choice.Variant == nil {
+		return streamUrl, "", qualityForDisplay, err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return "", "", "", errors.New(resp.Status)
-	}
-	body, err := io.ReadAll(resp.Body)
+	return streamUrl, choice.Variant.Label(), qualityForDisplay, nil
+}
+
+// SelectMedia 按音质策略（opts.Rules）从主播放列表中选择档位，返回档位地址、选择结果（含原因）与可用的最高音质
+func SelectMedia(opts core.Options, b string, more_mode bool) (string, quality.Choice, string, error) {
+	masterUrl, master, err := decodeMaster(b)
 	if err != nil {
-		return "", "", "", err
-	}
-	masterString := string(body)
-	from, listType, err := m3u8.DecodeFrom(strings.NewReader(masterString), true)
-	if err != nil || listType != m3u8.MASTER {
-		return "", "", "", errors.New("m3u8 not of master type")
+		return "", quality.Choice{}, "", err
 	}
-	master := from.(*m3u8.MasterPlaylist)
-	var streamUrl *url.URL
-	sort.Slice(master.Variants, func(i, j int) bool {
-		return master.Variants[i].AverageBandwidth > master.Variants[j].AverageBandwidth
-	})
 
 	var hasAAC, hasLossless, hasHiRes, hasAtmos, hasDolbyAudio bool
 	var aacQuality, losslessQuality, hiResQuality, atmosQuality, dolbyAudioQuality string
@@ -255,13 +243,11 @@ func ExtractMedia(b string, more_mode bool) (string, string, string, error) {
 		logger.Debug("Dolby Audio     : %s", formatAvailability(hasDolbyAudio, dolbyAudioQuality))
 		logger.Debug("------------------------")
 
-		return "", "", "", nil
+		return "", quality.Choice{}, "", nil
 	}
-	var qualityForFilename string
-
 	// 调试：打印所有可用的AAC流
-	if core.Dl_aac && (*core.Aac_type == "aac-binaural" || *core.Aac_type == "aac-downmix") {
-		logger.Debug("🔍 查找 %s 流，可用的variants:", *core.Aac_type)
+	if opts.AAC && (opts.AacType == "aac-binaural" || opts.AacType == "aac-downmix") {
+		logger.Debug("🔍 查找 %s 流，可用的variants:", opts.AacType)
 		for i, variant := range master.Variants {
 			if variant.Codecs == "mp4a.40.2" || variant.Codecs == "mp4a.40.5" {
 				logger.Debug("  [%d] Codec=%s, Audio=%s, Bandwidth=%d", i, variant.Codecs, variant.Audio, variant.Bandwidth)
@@ -269,87 +255,66 @@ func ExtractMedia(b string, more_mode bool) (string, string, string, error) {
 		}
 	}
 
-	for _, variant := range master.Variants {
-		if core.Dl_atmos {
-			if variant.Codecs == "ec-3" && strings.Contains(variant.Audio, "atmos") {
-				split := strings.Split(variant.Audio, "-")
-				length_int, err := strconv.Atoi(split[len(split)-1])
-				if err == nil && length_int <= *core.Atmos_max {
-					streamUrl, _ = masterUrl.Parse(variant.URI)
-					qualityForFilename = fmt.Sprintf("%s kbps", split[len(split)-1])
-					break
-				}
-			} else if variant.Codecs == "ac-3" {
-				streamUrl, _ = masterUrl.Parse(variant.URI)
-				split := strings.Split(variant.Audio, "-")
-				qualityForFilename = fmt.Sprintf("%s kbps", split[len(split)-1])
-				break
-			}
-		} else if core.Dl_aac {
-			if variant.Codecs == "mp4a.40.2" || variant.Codecs == "mp4a.40.5" {
-				// Handle different AAC types including binaural and downmix
-				var matchedType string
+	choice := quality.Select(opts.Rules(), masterVariants(master))
+	if choice.Variant == nil {
+		return "", choice, qualityForDisplay, errors.New("no variants found in playlist")
+	}
+	logger.Debug("[音质策略] %s", choice.Reason)
+	streamUrl, err := masterUrl.Parse(choice.Variant.URI)
+	if err != nil {
+		return "", choice, qualityForDisplay, err
+	}
+	return streamUrl.String(), choice, qualityForDisplay, nil
+}
 
-				// Check for binaural streams (both regular AAC and HE-AAC)
-				if strings.Contains(variant.Audio, "-binaural") && *core.Aac_type == "aac-binaural" {
-					matchedType = "aac-binaural"
-				} else if strings.Contains(variant.Audio, "-downmix") && *core.Aac_type == "aac-downmix" {
-					matchedType = "aac-downmix"
-				} else if *core.Aac_type == "aac-lc" {
-					// For AAC-LC, match regular stereo streams (not binaural/downmix)
-					if !strings.Contains(variant.Audio, "-binaural") && !strings.Contains(variant.Audio, "-downmix") {
-						aacregex := regexp.MustCompile(`audio-stereo-\d+`)
-						replaced := aacregex.ReplaceAllString(variant.Audio, "aac")
-						if replaced == "aac" {
-							matchedType = "aac-lc"
-						}
-					}
-				} else if *core.Aac_type == "aac" {
-					// For generic AAC, match regular stereo streams (not binaural/downmix)
-					if !strings.Contains(variant.Audio, "-binaural") && !strings.Contains(variant.Audio, "-downmix") {
-						aacregex := regexp.MustCompile(`audio-stereo-\d+`)
-						replaced := aacregex.ReplaceAllString(variant.Audio, "aac")
-						if replaced == "aac" {
-							matchedType = "aac"
-						}
-					}
-				}
+// decodeMaster 下载并解析主播放列表，档位按平均码率从高到低排序
+func decodeMaster(b string) (*url.URL, *m3u8.MasterPlaylist, error) {
+	masterUrl, err := url.Parse(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := http.Get(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, errors.New(resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	from, listType, err := m3u8.DecodeFrom(bytes.NewReader(body), true)
+	if err != nil || listType != m3u8.MASTER {
+		return nil, nil, errors.New("m3u8 not of master type")
+	}
+	master := from.(*m3u8.MasterPlaylist)
+	sort.Slice(master.Variants, func(i, j int) bool {
+		return master.Variants[i].AverageBandwidth > master.Variants[j].AverageBandwidth
+	})
+	return masterUrl, master, nil
+}
 
-				if matchedType == *core.Aac_type {
-					streamUrl, _ = masterUrl.Parse(variant.URI)
-					split := strings.Split(variant.Audio, "-")
-					// Extract bitrate from the audio string
-					if len(split) >= 3 {
-						qualityForFilename = fmt.Sprintf("%s kbps", split[2])
-					}
-					break
-				}
-			}
-		} else {
-			if variant.Codecs == "alac" {
-				split := strings.Split(variant.Audio, "-")
-				length_int, err := strconv.Atoi(split[len(split)-2])
-				if err == nil && length_int <= *core.Alac_max {
-					streamUrl, _ = masterUrl.Parse(variant.URI)
-					KHZ := float64(length_int) / 1000.0
-					qualityForFilename = fmt.Sprintf("%sB-%.1fkHz", split[len(split)-1], KHZ)
-					break
-				}
-			}
-		}
+func masterVariants(master *m3u8.MasterPlaylist) []quality.Variant {
+	variants := make([]quality.Variant, 0, len(master.Variants))
+	for _, variant := range master.Variants {
+		variants = append(variants, quality.Variant{Codecs: variant.Codecs, Audio: variant.Audio, URI: variant.URI, Bandwidth: variant.AverageBandwidth})
 	}
-	if streamUrl == nil {
-		if len(master.Variants) > 0 {
-			streamUrl, _ = masterUrl.Parse(master.Variants[0].URI)
-		} else {
-			return "", "", qualityForDisplay, errors.New("no variants found in playlist")
-		}
+	return variants
+}
+
+// MediaVariants 返回主播放列表中的所有音频档位（按平均码率从高到低排序）
+func MediaVariants(b string) ([]quality.Variant, error) {
+	_, master, err := decodeMaster(b)
+	if err != nil {
+		return nil, err
 	}
-	return streamUrl.String(), qualityForFilename, qualityForDisplay, nil
+	return masterVariants(master), nil
 }
 
 // ExtractVideo extracts the best video stream URL from a master m3u8 and returns resolution info
-func ExtractVideo(c string) (string, string, error) {
+func ExtractVideo(opts core.Options, c string) (string, string, error) {
 	MediaUrl, err := url.Parse(c)
 	if err != nil {
 		return "", "", err
@@ -380,7 +345,7 @@ func ExtractVideo(c string) (string, string, error) {
 		return video.Variants[i].AverageBandwidth > video.Variants[j].AverageBandwidth
 	})
 
-	maxHeight := *core.Mv_max
+	maxHeight := opts.MvMax
 	re := regexp.MustCompile(`_(\d+)x(\d+)`)
 
 	for _, variant := range video.Variants {
@@ -0,0 +1,35 @@
+package progress
+
+import "main/internal/metrics"
+
+// MetricsListener 将曲目进度事件汇总为指标，每个专辑按其编码创建一个
+type MetricsListener struct {
+	codec string
+}
+
+// NewMetricsListener 创建指标监听器，codec 作为指标标签
+func NewMetricsListener(codec string) *MetricsListener {
+	return &MetricsListener{codec: codec}
+}
+
+// OnProgress 处理状态事件：本地已存在、跳过、重编码完成
+func (l *MetricsListener) OnProgress(event ProgressEvent) {
+	switch event.Stage {
+	case "exists":
+		metrics.Tracks.Inc(l.codec, "exists")
+	case "skipped":
+		metrics.Tracks.Inc(l.codec, "skipped")
+	case "complete":
+		metrics.Tracks.Inc(l.codec, "downloaded")
+	}
+}
+
+// OnComplete 曲目下载完成
+func (l *MetricsListener) OnComplete(trackIndex int) {
+	metrics.Tracks.Inc(l.codec, "downloaded")
+}
+
+// OnError 曲目下载失败
+func (l *MetricsListener) OnError(trackIndex int, err error) {
+	metrics.Tracks.Inc(l.codec, "failed")
+}
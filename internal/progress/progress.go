@@ -36,6 +36,19 @@ func NewNotifier() *ProgressNotifier {
 	}
 }
 
+// Fork 创建一个继承当前所有监听器的新通知器
+// 新通知器上添加的监听器不会影响原通知器；nil 通知器返回空的新通知器
+func (n *ProgressNotifier) Fork() *ProgressNotifier {
+	forked := NewNotifier()
+	if n == nil {
+		return forked
+	}
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	forked.listeners = append(forked.listeners, n.listeners...)
+	return forked
+}
+
 // AddListener 添加一个监听器
 // 线程安全，可在运行时动态添加监听器
 func (n *ProgressNotifier) AddListener(l ProgressListener) {
@@ -0,0 +1,351 @@
+package quality
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 音质档位
+const (
+	TierAlac        = "alac"         // ALAC（不区分采样率，受上限约束）
+	TierHiRes       = "hires"        // Hi-Res 无损（ALAC 采样率 > 48kHz）
+	TierLossless    = "lossless"     // 无损（ALAC 采样率 ≤ 48kHz）
+	TierAtmos       = "atmos"        // 杜比全景声（E-AC-3 JOC）
+	TierAC3         = "ac3"          // 杜比音效（AC-3）
+	TierAAC         = "aac"          // AAC 立体声
+	TierAACLC       = "aac-lc"       // AAC-LC（通过 wrapper 解密下载）
+	TierAACBinaural = "aac-binaural" // AAC 双耳
+	TierAACDownmix  = "aac-downmix"  // AAC 缩混
+)
+
+// Tiers 所有可用档位
+var Tiers = []string{TierHiRes, TierLossless, TierAlac, TierAtmos, TierAC3, TierAAC, TierAACLC, TierAACBinaural, TierAACDownmix}
+
+// 质量标签（文件夹 {Tag}、文件名 {Tag} 与 QUALITY 元数据共用）
+const (
+	TagHiRes       = "Hi-Res Lossless"
+	TagAlac        = "Alac"
+	TagAtmos       = "Dolby Atmos"
+	TagAAC         = "Aac 256"
+	TagAACBinaural = "Aac Binaural"
+	TagAACDownmix  = "Aac Downmix"
+)
+
+// losslessMaxRate 标准无损的最高采样率，超过即为 Hi-Res
+const losslessMaxRate = 48000
+
+// Rule 音质策略中的一个档位，Max 为上限（ALAC 为采样率，Atmos / AAC 为码率），0 表示不限制
+type Rule struct {
+	Tier string
+	Max  int
+}
+
+func (r Rule) String() string {
+	if r.Max > 0 {
+		return fmt.Sprintf("%s<=%d", r.Tier, r.Max)
+	}
+	return r.Tier
+}
+
+// IsAAC 档位是否为 AAC 系列
+func (r Rule) IsAAC() bool {
+	return strings.HasPrefix(r.Tier, TierAAC)
+}
+
+// AlwaysAvailable 档位是否总是可用（AAC 立体声）
+func (r Rule) AlwaysAvailable() bool {
+	return (r.Tier == TierAAC || r.Tier == TierAACLC) && r.Max == 0
+}
+
+// IsDolby 档位是否为杜比（全景声 / 杜比音效）
+func (r Rule) IsDolby() bool {
+	return r.Tier == TierAtmos || r.Tier == TierAC3
+}
+
+// 音乐库（保存目录）
+const (
+	LibraryAlac  = "alac"  // alac-save-folder
+	LibraryAtmos = "atmos" // atmos-save-folder
+	LibraryAAC   = "aac"   // aac-save-folder
+)
+
+// Library 档位保存到的音乐库
+func (r Rule) Library() string {
+	switch {
+	case r.IsDolby():
+		return LibraryAtmos
+	case r.IsAAC():
+		return LibraryAAC
+	}
+	return LibraryAlac
+}
+
+// ParseRule 解析档位，格式为 "档位" 或 "档位<=上限"（如 hires<=96000、atmos<=2768、lossless）
+func ParseRule(s string) (Rule, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	var r Rule
+	tier, limit, hasLimit := strings.Cut(s, "<=")
+	r.Tier = strings.TrimSpace(tier)
+	valid := false
+	for _, t := range Tiers {
+		if r.Tier == t {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return r, fmt.Errorf("未知的音质档位 %q（可选: %s）", r.Tier, strings.Join(Tiers, ", "))
+	}
+	if hasLimit {
+		n, err := strconv.Atoi(strings.TrimSpace(limit))
+		if err != nil || n <= 0 {
+			return r, fmt.Errorf("音质档位 %q 的上限无效", s)
+		}
+		r.Max = n
+	}
+	switch {
+	case r.Tier == TierHiRes && r.Max > 0 && r.Max <= losslessMaxRate:
+		return r, fmt.Errorf("hires 的采样率上限必须大于 %d（需要 48kHz 以下请使用 lossless）", losslessMaxRate)
+	case r.Max > 0 && (r.Tier == TierLossless || r.Tier == TierAC3):
+		return r, fmt.Errorf("%s 不支持设置上限", r.Tier)
+	}
+	return r, nil
+}
+
+// ParsePolicy 解析按顺序尝试的档位列表
+func ParsePolicy(items []string) ([]Rule, error) {
+	var rules []Rule
+	for _, item := range items {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		r, err := ParseRule(item)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// Variant 主播放列表中的一个音频档位
+type Variant struct {
+	Codecs    string // mp4a.40.2 / alac / ec-3 / ac-3
+	Audio     string // 音频组，如 audio-alac-stereo-96000-24、audio-atmos-2768、audio-stereo-256
+	URI       string
+	Bandwidth uint32
+}
+
+// sampleRate ALAC 档位的采样率与位深
+func (v Variant) sampleRate() (int, string) {
+	split := strings.Split(v.Audio, "-")
+	if len(split) < 3 {
+		return 0, ""
+	}
+	rate, _ := strconv.Atoi(split[len(split)-2])
+	return rate, split[len(split)-1]
+}
+
+// bitrate 音频组名中的码率（Atmos / AC-3 为最后一段，AAC 为第三段）
+func (v Variant) bitrate() (int, string) {
+	split := strings.Split(v.Audio, "-")
+	s := split[len(split)-1]
+	if v.isAAC() {
+		if len(split) < 3 {
+			return 0, ""
+		}
+		s = split[2]
+	}
+	n, _ := strconv.Atoi(s)
+	return n, s
+}
+
+func (v Variant) isAAC() bool {
+	return v.Codecs == "mp4a.40.2" || v.Codecs == "mp4a.40.5"
+}
+
+var stereoAudio = regexp.MustCompile(`^audio-stereo-\d+$`)
+
+// Matches 档位是否满足规则
+func (r Rule) Matches(v Variant) bool {
+	switch r.Tier {
+	case TierAlac, TierHiRes, TierLossless:
+		if v.Codecs != "alac" {
+			return false
+		}
+		rate, _ := v.sampleRate()
+		if rate == 0 || (r.Max > 0 && rate > r.Max) {
+			return false
+		}
+		switch r.Tier {
+		case TierHiRes:
+			return rate > losslessMaxRate
+		case TierLossless:
+			return rate <= losslessMaxRate
+		}
+		return true
+	case TierAtmos:
+		if v.Codecs != "ec-3" || !strings.Contains(v.Audio, "atmos") {
+			return false
+		}
+		n, _ := v.bitrate()
+		return n > 0 && (r.Max == 0 || n <= r.Max)
+	case TierAC3:
+		return v.Codecs == "ac-3"
+	case TierAACBinaural:
+		return v.isAAC() && strings.Contains(v.Audio, "-binaural")
+	case TierAACDownmix:
+		return v.isAAC() && strings.Contains(v.Audio, "-downmix")
+	case TierAAC, TierAACLC:
+		if !v.isAAC() || !stereoAudio.MatchString(v.Audio) {
+			return false
+		}
+		n, _ := v.bitrate()
+		return r.Max == 0 || n <= r.Max
+	}
+	return false
+}
+
+// Label 文件名 {Quality} 使用的音质描述（如 24B-96.0kHz、2768 kbps、256 kbps）
+func (v Variant) Label() string {
+	switch {
+	case v.Codecs == "alac":
+		rate, depth := v.sampleRate()
+		if rate == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%sB-%.1fkHz", depth, float64(rate)/1000.0)
+	case v.isAAC() || v.Codecs == "ec-3" || v.Codecs == "ac-3":
+		if _, s := v.bitrate(); s != "" {
+			return s + " kbps"
+		}
+	}
+	return ""
+}
+
+// Choice 音质策略的选择结果
+type Choice struct {
+	Rule    Rule     // 命中的档位；Fallback 为 true 时为空
+	Variant *Variant // 选中的档位（按专辑音频特性选择时为空）
+	Index   int      // 命中的档位在策略中的位置
+	// Fallback 没有档位满足策略，使用码率最高的档位
+	Fallback bool
+	Reason   string
+}
+
+// Select 按策略顺序为曲目选择档位：每个档位取满足条件且码率最高的档位（variants 须按码率从高到低排序）
+func Select(rules []Rule, variants []Variant) Choice {
+	var skipped []string
+	for i, r := range rules {
+		for j := range variants {
+			if r.Matches(variants[j]) {
+				v := variants[j]
+				return Choice{Rule: r, Variant: &v, Index: i, Reason: reason(skipped, fmt.Sprintf("%s: %s", r, describe(v)))}
+			}
+		}
+		skipped = append(skipped, fmt.Sprintf("%s: 没有符合条件的档位", r))
+	}
+	c := Choice{Fallback: true, Index: len(rules)}
+	if len(variants) > 0 {
+		v := variants[0]
+		c.Variant = &v
+		c.Reason = reason(skipped, "使用码率最高的档位 "+describe(v))
+	} else {
+		c.Reason = reason(skipped, "播放列表中没有音频档位")
+	}
+	return c
+}
+
+// SelectAlbum 按专辑各曲目的音频特性（audioTraits）选择档位：第一个至少一首曲目可用的档位
+// 音频特性不含采样率，上限在下载每首曲目时按实际档位判断
+func SelectAlbum(rules []Rule, traits [][]string) Choice {
+	var skipped []string
+	for i, r := range rules {
+		n := 0
+		for _, t := range traits {
+			if Available(r, t) {
+				n++
+			}
+		}
+		if n > 0 {
+			return Choice{Rule: r, Index: i, Reason: reason(skipped, fmt.Sprintf("%s: %d/%d 首曲目可用", r, n, len(traits)))}
+		}
+		skipped = append(skipped, fmt.Sprintf("%s: 没有曲目可用", r))
+	}
+	// 所有档位都不可用时使用最后一个档位，下载时按实际档位回退
+	c := Choice{Fallback: true, Reason: reason(skipped, "没有可用档位")}
+	if len(rules) > 0 {
+		c.Rule = rules[len(rules)-1]
+		c.Index = len(rules) - 1
+	}
+	return c
+}
+
+// Available 按曲目的音频特性判断档位是否可用（AAC 立体声总是可用）
+func Available(r Rule, traits []string) bool {
+	switch r.Tier {
+	case TierHiRes:
+		return hasTrait(traits, "hi-res-lossless")
+	case TierAlac, TierLossless:
+		return hasTrait(traits, "lossless") || hasTrait(traits, "hi-res-lossless")
+	case TierAtmos, TierAC3, TierAACBinaural, TierAACDownmix:
+		// 双耳与缩混版本由空间音频混音生成
+		return hasTrait(traits, "atmos")
+	}
+	return true
+}
+
+// Tag 返回档位对应的质量标签；ALAC 档位按音频特性区分 Hi-Res 与普通无损（采样率上限 ≤ 48kHz 时为普通无损）
+func Tag(r Rule, traits []string) string {
+	switch r.Tier {
+	case TierAtmos, TierAC3:
+		return TagAtmos
+	case TierAACBinaural:
+		return TagAACBinaural
+	case TierAACDownmix:
+		return TagAACDownmix
+	}
+	if r.IsAAC() {
+		return TagAAC
+	}
+	switch {
+	case hasTrait(traits, "hi-res-lossless") && r.Tier != TierLossless && (r.Max == 0 || r.Max > losslessMaxRate):
+		return TagHiRes
+	case hasTrait(traits, "lossless") || hasTrait(traits, "hi-res-lossless"):
+		return TagAlac
+	case hasTrait(traits, "atmos"):
+		return TagAtmos
+	}
+	return TagAAC
+}
+
+func hasTrait(traits []string, trait string) bool {
+	for _, t := range traits {
+		if t == trait {
+			return true
+		}
+	}
+	return false
+}
+
+// describe 档位的可读描述
+func describe(v Variant) string {
+	switch {
+	case v.Codecs == "alac":
+		rate, depth := v.sampleRate()
+		return fmt.Sprintf("ALAC %sbit/%.1fkHz", depth, float64(rate)/1000.0)
+	case v.Codecs == "ec-3":
+		return "Dolby Atmos " + v.Label()
+	case v.Codecs == "ac-3":
+		return "Dolby Audio " + v.Label()
+	case v.isAAC():
+		return fmt.Sprintf("AAC %s (%s)", v.Label(), v.Audio)
+	}
+	return v.Audio
+}
+
+func reason(skipped []string, chosen string) string {
+	return strings.Join(append(skipped, chosen), " → ")
+}
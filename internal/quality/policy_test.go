@@ -0,0 +1,127 @@
+package quality
+
+import (
+	"strings"
+	"testing"
+)
+
+// 按码率从高到低排序的主播放列表档位
+var variants = []Variant{
+	{Codecs: "alac", Audio: "audio-alac-stereo-192000-24", URI: "alac192"},
+	{Codecs: "alac", Audio: "audio-alac-stereo-96000-24", URI: "alac96"},
+	{Codecs: "ec-3", Audio: "audio-atmos-2768", URI: "atmos2768"},
+	{Codecs: "alac", Audio: "audio-alac-stereo-48000-24", URI: "alac48"},
+	{Codecs: "ac-3", Audio: "audio-ac3-640", URI: "ac3"},
+	{Codecs: "mp4a.40.2", Audio: "audio-stereo-256", URI: "aac256"},
+	{Codecs: "mp4a.40.2", Audio: "audio-stereo-256-binaural", URI: "binaural"},
+	{Codecs: "mp4a.40.5", Audio: "audio-HE-stereo-64", URI: "he64"},
+}
+
+// TestParseRule 测试档位解析
+func TestParseRule(t *testing.T) {
+	valid := map[string]string{
+		"hires<=96000":  "hires<=96000",
+		" Lossless ":    "lossless",
+		"atmos <= 2768": "atmos<=2768",
+		"aac-binaural":  "aac-binaural",
+	}
+	for in, want := range valid {
+		r, err := ParseRule(in)
+		if err != nil || r.String() != want {
+			t.Errorf("ParseRule(%q) = %v, %v; want %s", in, r, err, want)
+		}
+	}
+	for _, in := range []string{"flac", "hires<=48000", "lossless<=44100", "alac<=abc", "aac<=0"} {
+		if _, err := ParseRule(in); err == nil {
+			t.Errorf("ParseRule(%q) should fail", in)
+		}
+	}
+	rules, err := ParsePolicy([]string{"hires<=96000", "", "aac"})
+	if err != nil || len(rules) != 2 {
+		t.Errorf("ParsePolicy = %v, %v", rules, err)
+	}
+	libraries := map[string]string{"hires": LibraryAlac, "lossless": LibraryAlac, "atmos": LibraryAtmos, "ac3": LibraryAtmos, "aac-downmix": LibraryAAC}
+	for in, want := range libraries {
+		if r, _ := ParseRule(in); r.Library() != want {
+			t.Errorf("ParseRule(%q).Library() = %s, want %s", in, r.Library(), want)
+		}
+	}
+}
+
+// TestSelect 测试按策略顺序选择档位与回退
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		policy  []string
+		uri     string
+		label   string
+		index   int
+		reasons int // 原因中的步骤数
+	}{
+		{[]string{"hires<=96000", "lossless", "aac"}, "alac96", "24B-96.0kHz", 0, 1},
+		{[]string{"alac<=192000"}, "alac192", "24B-192.0kHz", 0, 1},
+		{[]string{"lossless"}, "alac48", "24B-48.0kHz", 0, 1},
+		{[]string{"atmos<=2448", "ac3"}, "ac3", "640 kbps", 1, 2},
+		{[]string{"atmos<=2768"}, "atmos2768", "2768 kbps", 0, 1},
+		{[]string{"aac"}, "aac256", "256 kbps", 0, 1},
+		{[]string{"aac-binaural"}, "binaural", "256 kbps", 0, 1},
+		{[]string{"aac-downmix"}, "alac192", "24B-192.0kHz", 1, 2}, // 没有符合的档位，使用码率最高的档位
+	}
+	for _, tt := range tests {
+		rules, err := ParsePolicy(tt.policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := Select(rules, variants)
+		if c.Variant == nil || c.Variant.URI != tt.uri || c.Variant.Label() != tt.label || c.Index != tt.index {
+			t.Errorf("%v: choice = %+v (%v)", tt.policy, c, c.Variant)
+			continue
+		}
+		if steps := len(strings.Split(c.Reason, " → ")); steps != tt.reasons {
+			t.Errorf("%v: reason %q has %d steps, want %d", tt.policy, c.Reason, steps, tt.reasons)
+		}
+	}
+	if c := Select(nil, nil); c.Variant != nil || !c.Fallback {
+		t.Errorf("empty playlist choice = %+v", c)
+	}
+}
+
+// TestSelectAlbum 测试按专辑音频特性选择档位
+func TestSelectAlbum(t *testing.T) {
+	rules, _ := ParsePolicy([]string{"atmos", "hires<=96000", "lossless", "aac"})
+	traits := [][]string{{"lossless"}, {"lossless", "hi-res-lossless"}}
+	c := SelectAlbum(rules, traits)
+	if c.Index != 1 || c.Fallback {
+		t.Errorf("choice = %+v, want hires", c)
+	}
+	if !strings.Contains(c.Reason, "atmos: 没有曲目可用") || !strings.Contains(c.Reason, "1/2") {
+		t.Errorf("reason = %q", c.Reason)
+	}
+	if c := SelectAlbum(rules[:2], [][]string{{"lossless"}}); !c.Fallback || c.Index != 1 {
+		t.Errorf("unavailable policy choice = %+v, want last rule", c)
+	}
+}
+
+// TestTag 测试质量标签
+func TestTag(t *testing.T) {
+	hires := []string{"lossless", "hi-res-lossless"}
+	tests := []struct {
+		rule   Rule
+		traits []string
+		want   string
+	}{
+		{Rule{Tier: TierAlac, Max: 192000}, hires, TagHiRes},
+		{Rule{Tier: TierAlac, Max: 48000}, hires, TagAlac},
+		{Rule{Tier: TierLossless}, hires, TagAlac},
+		{Rule{Tier: TierAlac}, []string{"lossless"}, TagAlac},
+		{Rule{Tier: TierAlac}, []string{"atmos"}, TagAtmos},
+		{Rule{Tier: TierAlac}, nil, TagAAC},
+		{Rule{Tier: TierAtmos}, hires, TagAtmos},
+		{Rule{Tier: TierAACLC}, hires, TagAAC},
+		{Rule{Tier: TierAACDownmix}, nil, TagAACDownmix},
+	}
+	for _, tt := range tests {
+		if got := Tag(tt.rule, tt.traits); got != tt.want {
+			t.Errorf("Tag(%v, %v) = %q, want %q", tt.rule, tt.traits, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,364 @@
+package retag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"main/internal/checksum"
+	"main/internal/core"
+	"main/internal/metadata"
+	"main/internal/utils"
+	"main/utils/structs"
+
+	"github.com/zhaarey/go-mp4tag"
+)
+
+// 文件处理结果
+const (
+	StatusChanged   = "changed"
+	StatusUnchanged = "unchanged"
+	StatusSkipped   = "skipped"
+	StatusFailed    = "failed"
+)
+
+// Catalog 按专辑 ID 获取目录信息
+type Catalog func(albumID string) (*structs.AutoGenerated, error)
+
+// Options 重新写入标签的参数
+type Options struct {
+	Job      *core.Job // 推断音质标签等使用的任务参数
+	Catalog  Catalog
+	Preserve []string // 保留文件中原值的字段（见 Fields）
+	DryRun   bool     // 只显示差异，不写入文件
+	Progress func(done, total int)
+}
+
+// Change 单个字段的差异
+type Change struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// FileResult 单个文件的处理结果
+type FileResult struct {
+	Path    string   `json:"path"`
+	AlbumID string   `json:"album_id,omitempty"`
+	TrackID string   `json:"track_id,omitempty"`
+	Status  string   `json:"status"`
+	Reason  string   `json:"reason,omitempty"` // 跳过或失败的原因
+	Changes []Change `json:"changes,omitempty"`
+}
+
+// Report 重新写入标签的报告
+type Report struct {
+	Started   time.Time    `json:"started"`
+	Roots     []string     `json:"roots"`
+	DryRun    bool         `json:"dry_run"`
+	Checked   int          `json:"checked"`
+	Changed   int          `json:"changed"`
+	Unchanged int          `json:"unchanged"`
+	Skipped   int          `json:"skipped"`
+	Failed    int          `json:"failed"`
+	Files     []FileResult `json:"files,omitempty"` // 只包含有差异、跳过或失败的文件
+}
+
+// field 可比较、可保留的标签字段
+type field struct {
+	name  string
+	get   func(t *mp4tag.MP4Tags) string
+	clear func(t *mp4tag.MP4Tags) // 清空后合并写入时保留文件中的原值
+}
+
+// fields 可比较的标准字段；自定义标签另外按小写键名比较（如 isrc、label、upc、quality）
+var fields = []field{
+	{"title", func(t *mp4tag.MP4Tags) string { return t.Title }, func(t *mp4tag.MP4Tags) { t.Title = "" }},
+	{"title-sort", func(t *mp4tag.MP4Tags) string { return t.TitleSort }, func(t *mp4tag.MP4Tags) { t.TitleSort = "" }},
+	{"artist", func(t *mp4tag.MP4Tags) string { return t.Artist }, func(t *mp4tag.MP4Tags) { t.Artist = "" }},
+	{"artist-sort", func(t *mp4tag.MP4Tags) string { return t.ArtistSort }, func(t *mp4tag.MP4Tags) { t.ArtistSort = "" }},
+	{"album", func(t *mp4tag.MP4Tags) string { return t.Album }, func(t *mp4tag.MP4Tags) { t.Album = "" }},
+	{"album-sort", func(t *mp4tag.MP4Tags) string { return t.AlbumSort }, func(t *mp4tag.MP4Tags) { t.AlbumSort = "" }},
+	{"album-artist", func(t *mp4tag.MP4Tags) string { return t.AlbumArtist }, func(t *mp4tag.MP4Tags) { t.AlbumArtist = "" }},
+	{"album-artist-sort", func(t *mp4tag.MP4Tags) string { return t.AlbumArtistSort }, func(t *mp4tag.MP4Tags) { t.AlbumArtistSort = "" }},
+	{"composer", func(t *mp4tag.MP4Tags) string { return t.Composer }, func(t *mp4tag.MP4Tags) { t.Composer = "" }},
+	{"composer-sort", func(t *mp4tag.MP4Tags) string { return t.ComposerSort }, func(t *mp4tag.MP4Tags) { t.ComposerSort = "" }},
+	{"genre", func(t *mp4tag.MP4Tags) string { return t.CustomGenre }, func(t *mp4tag.MP4Tags) { t.CustomGenre = "" }},
+	{"date", func(t *mp4tag.MP4Tags) string { return t.Date }, func(t *mp4tag.MP4Tags) { t.Date = "" }},
+	{"copyright", func(t *mp4tag.MP4Tags) string { return t.Copyright }, func(t *mp4tag.MP4Tags) { t.Copyright = "" }},
+	{"publisher", func(t *mp4tag.MP4Tags) string { return t.Publisher }, func(t *mp4tag.MP4Tags) { t.Publisher = "" }},
+	{"comment", func(t *mp4tag.MP4Tags) string { return t.Comment }, func(t *mp4tag.MP4Tags) { t.Comment = "" }},
+	{"track", func(t *mp4tag.MP4Tags) string { return numberPair(t.TrackNumber, t.TrackTotal) },
+		func(t *mp4tag.MP4Tags) { t.TrackNumber, t.TrackTotal = 0, 0 }},
+	{"disc", func(t *mp4tag.MP4Tags) string { return numberPair(t.DiscNumber, t.DiscTotal) },
+		func(t *mp4tag.MP4Tags) { t.DiscNumber, t.DiscTotal = 0, 0 }},
+	{"advisory", func(t *mp4tag.MP4Tags) string { return advisoryName(t.ItunesAdvisory) },
+		func(t *mp4tag.MP4Tags) { t.ItunesAdvisory = mp4tag.ItunesAdvisoryNone }},
+	{"artist-id", func(t *mp4tag.MP4Tags) string { return idString(t.ItunesArtistID) }, func(t *mp4tag.MP4Tags) { t.ItunesArtistID = 0 }},
+}
+
+// Fields 返回所有标准字段名（用于帮助信息）
+func Fields() []string {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.name
+	}
+	return names
+}
+
+// numberPair 曲号 / 碟号显示为 "n/total"，未设置时为空
+func numberPair(n, total int16) string {
+	if n <= 0 {
+		return ""
+	}
+	if total <= 0 {
+		return strconv.Itoa(int(n))
+	}
+	return fmt.Sprintf("%d/%d", n, total)
+}
+
+func advisoryName(a mp4tag.ItunesAdvisory) string {
+	switch a {
+	case mp4tag.ItunesAdvisoryExplicit:
+		return "explicit"
+	case mp4tag.ItunesAdvisoryClean:
+		return "clean"
+	}
+	return ""
+}
+
+func idString(id int32) string {
+	if id <= 0 {
+		return ""
+	}
+	return strconv.Itoa(int(id))
+}
+
+// diff 比较新旧标签并清空保留字段；新值为空的字段合并写入时不会改变，不计为差异
+func diff(old, updated *mp4tag.MP4Tags, preserve map[string]bool) []Change {
+	var changes []Change
+	for _, f := range fields {
+		if preserve[f.name] {
+			f.clear(updated)
+			continue
+		}
+		if v := f.get(updated); v != "" && v != f.get(old) {
+			changes = append(changes, Change{Field: f.name, Old: f.get(old), New: v})
+		}
+	}
+
+	keys := make([]string, 0, len(updated.Custom))
+	for k := range updated.Custom {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		name := strings.ToLower(k)
+		// 多值标签（如 ARTISTS）按全部值比较
+		v := strings.Join(metadata.CustomValues(updated, k), "; ")
+		if preserve[name] || v == "" {
+			delete(updated.Custom, k)
+			delete(updated.OtherCustom, k)
+			continue
+		}
+		if prev := strings.Join(metadata.CustomValues(old, k), "; "); v != prev {
+			changes = append(changes, Change{Field: name, Old: prev, New: v})
+		}
+	}
+	return changes
+}
+
+// fileTags 待处理的文件及其现有标签
+type fileTags struct {
+	path string
+	tags *mp4tag.MP4Tags
+}
+
+// Run 遍历目录中的 .m4a 文件，按专辑 ID 标签重新获取目录信息并更新标签（不重新下载音频）
+func Run(ctx context.Context, roots []string, opts Options) (*Report, error) {
+	report := &Report{Started: time.Now(), Roots: roots, DryRun: opts.DryRun}
+	preserve := make(map[string]bool)
+	for _, p := range opts.Preserve {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			preserve[p] = true
+		}
+	}
+
+	var paths []string
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".m4a") {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("遍历 %s 失败: %w", root, err)
+		}
+	}
+	sort.Strings(paths)
+
+	// 按专辑分组，每个专辑只请求一次目录信息
+	albums := make(map[string][]fileTags)
+	var albumIDs []string
+	for _, path := range paths {
+		tags, err := metadata.ReadMP4Tags(path)
+		if err != nil {
+			report.add(FileResult{Path: path, Status: StatusFailed, Reason: fmt.Sprintf("读取标签失败: %v", err)})
+			continue
+		}
+		if tags.ItunesAlbumID <= 0 {
+			report.add(FileResult{Path: path, Status: StatusSkipped, Reason: "没有专辑 ID 标签（播放列表或其他来源的文件）"})
+			continue
+		}
+		id := strconv.Itoa(int(tags.ItunesAlbumID))
+		if _, ok := albums[id]; !ok {
+			albumIDs = append(albumIDs, id)
+		}
+		albums[id] = append(albums[id], fileTags{path: path, tags: tags})
+	}
+
+	done := report.Checked
+	total := len(paths)
+	for _, id := range albumIDs {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		files := albums[id]
+		meta, err := opts.Catalog(id)
+		if err == nil && (meta == nil || len(meta.Data) == 0) {
+			err = fmt.Errorf("目录中没有该专辑")
+		}
+		for _, f := range files {
+			if err != nil {
+				report.add(FileResult{Path: f.path, AlbumID: id, Status: StatusFailed, Reason: fmt.Sprintf("获取专辑信息失败: %v", err)})
+			} else {
+				report.add(retagFile(opts, meta, id, f, preserve))
+			}
+			done++
+			if opts.Progress != nil {
+				opts.Progress(done, total)
+			}
+		}
+	}
+	return report, nil
+}
+
+// retagFile 生成单个文件的新标签并比较、写入
+func retagFile(opts Options, meta *structs.AutoGenerated, albumID string, f fileTags, preserve map[string]bool) FileResult {
+	r := FileResult{Path: f.path, AlbumID: albumID}
+	tracks := meta.Data[0].Relationships.Tracks.Data
+	i := metadata.MatchTrack(tracks, metadata.CustomTag(f.tags, "ISRC"), int(f.tags.DiscNumber), int(f.tags.TrackNumber))
+	if i < 0 {
+		r.Status, r.Reason = StatusSkipped, "在专辑目录中找不到对应曲目"
+		return r
+	}
+	r.TrackID = tracks[i].ID
+
+	// 虚拟 Singles 的曲号与合辑日期由下载时决定，沿用文件中的值
+	keep := preserve
+	if core.IsSingleAlbum(meta) {
+		opts.Job.SetEffectiveTrackNumber(tracks[i].ID, int(f.tags.TrackNumber))
+		keep = make(map[string]bool, len(preserve)+1)
+		for k := range preserve {
+			keep[k] = true
+		}
+		keep["date"] = true
+	}
+
+	updated := metadata.BuildMP4Tags(opts.Job, "", metadata.CustomTag(f.tags, "QUALITY"), meta, i+1, len(tracks))
+	r.Changes = diff(f.tags, updated, keep)
+	if len(r.Changes) == 0 {
+		r.Status = StatusUnchanged
+		return r
+	}
+	r.Status = StatusChanged
+	if opts.DryRun {
+		return r
+	}
+	if err := metadata.UpdateMP4Tags(f.path, updated); err != nil {
+		r.Status, r.Reason = StatusFailed, fmt.Sprintf("写入标签失败: %v", err)
+		return r
+	}
+	// 目录有校验清单时同步更新
+	if err := checksum.Update(filepath.Dir(f.path), f.path); err != nil {
+		r.Reason = fmt.Sprintf("更新校验清单失败: %v", err)
+	}
+	return r
+}
+
+// add 记录结果并更新统计
+func (r *Report) add(f FileResult) {
+	r.Checked++
+	switch f.Status {
+	case StatusChanged:
+		r.Changed++
+	case StatusUnchanged:
+		r.Unchanged++
+		return
+	case StatusSkipped:
+		r.Skipped++
+	case StatusFailed:
+		r.Failed++
+	}
+	r.Files = append(r.Files, f)
+}
+
+// WriteReport 写入报告，扩展名为 .json 时输出 JSON，否则输出文本
+func WriteReport(r *Report, path string) error {
+	var data []byte
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var err error
+		data, err = json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+	} else {
+		data = []byte(r.Text())
+	}
+	return utils.WriteFileAtomic(path, data)
+}
+
+// Text 文本格式的报告
+func (r *Report) Text() string {
+	var b strings.Builder
+	mode := ""
+	if r.DryRun {
+		mode = "（预览，未写入）"
+	}
+	fmt.Fprintf(&b, "更新时间: %s%s\n", r.Started.Format("2006-01-02 15:04:05"), mode)
+	fmt.Fprintf(&b, "目录: %s\n", strings.Join(r.Roots, ", "))
+	fmt.Fprintf(&b, "文件: %d | 有更新: %d | 无变化: %d | 跳过: %d | 失败: %d\n",
+		r.Checked, r.Changed, r.Unchanged, r.Skipped, r.Failed)
+	for _, f := range r.Files {
+		fmt.Fprintf(&b, "\n[%s] %s\n", statusLabel(f.Status), f.Path)
+		for _, c := range f.Changes {
+			fmt.Fprintf(&b, "  %s: %q → %q\n", c.Field, c.Old, c.New)
+		}
+		if f.Reason != "" {
+			fmt.Fprintf(&b, "  - %s\n", f.Reason)
+		}
+	}
+	return b.String()
+}
+
+func statusLabel(status string) string {
+	switch status {
+	case StatusChanged:
+		return "更新"
+	case StatusSkipped:
+		return "跳过"
+	case StatusFailed:
+		return "失败"
+	}
+	return "无变化"
+}
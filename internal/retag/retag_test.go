@@ -0,0 +1,107 @@
+package retag
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"main/utils/structs"
+
+	"github.com/zhaarey/go-mp4tag"
+)
+
+// TestDiff 测试字段差异与保留字段
+func TestDiff(t *testing.T) {
+	old := &mp4tag.MP4Tags{
+		Title:       "Song (Remastered)",
+		Album:       "Album",
+		CustomGenre: "My Genre",
+		TrackNumber: 3,
+		TrackTotal:  10,
+		Custom:      map[string]string{"ISRC": "USABC0000001", "LABEL": "Old Label"},
+	}
+	updated := &mp4tag.MP4Tags{
+		Title:       "Song",
+		Album:       "Album",
+		CustomGenre: "Pop",
+		TrackNumber: 3,
+		TrackTotal:  12,
+		Comment:     "",
+		Custom:      map[string]string{"ISRC": "USABC0000001", "LABEL": "New Label", "UPC": ""},
+	}
+
+	changes := diff(old, updated, map[string]bool{"genre": true})
+	got := make(map[string]Change)
+	for _, c := range changes {
+		got[c.Field] = c
+	}
+	if len(changes) != 3 {
+		t.Errorf("changes = %+v, want title, track and label", changes)
+	}
+	if c := got["title"]; c.Old != "Song (Remastered)" || c.New != "Song" {
+		t.Errorf("title change = %+v", c)
+	}
+	if c := got["track"]; c.Old != "3/10" || c.New != "3/12" {
+		t.Errorf("track change = %+v", c)
+	}
+	if c := got["label"]; c.Old != "Old Label" || c.New != "New Label" {
+		t.Errorf("label change = %+v", c)
+	}
+
+	// 保留字段与空值在合并写入时不能覆盖原值
+	if updated.CustomGenre != "" {
+		t.Errorf("preserved genre should be cleared, got %q", updated.CustomGenre)
+	}
+	if _, ok := updated.Custom["UPC"]; ok {
+		t.Error("empty custom tag should be removed")
+	}
+}
+
+// TestDiffPreserveCustom 测试按小写键名保留自定义标签
+func TestDiffPreserveCustom(t *testing.T) {
+	old := &mp4tag.MP4Tags{Custom: map[string]string{"ISRC": "A"}}
+	updated := &mp4tag.MP4Tags{Custom: map[string]string{"ISRC": "B"}}
+	if changes := diff(old, updated, map[string]bool{"isrc": true}); len(changes) != 0 {
+		t.Errorf("changes = %+v, want none", changes)
+	}
+	if _, ok := updated.Custom["ISRC"]; ok {
+		t.Error("preserved custom tag should be removed from the update")
+	}
+}
+
+// TestRunUnreadable 测试无法读取标签的文件记为失败，且不请求目录信息
+func TestRunUnreadable(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "broken.m4a"), []byte("not an mp4"), 0644)
+	os.WriteFile(filepath.Join(dir, "cover.jpg"), []byte("jpg"), 0644)
+
+	called := false
+	opts := Options{
+		DryRun: true,
+		Catalog: func(string) (*structs.AutoGenerated, error) {
+			called = true
+			return nil, nil
+		},
+	}
+	var progress []int
+	opts.Progress = func(done, total int) { progress = append(progress, done) }
+
+	report, err := Run(context.Background(), []string{dir}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Checked != 1 || report.Failed != 1 {
+		t.Errorf("checked=%d failed=%d, want 1/1", report.Checked, report.Failed)
+	}
+	if called {
+		t.Error("catalog should not be queried without album IDs")
+	}
+	if len(progress) != 0 {
+		t.Errorf("progress = %v, want none", progress)
+	}
+	if text := report.Text(); !strings.Contains(text, "[失败]") || !strings.Contains(text, "预览") {
+		t.Errorf("unexpected report text:\n%s", text)
+	}
+}
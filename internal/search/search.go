@@ -0,0 +1,141 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+
+	"main/internal/core"
+	"main/utils/ampapi"
+)
+
+// 搜索类型（与目录搜索 API 的 types 参数一致）
+const (
+	TypeSongs   = "songs"
+	TypeAlbums  = "albums"
+	TypeArtists = "artists"
+)
+
+// maxLimit 搜索 API 单次返回的最大结果数
+const maxLimit = 25
+
+// Types 所有可用的搜索类型
+var Types = []string{TypeSongs, TypeAlbums, TypeArtists}
+
+// Result 一条搜索结果
+type Result struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Artist     string   `json:"artist,omitempty"`
+	Album      string   `json:"album,omitempty"` // 单曲所属专辑
+	Year       string   `json:"year,omitempty"`
+	TrackCount int      `json:"track_count,omitempty"`
+	Explicit   bool     `json:"explicit"`
+	Traits     []string `json:"audio_traits,omitempty"`
+	Genres     []string `json:"genres,omitempty"` // 艺术家的流派
+	URL        string   `json:"url"`              // 加入下载队列使用的链接
+}
+
+// Search 在 storefront 地区的目录中搜索 term，typ 为 songs / albums / artists
+func Search(storefront, term, typ string, limit int) ([]Result, error) {
+	typ = strings.ToLower(strings.TrimSpace(typ))
+	if typ != "" && !strings.HasSuffix(typ, "s") {
+		typ += "s" // 兼容 song / album / artist
+	}
+	valid := false
+	for _, t := range Types {
+		if typ == t {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, fmt.Errorf("未知的搜索类型 %q（可选: %s）", typ, strings.Join(Types, ", "))
+	}
+	if strings.TrimSpace(term) == "" {
+		return nil, fmt.Errorf("搜索关键词为空")
+	}
+	if limit <= 0 || limit > maxLimit {
+		limit = maxLimit
+	}
+	storefront = strings.ToLower(storefront)
+	resp, err := ampapi.Search(storefront, term, typ, core.MetadataLanguage(storefront, nil), core.DeveloperToken, limit, 0)
+	if err != nil {
+		return nil, fmt.Errorf("搜索失败: %w", err)
+	}
+	return results(resp, typ, storefront), nil
+}
+
+// results 将搜索 API 的响应转换为搜索结果
+// 单曲使用 /song/ 链接，下载时按单曲模式只下载该曲目
+func results(resp *ampapi.SearchResp, typ, storefront string) []Result {
+	var out []Result
+	switch typ {
+	case TypeSongs:
+		if resp.Results.Songs == nil {
+			return nil
+		}
+		for _, s := range resp.Results.Songs.Data {
+			a := s.Attributes
+			out = append(out, Result{
+				Type:     TypeSongs,
+				ID:       s.ID,
+				Name:     a.Name,
+				Artist:   a.ArtistName,
+				Album:    a.AlbumName,
+				Year:     year(a.ReleaseDate),
+				Explicit: a.ContentRating == "explicit",
+				Traits:   a.AudioTraits,
+				URL:      fmt.Sprintf("https://music.apple.com/%s/song/%s", storefront, s.ID),
+			})
+		}
+	case TypeAlbums:
+		if resp.Results.Albums == nil {
+			return nil
+		}
+		for _, al := range resp.Results.Albums.Data {
+			a := al.Attributes
+			url := a.URL
+			if url == "" {
+				url = fmt.Sprintf("https://music.apple.com/%s/album/%s", storefront, al.ID)
+			}
+			out = append(out, Result{
+				Type:       TypeAlbums,
+				ID:         al.ID,
+				Name:       a.Name,
+				Artist:     a.ArtistName,
+				Year:       year(a.ReleaseDate),
+				TrackCount: a.TrackCount,
+				Explicit:   a.ContentRating == "explicit",
+				Traits:     a.AudioTraits,
+				URL:        url,
+			})
+		}
+	case TypeArtists:
+		if resp.Results.Artists == nil {
+			return nil
+		}
+		for _, ar := range resp.Results.Artists.Data {
+			url := ar.Attributes.URL
+			if url == "" {
+				url = fmt.Sprintf("https://music.apple.com/%s/artist/%s", storefront, ar.ID)
+			}
+			out = append(out, Result{
+				Type:   TypeArtists,
+				ID:     ar.ID,
+				Name:   ar.Attributes.Name,
+				Genres: ar.Attributes.GenreNames,
+				URL:    url,
+			})
+		}
+	}
+	return out
+}
+
+// year 发行日期（YYYY-MM-DD）中的年份
+func year(date string) string {
+	if len(date) >= 4 {
+		return date[:4]
+	}
+	return date
+}
@@ -0,0 +1,52 @@
+package search
+
+import (
+	"encoding/json"
+	"testing"
+
+	"main/utils/ampapi"
+)
+
+// TestResults 测试搜索响应转换为结果：年份、分级、单曲链接与缺失链接的补全
+func TestResults(t *testing.T) {
+	raw := `{"results":{
+		"songs":{"data":[{"id":"1440","type":"songs","attributes":{"name":"Song","artistName":"Artist","albumName":"Album","releaseDate":"2019-05-01","contentRating":"explicit","audioTraits":["lossless","atmos"],"url":"https://music.apple.com/us/album/album/1439?i=1440"}}]},
+		"albums":{"data":[
+			{"id":"1439","type":"albums","attributes":{"name":"Album","artistName":"Artist","releaseDate":"2019-05-01","trackCount":12,"audioTraits":["hi-res-lossless"],"url":"https://music.apple.com/us/album/album/1439"}},
+			{"id":"2000","type":"albums","attributes":{"name":"Other","releaseDate":"2020"}}
+		]},
+		"artists":{"data":[{"id":"99","type":"artists","attributes":{"name":"Artist","genreNames":["Pop"],"url":"https://music.apple.com/us/artist/artist/99"}}]}
+	}}`
+	var resp ampapi.SearchResp
+	if err := json.Unmarshal([]byte(raw), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	songs := results(&resp, TypeSongs, "us")
+	if len(songs) != 1 {
+		t.Fatalf("songs = %+v", songs)
+	}
+	if s := songs[0]; s.URL != "https://music.apple.com/us/song/1440" || s.Year != "2019" || !s.Explicit || s.Album != "Album" || len(s.Traits) != 2 {
+		t.Errorf("song = %+v", s)
+	}
+
+	albums := results(&resp, TypeAlbums, "us")
+	if len(albums) != 2 {
+		t.Fatalf("albums = %+v", albums)
+	}
+	if a := albums[0]; a.TrackCount != 12 || a.Explicit || a.URL != "https://music.apple.com/us/album/album/1439" {
+		t.Errorf("album = %+v", a)
+	}
+	if a := albums[1]; a.URL != "https://music.apple.com/us/album/2000" || a.Year != "2020" {
+		t.Errorf("album without url = %+v", a)
+	}
+
+	artists := results(&resp, TypeArtists, "us")
+	if len(artists) != 1 || artists[0].Name != "Artist" || artists[0].Genres[0] != "Pop" {
+		t.Errorf("artists = %+v", artists)
+	}
+
+	if got := results(&ampapi.SearchResp{}, TypeAlbums, "us"); got != nil {
+		t.Errorf("empty response = %+v, want nil", got)
+	}
+}
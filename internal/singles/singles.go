@@ -0,0 +1,347 @@
+package singles
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"main/internal/checksum"
+	"main/internal/core"
+	"main/internal/metadata"
+
+	"github.com/zhaarey/go-mp4tag"
+)
+
+// Entry 虚拟 Singles 目录中的一首单曲
+type Entry struct {
+	Path        string `json:"path,omitempty"`
+	ISRC        string `json:"isrc,omitempty"`
+	AlbumID     string `json:"album_id,omitempty"`
+	Title       string `json:"title,omitempty"`
+	ReleaseDate string `json:"release_date,omitempty"` // 原始发行日期（YYYY-MM-DD）
+	Number      int    `json:"number"`
+}
+
+// key 识别同一单曲：优先使用 ISRC，没有时使用专辑 ID + 曲名
+func (e Entry) key() string {
+	if e.ISRC != "" {
+		return strings.ToUpper(e.ISRC)
+	}
+	return e.AlbumID + "/" + e.Title
+}
+
+// Change 单曲编号的变化
+type Change struct {
+	Title string `json:"title"`
+	Old   int    `json:"old"`
+	New   int    `json:"new"`
+	From  string `json:"from"`
+	To    string `json:"to"` // 文件名不含编号时与 From 相同
+}
+
+// folder 进程内记录的目录编号，包含已分配但尚未转移到目录中的单曲
+type folder struct {
+	numbers map[string]int // key: Entry.key()
+	next    int
+}
+
+var (
+	foldersLock sync.Mutex
+	folders     = make(map[string]*folder)
+)
+
+// Scan 读取目录中 .m4a 文件的标签（不递归），按曲号排序返回
+// 无法读取标签的文件忽略
+func Scan(dir string) ([]Entry, error) {
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for _, f := range files {
+		if f.IsDir() || !strings.EqualFold(filepath.Ext(f.Name()), ".m4a") {
+			continue
+		}
+		path := filepath.Join(dir, f.Name())
+		tags, err := metadata.ReadMP4Tags(path)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, entryFromTags(path, tags))
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Number < entries[j].Number })
+	return entries, nil
+}
+
+// entryFromTags 原始发行日期依次取 TDOR、RELEASETIME 与日期标签（虚拟 Singles 的日期标签为合辑日期）
+func entryFromTags(path string, tags *mp4tag.MP4Tags) Entry {
+	e := Entry{
+		Path:   path,
+		ISRC:   metadata.CustomTag(tags, "ISRC"),
+		Title:  tags.Title,
+		Number: int(tags.TrackNumber),
+	}
+	if tags.ItunesAlbumID > 0 {
+		e.AlbumID = strconv.Itoa(int(tags.ItunesAlbumID))
+	}
+	for _, d := range []string{metadata.CustomTag(tags, "TDOR"), metadata.CustomTag(tags, "RELEASETIME"), tags.Date} {
+		if d != "" {
+			e.ReleaseDate = d
+			break
+		}
+	}
+	return e
+}
+
+// load 返回目录的编号记录，首次使用时从目录中已有的文件建立（调用方持有 foldersLock）
+func load(dir string) (*folder, error) {
+	dir = filepath.Clean(dir)
+	if f, ok := folders[dir]; ok {
+		return f, nil
+	}
+	entries, err := Scan(dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取 Singles 目录失败: %w", err)
+	}
+	f := &folder{numbers: make(map[string]int), next: 1}
+	for _, e := range entries {
+		f.numbers[e.key()] = e.Number
+		f.next = max(f.next, e.Number+1)
+	}
+	folders[dir] = f
+	return f, nil
+}
+
+// Assign 为单曲分配目录中的曲号
+// 目录中已有的单曲（如重新下载或升级音质）沿用原编号；新单曲使用目录中最大编号之后的编号，
+// 因此多次运行、多个专辑并行下载到同一目录时编号不会重复
+func Assign(dir string, e Entry) (int, error) {
+	foldersLock.Lock()
+	defer foldersLock.Unlock()
+	f, err := load(dir)
+	if err != nil {
+		return 0, err
+	}
+	if n, ok := f.numbers[e.key()]; ok {
+		return n, nil
+	}
+	n := f.next
+	f.numbers[e.key()] = n
+	f.next++
+	return n, nil
+}
+
+// Lookup 返回单曲在目录中已有的编号，不分配新编号
+func Lookup(dir string, e Entry) (int, bool) {
+	foldersLock.Lock()
+	defer foldersLock.Unlock()
+	f, err := load(dir)
+	if err != nil {
+		return 0, false
+	}
+	n, ok := f.numbers[e.key()]
+	return n, ok
+}
+
+// ordered 目录中的编号是否已按发行日期排列
+func ordered(entries []Entry) bool {
+	return sort.SliceIsSorted(entries, func(i, j int) bool { return entries[i].ReleaseDate < entries[j].ReleaseDate })
+}
+
+// Plan 按发行日期（相同时保持原顺序）计算目录中单曲的新编号 1..n
+func Plan(entries []Entry) []Change {
+	sorted := append([]Entry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ReleaseDate < sorted[j].ReleaseDate })
+	var changes []Change
+	for i, e := range sorted {
+		if e.Number == i+1 {
+			continue
+		}
+		to := filepath.Join(filepath.Dir(e.Path), renamed(filepath.Base(e.Path), e.Number, i+1))
+		changes = append(changes, Change{Title: e.Title, Old: e.Number, New: i + 1, From: e.Path, To: to})
+	}
+	return changes
+}
+
+// renamed 将文件名中的曲号替换为新编号（曲号由 song-file-format 的 {SongNumer} 生成）
+func renamed(name string, oldNum, newNum int) string {
+	format := core.Config.SongFileFormat
+	i := strings.Index(format, "{SongNumer}")
+	if i < 0 {
+		return name
+	}
+	from, to := fmt.Sprintf("%02d", oldNum), fmt.Sprintf("%02d", newNum)
+	// 编号之前只有固定文本时按位置替换，否则替换第一次出现的编号
+	if prefix := format[:i]; !strings.Contains(prefix, "{") {
+		if strings.HasPrefix(name, prefix+from) {
+			return prefix + to + name[len(prefix)+len(from):]
+		}
+		return name
+	}
+	return strings.Replace(name, from, to, 1)
+}
+
+// rename 重命名文件，updateTags 合并写入标签；测试中替换以模拟失败
+var (
+	rename     = os.Rename
+	updateTags = metadata.UpdateMP4Tags
+)
+
+// Rebuild 按发行日期重新编号目录中的单曲：重命名文件（及同名歌词等附属文件）并更新曲号标签
+// onlyUnordered 为 true 时编号已按发行日期排列的目录不做改动（下载完成后自动整理时使用，不填补编号空缺）
+func Rebuild(dir string, onlyUnordered, dryRun bool) ([]Change, error) {
+	foldersLock.Lock()
+	defer foldersLock.Unlock()
+
+	entries, err := Scan(dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取 Singles 目录失败: %w", err)
+	}
+	if onlyUnordered && ordered(entries) {
+		return nil, nil
+	}
+	changes := Plan(entries)
+	if dryRun || len(changes) == 0 {
+		return changes, nil
+	}
+
+	// 两阶段重命名，避免新旧文件名互相覆盖
+	type move struct{ from, tmp, to string }
+	var moves []move
+	for _, c := range changes {
+		if c.From == c.To {
+			continue
+		}
+		for _, side := range sidecars(c.From) {
+			to := strings.TrimSuffix(c.To, filepath.Ext(c.To)) + strings.TrimPrefix(side, strings.TrimSuffix(c.From, filepath.Ext(c.From)))
+			moves = append(moves, move{from: side, tmp: side + ".renumber", to: to})
+		}
+	}
+	moving := make(map[string]bool, len(moves))
+	for _, m := range moves {
+		moving[m.from] = true
+	}
+	for _, m := range moves {
+		if _, err := os.Stat(m.to); err == nil && !moving[m.to] {
+			return nil, fmt.Errorf("目标文件已存在: %s", filepath.Base(m.to))
+		}
+	}
+	// undo 中途失败时撤销已完成的重命名：先把已到位的文件移回临时名，再从临时名恢复原文件名
+	undo := func(placed, staged int, err error) error {
+		errs := []error{err}
+		for _, m := range moves[:placed] {
+			if err := rename(m.to, m.tmp); err != nil {
+				errs = append(errs, fmt.Errorf("恢复 %s 失败: %w", filepath.Base(m.from), err))
+			}
+		}
+		for _, m := range moves[:staged] {
+			if err := rename(m.tmp, m.from); err != nil {
+				errs = append(errs, fmt.Errorf("恢复 %s 失败: %w", filepath.Base(m.from), err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+	for i, m := range moves {
+		if err := rename(m.from, m.tmp); err != nil {
+			return nil, undo(0, i, fmt.Errorf("重命名 %s 失败: %w", filepath.Base(m.from), err))
+		}
+	}
+	for i, m := range moves {
+		if err := rename(m.tmp, m.to); err != nil {
+			return nil, undo(i, len(moves), fmt.Errorf("重命名 %s 失败: %w", filepath.Base(m.from), err))
+		}
+	}
+
+	var touched []string
+	for i, c := range changes {
+		if err := updateTags(c.To, &mp4tag.MP4Tags{TrackNumber: int16(c.New)}); err != nil {
+			// 曲号与文件名保持一致：恢复已更新的曲号后撤销全部重命名
+			err = fmt.Errorf("更新 %s 曲号失败: %w", filepath.Base(c.To), err)
+			for _, done := range changes[:i] {
+				if rerr := updateTags(done.To, &mp4tag.MP4Tags{TrackNumber: int16(done.Old)}); rerr != nil {
+					err = errors.Join(err, fmt.Errorf("恢复 %s 曲号失败: %w", filepath.Base(done.From), rerr))
+				}
+			}
+			return nil, undo(len(moves), len(moves), err)
+		}
+		touched = append(touched, c.From, c.To)
+	}
+	for _, m := range moves {
+		touched = append(touched, m.from, m.to)
+	}
+
+	// 更新进程内记录，保留已分配但尚未转移到目录中的编号（均大于目录中的编号，不会冲突）
+	if f, ok := folders[filepath.Clean(dir)]; ok {
+		for _, c := range changes {
+			for _, e := range entries {
+				if e.Path == c.From {
+					f.numbers[e.key()] = c.New
+				}
+			}
+		}
+	}
+	if err := checksum.Update(dir, touched...); err != nil {
+		return changes, fmt.Errorf("更新校验清单失败: %w", err)
+	}
+	return changes, nil
+}
+
+// sidecars 返回与音频同名（扩展名不同）的文件，包括音频本身
+func sidecars(path string) []string {
+	stem := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	files, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return []string{path}
+	}
+	var out []string
+	for _, f := range files {
+		if !f.IsDir() && strings.TrimSuffix(f.Name(), filepath.Ext(f.Name())) == stem {
+			out = append(out, filepath.Join(filepath.Dir(path), f.Name()))
+		}
+	}
+	return out
+}
+
+// FolderNames 虚拟合辑目录名的后缀（Singles 与 EPs）
+func FolderNames() []string {
+	return []string{core.SinglesFolderName(), core.EPsFolderName()}
+}
+
+// Dirs 查找 roots 下（包括 roots 本身）的虚拟合辑目录（目录名为 "艺术家 - Singles" 或 "艺术家 - EPs"）
+func Dirs(roots []string) ([]string, error) {
+	var suffixes []string
+	for _, name := range FolderNames() {
+		suffixes = append(suffixes, " - "+name)
+	}
+	var dirs []string
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() {
+				return nil
+			}
+			for _, suffix := range suffixes {
+				if strings.HasSuffix(d.Name(), suffix) {
+					dirs = append(dirs, path)
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("遍历 %s 失败: %w", root, err)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
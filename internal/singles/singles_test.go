@@ -0,0 +1,243 @@
+package singles
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"main/internal/core"
+	"main/internal/metadata"
+	"main/internal/mp4test"
+
+	"github.com/zhaarey/go-mp4tag"
+)
+
+// writeSingle 生成带标签的最小音频文件
+func writeSingle(t *testing.T, dir, name, isrc, title, released string, number int16) string {
+	t.Helper()
+	data := mp4test.Track(1000, nil, []byte("audio"))
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tags := &mp4tag.MP4Tags{
+		Title:       title,
+		TrackNumber: number,
+		Date:        "2025-01-01",
+		Custom:      map[string]string{"ISRC": isrc, "TDOR": released},
+	}
+	if err := metadata.UpdateMP4Tags(path, tags); err != nil {
+		t.Fatalf("write tags: %v", err)
+	}
+	return path
+}
+
+// TestRenamed 测试替换文件名中的曲号
+func TestRenamed(t *testing.T) {
+	defer func(format string) { core.Config.SongFileFormat = format }(core.Config.SongFileFormat)
+
+	tests := []struct {
+		format, name, want string
+	}{
+		{"{SongNumer}. {SongName}", "03. Song 03.m4a", "01. Song 03.m4a"},
+		{"Track {SongNumer} - {SongName}", "Track 03 - Song.m4a", "Track 01 - Song.m4a"},
+		{"{SongName} ({SongNumer})", "Song (03).m4a", "Song (01).m4a"},
+		{"{SongName}", "Song 03.m4a", "Song 03.m4a"},
+	}
+	for _, tt := range tests {
+		core.Config.SongFileFormat = tt.format
+		if got := renamed(tt.name, 3, 1); got != tt.want {
+			t.Errorf("renamed(%q) with %q = %q, want %q", tt.name, tt.format, got, tt.want)
+		}
+	}
+}
+
+// TestPlan 测试按发行日期计算新编号，日期相同时保持原顺序
+func TestPlan(t *testing.T) {
+	defer func(format string) { core.Config.SongFileFormat = format }(core.Config.SongFileFormat)
+	core.Config.SongFileFormat = "{SongNumer}. {SongName}"
+
+	entries := []Entry{
+		{Path: "/s/01. B.m4a", Title: "B", ReleaseDate: "2024-05-01", Number: 1},
+		{Path: "/s/02. C.m4a", Title: "C", ReleaseDate: "2024-05-01", Number: 2},
+		{Path: "/s/05. A.m4a", Title: "A", ReleaseDate: "2023-01-01", Number: 5},
+	}
+	if ordered(entries) {
+		t.Error("entries should not be ordered")
+	}
+	changes := Plan(entries)
+	want := map[string]int{"A": 1, "B": 2, "C": 3}
+	if len(changes) != 3 {
+		t.Fatalf("changes = %+v", changes)
+	}
+	for _, c := range changes {
+		if c.New != want[c.Title] {
+			t.Errorf("%s: new = %d, want %d", c.Title, c.New, want[c.Title])
+		}
+	}
+	if changes[0].To != filepath.Join("/s", "01. A.m4a") {
+		t.Errorf("A renamed to %s", changes[0].To)
+	}
+}
+
+// TestAssignAndRebuild 测试按目录已有单曲分配编号，以及插入更早的单曲后重新编号
+func TestAssignAndRebuild(t *testing.T) {
+	defer func(format string) { core.Config.SongFileFormat = format }(core.Config.SongFileFormat)
+	core.Config.SongFileFormat = "{SongNumer}. {SongName}"
+
+	dir := t.TempDir()
+	writeSingle(t, dir, "01. B.m4a", "ISRC000000B", "B", "2024-05-01", 1)
+	writeSingle(t, dir, "02. C.m4a", "ISRC000000C", "C", "2024-08-01", 2)
+
+	// 已有的单曲沿用原编号，新单曲排在最后（即使发行日期更早）
+	if n, err := Assign(dir, Entry{ISRC: "isrc000000b"}); err != nil || n != 1 {
+		t.Errorf("existing single = %d, %v; want 1", n, err)
+	}
+	if n, _ := Assign(dir, Entry{ISRC: "ISRC000000A"}); n != 3 {
+		t.Errorf("new single = %d, want 3", n)
+	}
+	if n, _ := Assign(dir, Entry{ISRC: "ISRC000000D"}); n != 4 {
+		t.Errorf("second new single = %d, want 4", n)
+	}
+	if n, ok := Lookup(dir, Entry{ISRC: "ISRC000000A"}); !ok || n != 3 {
+		t.Errorf("lookup = %d, %v; want 3", n, ok)
+	}
+	if _, ok := Lookup(dir, Entry{ISRC: "ISRC000000X"}); ok {
+		t.Error("unknown single should not be found")
+	}
+
+	if changes, err := Rebuild(dir, true, false); err != nil || len(changes) != 0 {
+		t.Fatalf("ordered folder should be kept: %+v, %v", changes, err)
+	}
+
+	writeSingle(t, dir, "03. A.m4a", "ISRC000000A", "A", "2023-01-01", 3)
+	os.WriteFile(filepath.Join(dir, "03. A.lrc"), []byte("[00:00.00]A"), 0644)
+
+	changes, err := Rebuild(dir, true, true)
+	if err != nil || len(changes) != 3 {
+		t.Fatalf("dry run changes = %+v, %v", changes, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "03. A.m4a")); err != nil {
+		t.Fatal("dry run should not rename files")
+	}
+
+	if _, err := Rebuild(dir, true, false); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"01. A.m4a", "02. B.m4a", "03. C.m4a"}
+	if len(entries) != len(want) {
+		t.Fatalf("entries = %+v", entries)
+	}
+	for i, e := range entries {
+		if filepath.Base(e.Path) != want[i] || e.Number != i+1 {
+			t.Errorf("entry %d = %s (#%d), want %s", i, filepath.Base(e.Path), e.Number, want[i])
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "01. A.lrc")); err != nil {
+		t.Error("lyrics should be renamed with the track")
+	}
+
+	// 未转移的单曲保留原编号，已转移的单曲使用新编号
+	if n, _ := Assign(dir, Entry{ISRC: "ISRC000000D"}); n != 4 {
+		t.Errorf("pending single = %d, want 4", n)
+	}
+	if n, _ := Assign(dir, Entry{ISRC: "ISRC000000A"}); n != 1 {
+		t.Errorf("renumbered single = %d, want 1", n)
+	}
+}
+
+// TestRebuildRollback 测试重命名中途失败时撤销已完成的重命名，目录恢复原状
+func TestRebuildRollback(t *testing.T) {
+	defer func(format string) { core.Config.SongFileFormat = format }(core.Config.SongFileFormat)
+	core.Config.SongFileFormat = "{SongNumer}. {SongName}"
+	defer func() { rename = os.Rename }()
+
+	// 两首单曲互换编号：共 4 次重命名（2 次移到临时名，2 次移到新名），依次让每一次失败
+	for failAt := 0; failAt < 4; failAt++ {
+		dir := t.TempDir()
+		writeSingle(t, dir, "01. B.m4a", "ISRC000000B", "B", "2024-05-01", 1)
+		writeSingle(t, dir, "02. A.m4a", "ISRC000000A", "A", "2023-01-01", 2)
+
+		calls := 0
+		rename = func(from, to string) error {
+			calls++
+			if calls == failAt+1 {
+				return os.ErrPermission
+			}
+			return os.Rename(from, to)
+		}
+		if _, err := Rebuild(dir, false, false); err == nil {
+			t.Fatalf("failAt %d: expected an error", failAt)
+		}
+		rename = os.Rename
+
+		names, err := filepath.Glob(filepath.Join(dir, "*"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(names) != 2 || filepath.Base(names[0]) != "01. B.m4a" || filepath.Base(names[1]) != "02. A.m4a" {
+			t.Fatalf("failAt %d: files = %v, want the original names", failAt, names)
+		}
+		entries, err := Scan(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range entries {
+			if want := "ISRC000000" + strings.TrimSuffix(filepath.Base(e.Path)[4:], ".m4a"); e.ISRC != want {
+				t.Errorf("failAt %d: %s has ISRC %s, want %s", failAt, filepath.Base(e.Path), e.ISRC, want)
+			}
+		}
+	}
+
+	// 重命名完成后第二个文件的曲号写入失败：恢复第一个文件的曲号并撤销重命名
+	defer func() { updateTags = metadata.UpdateMP4Tags }()
+	dir := t.TempDir()
+	writeSingle(t, dir, "01. B.m4a", "ISRC000000B", "B", "2024-05-01", 1)
+	writeSingle(t, dir, "02. A.m4a", "ISRC000000A", "A", "2023-01-01", 2)
+	calls := 0
+	updateTags = func(path string, tags *mp4tag.MP4Tags) error {
+		calls++
+		if calls == 2 {
+			return os.ErrPermission
+		}
+		return metadata.UpdateMP4Tags(path, tags)
+	}
+	if _, err := Rebuild(dir, false, false); err == nil {
+		t.Fatal("tag failure: expected an error")
+	}
+	updateTags = metadata.UpdateMP4Tags
+	entries, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		want := map[string]string{"ISRC000000B": "01. B.m4a", "ISRC000000A": "02. A.m4a"}[e.ISRC]
+		if filepath.Base(e.Path) != want || !strings.HasPrefix(want, fmt.Sprintf("%02d", e.Number)) {
+			t.Errorf("tag failure: %s has number %d, want %s", filepath.Base(e.Path), e.Number, want)
+		}
+	}
+}
+
+// TestDirs 测试查找虚拟 Singles 目录
+func TestDirs(t *testing.T) {
+	root := t.TempDir()
+	for _, d := range []string{"Artist/Artist - Singles", "Artist/Album", "Other - Singles"} {
+		os.MkdirAll(filepath.Join(root, d), 0755)
+	}
+	dirs, err := Dirs([]string{root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) != 2 {
+		t.Errorf("dirs = %v, want 2 Singles folders", dirs)
+	}
+	if dirs, _ := Dirs([]string{filepath.Join(root, "Other - Singles")}); len(dirs) != 1 {
+		t.Errorf("a Singles folder given directly should be included, got %v", dirs)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !linux && !darwin
+
+package ui
+
+import (
+	"os"
+	"time"
+)
+
+// inputPollSupported 当前平台无法在等待按键时响应退出信号，读取会一直阻塞并吞掉后续输入，
+// 因此不启用全屏界面
+const inputPollSupported = false
+
+// waitInput 不支持等待输入的平台上直接返回可读（全屏界面不会在这些平台上启用）
+func waitInput(f *os.File, timeout time.Duration) (bool, error) {
+	return true, nil
+}
@@ -0,0 +1,23 @@
+//go:build linux || darwin
+
+package ui
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// inputPollSupported 当前平台支持带超时等待按键，全屏界面可以在 Stop 后停止读取标准输入
+const inputPollSupported = true
+
+// waitInput 等待 f 可读，最多等待 timeout，超时返回 false
+func waitInput(f *os.File, timeout time.Duration) (bool, error) {
+	fds := []unix.PollFd{{Fd: int32(f.Fd()), Events: unix.POLLIN}}
+	n, err := unix.Poll(fds, int(timeout/time.Millisecond))
+	if err == unix.EINTR {
+		return false, nil
+	}
+	return n > 0, err
+}
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"main/internal/core"
 	"main/internal/progress"
 
 	"github.com/fatih/color"
@@ -11,32 +12,34 @@ import (
 // 实现progress.ProgressListener接口
 // 将进度事件转换为UI更新
 type UIProgressListener struct {
-	// 可以添加需要的状态
+	board *core.TrackBoard // 事件更新的目标状态面板
 }
 
-// NewUIProgressListener 创建UI进度监听器
-func NewUIProgressListener() *UIProgressListener {
-	return &UIProgressListener{}
+// NewUIProgressListener 创建UI进度监听器，事件将更新到指定的状态面板
+func NewUIProgressListener(board *core.TrackBoard) *UIProgressListener {
+	return &UIProgressListener{board: board}
 }
 
 // OnProgress 处理进度更新事件
 func (l *UIProgressListener) OnProgress(event progress.ProgressEvent) {
 	status := formatStatus(event)
 	colorFunc := getColorFunc(event.Stage)
-	UpdateStatus(event.TrackIndex, status, colorFunc)
+	UpdateStatus(l.board, event.TrackIndex, status, colorFunc)
 }
 
 // OnComplete 处理完成事件
 func (l *UIProgressListener) OnComplete(trackIndex int) {
 	greenFunc := color.New(color.FgGreen).SprintFunc()
-	UpdateStatus(trackIndex, "下载完成", greenFunc)
+	UpdateStatus(l.board, trackIndex, "下载完成", greenFunc)
+	l.board.Update(trackIndex, func(ts *core.TrackStatus) { ts.Failed = false })
 }
 
 // OnError 处理错误事件
 func (l *UIProgressListener) OnError(trackIndex int, err error) {
 	errMsg := truncateError(err)
 	redFunc := color.New(color.FgRed).SprintFunc()
-	UpdateStatus(trackIndex, errMsg, redFunc)
+	UpdateStatus(l.board, trackIndex, errMsg, redFunc)
+	l.board.Update(trackIndex, func(ts *core.TrackStatus) { ts.Failed = true })
 }
 
 // formatStatus 根据进度事件格式化状态文本
@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"main/internal/logger"
+	"main/internal/search"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+)
+
+// SelectSearchResults 以表格显示搜索结果并读取选择，返回选中的结果
+func SelectSearchResults(results []search.Result, storefront, term string) []search.Result {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"", "Name", "Artist", "Year", "Tracks", "Rating", "Audio"})
+	table.SetRowLine(false)
+	table.SetCaption(true, fmt.Sprintf("Storefront: %s, %d results for %q", strings.ToUpper(storefront), len(results), term))
+	table.SetHeaderColor(tablewriter.Colors{},
+		tablewriter.Colors{tablewriter.FgRedColor, tablewriter.Bold},
+		tablewriter.Colors{tablewriter.FgBlackColor, tablewriter.Bold},
+		tablewriter.Colors{tablewriter.FgBlackColor, tablewriter.Bold},
+		tablewriter.Colors{tablewriter.FgBlackColor, tablewriter.Bold},
+		tablewriter.Colors{tablewriter.FgBlackColor, tablewriter.Bold},
+		tablewriter.Colors{tablewriter.FgBlackColor, tablewriter.Bold})
+	table.SetColumnColor(tablewriter.Colors{tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgRedColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgBlackColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgBlackColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgBlackColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgBlackColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgBlackColor})
+	for i, r := range results {
+		name := r.Name
+		if r.Album != "" {
+			name = fmt.Sprintf("%s (%s)", r.Name, r.Album)
+		}
+		tracks := ""
+		if r.TrackCount > 0 {
+			tracks = fmt.Sprint(r.TrackCount)
+		}
+		rating := ""
+		if r.Explicit {
+			rating = "E"
+		}
+		audio := strings.Join(r.Traits, ", ")
+		if r.Type == search.TypeArtists {
+			audio = strings.Join(r.Genres, ", ")
+		}
+		table.Append([]string{fmt.Sprint(i + 1), name, r.Artist, r.Year, tracks, rating, audio})
+	}
+	table.Render()
+
+	logger.Info("Please select from the results above (multiple options separated by commas, ranges supported, or type 'all' to select all)")
+	cyanColor := color.New(color.FgCyan)
+	cyanColor.Print("select: ")
+	reader := bufio.NewReader(os.Stdin)
+	input, err := reader.ReadString('\n')
+	if err != nil {
+		logger.Error("读取输入错误: %v", err)
+	}
+	var selected []search.Result
+	for _, n := range ParseSelection(input, len(results)) {
+		selected = append(selected, results[n-1])
+	}
+	return selected
+}
@@ -0,0 +1,645 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"main/internal/core"
+	"main/internal/logger"
+	"main/internal/metrics"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-runewidth"
+	"golang.org/x/term"
+)
+
+// tuiRefreshInterval 全屏界面刷新间隔
+const tuiRefreshInterval = 250 * time.Millisecond
+
+// keyPollInterval 等待按键时检查退出信号的间隔
+const keyPollInterval = 100 * time.Millisecond
+
+// activeTUI 当前运行中的全屏界面，供信号处理在退出前恢复终端
+var (
+	activeTUI   *TUI
+	activeTUIMu sync.Mutex
+)
+
+// TUISupported 判断当前终端是否可以使用全屏界面
+// 标准输入和标准输出都必须是终端（重定向到文件或管道时回退到普通界面），
+// 且平台支持带超时等待按键（否则退出后按键读取仍会阻塞并吞掉后续输入）
+func TUISupported() bool {
+	return inputPollSupported && !core.DisableDynamicUI &&
+		term.IsTerminal(int(os.Stdin.Fd())) &&
+		term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// TUI 全屏终端界面
+// 在备用屏幕中同时显示任务队列、选中专辑的曲目表和可滚动的日志面板，并支持快捷键：
+//
+//	p/空格 暂停/继续    s 跳过选中的专辑    r 重试选中的失败任务    w 开关工作-休息循环
+//	↑/↓ 选择队列项      Esc 恢复自动跟随    PgUp/PgDn 滚动日志     q 退出
+type TUI struct {
+	queue   *core.TaskQueue
+	control *core.Control
+	out     *os.File
+	state   *term.State
+	logs    logBuffer
+
+	prevLogger io.Writer
+	prevColor  io.Writer
+
+	mu         sync.Mutex
+	selectedID int  // 选中的队列项编号
+	follow     bool // 自动选中进行中的任务
+	logOffset  int  // 日志面板向上滚动的行数，0 表示显示最新日志
+	waiting    bool // 全部任务已结束，等待重试或退出
+	finish     chan struct{}
+
+	stop     chan struct{}
+	rendered chan struct{}
+	keysDone chan struct{} // 按键读取结束
+	stopOnce sync.Once
+}
+
+// StartTUI 进入全屏界面：切换到备用屏幕、开启原始输入模式，并接管 logger 输出
+func StartTUI(queue *core.TaskQueue, control *core.Control) (*TUI, error) {
+	stdinFd := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(stdinFd)
+	if err != nil {
+		return nil, fmt.Errorf("切换终端原始模式失败: %w", err)
+	}
+
+	t := &TUI{
+		queue:    queue,
+		control:  control,
+		out:      os.Stdout,
+		state:    state,
+		follow:   true,
+		stop:     make(chan struct{}),
+		rendered: make(chan struct{}),
+		keysDone: make(chan struct{}),
+	}
+
+	// 日志写入日志面板；配置了日志文件时同时保留文件输出
+	t.prevLogger = logger.Output()
+	if t.prevLogger == os.Stdout || t.prevLogger == os.Stderr {
+		logger.SetOutput(t)
+	} else {
+		logger.SetOutput(io.MultiWriter(t.prevLogger, t))
+	}
+	t.prevColor = color.Output
+	color.Output = t
+	log.SetOutput(t)
+
+	fmt.Fprint(t.out, "\033[?1049h\033[?25l\033[2J")
+
+	activeTUIMu.Lock()
+	activeTUI = t
+	activeTUIMu.Unlock()
+
+	go t.readKeys()
+	go t.renderLoop()
+	return t, nil
+}
+
+// StopTUI 关闭当前运行中的全屏界面（未启用时无操作），用于信号处理等退出路径
+func StopTUI() {
+	activeTUIMu.Lock()
+	t := activeTUI
+	activeTUIMu.Unlock()
+	if t != nil {
+		t.Stop()
+	}
+}
+
+// Stop 退出全屏界面：恢复终端和日志输出，并将日志面板的内容输出到普通终端，便于退出后查看
+func (t *TUI) Stop() {
+	t.stopOnce.Do(func() {
+		close(t.stop)
+		<-t.rendered
+		// 等待按键读取退出，避免其在界面关闭后读走后续交互（如曲目选择）的输入
+		<-t.keysDone
+
+		fmt.Fprint(t.out, "\033[?25h\033[?1049l")
+		term.Restore(int(os.Stdin.Fd()), t.state)
+
+		logger.SetOutput(t.prevLogger)
+		color.Output = t.prevColor
+		log.SetOutput(os.Stderr)
+
+		activeTUIMu.Lock()
+		activeTUI = nil
+		activeTUIMu.Unlock()
+
+		for _, line := range t.logs.Lines() {
+			fmt.Fprintln(t.out, line)
+		}
+	})
+}
+
+// Write 实现 io.Writer，写入的内容显示在日志面板中
+func (t *TUI) Write(p []byte) (int, error) {
+	return t.logs.Write(p)
+}
+
+// WaitForRetry 在全部任务结束后调用
+// 存在失败任务时保持界面，等待用户按 r 重试（返回 true）或按 q 退出（返回 false）；没有失败任务时直接返回 false
+func (t *TUI) WaitForRetry() bool {
+	if t.queue.Count(core.TaskFailed) == 0 {
+		return false
+	}
+	requeued := t.queue.Requeued()
+	if t.queue.Count(core.TaskPending) > 0 {
+		return true
+	}
+
+	t.mu.Lock()
+	t.waiting = true
+	t.finish = make(chan struct{})
+	finish := t.finish
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		t.waiting = false
+		t.mu.Unlock()
+	}()
+
+	logger.Info("🏁 全部任务已结束，%d 个任务失败：选中后按 r 重试，按 q 退出", t.queue.Count(core.TaskFailed))
+	select {
+	case <-requeued:
+		return true
+	case <-finish:
+		return false
+	case <-t.stop:
+		return false
+	}
+}
+
+// renderLoop 定时重绘整个屏幕，直到 Stop
+func (t *TUI) renderLoop() {
+	defer close(t.rendered)
+	ticker := time.NewTicker(tuiRefreshInterval)
+	defer ticker.Stop()
+
+	t.render()
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+			t.render()
+		}
+	}
+}
+
+// readKeys 读取并处理按键，直到 Stop
+// 只在标准输入可读时读取，每隔 keyPollInterval 检查一次退出信号，Stop 后不会再读取标准输入
+func (t *TUI) readKeys() {
+	defer close(t.keysDone)
+	buf := make([]byte, 64)
+	for {
+		select {
+		case <-t.stop:
+			return
+		default:
+		}
+		ready, err := waitInput(os.Stdin, keyPollInterval)
+		if err != nil {
+			return
+		}
+		if !ready {
+			continue
+		}
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, key := range parseKeys(buf[:n]) {
+			t.handleKey(key)
+		}
+	}
+}
+
+// 按键名称
+const (
+	keyUp       = "up"
+	keyDown     = "down"
+	keyPageUp   = "pgup"
+	keyPageDown = "pgdn"
+	keyEscape   = "esc"
+	keyCtrlC    = "ctrl-c"
+)
+
+// parseKeys 将一次读取的原始输入解析为按键列表（方向键和翻页键为转义序列）
+func parseKeys(data []byte) []string {
+	var keys []string
+	for i := 0; i < len(data); i++ {
+		switch {
+		case data[i] == 0x1b && i+2 < len(data) && data[i+1] == '[':
+			switch data[i+2] {
+			case 'A':
+				keys = append(keys, keyUp)
+			case 'B':
+				keys = append(keys, keyDown)
+			case '5':
+				keys = append(keys, keyPageUp)
+			case '6':
+				keys = append(keys, keyPageDown)
+			}
+			i += 2
+			// 翻页键的序列以 ~ 结尾
+			if i+1 < len(data) && data[i+1] == '~' {
+				i++
+			}
+		case data[i] == 0x1b:
+			keys = append(keys, keyEscape)
+		case data[i] == 0x03:
+			keys = append(keys, keyCtrlC)
+		default:
+			keys = append(keys, string(data[i]))
+		}
+	}
+	return keys
+}
+
+// handleKey 执行快捷键对应的操作
+func (t *TUI) handleKey(key string) {
+	switch key {
+	case "p", "P", " ":
+		if t.control.TogglePause() {
+			logger.Info("⏸️  已暂停：进行中的曲目完成后不再开始新的曲目，按 p 继续")
+		} else {
+			logger.Info("▶️  已继续下载")
+		}
+
+	case "s", "S":
+		item, ok := t.selectedItem()
+		if !ok {
+			return
+		}
+		if t.queue.Skip(item.ID) {
+			logger.Info("⏭️  已跳过: %s", item.Title())
+		} else {
+			logger.Info("ℹ️  只能跳过等待中或进行中的专辑: %s", item.Title())
+		}
+
+	case "r", "R":
+		item, ok := t.selectedItem()
+		if !ok {
+			return
+		}
+		if t.queue.Retry(item.ID) {
+			logger.Info("🔁 已重新加入队列: %s", item.Title())
+		} else {
+			logger.Info("ℹ️  只能重试失败的任务: %s", item.Title())
+		}
+
+	case "w", "W":
+		if core.Config.WorkDurationMinutes <= 0 || core.Config.RestDurationMinutes <= 0 {
+			logger.Info("ℹ️  未配置工作-休息时长（work-duration-minutes / rest-duration-minutes），无法开启")
+			return
+		}
+		if t.control.ToggleWorkRest() {
+			metrics.SetWorkRestState(metrics.WorkRestWorking)
+			logger.Info("⏰ 工作-休息循环已开启: 工作 %d 分钟 / 休息 %d 分钟",
+				core.Config.WorkDurationMinutes, core.Config.RestDurationMinutes)
+		} else {
+			metrics.SetWorkRestState(metrics.WorkRestDisabled)
+			logger.Info("⏰ 工作-休息循环已关闭")
+		}
+
+	case keyUp, "k":
+		t.moveSelection(-1)
+	case keyDown, "j":
+		t.moveSelection(1)
+
+	case keyEscape:
+		t.mu.Lock()
+		t.follow = true
+		t.logOffset = 0
+		t.mu.Unlock()
+
+	case keyPageUp:
+		t.scrollLogs(1)
+	case keyPageDown:
+		t.scrollLogs(-1)
+
+	case "q", "Q", keyCtrlC:
+		t.mu.Lock()
+		waiting, finish := t.waiting, t.finish
+		if waiting {
+			t.waiting = false
+		}
+		t.mu.Unlock()
+		if waiting {
+			close(finish)
+			return
+		}
+		// 原始模式下 Ctrl+C 不会产生信号，向自身发送中断信号以走统一的安全退出流程
+		if p, err := os.FindProcess(os.Getpid()); err != nil || p.Signal(os.Interrupt) != nil {
+			t.Stop()
+			os.Exit(130)
+		}
+	}
+}
+
+// selectedItem 返回当前选中的队列项
+func (t *TUI) selectedItem() (core.QueueItem, bool) {
+	items := t.queue.Snapshot()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	idx := t.selectedIndex(items)
+	if idx < 0 {
+		return core.QueueItem{}, false
+	}
+	return items[idx], true
+}
+
+// selectedIndex 计算选中项在队列快照中的位置（调用方需持有锁）
+// 自动跟随时选中第一个进行中的任务，没有进行中的任务时选中最后一个已结束的任务
+func (t *TUI) selectedIndex(items []core.QueueItem) int {
+	if len(items) == 0 {
+		return -1
+	}
+	if !t.follow {
+		for i, item := range items {
+			if item.ID == t.selectedID {
+				return i
+			}
+		}
+	}
+	last := -1
+	for i, item := range items {
+		if item.State == core.TaskRunning {
+			t.selectedID = item.ID
+			return i
+		}
+		if item.State != core.TaskPending {
+			last = i
+		}
+	}
+	if last < 0 {
+		last = 0
+	}
+	t.selectedID = items[last].ID
+	return last
+}
+
+// moveSelection 上下移动选中项，手动选择后停止自动跟随
+func (t *TUI) moveSelection(delta int) {
+	items := t.queue.Snapshot()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	idx := t.selectedIndex(items)
+	if idx < 0 {
+		return
+	}
+	idx += delta
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(items) {
+		idx = len(items) - 1
+	}
+	t.selectedID = items[idx].ID
+	t.follow = false
+}
+
+// scrollLogs 按页滚动日志面板，pages 为正表示向上（更早的日志）
+func (t *TUI) scrollLogs(pages int) {
+	_, height, _ := term.GetSize(int(t.out.Fd()))
+	step := height / 3
+	if step < 1 {
+		step = 1
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.logOffset += pages * step
+	if t.logOffset < 0 {
+		t.logOffset = 0
+	}
+}
+
+// render 重绘整个屏幕
+func (t *TUI) render() {
+	width, height, err := term.GetSize(int(t.out.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		width, height = 80, 24
+	}
+
+	var lines []string
+	if width < 40 || height < 12 {
+		lines = []string{"终端窗口过小，请调整到至少 40x12"}
+	} else {
+		lines = t.layout(width, height)
+	}
+
+	var b strings.Builder
+	for row := 0; row < height; row++ {
+		fmt.Fprintf(&b, "\033[%d;1H", row+1)
+		if row < len(lines) {
+			b.WriteString(fitWidth(lines[row], width))
+		}
+		b.WriteString("\033[K")
+	}
+	fmt.Fprint(t.out, b.String()) // OK: 全屏界面渲染，直接输出到终端
+}
+
+// layout 生成整屏内容：标题栏、队列面板、曲目面板、日志面板和快捷键提示
+func (t *TUI) layout(width, height int) []string {
+	items := t.queue.Snapshot()
+
+	t.mu.Lock()
+	selIdx := t.selectedIndex(items)
+	follow := t.follow
+	logOffset := t.logOffset
+	waiting := t.waiting
+	t.mu.Unlock()
+
+	var selected *core.QueueItem
+	if selIdx >= 0 {
+		selected = &items[selIdx]
+	}
+	var tracks []core.TrackStatus
+	if selected != nil && selected.Job != nil && selected.Job.Board != nil {
+		tracks = selected.Job.Board.Snapshot()
+	}
+
+	// 行数分配：标题栏 1 + 三个面板各 1 行标题 + 快捷键提示 1，其余按比例分给面板内容
+	body := height - 5
+	queueRows := clampInt(len(items), 1, body/4)
+	trackRows := clampInt(len(tracks), 1, body/3)
+	logRows := body - queueRows - trackRows
+
+	lines := make([]string, 0, height)
+	lines = append(lines, t.headerLine(items))
+
+	// 队列面板
+	queueTitle := fmt.Sprintf("队列 (%d)", len(items))
+	if !follow {
+		queueTitle += " · 手动选择，Esc 恢复跟随"
+	}
+	lines = append(lines, sectionLine(queueTitle, width))
+	start := windowStart(selIdx, len(items), queueRows)
+	for i := start; i < start+queueRows; i++ {
+		if i >= len(items) {
+			lines = append(lines, "")
+			continue
+		}
+		lines = append(lines, queueLine(items[i], i == selIdx))
+	}
+
+	// 曲目面板
+	trackTitle := "曲目"
+	if selected != nil {
+		trackTitle = "曲目 · " + selected.Title()
+	}
+	lines = append(lines, sectionLine(trackTitle, width))
+	if len(tracks) == 0 {
+		lines = append(lines, "  （该任务尚未开始或没有曲目）")
+	}
+	trackStart := 0
+	if len(tracks) > trackRows {
+		// 曲目较多时从第一首未完成的曲目附近开始显示
+		for i, ts := range tracks {
+			if !strings.Contains(ts.Status, "完成") && !strings.Contains(ts.Status, "已存在") {
+				trackStart = i
+				break
+			}
+		}
+		trackStart = clampInt(trackStart-1, 0, len(tracks)-trackRows)
+	}
+	for i := trackStart; i < trackStart+trackRows && len(tracks) > 0; i++ {
+		if i >= len(tracks) {
+			lines = append(lines, "")
+			continue
+		}
+		ts := tracks[i]
+		line := FormatTrackLine(ts, width)
+		if ts.StatusColor != nil {
+			line = ts.StatusColor(line)
+		}
+		lines = append(lines, line)
+	}
+	for len(lines) < 3+queueRows+trackRows {
+		lines = append(lines, "")
+	}
+
+	// 日志面板
+	logLines, logOffset := t.logs.Tail(logRows, logOffset)
+	logTitle := "日志"
+	if logOffset > 0 {
+		logTitle = fmt.Sprintf("日志 · 已向上滚动 %d 行，PgDn 返回", logOffset)
+	}
+	t.mu.Lock()
+	t.logOffset = logOffset
+	t.mu.Unlock()
+	lines = append(lines, sectionLine(logTitle, width))
+	lines = append(lines, logLines...)
+	for len(lines) < height-1 {
+		lines = append(lines, "")
+	}
+
+	// 快捷键提示
+	help := "p 暂停/继续  s 跳过  r 重试  w 工作-休息  ↑↓ 选择  PgUp/PgDn 日志  q 退出"
+	if waiting {
+		help = "全部任务已结束 · r 重试选中的失败任务  ↑↓ 选择  q 退出"
+	}
+	lines = append(lines, color.New(color.FgBlack, color.BgWhite).Sprint(padRight(help, width)))
+	return lines
+}
+
+// headerLine 标题栏：进度统计、暂停状态和工作-休息开关
+func (t *TUI) headerLine(items []core.QueueItem) string {
+	var done, failed, skipped int
+	for _, item := range items {
+		switch item.State {
+		case core.TaskDone:
+			done++
+		case core.TaskFailed:
+			failed++
+		case core.TaskSkipped:
+			skipped++
+		}
+	}
+
+	state := color.GreenString("▶ 下载中")
+	if t.control.Paused() {
+		state = color.YellowString("⏸ 已暂停")
+	}
+	workRest := "关"
+	if t.control.WorkRestEnabled() {
+		workRest = "开"
+	}
+
+	return fmt.Sprintf("%s  %s  进度 %d/%d  ✓%d ✗%d ⏭%d  工作-休息: %s  %s",
+		color.New(color.FgCyan, color.Bold).Sprint("🎵 Apple Music Downloader"),
+		state, done+failed+skipped, len(items), done, failed, skipped, workRest,
+		time.Now().Format("15:04:05"))
+}
+
+// queueLine 队列面板中的一行
+func queueLine(item core.QueueItem, selected bool) string {
+	var icon string
+	paint := fmt.Sprint
+	switch item.State {
+	case core.TaskPending:
+		icon = "·"
+	case core.TaskRunning:
+		icon, paint = "▶", color.New(color.FgYellow).Sprint
+	case core.TaskDone:
+		icon, paint = "✓", color.New(color.FgGreen).Sprint
+	case core.TaskFailed:
+		icon, paint = "✗", color.New(color.FgRed).Sprint
+	case core.TaskSkipped:
+		icon, paint = "⏭", color.New(color.FgHiBlack).Sprint
+	}
+
+	text := fmt.Sprintf("%s [%d] %s  %s", icon, item.Num, item.Title(), item.State)
+	if item.Note != "" {
+		text += ": " + item.Note
+	}
+	if selected {
+		return "\033[7m" + text + "\033[0m"
+	}
+	return paint(text)
+}
+
+// sectionLine 面板标题行
+func sectionLine(title string, width int) string {
+	text := "── " + title + " "
+	return color.New(color.FgCyan).Sprint(text + strings.Repeat("─", clampInt(width-len([]rune(text))-2, 0, width)))
+}
+
+// windowStart 计算长列表中包含选中项的显示窗口起点
+func windowStart(selected, total, rows int) int {
+	if total <= rows || selected < 0 {
+		return 0
+	}
+	return clampInt(selected-rows/2, 0, total-rows)
+}
+
+// padRight 用空格将字符串补齐到指定宽度
+func padRight(s string, width int) string {
+	if pad := width - runewidth.StringWidth(s); pad > 0 {
+		return s + strings.Repeat(" ", pad)
+	}
+	return s
+}
+
+// clampInt 将 v 限制在 [lo, hi] 范围内（hi < lo 时返回 lo）
+func clampInt(v, lo, hi int) int {
+	if v > hi {
+		v = hi
+	}
+	if v < lo {
+		v = lo
+	}
+	return v
+}
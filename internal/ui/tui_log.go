@@ -0,0 +1,119 @@
+package ui
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// tuiLogLimit 日志面板保留的最大行数
+const tuiLogLimit = 2000
+
+// controlSeqRegex 匹配除颜色码（SGR）以外的终端控制序列，如光标移动和清行
+var controlSeqRegex = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-ln-z]`)
+
+// logBuffer 日志面板的行缓冲，实现 io.Writer
+// 按换行切分写入内容，保留颜色码，去掉会破坏全屏布局的光标控制序列
+type logBuffer struct {
+	mu      sync.Mutex
+	lines   []string
+	partial string
+}
+
+// Write 追加日志内容
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	text := b.partial + string(p)
+	parts := strings.Split(text, "\n")
+	b.partial = parts[len(parts)-1]
+	for _, line := range parts[:len(parts)-1] {
+		b.lines = append(b.lines, sanitizeLogLine(line))
+	}
+	if over := len(b.lines) - tuiLogLimit; over > 0 {
+		b.lines = append(b.lines[:0], b.lines[over:]...)
+	}
+	return len(p), nil
+}
+
+// Tail 返回倒数第 offset 行之前的最多 n 行（offset 为 0 时即最新的 n 行）
+// offset 超出范围时按最早的一页处理，返回实际使用的 offset
+func (b *logBuffer) Tail(n, offset int) ([]string, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if maxOffset := len(b.lines) - n; offset > maxOffset {
+		offset = maxOffset
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	end := len(b.lines) - offset
+	start := end - n
+	if start < 0 {
+		start = 0
+	}
+	out := make([]string, end-start)
+	copy(out, b.lines[start:end])
+	return out, offset
+}
+
+// Lines 返回全部日志行（含尚未换行的最后一行）
+func (b *logBuffer) Lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]string, len(b.lines), len(b.lines)+1)
+	copy(out, b.lines)
+	if b.partial != "" {
+		out = append(out, sanitizeLogLine(b.partial))
+	}
+	return out
+}
+
+// sanitizeLogLine 清理单行日志：回车后的内容覆盖之前的内容，去掉光标控制序列，制表符展开为空格
+func sanitizeLogLine(line string) string {
+	if i := strings.LastIndex(line, "\r"); i >= 0 {
+		if rest := line[i+1:]; rest != "" {
+			line = rest
+		} else {
+			line = line[:i]
+		}
+	}
+	line = controlSeqRegex.ReplaceAllString(line, "")
+	return strings.ReplaceAll(line, "\t", "    ")
+}
+
+// fitWidth 将含颜色码的字符串截断到指定显示宽度（中文和 emoji 按双宽计算）
+func fitWidth(s string, width int) string {
+	var b strings.Builder
+	used := 0
+	colored := false
+	for i := 0; i < len(s); {
+		if s[i] == 0x1b {
+			end := strings.IndexByte(s[i:], 'm')
+			if end < 0 {
+				break
+			}
+			b.WriteString(s[i : i+end+1])
+			colored = true
+			i += end + 1
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		w := runewidth.RuneWidth(r)
+		if used+w > width {
+			break
+		}
+		b.WriteString(s[i : i+size])
+		used += w
+		i += size
+	}
+	if colored {
+		b.WriteString("\033[0m")
+	}
+	return b.String()
+}
@@ -3,11 +3,13 @@ package ui
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"main/internal/logger"
 	"main/utils/structs"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"main/internal/core"
@@ -56,7 +58,8 @@ func getTerminalWidth() int {
 	return width
 }
 
-func RenderUI(done <-chan struct{}) {
+// RenderUI 定时将状态面板渲染到终端，直到 done 被关闭
+func RenderUI(board *core.TrackBoard, done <-chan struct{}) {
 	// 增加刷新间隔到500ms，减少视觉噪音
 	ticker := time.NewTicker(500 * time.Millisecond)
 	defer ticker.Stop()
@@ -72,17 +75,16 @@ func RenderUI(done <-chan struct{}) {
 			// UI暂停，等待恢复信号
 			<-resumeChan
 		case <-ticker.C:
-			PrintUI(firstUpdate)
+			PrintUI(board, firstUpdate)
 			firstUpdate = false
 		}
 	}
 }
 
-func PrintUI(isFirstUpdate bool) {
-	core.UiMutex.Lock()
-	defer core.UiMutex.Unlock()
-
-	if len(core.TrackStatuses) == 0 {
+// PrintUI 将状态面板的当前内容原地刷新到终端
+func PrintUI(board *core.TrackBoard, isFirstUpdate bool) {
+	statuses := board.Snapshot()
+	if len(statuses) == 0 {
 		return
 	}
 
@@ -90,18 +92,18 @@ func PrintUI(isFirstUpdate bool) {
 
 	// 首次更新时打印占位换行符，后续更新时向上移动光标
 	if isFirstUpdate {
-		builder.WriteString(strings.Repeat("\n", len(core.TrackStatuses)))
+		builder.WriteString(strings.Repeat("\n", len(statuses)))
 	}
 
 	// 向上移动N行（N = 曲目数）
 	// 新的formatter保证每个track只占一行，不会换行
-	builder.WriteString(fmt.Sprintf("\033[%dA", len(core.TrackStatuses)))
+	builder.WriteString(fmt.Sprintf("\033[%dA", len(statuses)))
 
 	// 获取终端宽度，用于智能格式化
 	terminalWidth := getTerminalWidth()
 
 	// 使用新的智能格式化系统
-	for _, ts := range core.TrackStatuses {
+	for _, ts := range statuses {
 		// 1. 格式化曲目行（自动适应终端宽度，保证不换行）
 		line := FormatTrackLine(ts, terminalWidth)
 
@@ -116,12 +118,117 @@ func PrintUI(isFirstUpdate bool) {
 	fmt.Print(builder.String()) // OK: UI渲染核心，必须使用fmt.Print输出到stdout
 }
 
-func UpdateStatus(index int, status string, sColor func(a ...interface{}) string) {
-	core.UiMutex.Lock()
-	defer core.UiMutex.Unlock()
-	if index < len(core.TrackStatuses) {
+// RenderBoards 定时将多个专辑的状态面板渲染到终端（多专辑并行模式），直到 done 被关闭
+// 渲染期间日志经由面板输出：先擦除面板，打印日志，再在日志下方重绘面板，避免两者互相覆盖
+func RenderBoards(set *core.BoardSet, done <-chan struct{}) {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	r := &boardRenderer{set: set, out: os.Stdout}
+	prevOutput := logger.Output()
+	logger.SetOutput(r)
+	defer logger.SetOutput(prevOutput)
+
+	for {
+		select {
+		case <-done:
+			r.refresh()
+			return
+		case <-suspendChan:
+			<-resumeChan
+		case <-ticker.C:
+			r.refresh()
+		}
+	}
+}
+
+// boardRenderer 多专辑状态面板的渲染器，实现 io.Writer 供日志输出使用
+// 面板始终位于终端底部，lastLines 为当前面板占用的行数
+type boardRenderer struct {
+	mu        sync.Mutex
+	set       *core.BoardSet
+	out       io.Writer
+	lastLines int
+	partial   string
+}
+
+// refresh 原地重绘面板
+func (r *boardRenderer) refresh() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastLines = PrintBoards(r.out, r.set, r.lastLines)
+}
+
+// Write 在面板上方输出日志；不完整的行先缓存，等换行后再输出
+func (r *boardRenderer) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	text := r.partial + string(p)
+	cut := strings.LastIndexByte(text, '\n') + 1
+	r.partial = text[cut:]
+	if cut == 0 {
+		return len(p), nil
+	}
+
+	var builder strings.Builder
+	if r.lastLines > 0 {
+		// 回到面板首行并清除到屏幕末尾，日志写在原面板的位置上
+		builder.WriteString(fmt.Sprintf("\033[%dA\r\033[J", r.lastLines))
+	}
+	builder.WriteString(text[:cut])
+	if _, err := io.WriteString(r.out, builder.String()); err != nil {
+		return 0, err
+	}
+	r.lastLines = PrintBoards(r.out, r.set, 0)
+	return len(p), nil
+}
+
+// PrintBoards 将所有专辑的状态面板原地刷新到 w，每个专辑一行标题加若干曲目行
+// lastLines 为上次输出的行数，返回本次输出的行数，用于下次刷新时回退光标
+func PrintBoards(w io.Writer, set *core.BoardSet, lastLines int) int {
+	terminalWidth := getTerminalWidth()
+	titleColor := color.New(color.FgCyan, color.Bold).SprintFunc()
+
+	var lines []string
+	for _, board := range set.Boards() {
+		statuses := board.Snapshot()
+		if len(statuses) == 0 {
+			continue
+		}
+		title := truncateToWidth("💽 "+board.Title(), terminalWidth-2)
+		lines = append(lines, titleColor(title))
+		for _, ts := range statuses {
+			lines = append(lines, ts.StatusColor(FormatTrackLine(ts, terminalWidth)))
+		}
+	}
+
+	if len(lines) == 0 && lastLines == 0 {
+		return 0
+	}
+
+	var builder strings.Builder
+	if lastLines > 0 {
+		builder.WriteString(fmt.Sprintf("\033[%dA", lastLines))
+	}
+	for _, line := range lines {
+		builder.WriteString(fmt.Sprintf("\r\033[K%s\n", line))
+	}
+	// 面板变少时清除多余的旧行，并把光标移回内容末尾
+	if extra := lastLines - len(lines); extra > 0 {
+		builder.WriteString(strings.Repeat("\r\033[K\n", extra))
+		builder.WriteString(fmt.Sprintf("\033[%dA", extra))
+	}
+
+	io.WriteString(w, builder.String()) // OK: UI渲染核心，直接写终端
+	return len(lines)
+}
+
+// UpdateStatus 更新状态面板中指定曲目的状态
+func UpdateStatus(board *core.TrackBoard, index int, status string, sColor func(a ...interface{}) string) {
+	board.Update(index, func(ts *core.TrackStatus) {
 		now := time.Now().UnixNano()
-		lastUpdate := core.TrackStatuses[index].LastUpdateNs
+		lastUpdate := ts.LastUpdateNs
 
 		// 防抖策略：除非是重要状态（完成、错误），否则限制最小更新间隔为100ms
 		// 这减少了不必要的UI刷新，降低视觉噪音
@@ -139,13 +246,13 @@ func UpdateStatus(index int, status string, sColor func(a ...interface{}) string
 			return
 		}
 
-		core.TrackStatuses[index].Status = status
-		core.TrackStatuses[index].StatusColor = sColor
-		core.TrackStatuses[index].LastUpdateNs = now
-	}
+		ts.Status = status
+		ts.StatusColor = sColor
+		ts.LastUpdateNs = now
+	})
 }
 
-func SelectTracks(meta *structs.AutoGenerated, storefront, urlArg_i string) []int {
+func SelectTracks(job *core.Job, meta *structs.AutoGenerated, storefront, urlArg_i string) []int {
 	trackTotal := len(meta.Data[0].Relationships.Tracks.Data)
 	arr := make([]int, trackTotal)
 	for i := 0; i < trackTotal; i++ {
@@ -153,7 +260,7 @@ func SelectTracks(meta *structs.AutoGenerated, storefront, urlArg_i string) []in
 	}
 	selected := []int{}
 
-	if core.Dl_song {
+	if job.Song {
 		found := false
 		for i, track := range meta.Data[0].Relationships.Tracks.Data {
 			if urlArg_i == track.ID {
@@ -166,7 +273,7 @@ func SelectTracks(meta *structs.AutoGenerated, storefront, urlArg_i string) []in
 			logger.Error("指定的单曲ID未在专辑中找到")
 			return nil
 		}
-	} else if !core.Dl_select {
+	} else if !job.Select {
 		selected = arr
 	} else {
 		var data [][]string
@@ -220,43 +327,40 @@ func SelectTracks(meta *structs.AutoGenerated, storefront, urlArg_i string) []in
 		if err != nil {
 			logger.Error("读取输入错误: %v", err)
 		}
-		input = strings.TrimSpace(input)
-		if input == "all" {
-			selected = arr
-		} else {
-			selectedOptions := [][]string{}
-			parts := strings.Split(input, ",")
-			for _, part := range parts {
-				if strings.Contains(part, "-") {
-					rangeParts := strings.Split(part, "-")
-					selectedOptions = append(selectedOptions, rangeParts)
-				} else {
-					selectedOptions = append(selectedOptions, []string{part})
-				}
+		selected = ParseSelection(input, trackTotal)
+	}
+	return selected
+}
+
+// ParseSelection 解析选择输入：逗号分隔的序号与范围（如 1,3-5），或 all 选择全部；超出 1..total 的选项被忽略
+func ParseSelection(input string, total int) []int {
+	input = strings.TrimSpace(input)
+	selected := []int{}
+	if input == "all" {
+		for i := 1; i <= total; i++ {
+			selected = append(selected, i)
+		}
+		return selected
+	}
+	for _, part := range strings.Split(input, ",") {
+		part = strings.TrimSpace(part)
+		if start, end, isRange := strings.Cut(part, "-"); isRange {
+			from, err1 := strconv.Atoi(strings.TrimSpace(start))
+			to, err2 := strconv.Atoi(strings.TrimSpace(end))
+			if err1 != nil || err2 != nil || from < 1 || to > total || from > to {
+				continue
 			}
-			for _, opt := range selectedOptions {
-				if len(opt) == 1 {
-					num, err := strconv.Atoi(opt[0])
-					if err != nil {
-						continue
-					}
-					if num > 0 && num <= len(arr) {
-						selected = append(selected, num)
-					}
-				} else if len(opt) == 2 {
-					start, err1 := strconv.Atoi(opt[0])
-					end, err2 := strconv.Atoi(opt[1])
-					if err1 != nil || err2 != nil {
-						continue
-					}
-					if start < 1 || end > len(arr) || start > end {
-						continue
-					}
-					for i := start; i <= end; i++ {
-						selected = append(selected, i)
-					}
-				}
+			for i := from; i <= to; i++ {
+				selected = append(selected, i)
 			}
+			continue
+		}
+		num, err := strconv.Atoi(part)
+		if err != nil {
+			continue
+		}
+		if num > 0 && num <= total {
+			selected = append(selected, num)
 		}
 	}
 	return selected
@@ -0,0 +1,213 @@
+package utils
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// PartSuffix 未完成文件的后缀
+// 所有输出先写入 "<最终文件名>.part"，fsync 后再重命名为最终文件名，
+// 因此最终文件名只会出现在完整写入的文件上；进程崩溃或被中断时只会留下 .part 文件
+const PartSuffix = ".part"
+
+// PartPath 返回最终路径对应的临时文件路径
+func PartPath(path string) string {
+	return path + PartSuffix
+}
+
+// IsPartFile 判断路径是否为未完成的临时文件
+func IsPartFile(path string) bool {
+	return strings.HasSuffix(path, PartSuffix)
+}
+
+// CommitFile 将已写完的临时文件落盘并重命名为最终文件名（覆盖已存在的同名文件）
+func CommitFile(partPath, finalPath string) error {
+	f, err := os.OpenFile(partPath, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("打开临时文件失败: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("同步临时文件失败: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("关闭临时文件失败: %w", err)
+	}
+	if err := os.Rename(partPath, finalPath); err != nil {
+		return fmt.Errorf("重命名临时文件失败: %w", err)
+	}
+	syncDir(filepath.Dir(finalPath))
+	return nil
+}
+
+// syncDir 尽力将目录项落盘，确保重命名在掉电后仍然有效（部分平台不支持，忽略错误）
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	d.Close()
+}
+
+// AtomicFile 以 .part 临时文件写入、提交时才出现在最终路径的文件
+type AtomicFile struct {
+	*os.File
+	finalPath string
+	done      bool
+}
+
+// CreateAtomic 创建一个原子写入文件，写完后调用 Commit，失败时调用 Abort（Commit 后调用 Abort 无副作用）
+func CreateAtomic(path string) (*AtomicFile, error) {
+	f, err := os.Create(PartPath(path))
+	if err != nil {
+		return nil, err
+	}
+	return &AtomicFile{File: f, finalPath: path}, nil
+}
+
+// Commit 落盘并重命名为最终文件名
+func (a *AtomicFile) Commit() error {
+	if a.done {
+		return nil
+	}
+	a.done = true
+	if err := a.File.Sync(); err != nil {
+		a.File.Close()
+		os.Remove(a.File.Name())
+		return fmt.Errorf("同步文件失败: %w", err)
+	}
+	if err := a.File.Close(); err != nil {
+		os.Remove(a.File.Name())
+		return fmt.Errorf("关闭文件失败: %w", err)
+	}
+	if err := os.Rename(a.File.Name(), a.finalPath); err != nil {
+		os.Remove(a.File.Name())
+		return fmt.Errorf("重命名临时文件失败: %w", err)
+	}
+	syncDir(filepath.Dir(a.finalPath))
+	return nil
+}
+
+// Abort 放弃写入并删除临时文件
+func (a *AtomicFile) Abort() {
+	if a.done {
+		return
+	}
+	a.done = true
+	a.File.Close()
+	os.Remove(a.File.Name())
+}
+
+// WriteFileAtomic 原子地写入整个文件
+func WriteFileAtomic(path string, data []byte) error {
+	f, err := CreateAtomic(path)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	return f.Commit()
+}
+
+// CleanupPartFiles 删除目录树中遗留的 .part 文件（上次运行崩溃或被中断时留下）
+// 只删除修改时间早于 minAge 的文件，避免误删同时运行的其他实例正在写入的文件
+func CleanupPartFiles(root string, minAge time.Duration) (int, error) {
+	if root == "" {
+		return 0, nil
+	}
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return 0, nil
+	}
+
+	cutoff := time.Now().Add(-minAge)
+	removed := 0
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() || !IsPartFile(path) || info.ModTime().After(cutoff) {
+			return nil
+		}
+		if os.Remove(path) == nil {
+			removed++
+		}
+		return nil
+	})
+	return removed, err
+}
+
+// mp4Complete 检查 MP4 文件的顶层 box 结构是否完整
+// 直接写入最终文件名的旧版本在中断时会留下被截断的文件，其最后一个 box 的长度会超出文件末尾
+func mp4Complete(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	size := info.Size()
+
+	var offset int64
+	hasMoov := false
+	header := make([]byte, 16)
+	for offset < size {
+		if _, err := f.ReadAt(header[:8], offset); err != nil {
+			return false
+		}
+		boxSize := int64(binary.BigEndian.Uint32(header[:4]))
+		boxType := string(header[4:8])
+		switch boxSize {
+		case 0:
+			// 长度为 0 表示延伸到文件末尾
+			boxSize = size - offset
+		case 1:
+			// 64 位扩展长度
+			if _, err := f.ReadAt(header[8:16], offset+8); err != nil {
+				return false
+			}
+			boxSize = int64(binary.BigEndian.Uint64(header[8:16]))
+		}
+		if boxSize < 8 || offset+boxSize > size {
+			return false
+		}
+		if boxType == "moov" {
+			hasMoov = true
+		}
+		offset += boxSize
+	}
+	return hasMoov
+}
+
+// isMP4Path 判断是否为需要检查结构完整性的 MP4 容器文件
+func isMP4Path(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".m4a", ".mp4":
+		return true
+	}
+	return false
+}
+
+// copyToPart 将 src 拷贝到 dst 对应的临时文件并提交
+func copyToPart(src io.Reader, dst string) error {
+	f, err := CreateAtomic(dst)
+	if err != nil {
+		return fmt.Errorf("创建目标文件失败: %w", err)
+	}
+	defer f.Abort()
+	if _, err := io.Copy(f, src); err != nil {
+		return fmt.Errorf("拷贝文件内容失败: %w", err)
+	}
+	return f.Commit()
+}
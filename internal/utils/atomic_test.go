@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"main/internal/mp4test"
+)
+
+// TestAtomicFile 测试提交前最终路径不存在、放弃后不留临时文件
+func TestAtomicFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cover.jpg")
+
+	f, err := CreateAtomic(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("data"))
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("final path should not exist before Commit")
+	}
+	if err := f.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	f.Abort()
+	if data, _ := os.ReadFile(path); string(data) != "data" {
+		t.Errorf("committed content = %q", data)
+	}
+	if _, err := os.Stat(PartPath(path)); !os.IsNotExist(err) {
+		t.Error("part file should be gone after Commit")
+	}
+
+	g, _ := CreateAtomic(path)
+	g.Write([]byte("partial"))
+	g.Abort()
+	if data, _ := os.ReadFile(path); string(data) != "data" {
+		t.Errorf("aborted write changed final file: %q", data)
+	}
+	if _, err := os.Stat(PartPath(path)); !os.IsNotExist(err) {
+		t.Error("part file should be removed by Abort")
+	}
+}
+
+// TestValidateFileRejectsPartial 测试校验拒绝 .part 文件和被截断的 MP4
+func TestValidateFileRejectsPartial(t *testing.T) {
+	dir := t.TempDir()
+
+	var complete []byte
+	complete = append(complete, mp4test.Box("ftyp", make([]byte, 16))...)
+	complete = append(complete, mp4test.Box("moov", make([]byte, 64))...)
+	complete = append(complete, mp4test.Box("mdat", make([]byte, 256))...)
+
+	good := filepath.Join(dir, "01. Good.m4a")
+	os.WriteFile(good, complete, 0644)
+	if v, _ := ValidateFile(good, 0); !v.Exists || !v.IsValid {
+		t.Errorf("complete file: %+v", v)
+	}
+
+	truncated := filepath.Join(dir, "02. Truncated.m4a")
+	os.WriteFile(truncated, complete[:len(complete)-100], 0644)
+	if v, _ := ValidateFile(truncated, 0); v.IsValid {
+		t.Error("truncated file should be invalid")
+	}
+
+	noMoov := filepath.Join(dir, "03. NoMoov.m4a")
+	os.WriteFile(noMoov, append(mp4test.Box("ftyp", make([]byte, 16)), mp4test.Box("mdat", make([]byte, 256))...), 0644)
+	if v, _ := ValidateFile(noMoov, 0); v.IsValid {
+		t.Error("file without moov should be invalid")
+	}
+
+	part := PartPath(good)
+	os.WriteFile(part, complete, 0644)
+	if v, _ := ValidateFile(part, 0); v.Exists || v.IsValid {
+		t.Error(".part file should be treated as missing")
+	}
+}
+
+// TestCleanupPartFiles 测试只清理足够旧的 .part 文件
+func TestCleanupPartFiles(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "Artist", "Album")
+	os.MkdirAll(sub, 0755)
+
+	stale := filepath.Join(sub, "01. Song.m4a.part")
+	fresh := filepath.Join(sub, "02. Song.m4a.part")
+	keep := filepath.Join(sub, "03. Song.m4a")
+	for _, p := range []string{stale, fresh, keep} {
+		os.WriteFile(p, []byte("x"), 0644)
+	}
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(stale, old, old)
+	os.Chtimes(keep, old, old)
+
+	removed, err := CleanupPartFiles(dir, 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Errorf("removed = %d, want 1", removed)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("stale part file should be removed")
+	}
+	for _, p := range []string{fresh, keep} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("%s should be kept", filepath.Base(p))
+		}
+	}
+}
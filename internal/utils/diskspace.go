@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// errFreeSpaceUnsupported 当前平台不支持查询剩余空间
+var errFreeSpaceUnsupported = errors.New("当前平台不支持查询磁盘剩余空间")
+
+// FreeSpace 返回 path 所在文件系统对当前用户可用的剩余空间（字节）
+// path 不存在时向上查找最近的已存在目录（目标目录通常尚未创建）
+func FreeSpace(path string) (uint64, error) {
+	dir, err := existingDir(path)
+	if err != nil {
+		return 0, err
+	}
+	return freeSpace(dir)
+}
+
+// FileSystemID 返回 path 所在文件系统的标识，位于同一文件系统的目录返回相同的值
+// path 不存在时同样向上查找最近的已存在目录
+func FileSystemID(path string) (string, error) {
+	dir, err := existingDir(path)
+	if err != nil {
+		return "", err
+	}
+	return fileSystemID(dir)
+}
+
+// existingDir 返回 path 自身或其最近的已存在上级目录（绝对路径）
+func existingDir(path string) (string, error) {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir, nil
+		}
+		dir = parent
+	}
+}
+
+// FormatBytes 将字节数格式化为易读字符串
+func FormatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit && exp < 3; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGT"[exp])
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !windows
+
+package utils
+
+func freeSpace(dir string) (uint64, error) {
+	return 0, errFreeSpaceUnsupported
+}
+
+func fileSystemID(dir string) (string, error) {
+	return "", errFreeSpaceUnsupported
+}
@@ -0,0 +1,42 @@
+//go:build windows
+
+package utils
+
+import (
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32                = syscall.NewLazyDLL("kernel32.dll")
+	procGetDiskFreeSpaceExW = kernel32.NewProc("GetDiskFreeSpaceExW")
+	procGetVolumePathNameW  = kernel32.NewProc("GetVolumePathNameW")
+)
+
+func freeSpace(dir string) (uint64, error) {
+	p, err := syscall.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, err
+	}
+	var available uint64
+	r, _, callErr := procGetDiskFreeSpaceExW.Call(uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&available)), 0, 0)
+	if r == 0 {
+		return 0, callErr
+	}
+	return available, nil
+}
+
+// fileSystemID 以卷挂载点（如 C:\）作为文件系统标识
+func fileSystemID(dir string) (string, error) {
+	p, err := syscall.UTF16PtrFromString(dir)
+	if err != nil {
+		return "", err
+	}
+	buf := make([]uint16, syscall.MAX_PATH+1)
+	r, _, callErr := procGetVolumePathNameW.Call(uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+	if r == 0 {
+		return "", callErr
+	}
+	return strings.ToUpper(syscall.UTF16ToString(buf)), nil
+}
@@ -2,7 +2,6 @@ package utils
 
 import (
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -132,8 +131,8 @@ func ValidateFile(path string, minSize int64) (*FileValidation, error) {
 		return nil, fmt.Errorf("检查文件失败: %w", err)
 	}
 	
-	// 如果是目录，返回不存在
-	if info.IsDir() {
+	// 如果是目录或未完成的临时文件，返回不存在
+	if info.IsDir() || IsPartFile(path) {
 		return &FileValidation{Exists: false, IsValid: false}, nil
 	}
 	
@@ -150,6 +149,12 @@ func ValidateFile(path string, minSize int64) (*FileValidation, error) {
 		return validation, fmt.Errorf("文件不完整: 当前 %d 字节 < 预期 %d 字节", 
 			info.Size(), minSize)
 	}
+
+	// MP4 结构校验（排除被截断的半成品）
+	if isMP4Path(path) && !mp4Complete(path) {
+		validation.IsValid = false
+		return validation, fmt.Errorf("文件不完整: MP4 结构被截断")
+	}
 	
 	return validation, nil
 }
@@ -299,33 +304,16 @@ func SafeMoveFile(src, dst string) error {
 	}
 
 	// 如果重命名失败（可能是跨文件系统），使用拷贝+删除
+	// 先拷贝到目标的 .part 临时文件，落盘后再重命名，中断时不会留下不完整的目标文件
 	srcFile, err := os.Open(src)
 	if err != nil {
 		return fmt.Errorf("打开源文件失败: %w", err)
 	}
 	defer srcFile.Close()
 
-	dstFile, err := os.Create(dst)
-	if err != nil {
-		return fmt.Errorf("创建目标文件失败: %w", err)
+	if err := copyToPart(srcFile, dst); err != nil {
+		return err
 	}
-	defer dstFile.Close()
-
-	// 拷贝文件内容
-	if _, err := io.Copy(dstFile, srcFile); err != nil {
-		dstFile.Close()
-		os.Remove(dst) // 清理不完整的目标文件
-		return fmt.Errorf("拷贝文件内容失败: %w", err)
-	}
-
-	// 确保数据写入磁盘
-	if err := dstFile.Sync(); err != nil {
-		dstFile.Close()
-		os.Remove(dst)
-		return fmt.Errorf("同步文件失败: %w", err)
-	}
-
-	dstFile.Close()
 	srcFile.Close()
 
 	// 拷贝文件权限
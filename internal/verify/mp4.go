@@ -0,0 +1,155 @@
+package verify
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// maxMoovSize moov 原子的读取上限，正常音频文件的 moov 远小于该值
+const maxMoovSize = 64 << 20
+
+// mp4Info MP4 容器结构检查结果
+type mp4Info struct {
+	Duration time.Duration // mvhd 中记录的时长
+	HasIlst  bool          // moov.udta.meta.ilst 是否存在
+}
+
+// inspectMP4 检查顶层原子结构（ftyp / moov / mdat 存在且大小不超出文件末尾），并读取 mvhd 时长
+// 文件在复制或写入过程中被截断时，最后一个原子的大小会超出文件末尾
+func inspectMP4(path string) (mp4Info, error) {
+	var info mp4Info
+	f, err := os.Open(path)
+	if err != nil {
+		return info, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return info, err
+	}
+	fileSize := st.Size()
+
+	var offset int64
+	var hasFtyp, hasMoov, hasMdat bool
+	header := make([]byte, 16)
+	for offset < fileSize {
+		if fileSize-offset < 8 {
+			return info, fmt.Errorf("文件末尾有 %d 字节不完整的原子头", fileSize-offset)
+		}
+		if _, err := f.ReadAt(header[:8], offset); err != nil {
+			return info, err
+		}
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		boxType := string(header[4:8])
+		headerSize := int64(8)
+		switch size {
+		case 0: // 延伸到文件末尾
+			size = fileSize - offset
+		case 1: // 64 位大小
+			if _, err := f.ReadAt(header[8:16], offset+8); err != nil {
+				return info, fmt.Errorf("读取原子 %s 大小失败: %w", boxType, err)
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerSize = 16
+		}
+		if size < headerSize {
+			return info, fmt.Errorf("原子 %s 大小无效（%d）", boxType, size)
+		}
+		if offset+size > fileSize {
+			return info, fmt.Errorf("原子 %s 超出文件末尾 %d 字节（文件可能被截断）", boxType, offset+size-fileSize)
+		}
+
+		switch boxType {
+		case "ftyp":
+			hasFtyp = true
+		case "mdat":
+			hasMdat = true
+		case "moov":
+			hasMoov = true
+			if size > maxMoovSize {
+				return info, fmt.Errorf("moov 原子过大（%d 字节）", size)
+			}
+			body := make([]byte, size-headerSize)
+			if _, err := f.ReadAt(body, offset+headerSize); err != nil && err != io.EOF {
+				return info, err
+			}
+			if err := parseMoov(body, &info); err != nil {
+				return info, err
+			}
+		}
+		offset += size
+	}
+
+	switch {
+	case !hasFtyp:
+		return info, errors.New("缺少 ftyp 原子，不是有效的 MP4 文件")
+	case !hasMoov:
+		return info, errors.New("缺少 moov 原子")
+	case !hasMdat:
+		return info, errors.New("缺少 mdat 原子（没有媒体数据）")
+	}
+	return info, nil
+}
+
+// parseMoov 在 moov 中查找 mvhd 与 udta.meta.ilst
+func parseMoov(moov []byte, info *mp4Info) error {
+	mvhd := findBox(moov, "mvhd")
+	if mvhd == nil {
+		return errors.New("缺少 mvhd 原子")
+	}
+	if len(mvhd) < 20 {
+		return errors.New("mvhd 原子不完整")
+	}
+	var timescale uint32
+	var duration uint64
+	if mvhd[0] == 1 {
+		if len(mvhd) < 32 {
+			return errors.New("mvhd 原子不完整")
+		}
+		timescale = binary.BigEndian.Uint32(mvhd[20:24])
+		duration = binary.BigEndian.Uint64(mvhd[24:32])
+	} else {
+		timescale = binary.BigEndian.Uint32(mvhd[12:16])
+		duration = uint64(binary.BigEndian.Uint32(mvhd[16:20]))
+	}
+	if timescale > 0 {
+		info.Duration = time.Duration(float64(duration) / float64(timescale) * float64(time.Second))
+	}
+
+	if udta := findBox(moov, "udta"); udta != nil {
+		// meta 是 full box，子原子从 4 字节 version/flags 之后开始
+		if meta := findBox(udta, "meta"); len(meta) > 4 {
+			info.HasIlst = findBox(meta[4:], "ilst") != nil
+		}
+	}
+	return nil
+}
+
+// findBox 在一段连续的子原子中查找指定类型，返回其内容（不含原子头）
+func findBox(data []byte, boxType string) []byte {
+	for len(data) >= 8 {
+		size := int(binary.BigEndian.Uint32(data[:4]))
+		headerSize := 8
+		if size == 1 {
+			if len(data) < 16 {
+				return nil
+			}
+			size = int(binary.BigEndian.Uint64(data[8:16]))
+			headerSize = 16
+		} else if size == 0 {
+			size = len(data)
+		}
+		if size < headerSize || size > len(data) {
+			return nil
+		}
+		if string(data[4:8]) == boxType {
+			return data[headerSize:size]
+		}
+		data = data[size:]
+	}
+	return nil
+}
@@ -0,0 +1,368 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"main/internal/logger"
+	"main/internal/metadata"
+	"main/internal/utils"
+	"main/utils/structs"
+
+	"github.com/zhaarey/go-mp4tag"
+)
+
+// 文件校验结果
+const (
+	StatusOK      = "ok"
+	StatusWarning = "warning" // 可播放，但标签不完整等
+	StatusCorrupt = "corrupt" // 无法完整解码、结构损坏或时长与目录不符
+)
+
+// durationTolerance 实际时长与目录 DurationInMillis 的允许误差
+const durationTolerance = 2 * time.Second
+
+// CorruptSuffix 重新入队时损坏文件的重命名后缀（避免下次下载时被判定为已存在）
+const CorruptSuffix = ".corrupt"
+
+// Catalog 根据专辑 ID 获取目录信息，用于核对曲目时长
+type Catalog func(albumID string) (*structs.AutoGenerated, error)
+
+// Options 校验选项
+type Options struct {
+	Workers   int      // 并发数，<= 0 时使用 CPU 核数
+	Decode    bool     // 使用 ffmpeg 完整解码
+	CheckArgs []string // ffmpeg 解码检测参数（同 ffmpeg-check-args）
+	Catalog   Catalog  // 为空时不核对时长
+	Progress  func(done, total int)
+}
+
+// FileResult 单个文件的校验结果
+type FileResult struct {
+	Path        string   `json:"path"`
+	Status      string   `json:"status"`
+	Problems    []string `json:"problems,omitempty"`
+	AlbumID     string   `json:"album_id,omitempty"`
+	TrackID     string   `json:"track_id,omitempty"`
+	DurationSec float64  `json:"duration_sec,omitempty"`
+	ExpectedSec float64  `json:"expected_sec,omitempty"` // 目录中的时长
+}
+
+// Report 校验报告
+type Report struct {
+	Started     time.Time    `json:"started"`
+	Roots       []string     `json:"roots"`
+	Checked     int          `json:"checked"`
+	OK          int          `json:"ok"`
+	Warnings    int          `json:"warnings"`
+	Corrupt     int          `json:"corrupt"`
+	DurationSec float64      `json:"duration_sec"`
+	Files       []FileResult `json:"files"` // 仅包含有问题的文件
+}
+
+// Run 遍历目录并校验其中的音频与 MV 文件
+func Run(ctx context.Context, roots []string, opts Options) (*Report, error) {
+	report := &Report{Started: time.Now(), Roots: roots}
+	var paths []string
+	for _, root := range roots {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && isMediaFile(path) {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("遍历目录 %s 失败: %w", root, err)
+		}
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	c := &checker{opts: opts, albums: make(map[string]*albumEntry)}
+	results := make([]FileResult, len(paths))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = c.check(ctx, paths[i])
+				if opts.Progress != nil {
+					mu.Lock()
+					done++
+					opts.Progress(done, len(paths))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range paths {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, r := range results {
+		report.Checked++
+		switch r.Status {
+		case StatusOK:
+			report.OK++
+			continue
+		case StatusWarning:
+			report.Warnings++
+		case StatusCorrupt:
+			report.Corrupt++
+		}
+		report.Files = append(report.Files, r)
+	}
+	sort.Slice(report.Files, func(i, j int) bool { return report.Files[i].Path < report.Files[j].Path })
+	report.DurationSec = time.Since(report.Started).Round(time.Second).Seconds()
+	return report, nil
+}
+
+// isMediaFile 只校验已完成的音频与 MV 文件（.part 临时文件除外）
+func isMediaFile(path string) bool {
+	if utils.IsPartFile(path) {
+		return false
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".m4a" || ext == ".mp4"
+}
+
+// albumEntry 专辑目录信息缓存（同一专辑只请求一次）
+type albumEntry struct {
+	once sync.Once
+	meta *structs.AutoGenerated
+	err  error
+}
+
+type checker struct {
+	opts   Options
+	mu     sync.Mutex
+	albums map[string]*albumEntry
+}
+
+// album 获取专辑目录信息
+func (c *checker) album(id string) (*structs.AutoGenerated, error) {
+	c.mu.Lock()
+	e, ok := c.albums[id]
+	if !ok {
+		e = &albumEntry{}
+		c.albums[id] = e
+	}
+	c.mu.Unlock()
+	e.once.Do(func() {
+		e.meta, e.err = c.opts.Catalog(id)
+	})
+	return e.meta, e.err
+}
+
+// check 校验单个文件：容器结构、标签、完整解码、与目录时长核对
+func (c *checker) check(ctx context.Context, path string) FileResult {
+	r := FileResult{Path: path, Status: StatusOK}
+	corrupt := func(format string, args ...interface{}) {
+		r.Status = StatusCorrupt
+		r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+	}
+	warn := func(format string, args ...interface{}) {
+		if r.Status == StatusOK {
+			r.Status = StatusWarning
+		}
+		r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+	}
+
+	info, err := inspectMP4(path)
+	if err != nil {
+		corrupt("容器结构损坏: %v", err)
+		return r
+	}
+	r.DurationSec = info.Duration.Seconds()
+
+	tags, err := metadata.ReadMP4Tags(path)
+	switch {
+	case err != nil:
+		corrupt("标签无法读取: %v", err)
+	case !info.HasIlst:
+		warn("缺少标签原子（ilst）")
+	default:
+		var missing []string
+		if tags.Title == "" {
+			missing = append(missing, "标题")
+		}
+		if tags.Artist == "" {
+			missing = append(missing, "艺术家")
+		}
+		if tags.Album == "" {
+			missing = append(missing, "专辑")
+		}
+		if len(missing) > 0 {
+			warn("标签缺少%s", strings.Join(missing, "、"))
+		}
+	}
+
+	if c.opts.Decode {
+		if err := decode(ctx, path, c.opts.CheckArgs); err != nil {
+			corrupt("解码失败: %v", err)
+		}
+	}
+
+	if tags != nil && tags.ItunesAlbumID > 0 {
+		r.AlbumID = strconv.Itoa(int(tags.ItunesAlbumID))
+		if c.opts.Catalog != nil {
+			c.checkDuration(&r, tags, info.Duration, corrupt)
+		}
+	}
+	return r
+}
+
+// checkDuration 按 ISRC（或碟号、曲号）在专辑目录中找到曲目并核对时长
+func (c *checker) checkDuration(r *FileResult, tags *mp4tag.MP4Tags, actual time.Duration, corrupt func(string, ...interface{})) {
+	meta, err := c.album(r.AlbumID)
+	if err != nil || meta == nil || len(meta.Data) == 0 {
+		logger.Debug("[校验] 获取专辑 %s 信息失败，跳过时长核对: %v", r.AlbumID, err)
+		return
+	}
+	tracks := meta.Data[0].Relationships.Tracks.Data
+	i := metadata.MatchTrack(tracks, metadata.CustomTag(tags, "ISRC"), int(tags.DiscNumber), int(tags.TrackNumber))
+	if i < 0 || tracks[i].Attributes.DurationInMillis <= 0 {
+		return
+	}
+	track := &tracks[i]
+	r.TrackID = track.ID
+	expected := time.Duration(track.Attributes.DurationInMillis) * time.Millisecond
+	r.ExpectedSec = expected.Seconds()
+	if diff := actual - expected; diff > durationTolerance || diff < -durationTolerance {
+		corrupt("时长 %s 与目录中的 %s 不符", actual.Round(time.Second), expected.Round(time.Second))
+	}
+}
+
+// decode 使用 ffmpeg 完整解码音轨，出现错误输出即视为损坏
+func decode(ctx context.Context, path string, checkArgs []string) error {
+	args := append([]string{"-nostdin", "-i", path}, checkArgs...)
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	cmd.Stdout = io.Discard
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	msg := strings.TrimSpace(stderr.String())
+	if len(msg) > 300 {
+		msg = msg[:300] + "..."
+	}
+	if err != nil {
+		if msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	if msg != "" {
+		return fmt.Errorf("%s", msg)
+	}
+	return nil
+}
+
+// WriteReport 写入报告，.json 扩展名输出 JSON，否则输出文本
+func WriteReport(r *Report, path string) error {
+	var data []byte
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var err error
+		data, err = json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+	} else {
+		data = []byte(r.Text())
+	}
+	return utils.WriteFileAtomic(path, data)
+}
+
+// Text 文本格式的报告
+func (r *Report) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "校验时间: %s\n", r.Started.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "校验目录: %s\n", strings.Join(r.Roots, ", "))
+	fmt.Fprintf(&b, "文件: %d | 正常: %d | 警告: %d | 损坏: %d | 用时: %s\n",
+		r.Checked, r.OK, r.Warnings, r.Corrupt, time.Duration(r.DurationSec)*time.Second)
+	for _, f := range r.Files {
+		label := "警告"
+		if f.Status == StatusCorrupt {
+			label = "损坏"
+		}
+		fmt.Fprintf(&b, "\n[%s] %s\n", label, f.Path)
+		for _, p := range f.Problems {
+			fmt.Fprintf(&b, "  - %s\n", p)
+		}
+	}
+	return b.String()
+}
+
+// Requeue 将损坏的曲目追加到任务文件（可直接作为下载参数），并将损坏文件重命名为 *.corrupt
+// 无法确定曲目 ID 时加入整张专辑；返回加入的链接数
+func Requeue(r *Report, storefront, taskFile string) (int, error) {
+	seen := make(map[string]bool)
+	var urls []string
+	for _, f := range r.Files {
+		if f.Status != StatusCorrupt {
+			continue
+		}
+		var url string
+		switch {
+		case f.TrackID != "":
+			url = fmt.Sprintf("https://music.apple.com/%s/song/%s", storefront, f.TrackID)
+		case f.AlbumID != "":
+			url = fmt.Sprintf("https://music.apple.com/%s/album/%s", storefront, f.AlbumID)
+		default:
+			logger.Warn("[校验] 无法确定曲目来源，未加入任务: %s", f.Path)
+			continue
+		}
+		if err := os.Rename(f.Path, f.Path+CorruptSuffix); err != nil {
+			logger.Warn("[校验] 重命名损坏文件失败 %s: %v", f.Path, err)
+			continue
+		}
+		if !seen[url] {
+			seen[url] = true
+			urls = append(urls, url)
+		}
+	}
+	if len(urls) == 0 {
+		return 0, nil
+	}
+
+	file, err := os.OpenFile(taskFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("打开任务文件失败: %w", err)
+	}
+	defer file.Close()
+	fmt.Fprintf(file, "# verify %s: %d 个损坏的曲目\n", time.Now().Format("2006-01-02 15:04:05"), len(urls))
+	for _, url := range urls {
+		if _, err := fmt.Fprintln(file, url); err != nil {
+			return 0, fmt.Errorf("写入任务文件失败: %w", err)
+		}
+	}
+	return len(urls), nil
+}
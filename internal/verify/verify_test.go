@@ -0,0 +1,103 @@
+package verify
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"main/internal/mp4test"
+)
+
+// testMP4 构造一个包含 ftyp、moov（mvhd + ilst）和 mdat 的最小 MP4 文件
+func testMP4(t *testing.T, duration uint32, mdatSize int) string {
+	t.Helper()
+	data := mp4test.Track(duration, mp4test.Ilst(), make([]byte, mdatSize))
+	path := filepath.Join(t.TempDir(), "01. Track.m4a")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestInspectMP4 测试读取 mvhd 时长与 ilst，以及截断文件的检测
+func TestInspectMP4(t *testing.T) {
+	path := testMP4(t, 183500, 64)
+	info, err := inspectMP4(path)
+	if err != nil {
+		t.Fatalf("inspectMP4 failed: %v", err)
+	}
+	if info.Duration != 183500*time.Millisecond || !info.HasIlst {
+		t.Errorf("Unexpected info: %+v", info)
+	}
+
+	// 截断 mdat
+	st, _ := os.Stat(path)
+	if err := os.Truncate(path, st.Size()-10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := inspectMP4(path); err == nil || !strings.Contains(err.Error(), "截断") {
+		t.Errorf("Expected truncation error, got %v", err)
+	}
+
+	notMP4 := filepath.Join(t.TempDir(), "x.m4a")
+	os.WriteFile(notMP4, mp4test.Box("free", make([]byte, 16)), 0644)
+	if _, err := inspectMP4(notMP4); err == nil {
+		t.Error("Expected error for file without ftyp")
+	}
+}
+
+// TestRunAndRequeue 测试报告统计、报告输出与损坏文件重新入队
+func TestRunAndRequeue(t *testing.T) {
+	root := t.TempDir()
+	good := testMP4(t, 1000, 16)
+	bad := testMP4(t, 1000, 16)
+	os.Rename(good, filepath.Join(root, "good.m4a"))
+	os.Rename(bad, filepath.Join(root, "bad.m4a"))
+	os.WriteFile(filepath.Join(root, "cover.jpg"), []byte("jpg"), 0644)
+	os.WriteFile(filepath.Join(root, "partial.m4a.part"), []byte("x"), 0644)
+	os.Truncate(filepath.Join(root, "bad.m4a"), 40)
+
+	report, err := Run(t.Context(), []string{root}, Options{Workers: 2})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Checked != 2 || report.Corrupt < 1 {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+	var corrupt *FileResult
+	for i := range report.Files {
+		if filepath.Base(report.Files[i].Path) == "bad.m4a" {
+			corrupt = &report.Files[i]
+		}
+	}
+	if corrupt == nil || corrupt.Status != StatusCorrupt {
+		t.Fatalf("bad.m4a should be corrupt: %+v", report.Files)
+	}
+
+	out := filepath.Join(root, "report.json")
+	if err := WriteReport(report, out); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(out); !strings.Contains(string(data), `"corrupt"`) {
+		t.Errorf("Unexpected JSON report: %s", data)
+	}
+	if !strings.Contains(report.Text(), "[损坏]") {
+		t.Errorf("Unexpected text report: %s", report.Text())
+	}
+
+	corrupt.TrackID = "1441"
+	taskFile := filepath.Join(root, "requeue.txt")
+	n, err := Requeue(report, "cn", taskFile)
+	if err != nil || n != 1 {
+		t.Fatalf("Requeue = %d, %v", n, err)
+	}
+	data, _ := os.ReadFile(taskFile)
+	if !strings.Contains(string(data), "https://music.apple.com/cn/song/1441") {
+		t.Errorf("Unexpected task file: %s", data)
+	}
+	if _, err := os.Stat(corrupt.Path + CorruptSuffix); err != nil {
+		t.Errorf("Corrupt file should be renamed: %v", err)
+	}
+}
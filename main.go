@@ -3,8 +3,8 @@ package main
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
-	"log"
 	"net/url"
 	"os"
 	"os/exec"
@@ -12,18 +12,30 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"main/internal/api"
+	"main/internal/checksum"
 	"main/internal/constants"
 	"main/internal/core"
 	"main/internal/downloader"
+	"main/internal/hooks"
+	"main/internal/importer"
 	"main/internal/logger"
+	"main/internal/metrics"
 	"main/internal/network"
+	"main/internal/notify"
 	"main/internal/parser"
 	"main/internal/progress"
+	"main/internal/retag"
+	"main/internal/search"
+	"main/internal/singles"
 	"main/internal/ui"
+	"main/internal/utils"
+	"main/internal/verify"
+	"main/utils/structs"
 
 	"github.com/fatih/color"
 	"github.com/spf13/pflag"
@@ -63,69 +75,60 @@ func loadDevEnv() {
 	}
 }
 
-func handleSingleMV(urlRaw string) {
+func handleSingleMV(job *core.Job, urlRaw string) {
 	if core.Debug_mode {
 		return
 	}
 	storefront, albumId := parser.CheckUrlMv(urlRaw)
+	mvLog := logger.With("mv_id", albumId, "url", urlRaw)
 	accountForMV, err := core.GetAccountForStorefront(storefront)
 	if err != nil {
-		logger.Error("MV 下载失败: %v", err)
-		core.SharedLock.Lock()
-		core.Counter.Error++
-		core.SharedLock.Unlock()
+		mvLog.Error("MV 下载失败: %v", err)
+		job.UpdateCounter(func(c *structs.Counter) { c.Error++ })
 		return
 	}
 
-	core.SharedLock.Lock()
-	core.Counter.Total++
-	core.SharedLock.Unlock()
+	job.UpdateCounter(func(c *structs.Counter) { c.Total++ })
 
 	if len(accountForMV.MediaUserToken) < constants.MinTokenLength {
-		logger.Error("MV 下载失败: MediaUserToken 无效或过短")
-		logger.Info("提示: 请确保在 dev.env 中配置了有效的 APPLE_MUSIC_MEDIA_USER_TOKEN_CN")
-		core.SharedLock.Lock()
-		core.Counter.Error++
-		core.SharedLock.Unlock()
+		mvLog.Error("MV 下载失败: MediaUserToken 无效或过短")
+		mvLog.Info("提示: 请确保在 dev.env 中配置了有效的 APPLE_MUSIC_MEDIA_USER_TOKEN_CN")
+		job.UpdateCounter(func(c *structs.Counter) { c.Error++ })
 		return
 	}
 
 	if _, err := exec.LookPath("mp4decrypt"); err != nil {
-		logger.Error("MV 下载失败: 未找到 mp4decrypt 工具")
-		logger.Info("提示: 请安装 mp4decrypt (https://www.bento4.com/downloads/)")
-		core.SharedLock.Lock()
-		core.Counter.Error++
-		core.SharedLock.Unlock()
+		mvLog.Error("MV 下载失败: 未找到 mp4decrypt 工具")
+		mvLog.Info("提示: 请安装 mp4decrypt (https://www.bento4.com/downloads/)")
+		job.UpdateCounter(func(c *structs.Counter) { c.Error++ })
 		return
 	}
 
 	mvInfo, err := api.GetMVInfoFromAdam(albumId, accountForMV, storefront)
 	if err != nil {
-		logger.Error("获取 MV 信息失败: %v", err)
-		core.SharedLock.Lock()
-		core.Counter.Error++
-		core.SharedLock.Unlock()
+		mvLog.Error("获取 MV 信息失败: %v", err)
+		job.UpdateCounter(func(c *structs.Counter) { c.Error++ })
 		return
 	}
 
 	// 输出MV信息
-	core.SafePrintf("🎤 歌手: %s\n", mvInfo.Data[0].Attributes.ArtistName)
-	core.SafePrintf("🎬 MV: %s\n", mvInfo.Data[0].Attributes.Name)
+	mvLog.Info("🎤 歌手: %s", mvInfo.Data[0].Attributes.ArtistName)
+	mvLog.Info("🎬 MV: %s", mvInfo.Data[0].Attributes.Name)
 
 	// 提取发行年份
 	var releaseYear string
 	if len(mvInfo.Data[0].Attributes.ReleaseDate) >= 4 {
 		releaseYear = mvInfo.Data[0].Attributes.ReleaseDate[:4]
-		core.SafePrintf("📅 发行年份: %s\n", releaseYear)
+		mvLog.Info("📅 发行年份: %s", releaseYear)
 	}
 
 	var artistFolder string
-	if core.Config.ArtistFolderFormat != "" {
+	if job.ArtistFolderFormat != "" {
 		artistFolder = strings.NewReplacer(
 			"{UrlArtistName}", core.LimitString(mvInfo.Data[0].Attributes.ArtistName),
 			"{ArtistName}", core.LimitString(mvInfo.Data[0].Attributes.ArtistName),
 			"{ArtistId}", "",
-		).Replace(core.Config.ArtistFolderFormat)
+		).Replace(job.ArtistFolderFormat)
 	}
 	sanitizedArtistFolder := core.ForbiddenNames.ReplaceAllString(artistFolder, "_")
 
@@ -138,7 +141,7 @@ func handleSingleMV(urlRaw string) {
 	// 应用缓存机制
 	cachePath, finalPath, usingCache := downloader.GetCacheBasePath(mvSaveFolder, albumId)
 
-	mvOutPath, mvResolution, err := downloader.MvDownloader(albumId, cachePath, sanitizedArtistFolder, "", storefront, nil, accountForMV)
+	mvOutPath, mvResolution, err := downloader.MvDownloader(job, albumId, cachePath, sanitizedArtistFolder, "", storefront, nil, accountForMV)
 
 	// 分辨率信息已在 MvDownloader 内部显示，这里不再重复显示
 	_ = mvResolution
@@ -150,21 +153,21 @@ func handleSingleMV(urlRaw string) {
 		finalMvPath := filepath.Join(finalPath, relPath)
 
 		// 移动文件
-		core.SafePrintf("\n📤 正在从缓存转移MV文件到目标位置...\n")
+		mvLog.Info("\n📤 正在从缓存转移MV文件到目标位置...")
 		if moveErr := downloader.SafeMoveFile(mvOutPath, finalMvPath); moveErr != nil {
 			// 检查是否是文件已存在的情况
 			if strings.Contains(moveErr.Error(), "目标文件已存在") {
-				logger.Info("✅ MV 文件已存在，跳过下载")
-				logger.Info("💾 保存路径: %s", finalMvPath)
+				mvLog.Info("✅ MV 文件已存在，跳过下载")
+				mvLog.Info("💾 保存路径: %s", finalMvPath)
 				// 文件已存在视为成功，清理缓存
 				os.RemoveAll(cachePath)
 			} else {
-				logger.Error("从缓存移动MV文件失败: %v", moveErr)
+				mvLog.Error("从缓存移动MV文件失败: %v", moveErr)
 				err = moveErr
 			}
 		} else {
-			core.SafePrintf("📥 MV文件转移完成！\n")
-			core.SafePrintf("💾 保存路径: %s\n", finalMvPath)
+			mvLog.Info("📥 MV文件转移完成！")
+			mvLog.Info("💾 保存路径: %s", finalMvPath)
 
 			// 清理缓存目录
 			mvCacheDir := filepath.Dir(mvOutPath)
@@ -177,8 +180,8 @@ func handleSingleMV(urlRaw string) {
 		}
 	} else if err == nil && !usingCache && mvOutPath != "" {
 		// 未使用缓存，直接保存
-		core.SafePrintf("\n📥 MV下载完成！\n")
-		core.SafePrintf("💾 保存路径: %s\n", mvOutPath)
+		mvLog.Info("\n📥 MV下载完成！")
+		mvLog.Info("💾 保存路径: %s", mvOutPath)
 	}
 
 	// 如果出错且使用了缓存，清理缓存
@@ -187,17 +190,13 @@ func handleSingleMV(urlRaw string) {
 	}
 
 	if err != nil {
-		core.SharedLock.Lock()
-		core.Counter.Error++
-		core.SharedLock.Unlock()
+		job.UpdateCounter(func(c *structs.Counter) { c.Error++ })
 		return
 	}
-	core.SharedLock.Lock()
-	core.Counter.Success++
-	core.SharedLock.Unlock()
+	job.UpdateCounter(func(c *structs.Counter) { c.Success++ })
 }
 
-func processURL(ctx context.Context, urlRaw string, wg *sync.WaitGroup, semaphore chan struct{}, currentTask int, totalTasks int, notifier *progress.ProgressNotifier) (string, string, error) {
+func processURL(ctx context.Context, job *core.Job, urlRaw string, wg *sync.WaitGroup, semaphore chan struct{}, currentTask int, totalTasks int, notifier *progress.ProgressNotifier) (string, string, error) {
 	if wg != nil {
 		defer wg.Done()
 	}
@@ -214,37 +213,26 @@ func processURL(ctx context.Context, urlRaw string, wg *sync.WaitGroup, semaphor
 	}
 
 	if totalTasks > 1 {
-		core.SafePrintf("🧾 [%d/%d] 开始处理: %s\n", currentTask, totalTasks, urlRaw)
+		logger.With("url", urlRaw).Info("🧾 [%d/%d] 开始处理: %s", currentTask, totalTasks, urlRaw)
 	}
 
 	var storefront, albumId string
 	var albumName string
 
 	if strings.Contains(urlRaw, "/music-video/") {
-		handleSingleMV(urlRaw)
+		handleSingleMV(job, urlRaw)
 		return "", "", nil
 	}
 
 	if strings.Contains(urlRaw, "/song/") {
-		tempStorefront, _ := parser.CheckUrlSong(urlRaw)
-		accountForSong, err := core.GetAccountForStorefront(tempStorefront)
+		var err error
+		urlRaw, job, err = resolveSongURL(job, urlRaw)
 		if err != nil {
-			logger.Error("获取歌曲信息失败 for %s: %v", urlRaw, err)
 			return "", "", err
 		}
-		urlRaw, err = api.GetUrlSong(urlRaw, accountForSong)
-		if err != nil {
-			logger.Error("获取歌曲链接失败 for %s: %v", urlRaw, err)
-			return "", "", err
-		}
-		core.Dl_song = true
 	}
 
-	if strings.Contains(urlRaw, "/playlist/") {
-		storefront, albumId = parser.CheckUrlPlaylist(urlRaw)
-	} else {
-		storefront, albumId = parser.CheckUrl(urlRaw)
-	}
+	storefront, albumId = parseAlbumURL(urlRaw)
 
 	if albumId == "" {
 		err := fmt.Errorf("无效的URL")
@@ -263,22 +251,95 @@ func processURL(ctx context.Context, urlRaw string, wg *sync.WaitGroup, semaphor
 
 	parse, err := url.Parse(urlRaw)
 	if err != nil {
-		log.Printf("解析URL失败 %s: %v", urlRaw, err)
+		logger.With("album_id", albumId, "stage", "parse").Error("解析URL失败 %s: %v", urlRaw, err)
 		return albumId, albumName, err
 	}
 	var urlArg_i = parse.Query().Get("i")
-	err = downloader.Rip(albumId, storefront, urlArg_i, urlRaw, notifier)
+	err = downloader.Rip(job, albumId, storefront, urlArg_i, urlRaw, notifier)
 	if err != nil {
-		core.SafePrintf("专辑下载失败: %s -> %v\n", urlRaw, err)
+		logger.With("album_id", albumId, "url", urlRaw, "stage", "download").Error("专辑下载失败: %v", err)
 		return albumId, albumName, err
 	} else {
 		if totalTasks > 1 {
-			core.SafePrintf("✅ [%d/%d] 任务完成: %s\n", currentTask, totalTasks, urlRaw)
+			logger.With("album_id", albumId, "url", urlRaw).Info("✅ [%d/%d] 任务完成", currentTask, totalTasks)
 		}
 		return albumId, albumName, nil
 	}
 }
 
+// resolveSongURL 将单曲链接转换为所在专辑的链接（?i= 指定曲目），并派生单曲下载模式的任务
+func resolveSongURL(job *core.Job, urlRaw string) (string, *core.Job, error) {
+	tempStorefront, _ := parser.CheckUrlSong(urlRaw)
+	accountForSong, err := core.GetAccountForStorefront(tempStorefront)
+	if err != nil {
+		logger.With("url", urlRaw, "stage", "resolve").Error("获取歌曲信息失败: %v", err)
+		return "", job, err
+	}
+	albumURL, err := api.GetUrlSong(urlRaw, accountForSong)
+	if err != nil {
+		logger.With("url", urlRaw, "stage", "resolve").Error("获取歌曲链接失败: %v", err)
+		job.UpdateCounter(func(c *structs.Counter) { c.NotSong++ })
+		return "", job, err
+	}
+	// 单曲链接只影响本链接，派生任务而不修改共享参数
+	songOpts := job.Options
+	songOpts.Song = true
+	return albumURL, job.WithOptions(songOpts), nil
+}
+
+// parseAlbumURL 解析专辑或播放列表链接中的地区与 ID
+func parseAlbumURL(urlRaw string) (string, string) {
+	if strings.Contains(urlRaw, "/playlist/") {
+		return parser.CheckUrlPlaylist(urlRaw)
+	}
+	return parser.CheckUrl(urlRaw)
+}
+
+// runDryRun 预检批量任务：展开链接后报告每个专辑的曲目、账户权限、可用音质、预计大小、目标路径与文件是否已存在，不下载任何文件
+func runDryRun(ctx context.Context, job *core.Job, initialUrls []string) {
+	plan := &downloader.Plan{Started: time.Now()}
+	tasks := expandTasks(job, initialUrls)
+	for i, task := range tasks {
+		if ctx.Err() != nil {
+			logger.Warn("预检已中断，已完成 %d/%d 个链接", i, len(tasks))
+			break
+		}
+		urlRaw, taskJob := task.url, task.job
+		if strings.Contains(urlRaw, "/music-video/") {
+			logger.Info("⏭️  跳过 MV 链接: %s", urlRaw)
+			continue
+		}
+		if strings.Contains(urlRaw, "/song/") {
+			var err error
+			urlRaw, taskJob, err = resolveSongURL(taskJob, urlRaw)
+			if err != nil {
+				plan.Add(downloader.AlbumPlan{URL: task.url, Error: err.Error()})
+				continue
+			}
+		}
+		storefront, albumId := parseAlbumURL(urlRaw)
+		if albumId == "" {
+			plan.Add(downloader.AlbumPlan{URL: urlRaw, Error: "无效的URL"})
+			continue
+		}
+		var urlArg_i string
+		if parse, err := url.Parse(urlRaw); err == nil {
+			urlArg_i = parse.Query().Get("i")
+		}
+		logger.Info("🔎 [%d/%d] 预检: %s", i+1, len(tasks), urlRaw)
+		plan.Add(downloader.PlanAlbum(taskJob, albumId, storefront, urlArg_i, urlRaw)...)
+	}
+
+	logger.Info("\n%s", plan.Text())
+	if core.DryRunReport != "" {
+		if err := downloader.WritePlan(plan, core.DryRunReport); err != nil {
+			logger.Error("写入报告失败: %v", err)
+		} else {
+			logger.Info("📝 报告已保存: %s", core.DryRunReport)
+		}
+	}
+}
+
 // parseTxtFile 从TXT文件中解析URL列表
 func parseTxtFile(filePath string) ([]string, error) {
 	fileBytes, err := os.ReadFile(filePath)
@@ -365,53 +426,79 @@ func detectDownloadMode(urls []string) string {
 	return mode
 }
 
-func runDownloads(ctx context.Context, initialUrls []string, isBatch bool, taskFile string, notifier *progress.ProgressNotifier) {
-	var finalUrls []string
-
-	// 检测下载模式
-	downloadMode := detectDownloadMode(initialUrls)
-
-	// 显示输入链接统计
-	if isBatch && len(initialUrls) > 0 {
-		core.SafePrintf("📋 初始链接总数: %d\n", len(initialUrls))
-		core.SafePrintf("🎯 下载模式: %s\n", downloadMode)
-		core.SafePrintf("🔄 开始预处理链接...\n\n")
-	}
+// downloadTask 下载队列中的单个链接及其任务上下文
+type downloadTask struct {
+	url string
+	job *core.Job
+}
 
+// expandTasks 展开歌手链接（专辑与 MV），其他链接原样加入任务列表
+func expandTasks(job *core.Job, initialUrls []string) []downloadTask {
+	var finalUrls []downloadTask
 	for _, urlRaw := range initialUrls {
 		if strings.Contains(urlRaw, "/artist/") {
-			core.SafePrintf("🔍 正在解析歌手页面: %s\n", urlRaw)
+			logger.Info("🔍 正在解析歌手页面: %s", urlRaw)
 			artistAccount := &core.Config.Accounts[0]
 			urlArtistName, urlArtistID, err := api.GetUrlArtistName(urlRaw, artistAccount)
 			if err != nil {
-				core.SafePrintf("获取歌手名称失败 for %s: %v\n", urlRaw, err)
+				logger.With("url", urlRaw, "stage", "artist").Error("获取歌手名称失败: %v", err)
 				continue
 			}
 
-			core.Config.ArtistFolderFormat = strings.NewReplacer(
+			// 艺术家文件夹格式仅作用于该艺术家展开出的链接，不修改全局配置
+			artistOpts := job.Options
+			artistOpts.ArtistFolderFormat = strings.NewReplacer(
 				"{UrlArtistName}", core.LimitString(urlArtistName),
 				"{ArtistId}", urlArtistID,
-			).Replace(core.Config.ArtistFolderFormat)
+			).Replace(job.ArtistFolderFormat)
+			artistJob := job.WithOptions(artistOpts)
 
 			albumArgs, err := api.CheckArtist(urlRaw, artistAccount, "albums")
 			if err != nil {
-				core.SafePrintf("获取歌手专辑失败 for %s: %v\n", urlRaw, err)
+				logger.With("artist_id", urlArtistID, "url", urlRaw, "stage", "artist").Error("获取歌手专辑失败: %v", err)
 			} else {
-				finalUrls = append(finalUrls, albumArgs...)
-				core.SafePrintf("📀 从歌手 %s 页面添加了 %d 张专辑到队列。\n", urlArtistName, len(albumArgs))
+				for _, albumUrl := range albumArgs {
+					finalUrls = append(finalUrls, downloadTask{url: albumUrl, job: artistJob})
+				}
+				logger.Info("📀 从歌手 %s 页面添加了 %d 张专辑到队列。", urlArtistName, len(albumArgs))
 			}
 
 			mvArgs, err := api.CheckArtist(urlRaw, artistAccount, "music-videos")
 			if err != nil {
-				core.SafePrintf("获取歌手MV失败 for %s: %v\n", urlRaw, err)
+				logger.With("artist_id", urlArtistID, "url", urlRaw, "stage", "artist").Error("获取歌手MV失败: %v", err)
 			} else {
-				finalUrls = append(finalUrls, mvArgs...)
-				core.SafePrintf("🎬 从歌手 %s 页面添加了 %d 个MV到队列。\n", urlArtistName, len(mvArgs))
+				for _, mvUrl := range mvArgs {
+					finalUrls = append(finalUrls, downloadTask{url: mvUrl, job: artistJob})
+				}
+				logger.Info("🎬 从歌手 %s 页面添加了 %d 个MV到队列。", urlArtistName, len(mvArgs))
 			}
 		} else {
-			finalUrls = append(finalUrls, urlRaw)
+			finalUrls = append(finalUrls, downloadTask{url: urlRaw, job: job})
 		}
 	}
+	return finalUrls
+}
+
+func runDownloads(ctx context.Context, job *core.Job, initialUrls []string, isBatch bool, taskFile string, notifier *progress.ProgressNotifier) {
+	if core.DryRun {
+		runDryRun(ctx, job, initialUrls)
+		return
+	}
+
+	// 后台清理上次运行崩溃或被中断时遗留在缓存中的 .part 临时文件
+	go cleanupStalePartFiles()
+
+	// 检测下载模式
+	downloadMode := detectDownloadMode(initialUrls)
+
+	// 显示输入链接统计
+	if isBatch && len(initialUrls) > 0 {
+		logger.Info("📋 初始链接总数: %d", len(initialUrls))
+		logger.Info("🎯 下载模式: %s", downloadMode)
+		logger.Info("🔄 开始预处理链接...\n")
+	}
+
+	finalUrls := expandTasks(job, initialUrls)
 
 	if len(finalUrls) == 0 {
 		logger.Warn("队列中没有有效的链接可供下载。")
@@ -429,12 +516,12 @@ func runDownloads(ctx context.Context, initialUrls []string, isBatch bool, taskF
 	startIndex := 0 // 实际数组索引（从0开始）
 	if core.StartFrom > 0 {
 		if core.StartFrom > totalTasks {
-			core.SafePrintf("⚠️  起始位置 %d 超过了总任务数 %d，将从第 1 个开始\n", core.StartFrom, totalTasks)
+			logger.Info("⚠️  起始位置 %d 超过了总任务数 %d，将从第 1 个开始", core.StartFrom, totalTasks)
 			core.StartFrom = 1
 		} else {
 			startIndex = core.StartFrom - 1 // 用户输入从1开始，转换为0开始的索引
 			skippedCount := startIndex
-			core.SafePrintf("⏭️  跳过前 %d 个任务，从第 %d 个开始下载\n", skippedCount, core.StartFrom)
+			logger.Info("⏭️  跳过前 %d 个任务，从第 %d 个开始下载", skippedCount, core.StartFrom)
 			finalUrls = finalUrls[startIndex:] // 跳过前面的链接
 		}
 	}
@@ -445,120 +532,831 @@ func runDownloads(ctx context.Context, initialUrls []string, isBatch bool, taskF
 	// 保存原始总数用于显示
 	originalTotalTasks := len(initialUrls)
 
+	// 交互式选择曲目需要独占终端，此时始终串行，也不使用全屏界面
+	parallel := isBatch && core.Config.AlbumConcurrency > 1 && !job.Select
+	useTUI := core.EnableTUI && !job.Select
+	if core.EnableTUI && job.Select {
+		logger.Info("ℹ️  选择曲目需要交互输入，本次不使用全屏界面")
+	} else if useTUI && !ui.TUISupported() {
+		logger.Info("ℹ️  当前输出不是终端、已禁用动态UI或平台不支持，全屏界面回退为普通界面")
+		useTUI = false
+	}
+
 	if isBatch {
-		core.SafePrintf("\n📋 ===== 开始下载任务 =====\n")
-		core.SafePrintf("🎯 下载模式: %s\n", downloadMode)
+		logger.Info("\n📋 ===== 开始下载任务 =====")
+		logger.Info("🎯 下载模式: %s", downloadMode)
 		if len(initialUrls) != totalTasks {
-			core.SafePrintf("📝 预处理完成: %d → %d 任务\n", len(initialUrls), originalTotalTasks)
+			logger.Info("📝 预处理完成: %d → %d 任务", len(initialUrls), originalTotalTasks)
 		} else {
-			core.SafePrintf("📝 任务总数: %d\n", originalTotalTasks)
+			logger.Info("📝 任务总数: %d", originalTotalTasks)
 		}
 		if core.StartFrom > 0 {
-			core.SafePrintf("📝 实际下载: 第 %d-%d 个（共 %d 个）\n", core.StartFrom, originalTotalTasks, totalTasks)
+			logger.Info("📝 实际下载: 第 %d-%d 个（共 %d 个）", core.StartFrom, originalTotalTasks, totalTasks)
+		}
+		if parallel {
+			logger.Info("⚡ 执行模式: 并行模式（同时处理 %d 个专辑）", core.Config.AlbumConcurrency)
+			if core.Config.MaxConcurrentTracks > 0 {
+				logger.Info("📦 曲目并发上限: %d（所有专辑合计）", core.Config.MaxConcurrentTracks)
+			} else {
+				logger.Info("📦 曲目并发上限: 不限制")
+			}
+		} else {
+			logger.Info("⚡ 执行模式: 串行模式")
+			logger.Info("📦 专辑内并发: 由配置控制")
 		}
-		core.SafePrintf("⚡ 执行模式: 串行模式\n")
-		core.SafePrintf("📦 专辑内并发: 由配置控制\n")
-		core.SafePrintf("=============================\n")
+		logger.Info("=============================")
 	} else {
-		core.SafePrintf("📋 开始下载任务\n🎯 模式: %s\n📝 总数: %d\n", downloadMode, originalTotalTasks)
+		logger.Info("📋 开始下载任务\n🎯 模式: %s\n📝 总数: %d", downloadMode, originalTotalTasks)
 	}
 
-	// 批量模式：串行执行（按链接顺序依次下载）
+	// 批量模式：默认串行执行（按链接顺序依次下载），album-concurrency > 1 时并行处理多个专辑
 	// 专辑内歌曲并发数由配置文件控制 (lossless_downloadthreads 等)
 
-	// 工作-休息循环机制
+	// 工作-休息循环机制（全屏界面中可随时开关）
+	control := job.Control()
+	control.SetWorkRest(isBatch && core.Config.WorkRestEnabled)
+	metrics.SetWorkRestState(workRestMetricState(control))
 	var workStartTime time.Time
-	if isBatch && core.Config.WorkRestEnabled {
+	if control.WorkRestEnabled() {
 		workStartTime = time.Now()
 		logger.Debug("[工作-休息] 循环已启用: 工作=%d分钟, 休息=%d分钟, 任务数=%d",
 			core.Config.WorkDurationMinutes, core.Config.RestDurationMinutes, len(finalUrls))
-		core.SafePrintf("⏰ 工作-休息循环: 工作 %d 分钟 / 休息 %d 分钟\n",
+		logger.Info("⏰ 工作-休息循环: 工作 %d 分钟 / 休息 %d 分钟",
 			core.Config.WorkDurationMinutes,
 			core.Config.RestDurationMinutes)
-		core.SafePrintf("⏱️  工作开始: %s\n", workStartTime.Format("15:04:05"))
+		logger.Info("⏱️  工作开始: %s", workStartTime.Format("15:04:05"))
 	} else if isBatch {
 		logger.Debug("[工作-休息] 循环未启用: WorkRestEnabled=%v, 任务数=%d",
 			core.Config.WorkRestEnabled, len(finalUrls))
 	}
 
-	for i, urlToProcess := range finalUrls {
+	// 建立下载队列；多专辑并行或全屏界面时每个链接使用独立的状态面板
+	queue := core.NewTaskQueue()
+	for i, task := range finalUrls {
+		taskJob := task.job
+		if parallel || useTUI {
+			taskJob = taskJob.WithBoard(core.NewTrackBoard())
+		}
+		queue.Add(i+1+startIndex, task.url, taskJob) // 实际编号 = 当前索引 + 1 + 跳过的数量
+	}
+
+	var tui *ui.TUI
+	if useTUI {
+		// 各专辑不再独占终端，状态面板由全屏界面统一显示
+		job.UseBoardSet(core.NewBoardSet())
+		var err error
+		tui, err = ui.StartTUI(queue, control)
+		if err != nil {
+			logger.Warn("启动全屏界面失败，使用普通界面: %v", err)
+			job.UseBoardSet(nil)
+		} else {
+			defer tui.Stop()
+		}
+	}
+
+	if parallel {
+		runParallelDownloads(ctx, job, queue, tui, originalTotalTasks, workStartTime, notifier)
+		return
+	}
+
+	for {
 		// 检查 context 是否已取消
 		select {
 		case <-ctx.Done():
-			logger.Warn("下载已中断，已完成 %d/%d 个任务", i, len(finalUrls))
+			logger.Warn("下载已中断，已完成 %d/%d 个任务", queue.Finished(), queue.Len())
 			return
 		default:
 		}
 
-		// 计算实际的任务编号（考虑 --start 参数）
-		actualTaskNum := i + 1 + startIndex    // 实际编号 = 当前索引 + 1 + 跳过的数量
-		originalTotalTasks := len(initialUrls) // 原始总数（包括被跳过的）
+		// 暂停期间不开始新的任务
+		if control.WaitIfPaused(ctx) != nil {
+			continue
+		}
+
+		item := queue.Next()
+		if item == nil {
+			// 全屏界面中可在全部任务结束后重试失败的任务
+			if tui != nil && tui.WaitForRetry() {
+				continue
+			}
+			break
+		}
 
-		_, _, _ = processURL(ctx, urlToProcess, nil, nil, actualTaskNum, originalTotalTasks, notifier)
+		albumNotifier, albumStats := notify.Watch(notifier)
+		_, _, err := processURL(ctx, item.Job, item.URL, nil, nil, item.Num, originalTotalTasks, albumNotifier)
+		notifyAlbumFinished(queue.Finish(item, err), albumStats, originalTotalTasks)
+		hasMore := queue.Count(core.TaskPending) > 0
 
 		// 任务之间添加视觉间隔（最后一个任务不需要）
-		if isBatch && i < len(finalUrls)-1 {
-			core.SafePrintf("\n%s\n", strings.Repeat("=", constants.VisualSeparatorLength))
+		if isBatch && hasMore {
+			logger.Info("\n%s", strings.Repeat("=", constants.VisualSeparatorLength))
 		}
 
 		// 工作-休息循环检查（在任务完成后）
-		if isBatch && core.Config.WorkRestEnabled && i < len(finalUrls)-1 {
-			elapsed := time.Since(workStartTime)
-			workDuration := time.Duration(core.Config.WorkDurationMinutes) * time.Minute
+		if isBatch && hasMore && control.WorkRestEnabled() {
+			workStartTime = checkWorkRest(control, workStartTime, queue.Finished(), queue.Len())
+		}
+	}
 
-			logger.Debug("[工作-休息] 检查点: 已工作 %.1f 分钟 / 阈值 %d 分钟, 任务进度 %d/%d",
-				elapsed.Minutes(), core.Config.WorkDurationMinutes, i+1, len(finalUrls))
-
-			if elapsed >= workDuration {
-				logger.Info("[工作-休息] 达到工作时长阈值，准备进入休息")
-				// 工作时间已到，需要休息
-				restDuration := time.Duration(core.Config.RestDurationMinutes) * time.Minute
-
-				cyan := color.New(color.FgCyan, color.Bold)
-				yellow := color.New(color.FgYellow)
-				green := color.New(color.FgGreen)
-
-				core.SafePrintf("\n%s\n", strings.Repeat("=", constants.VisualSeparatorLength))
-				cyan.Printf("⏸️  已工作 %d 分钟，进入休息\n", core.Config.WorkDurationMinutes)
-				yellow.Printf("😴 休息 %d 分钟\n", core.Config.RestDurationMinutes)
-				core.SafePrintf("📊 已完成: %d/%d\n", i+1, totalTasks)
-				core.SafePrintf("⏰ 当前时间: %s\n", time.Now().Format("15:04:05"))
-				core.SafePrintf("⏱️  恢复时间: %s\n", time.Now().Add(restDuration).Format("15:04:05"))
-				core.SafePrintf("%s\n", strings.Repeat("=", constants.VisualSeparatorLength))
-
-				// 休息倒计时（每30秒提示一次）
-				restTicker := time.NewTicker(constants.RestTickerInterval)
-				restTimer := time.NewTimer(restDuration)
-				restStartTime := time.Now()
-
-				restDone := false
-				for !restDone {
-					select {
-					case <-restTimer.C:
-						// 休息时间结束
-						restDone = true
-					case <-restTicker.C:
-						// 显示剩余时间
-						remainingTime := restDuration - time.Since(restStartTime)
-						if remainingTime > 0 {
-							core.SafePrintf("⏳ 休息中... 剩余时间: %.0f 分钟 %.0f 秒\n",
-								remainingTime.Minutes(),
-								remainingTime.Seconds()-remainingTime.Minutes()*60)
-						}
-					}
-				}
-				restTicker.Stop()
+}
+
+// runParallelDownloads 以专辑为单位并行执行批量任务
+// 任务按队列顺序派发，同时运行的专辑数由 album-concurrency 控制；
+// 工作-休息循环到点时停止派发新任务，等待进行中的专辑全部完成后再休息
+func runParallelDownloads(ctx context.Context, job *core.Job, queue *core.TaskQueue, tui *ui.TUI, originalTotalTasks int, workStartTime time.Time, notifier *progress.ProgressNotifier) {
+	// 动态UI：所有专辑的状态面板由此处统一渲染（全屏界面自行显示），渲染期间日志输出在面板上方
+	if tui == nil && !core.DisableDynamicUI {
+		boards := core.NewBoardSet()
+		job.UseBoardSet(boards)
+
+		doneUI := make(chan struct{})
+		rendered := make(chan struct{})
+		go func() {
+			ui.RenderBoards(boards, doneUI)
+			close(rendered)
+		}()
+		defer func() {
+			close(doneUI)
+			<-rendered
+		}()
+	}
 
-				// 休息结束，重新开始计时
+	control := job.Control()
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, core.Config.AlbumConcurrency)
+	defer wg.Wait()
+
+	dispatched := 0
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Warn("下载已中断，已派发 %d/%d 个任务", dispatched, queue.Len())
+			return
+		default:
+		}
+
+		// 工作-休息循环检查（在派发下一个任务前）
+		if control.WorkRestEnabled() && dispatched > 0 {
+			workDuration := time.Duration(core.Config.WorkDurationMinutes) * time.Minute
+			if workStartTime.IsZero() {
 				workStartTime = time.Now()
-				core.SafePrintf("\n%s\n", strings.Repeat("=", constants.VisualSeparatorLength))
-				green.Printf("✅ 休息完毕，继续任务\n")
-				core.SafePrintf("⏱️  工作开始: %s\n", workStartTime.Format("15:04:05"))
-				core.SafePrintf("%s\n", strings.Repeat("=", constants.VisualSeparatorLength))
+			} else if time.Since(workStartTime) >= workDuration {
+				wg.Wait()
+				workStartTime = checkWorkRest(control, workStartTime, queue.Finished(), queue.Len())
+			}
+		}
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			logger.Warn("下载已中断，已派发 %d/%d 个任务", dispatched, queue.Len())
+			return
+		}
+
+		// 暂停期间不派发新的专辑
+		if control.WaitIfPaused(ctx) != nil {
+			<-slots
+			continue
+		}
+
+		item := queue.Next()
+		if item == nil {
+			<-slots
+			// 等待进行中的专辑完成，其间可能有失败的任务被重新加入队列
+			wg.Wait()
+			if queue.Count(core.TaskPending) > 0 {
+				continue
+			}
+			if tui != nil && tui.WaitForRetry() {
+				continue
+			}
+			return
+		}
+
+		dispatched++
+		wg.Add(1)
+		go func(item *core.QueueItem) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			albumNotifier, albumStats := notify.Watch(notifier)
+			_, _, err := processURL(ctx, item.Job, item.URL, nil, nil, item.Num, originalTotalTasks, albumNotifier)
+			notifyAlbumFinished(queue.Finish(item, err), albumStats, originalTotalTasks)
+		}(item)
+	}
+}
+
+// notifyAlbumFinished 根据任务的最终状态发送专辑完成或失败通知（被跳过的任务不通知）
+func notifyAlbumFinished(item core.QueueItem, stats *notify.AlbumListener, totalTasks int) {
+	var eventType, title string
+	switch item.State {
+	case core.TaskDone:
+		eventType, title = notify.EventAlbumCompleted, "专辑下载完成"
+	case core.TaskFailed:
+		eventType, title = notify.EventAlbumFailed, "专辑下载失败"
+	default:
+		return
+	}
+
+	album := &notify.AlbumInfo{
+		Title:  item.Title(),
+		URL:    item.URL,
+		Index:  item.Num,
+		Total:  totalTasks,
+		Tracks: stats.Stats(),
+	}
+	if strings.Contains(item.URL, "/playlist/") {
+		_, album.ID = parser.CheckUrlPlaylist(item.URL)
+	} else {
+		_, album.ID = parser.CheckUrl(item.URL)
+	}
+
+	t := album.Tracks
+	message := fmt.Sprintf("%s（新下载 %d，已存在 %d，跳过 %d，失败 %d）",
+		album.Title, t.Downloaded, t.Exists, t.Skipped, t.Failed)
+	notify.Emit(notify.Event{
+		Type:    eventType,
+		Title:   title,
+		Message: message,
+		Album:   album,
+		Error:   item.Note,
+	})
+}
+
+// checkWorkRest 检查是否达到工作时长阈值，达到时休息并返回新的工作开始时间
+// completed 为已完成（或已派发并等待完成）的任务数；休息期间关闭工作-休息循环会提前结束休息
+func checkWorkRest(control *core.Control, workStartTime time.Time, completed int, totalTasks int) time.Time {
+	// 循环在运行中途开启：从现在开始计时
+	if workStartTime.IsZero() {
+		return time.Now()
+	}
+
+	elapsed := time.Since(workStartTime)
+	workDuration := time.Duration(core.Config.WorkDurationMinutes) * time.Minute
+
+	logger.Debug("[工作-休息] 检查点: 已工作 %.1f 分钟 / 阈值 %d 分钟, 任务进度 %d/%d",
+		elapsed.Minutes(), core.Config.WorkDurationMinutes, completed, totalTasks)
+
+	if elapsed < workDuration {
+		return workStartTime
+	}
+
+	logger.Info("[工作-休息] 达到工作时长阈值，准备进入休息")
+	// 工作时间已到，需要休息
+	restDuration := time.Duration(core.Config.RestDurationMinutes) * time.Minute
+
+	logger.Info("\n%s", strings.Repeat("=", constants.VisualSeparatorLength))
+	logger.Info("⏸️  已工作 %d 分钟，进入休息", core.Config.WorkDurationMinutes)
+	logger.Info("😴 休息 %d 分钟", core.Config.RestDurationMinutes)
+	logger.Info("📊 已完成: %d/%d", completed, totalTasks)
+	logger.Info("⏰ 当前时间: %s", time.Now().Format("15:04:05"))
+	logger.Info("⏱️  恢复时间: %s", time.Now().Add(restDuration).Format("15:04:05"))
+	logger.Info("%s", strings.Repeat("=", constants.VisualSeparatorLength))
+
+	// 休息倒计时（每30秒提示一次）
+	restTicker := time.NewTicker(constants.RestTickerInterval)
+	restTimer := time.NewTimer(restDuration)
+	restStartTime := time.Now()
+	changed := control.WorkRestChanged()
+	metrics.SetWorkRestState(metrics.WorkRestResting)
+
+	restDone := false
+	for !restDone {
+		select {
+		case <-restTimer.C:
+			// 休息时间结束
+			restDone = true
+		case <-changed:
+			changed = control.WorkRestChanged()
+			if !control.WorkRestEnabled() {
+				logger.Info("⏰ 工作-休息循环已关闭，提前结束休息")
+				restDone = true
+			}
+		case <-restTicker.C:
+			// 显示剩余时间
+			remainingTime := restDuration - time.Since(restStartTime)
+			if remainingTime > 0 {
+				logger.Info("⏳ 休息中... 剩余时间: %.0f 分钟 %.0f 秒",
+					remainingTime.Minutes(),
+					remainingTime.Seconds()-remainingTime.Minutes()*60)
 			}
 		}
 	}
+	restTicker.Stop()
+	restTimer.Stop()
+	metrics.SetWorkRestState(workRestMetricState(control))
+
+	// 休息结束，重新开始计时
+	workStartTime = time.Now()
+	logger.Info("\n%s", strings.Repeat("=", constants.VisualSeparatorLength))
+	logger.Info("✅ 休息完毕，继续任务")
+	logger.Info("⏱️  工作开始: %s", workStartTime.Format("15:04:05"))
+	logger.Info("%s", strings.Repeat("=", constants.VisualSeparatorLength))
+	return workStartTime
+}
 
+// workRestMetricState 返回工作-休息循环当前的指标状态（休息中的状态由 checkWorkRest 单独设置）
+func workRestMetricState(control *core.Control) string {
+	if control.WorkRestEnabled() {
+		return metrics.WorkRestWorking
+	}
+	return metrics.WorkRestDisabled
+}
+
+// runVerify 校验已下载的音频文件：容器结构、标签、完整解码与目录时长，输出报告
+func runVerify(ctx context.Context, roots []string) {
+	if len(roots) == 0 {
+		roots = libraryRoots()
+	}
+	if len(roots) == 0 {
+		logger.Error("没有可校验的目录")
+		return
+	}
+
+	opts := verify.Options{
+		Workers:   core.Config.FileValidation.ConcurrentWorkers,
+		Decode:    !core.VerifyNoDecode,
+		CheckArgs: strings.Fields(core.Config.FfmpegCheckArgs),
+	}
+	if opts.Decode {
+		if _, err := exec.LookPath("ffmpeg"); err != nil {
+			logger.Warn("未找到 ffmpeg，跳过完整解码检查")
+			opts.Decode = false
+		}
+	}
+
+	// 有可用令牌时按专辑 ID 获取目录信息，核对曲目时长
+	storefront := ""
+	if len(core.Config.Accounts) > 0 {
+		storefront = core.Config.Accounts[0].Storefront
+		if token, err := api.GetToken(); err == nil {
+			core.DeveloperToken = token
+			account := &core.Config.Accounts[0]
+			opts.Catalog = func(albumID string) (*structs.AutoGenerated, error) {
+				return api.GetMeta(albumID, account, account.Storefront)
+			}
+		} else {
+			logger.Warn("获取开发者 token 失败，跳过时长核对: %v", err)
+		}
+	}
+	if storefront == "" {
+		storefront = "us"
+	}
+
+	logger.Info("🔍 校验目录: %s", strings.Join(roots, ", "))
+	lastPercent := -1
+	opts.Progress = func(done, total int) {
+		if percent := done * 100 / total; percent/10 != lastPercent/10 {
+			lastPercent = percent
+			logger.Info("  已校验 %d/%d（%d%%）", done, total, percent)
+		}
+	}
+	report, err := verify.Run(ctx, roots, opts)
+	if err != nil {
+		logger.Error("校验失败: %v", err)
+		return
+	}
+
+	for _, f := range report.Files {
+		if f.Status == verify.StatusCorrupt {
+			logger.Error("❌ %s: %s", f.Path, strings.Join(f.Problems, "; "))
+		} else {
+			logger.Warn("⚠️  %s: %s", f.Path, strings.Join(f.Problems, "; "))
+		}
+	}
+	logger.Info("\n📋 已校验 %d 个文件 | 正常: %d | 警告: %d | 损坏: %d", report.Checked, report.OK, report.Warnings, report.Corrupt)
+
+	if core.VerifyReport != "" {
+		if err := verify.WriteReport(report, core.VerifyReport); err != nil {
+			logger.Error("写入报告失败: %v", err)
+		} else {
+			logger.Info("📝 报告已保存: %s", core.VerifyReport)
+		}
+	}
+	if core.VerifyRequeue != "" && report.Corrupt > 0 {
+		n, err := verify.Requeue(report, storefront, core.VerifyRequeue)
+		if err != nil {
+			logger.Error("重新入队失败: %v", err)
+		} else if n > 0 {
+			logger.Info("🔁 已将 %d 个链接加入 %s，损坏文件已重命名为 *%s，运行 ./程序名 %s 重新下载", n, core.VerifyRequeue, verify.CorruptSuffix, core.VerifyRequeue)
+		}
+	}
+}
+
+// libraryRoots 返回配置中存在的音频保存目录（去重）
+func libraryRoots() []string {
+	var roots []string
+	seen := make(map[string]bool)
+	for _, dir := range []string{core.Config.AlacSaveFolder, core.Config.AtmosSaveFolder, core.Config.AacSaveFolder} {
+		if dir == "" || seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		if _, err := os.Stat(dir); err == nil {
+			roots = append(roots, dir)
+		}
+	}
+	return roots
+}
+
+// runVerifyChecksums 按专辑目录中的校验清单检查位衰减与被修改的文件；--checksums-update 时为音乐库创建或更新清单
+func runVerifyChecksums(roots []string) {
+	if len(roots) == 0 {
+		roots = libraryRoots()
+	}
+	if len(roots) == 0 {
+		logger.Error("没有可校验的目录")
+		return
+	}
+
+	if core.ChecksumsUpdate {
+		alg := strings.ToLower(core.Config.Checksums.Algorithm)
+		if alg == "" {
+			alg = checksum.AlgSHA256
+		}
+		dirs, err := checksum.AlbumDirs(roots, true)
+		if err != nil {
+			logger.Error("扫描目录失败: %v", err)
+			return
+		}
+		logger.Info("🧾 更新校验清单: %d 个目录", len(dirs))
+		updated, failed := 0, 0
+		for _, dir := range dirs {
+			changed, err := checksum.Refresh(dir, alg)
+			if err != nil {
+				logger.Error("❌ %s: %v", dir, err)
+				failed++
+				continue
+			}
+			if changed > 0 {
+				logger.Debug("[校验清单] %s: 更新 %d 个文件", dir, changed)
+				updated++
+			}
+		}
+		logger.Info("\n📋 目录: %d | 已更新: %d | 失败: %d", len(dirs), updated, failed)
+		return
+	}
+
+	logger.Info("🔍 校验清单: %s", strings.Join(roots, ", "))
+	lastPercent := -1
+	report, err := checksum.VerifyAll(roots, func(done, total int) {
+		if percent := done * 100 / total; percent/10 != lastPercent/10 {
+			lastPercent = percent
+			logger.Info("  已校验 %d/%d（%d%%）", done, total, percent)
+		}
+	})
+	if err != nil {
+		logger.Error("校验失败: %v", err)
+		return
+	}
+
+	for _, r := range report.Results {
+		for _, name := range r.Mismatched {
+			logger.Error("❌ 摘要不一致: %s", filepath.Join(r.Dir, name))
+		}
+		for _, name := range r.Missing {
+			logger.Error("❌ 文件缺失: %s", filepath.Join(r.Dir, name))
+		}
+		for _, name := range r.Untracked {
+			logger.Warn("⚠️  未记录在清单中: %s", filepath.Join(r.Dir, name))
+		}
+	}
+	for _, e := range report.Errors {
+		logger.Error("❌ %s", e)
+	}
+	logger.Info("\n📋 清单: %d | 一致: %d | 不一致: %d | 错误: %d", report.Dirs, report.Clean, len(report.Results), len(report.Errors))
+
+	if core.VerifyReport != "" {
+		if err := checksum.WriteReport(report, core.VerifyReport); err != nil {
+			logger.Error("写入报告失败: %v", err)
+		} else {
+			logger.Info("📝 报告已保存: %s", core.VerifyReport)
+		}
+	}
+}
+
+// runExplainRelease 显示专辑按 release-rules 识别出的发行类型、命中的规则与保存位置
+func runExplainRelease(urls []string) {
+	if len(urls) == 0 {
+		logger.Error("用法: ./程序名 explain-release <专辑链接> [...]")
+		return
+	}
+	token, err := api.GetToken()
+	if err != nil {
+		logger.Error("获取开发者 token 失败: %v", err)
+		return
+	}
+	core.DeveloperToken = token
+
+	typeNames := map[string]string{core.ReleaseAlbum: "专辑", core.ReleaseSingle: "单曲", core.ReleaseEP: "EP"}
+	for _, url := range urls {
+		storefront, albumID := parser.CheckUrl(url)
+		if albumID == "" {
+			logger.Error("❌ 不是专辑链接: %s", url)
+			continue
+		}
+		account, err := core.GetAccountForStorefront(storefront)
+		if err != nil {
+			logger.Error("❌ %s: %v", url, err)
+			continue
+		}
+		meta, err := api.GetMeta(albumID, account, storefront)
+		if err != nil {
+			logger.Error("❌ 获取专辑信息失败 %s: %v", url, err)
+			continue
+		}
+
+		attrs := meta.Data[0].Attributes
+		c := core.ClassifyRelease(meta)
+		logger.Info("💽 %s - %s（%s）", attrs.ArtistName, attrs.Name, albumID)
+		logger.Info("  曲目数: %d | 时长: %.1f 分钟 | IsSingle: %v", attrs.TrackCount, core.ReleaseMinutes(meta), attrs.IsSingle)
+		logger.Info("  类型: %s | 规则: %s", typeNames[c.Type], c.Rule)
+		if c.Exception != "" {
+			logger.Info("  艺术家例外: %s", c.Exception)
+		}
+		switch {
+		case c.Collection != "":
+			logger.Info("  保存位置: %s - %s", core.PrimaryArtist(meta), c.Collection)
+		case c.Type != core.ReleaseAlbum && !core.Config.EnableVirtualSingles:
+			logger.Info("  保存位置: 普通专辑目录（enable-virtual-singles 未启用）")
+		default:
+			logger.Info("  保存位置: 普通专辑目录")
+		}
+	}
+}
+
+// runSearch 在第一个账户所在地区的目录中搜索，返回要加入下载队列的链接
+// --json 只输出结果，--first 直接选择第一个结果，否则以表格显示结果供选择
+func runSearch(args []string) []string {
+	term := strings.TrimSpace(strings.Join(args, " "))
+	if term == "" {
+		logger.Error("用法: ./程序名 search <关键词> [--type songs|albums|artists] [--first | --json]")
+		return nil
+	}
+	if len(core.Config.Accounts) == 0 {
+		logger.Error("搜索失败: 无可用账户")
+		return nil
+	}
+	storefront := core.Config.Accounts[0].Storefront
+	results, err := search.Search(storefront, term, core.SearchType, core.SearchLimit)
+	if err != nil {
+		logger.Error("%v", err)
+		return nil
+	}
+
+	if core.SearchJSON {
+		if results == nil {
+			results = []search.Result{}
+		}
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			logger.Error("输出搜索结果失败: %v", err)
+			return nil
+		}
+		fmt.Println(string(data)) // OK: JSON 输出到标准输出，供脚本读取
+		return nil
+	}
+	if len(results) == 0 {
+		logger.Warn("没有找到与 %q 相关的结果。", term)
+		return nil
+	}
+
+	selected := results[:1]
+	if !core.SearchFirst {
+		selected = ui.SelectSearchResults(results, storefront, term)
+	}
+	var urls []string
+	for _, r := range selected {
+		name := r.Name
+		if r.Artist != "" {
+			name = r.Artist + " - " + r.Name
+		}
+		logger.Info("🔎 加入队列: %s（%s）", name, r.URL)
+		urls = append(urls, r.URL)
+	}
+	return urls
+}
+
+// runRebuildSingles 按发行日期重新编号虚拟Singles目录中的单曲（重命名文件并更新曲号标签）
+func runRebuildSingles(roots []string) {
+	if len(roots) == 0 {
+		roots = libraryRoots()
+	}
+	dirs, err := singles.Dirs(roots)
+	if err != nil {
+		logger.Error("扫描目录失败: %v", err)
+		return
+	}
+	if len(dirs) == 0 {
+		logger.Error("没有找到虚拟合辑目录（名称以 \" - %s\" 结尾）", strings.Join(singles.FolderNames(), "\" / \" - "))
+		return
+	}
+
+	mode := ""
+	if core.SinglesDryRun {
+		mode = "（预览）"
+	}
+	logger.Info("🔢 重新编号虚拟Singles%s: %d 个目录", mode, len(dirs))
+	renumbered, failed := 0, 0
+	for _, dir := range dirs {
+		changes, err := singles.Rebuild(dir, false, core.SinglesDryRun)
+		if err != nil {
+			logger.Error("❌ %s: %v", dir, err)
+			failed++
+			continue
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		renumbered++
+		logger.Info("📁 %s", dir)
+		for _, c := range changes {
+			logger.Info("    %02d → %02d  %s", c.Old, c.New, c.Title)
+		}
+	}
+	verb := "已重新编号"
+	if core.SinglesDryRun {
+		verb = "需要重新编号"
+	}
+	logger.Info("\n📋 目录: %d | %s: %d | 失败: %d", len(dirs), verb, renumbered, failed)
+}
+
+// runImport 将外部导出的列表（CSV、ISRC / UPC 列表）解析为目录链接，写入任务文件与未解析项报告，不下载
+func runImport(ctx context.Context, paths []string) {
+	if len(paths) == 0 {
+		logger.Error("用法: ./程序名 import <file.csv | file.txt> [...] [--import-output tasks.txt] [--import-report unresolved.txt]")
+		return
+	}
+	if len(core.Config.Accounts) == 0 {
+		logger.Error("import 需要配置至少一个账户以查询目录")
+		return
+	}
+	token, err := api.GetToken()
+	if err != nil {
+		logger.Error("获取开发者 token 失败: %v", err)
+		return
+	}
+	core.DeveloperToken = token
+	account := &core.Config.Accounts[0]
+
+	base := strings.TrimSuffix(paths[0], filepath.Ext(paths[0]))
+	output := core.ImportOutput
+	if output == "" {
+		output = base + "-import.txt"
+	}
+	reportPath := core.ImportReport
+	if reportPath == "" {
+		reportPath = base + "-unresolved.txt"
+	}
+
+	opts := importer.Options{
+		Catalog:       importer.NewCatalog(account, account.Storefront),
+		Storefront:    account.Storefront,
+		MinConfidence: core.ImportMinScore,
+	}
+	logger.Info("📥 导入: %s（地区: %s）", strings.Join(paths, ", "), strings.ToUpper(account.Storefront))
+	lastPercent := -1
+	opts.Progress = func(done, total int) {
+		if percent := done * 100 / total; percent/10 != lastPercent/10 {
+			lastPercent = percent
+			logger.Info("  已处理 %d/%d（%d%%）", done, total, percent)
+		}
+	}
+	report, err := importer.Run(ctx, paths, opts)
+	if err != nil {
+		logger.Error("导入失败: %v", err)
+		if report == nil || report.Rows == 0 {
+			return
+		}
+	}
+
+	for _, item := range report.Items {
+		switch {
+		case item.Status == importer.StatusUnresolved:
+			logger.Warn("❌ %s:%d %s: %s", item.Row.Source, item.Row.Line, item.Row, item.Reason)
+		case item.By == importer.BySearch:
+			logger.Info("🔎 %s → %s（置信度 %.2f）", item.Row, item.Match, item.Confidence)
+		}
+	}
+	logger.Info("\n📋 行: %d | 已解析: %d（模糊匹配 %d）| 未解析: %d", report.Rows, report.Resolved, report.Fuzzy, report.Unresolved)
+
+	if len(report.Links) > 0 {
+		if err := importer.WriteTasks(report, output); err != nil {
+			logger.Error("写入任务文件失败: %v", err)
+		} else {
+			logger.Info("📝 %d 个链接已写入任务文件: %s（./程序名 %s 开始下载）", len(report.Links), output, output)
+		}
+	}
+	if report.Unresolved > 0 || report.Fuzzy > 0 || core.ImportReport != "" {
+		if err := importer.WriteReport(report, reportPath); err != nil {
+			logger.Error("写入报告失败: %v", err)
+		} else {
+			logger.Info("📝 报告已保存: %s", reportPath)
+		}
+	}
+}
+
+// runRetag 按文件中的专辑 ID 重新获取目录信息并更新标签，不重新下载音频
+func runRetag(ctx context.Context, roots []string) {
+	if len(roots) == 0 {
+		roots = libraryRoots()
+	}
+	if len(roots) == 0 {
+		logger.Error("没有可更新的目录")
+		return
+	}
+	if len(core.Config.Accounts) == 0 {
+		logger.Error("retag 需要配置至少一个账户以获取目录信息")
+		return
+	}
+	token, err := api.GetToken()
+	if err != nil {
+		logger.Error("获取开发者 token 失败: %v", err)
+		return
+	}
+	core.DeveloperToken = token
+	account := &core.Config.Accounts[0]
+
+	preserve := append([]string{}, core.Config.RetagPreserve...)
+	for _, f := range strings.Split(core.RetagPreserve, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			preserve = append(preserve, f)
+		}
+	}
+	opts := retag.Options{
+		Job:      core.NewJob(core.FlagOptions()),
+		Preserve: preserve,
+		DryRun:   core.RetagDryRun,
+		Catalog: func(albumID string) (*structs.AutoGenerated, error) {
+			return api.GetMeta(albumID, account, account.Storefront)
+		},
+	}
+
+	mode := ""
+	if opts.DryRun {
+		mode = "（预览）"
+	}
+	logger.Info("🏷️  更新标签%s: %s", mode, strings.Join(roots, ", "))
+	if len(preserve) > 0 {
+		logger.Info("  保留字段: %s", strings.Join(preserve, ", "))
+	}
+	lastPercent := -1
+	opts.Progress = func(done, total int) {
+		if percent := done * 100 / total; percent/10 != lastPercent/10 {
+			lastPercent = percent
+			logger.Info("  已处理 %d/%d（%d%%）", done, total, percent)
+		}
+	}
+	report, err := retag.Run(ctx, roots, opts)
+	if err != nil {
+		logger.Error("更新标签失败: %v", err)
+		if report == nil {
+			return
+		}
+	}
+
+	for _, f := range report.Files {
+		switch f.Status {
+		case retag.StatusChanged:
+			logger.Info("✏️  %s", f.Path)
+			for _, c := range f.Changes {
+				logger.Info("    %s: %q → %q", c.Field, c.Old, c.New)
+			}
+		case retag.StatusSkipped:
+			logger.Debug("[retag] 跳过 %s: %s", f.Path, f.Reason)
+		case retag.StatusFailed:
+			logger.Error("❌ %s: %s", f.Path, f.Reason)
+		}
+	}
+	verb := "已更新"
+	if opts.DryRun {
+		verb = "待更新"
+	}
+	logger.Info("\n📋 文件: %d | %s: %d | 无变化: %d | 跳过: %d | 失败: %d", report.Checked, verb, report.Changed, report.Unchanged, report.Skipped, report.Failed)
+
+	if core.RetagReport != "" {
+		if err := retag.WriteReport(report, core.RetagReport); err != nil {
+			logger.Error("写入报告失败: %v", err)
+		} else {
+			logger.Info("📝 报告已保存: %s", core.RetagReport)
+		}
+	}
+}
+
+// cleanupStalePartFiles 清理缓存目录中遗留的 .part 临时文件
+// 音乐库目录只在下载到某个专辑时清理该专辑目录，避免每次启动都遍历整个音乐库
+func cleanupStalePartFiles() {
+	if !core.Config.EnableCache {
+		return
+	}
+	removed, err := utils.CleanupPartFiles(core.Config.CacheFolder, constants.StalePartFileAge)
+	if err != nil {
+		logger.Debug("[启动清理] 扫描 %s 失败: %v", core.Config.CacheFolder, err)
+	}
+	if removed > 0 {
+		logger.Info("🧹 已清理 %d 个未完成的临时文件（.part）", removed)
+	}
 }
 
 func main() {
@@ -596,6 +1394,14 @@ func main() {
 		logger.Info("  3. 多链接模式: ./程序名 <url1> <url2> ...")
 		logger.Info("  4. TXT文件模式: ./程序名 <file.txt>")
 		logger.Info("  5. 混合模式: ./程序名 <url1> <file.txt> <url2> ...")
+		logger.Info("  6. 校验模式: ./程序名 verify [目录 ...]（默认校验配置中的保存目录）")
+		logger.Info("  7. 清单校验: ./程序名 verify-checksums [目录 ...]（--checksums-update 创建或更新清单）")
+		logger.Info("  8. 更新标签: ./程序名 retag [目录 ...]（--retag-dry-run 预览差异，--retag-preserve 保留字段）")
+		logger.Info("  9. 整理单曲: ./程序名 rebuild-singles [目录 ...]（按发行日期重新编号虚拟Singles，--singles-dry-run 预览）")
+		logger.Info("  10. 类型说明: ./程序名 explain-release <专辑链接>（显示单曲 / EP 识别命中的规则）")
+		logger.Info("  11. 可用性预检: ./程序名 --dry-run [url ... | file.txt]（不下载，--dry-run-report 保存文本或 JSON 报告）")
+		logger.Info("  12. 搜索: ./程序名 search <关键词> [--type songs|albums|artists]（选择结果后下载，--first / --json 用于脚本）")
+		logger.Info("  13. 导入列表: ./程序名 import <file.csv | file.txt>（按 ISRC / UPC 或名称解析为任务文件，并生成未解析项报告）")
 		logger.Info("")
 		logger.Info("TXT文件格式:")
 		logger.Info("  - 支持单行单链接（传统格式）")
@@ -623,29 +1429,76 @@ func main() {
 	}
 
 	// 初始化logger系统
+	logCfg := core.Config.Logging
 	loggerCfg := logger.Config{
-		Level:         core.Config.Logging.Level,
-		Output:        core.Config.Logging.Output,
-		ShowTimestamp: core.Config.Logging.ShowTimestamp,
+		Level:         logCfg.Level,
+		Output:        logCfg.Output,
+		Format:        logCfg.Format,
+		ShowTimestamp: logCfg.ShowTimestamp,
+		ShowFields:    logCfg.ShowFields,
+		File: logger.FileConfig{
+			Path:        logCfg.File.Path,
+			Level:       logCfg.File.Level,
+			Format:      logCfg.File.Format,
+			MaxSizeMB:   logCfg.File.MaxSizeMB,
+			RotateEvery: logCfg.File.RotateEvery,
+			MaxBackups:  logCfg.File.MaxBackups,
+			MaxAgeDays:  logCfg.File.MaxAgeDays,
+		},
 	}
 	if err := logger.InitFromConfig(loggerCfg); err != nil {
 		// OK: 这里不能用logger.Error，因为logger初始化失败
 		fmt.Printf("初始化logger失败: %v\n", err)
 		return
 	}
+	defer logger.Close()
 
 	// 初始化网络客户端（包括本地 wrapper 优化）
 	network.InitializeClients(&core.Config)
 
+	// 初始化全局带宽限制（max-bandwidth / bandwidth-schedule）
+	if err := network.InitializeBandwidth(&core.Config); err != nil {
+		logger.Error("初始化限速配置失败: %v", err)
+		return
+	}
+
+	// 任务事件通知（notifications）；退出前等待已发出的通知送达
+	if err := notify.Init(core.Config.Notifications); err != nil {
+		logger.Error("初始化通知配置失败: %v", err)
+		return
+	}
+	defer notify.Close(constants.NotifyFlushTimeout)
+
+	// 下载后处理钩子（post-hooks）
+	if err := hooks.Init(core.Config.PostHooks); err != nil {
+		logger.Error("初始化后处理钩子失败: %v", err)
+		return
+	}
+
+	// 长时间批量任务的监控指标（metrics-listen / --metrics-listen）
+	if core.MetricsListen != "" {
+		if err := metrics.Serve(core.MetricsListen); err != nil {
+			logger.Error("启动指标服务失败: %v", err)
+			return
+		}
+	}
+
 	// 创建可取消的 context 用于优雅退出
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// 创建任务上下文（命令行参数与配置文件合并后的下载参数）
+	job := core.NewJob(core.FlagOptions())
+	// 下载开始后中断时发送中断的批量任务事件
+	var downloading atomic.Bool
+
 	// 设置信号处理，确保程序退出时清理资源
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
 	go func() {
 		<-sigChan
+		// 全屏界面运行中时先恢复终端
+		ui.StopTUI()
 		yellow := color.New(color.FgYellow)
 		yellow.Printf("\n\n⚠️  收到中断信号，正在安全退出...\n")
 
@@ -655,34 +1508,71 @@ func main() {
 		// 等待清理完成
 		time.Sleep(constants.CleanupWaitSeconds * time.Second)
 
+		// os.Exit 不执行延迟调用：在此发送中断事件并等待通知送达
+		if downloading.Load() {
+			notify.BatchInterrupted(job.Counter())
+		}
+		notify.Close(constants.NotifyFlushTimeout)
+
 		yellow.Printf("✅ 清理完成\n")
 		yellow.Printf("👋 再见！\n")
 		os.Exit(0)
 	}()
 
-	// 创建进度通知器并注册UI监听器
+	// 创建进度通知器（UI监听器由各专辑下载时绑定到自己的状态面板）
 	progressNotifier := progress.NewNotifier()
-	uiListener := ui.NewUIProgressListener()
-	progressNotifier.AddListener(uiListener)
-	logger.Debug("Progress notifier initialized with UI listener")
+	logger.Debug("Progress notifier initialized")
 
 	if core.OutputPath != "" {
 		core.Config.AlacSaveFolder = core.OutputPath
 		core.Config.AtmosSaveFolder = core.OutputPath
 	}
 
+	args := pflag.Args()
+	if len(args) > 0 && args[0] == "verify" {
+		runVerify(ctx, args[1:])
+		return
+	}
+	if len(args) > 0 && args[0] == "verify-checksums" {
+		runVerifyChecksums(args[1:])
+		return
+	}
+	if len(args) > 0 && args[0] == "explain-release" {
+		runExplainRelease(args[1:])
+		return
+	}
+	if len(args) > 0 && args[0] == "rebuild-singles" {
+		runRebuildSingles(args[1:])
+		return
+	}
+	if len(args) > 0 && args[0] == "retag" {
+		runRetag(ctx, args[1:])
+		return
+	}
+	if len(args) > 0 && args[0] == "import" {
+		runImport(ctx, args[1:])
+		return
+	}
+
 	token, err := api.GetToken()
 	if err != nil {
 		if len(core.Config.Accounts) > 0 && core.Config.Accounts[0].AuthorizationToken != "" && core.Config.Accounts[0].AuthorizationToken != "your-authorization-token" {
 			token = strings.Replace(core.Config.Accounts[0].AuthorizationToken, "Bearer ", "", -1)
 		} else {
 			logger.Error("获取开发者 token 失败。")
+			notify.TokenExpired("", err)
 			return
 		}
 	}
 	core.DeveloperToken = token
 
-	args := pflag.Args()
+	if len(args) > 0 && args[0] == "search" {
+		// 选中的搜索结果按普通链接下载
+		if args = runSearch(args[1:]); len(args) == 0 {
+			return
+		}
+	}
+
 	if len(args) == 0 {
 		logger.Info("请输入专辑链接或TXT文件路径: ")
 		reader := bufio.NewReader(os.Stdin)
@@ -702,13 +1592,15 @@ func main() {
 					return
 				}
 				logger.Info("📊 从文件 %s 中解析到 %d 个链接\n", input, len(urls))
-				runDownloads(ctx, urls, true, input, progressNotifier)
+				downloading.Store(true)
+				runDownloads(ctx, job, urls, true, input, progressNotifier)
 			} else {
 				logger.Error("错误: 文件不存在 %s", input)
 				return
 			}
 		} else {
-			runDownloads(ctx, []string{input}, false, "", progressNotifier)
+			downloading.Store(true)
+			runDownloads(ctx, job, []string{input}, false, "", progressNotifier)
 		}
 	} else {
 		// 处理命令行参数：支持TXT文件或直接的URL列表
@@ -749,14 +1641,27 @@ func main() {
 			if isBatch {
 				logger.Info("")
 			}
-			runDownloads(ctx, urls, isBatch, taskFile, progressNotifier)
+			downloading.Store(true)
+			runDownloads(ctx, job, urls, isBatch, taskFile, progressNotifier)
 		} else {
 			logger.Warn("没有有效的链接可供处理。")
 		}
 	}
 
-	logger.Info("\n📦 已完成: %d/%d | 警告: %d | 错误: %d", core.Counter.Success, core.Counter.Total, core.Counter.Unavailable+core.Counter.NotSong, core.Counter.Error)
-	if core.Counter.Error > 0 {
+	if core.DryRun {
+		return
+	}
+
+	counter := job.Counter()
+	logger.Info("\n📦 已完成: %d/%d | 警告: %d | 错误: %d", counter.Success, counter.Total, counter.Unavailable+counter.NotSong, counter.Error)
+	if counter.Error > 0 {
 		logger.Warn("部分任务在执行过程中出错，请检查上面的日志记录。")
 	}
+	if matches := job.Matches(); len(matches) > 0 {
+		logger.Info("🌐 跨地区匹配: %d 个专辑从其他地区下载", len(matches))
+		for _, m := range matches {
+			logger.Info("  %s", m)
+		}
+	}
+	notify.BatchFinished(counter)
 }
@@ -17,6 +17,8 @@ import (
 	"time"
 
 	"main/internal/logger"
+	"main/internal/metrics"
+	"main/internal/network"
 	"main/utils/structs"
 
 	"github.com/Eyevinn/mp4ff/mp4"
@@ -64,17 +66,24 @@ func downloadChunk(wg *sync.WaitGroup, errChan chan error, progressBytes chan in
 	req.Header = header.Clone()
 	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
 
+	// 处于暂停时段时等待恢复后再发起请求
+	network.Bandwidth.WaitForWindow()
+
 	client := &http.Client{}
+	requestStart := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
+		metrics.SegmentErrors.Inc("chunk", "request")
 		errChan <- fmt.Errorf("chunk %d: request failed: %w", chunkIndex, err)
 		return
 	}
 	defer resp.Body.Close()
+	body := network.LimitReader(resp.Body)
 
 	if resp.StatusCode != http.StatusPartialContent {
 
 		if !(chunkIndex > 0 && resp.StatusCode == http.StatusOK) {
+			metrics.SegmentErrors.Inc("chunk", fmt.Sprintf("status_%d", resp.StatusCode))
 			errChan <- fmt.Errorf("chunk %d: server returned non-206 status: %s", chunkIndex, resp.Status)
 			return
 		}
@@ -83,7 +92,7 @@ func downloadChunk(wg *sync.WaitGroup, errChan chan error, progressBytes chan in
 	buffer := make([]byte, Config.NetworkReadBufferKB*1024)
 	var writtenBytes int64 = 0
 	for {
-		n, readErr := resp.Body.Read(buffer)
+		n, readErr := body.Read(buffer)
 		if n > 0 {
 			_, writeErr := tempFile.WriteAt(buffer[:n], start+writtenBytes)
 			if writeErr != nil {
@@ -97,10 +106,12 @@ func downloadChunk(wg *sync.WaitGroup, errChan chan error, progressBytes chan in
 			break
 		}
 		if readErr != nil {
+			metrics.SegmentErrors.Inc("chunk", "read")
 			errChan <- fmt.Errorf("chunk %d: failed to read body stream: %w", chunkIndex, readErr)
 			return
 		}
 	}
+	metrics.ObserveSegment("chunk", requestStart, writtenBytes)
 }
 func downloadFileInChunks(fileUrl string, header http.Header, totalSize int64, numChunks int, progressChan chan ProgressUpdate, Config structs.ConfigSet) (*os.File, error) {
 	tempFile, err := os.CreateTemp("", "amdl-*.tmp")
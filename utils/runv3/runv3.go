@@ -11,6 +11,8 @@ import (
 
 	//"log/slog"
 	"main/internal/logger"
+	"main/internal/metrics"
+	"main/internal/network"
 	cdm "main/utils/runv3/cdm"
 	key "main/utils/runv3/key"
 	"os"
@@ -460,8 +462,13 @@ func downloadSegment(url string, index int, wg *sync.WaitGroup, segmentsChan cha
 		return
 	}
 
+	// 处于暂停时段时等待恢复后再发起请求
+	network.Bandwidth.WaitForWindow()
+
+	start := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
+		metrics.SegmentErrors.Inc("segment", "request")
 		if shouldLog, count := globalErrorTracker.shouldLog("download_failed"); shouldLog {
 			if count > 1 {
 				logger.Error("分段下载失败 (已发生%d次，示例分段 %d): %v", count, index, err)
@@ -475,6 +482,7 @@ func downloadSegment(url string, index int, wg *sync.WaitGroup, segmentsChan cha
 
 	if resp.StatusCode != http.StatusOK {
 		errorType := fmt.Sprintf("status_%d", resp.StatusCode)
+		metrics.SegmentErrors.Inc("segment", errorType)
 		if shouldLog, count := globalErrorTracker.shouldLog(errorType); shouldLog {
 			if count > 1 {
 				logger.Error("服务器返回状态码 %d (已发生%d次，最新分段 %d)", resp.StatusCode, count, index)
@@ -485,8 +493,9 @@ func downloadSegment(url string, index int, wg *sync.WaitGroup, segmentsChan cha
 		return
 	}
 
-	data, err := io.ReadAll(resp.Body)
+	data, err := io.ReadAll(network.LimitReader(resp.Body))
 	if err != nil {
+		metrics.SegmentErrors.Inc("segment", "read")
 		if shouldLog, count := globalErrorTracker.shouldLog("read_failed"); shouldLog {
 			if count > 1 {
 				logger.Error("分段数据读取失败 (已发生%d次，示例分段 %d): %v", count, index, err)
@@ -497,6 +506,8 @@ func downloadSegment(url string, index int, wg *sync.WaitGroup, segmentsChan cha
 		return
 	}
 
+	metrics.ObserveSegment("segment", start, int64(len(data)))
+
 	// 将下载好的分段（包含序号和数据）发送到 Channel
 	segmentsChan <- Segment{Index: index, Data: data}
 }
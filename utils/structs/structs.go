@@ -1,654 +1,805 @@
-package structs
-
-type EditorialNotes struct {
-	Standard string `json:"standard"`
-}
-
-type Account struct {
-	Name               string `yaml:"name"`
-	Storefront         string `yaml:"storefront"`
-	MediaUserToken     string `yaml:"media-user-token"`
-	AuthorizationToken string `yaml:"authorization-token"`
-	DecryptM3u8Port    string `yaml:"decrypt-m3u8-port"`
-	GetM3u8Port        string `yaml:"get-m3u8-port"`
-}
-
-type ConfigSet struct {
-	Accounts                 []Account          `yaml:"accounts"`
-	Language                 string             `yaml:"language"`
-	SaveLrcFile              bool               `yaml:"save-lrc-file"`
-	LrcType                  string             `yaml:"lrc-type"`
-	LrcFormat                string             `yaml:"lrc-format"`
-	SaveAnimatedArtwork      bool               `yaml:"save-animated-artwork"`
-	EmbyAnimatedArtwork      bool               `yaml:"emby-animated-artwork"`
-	EmbedLrc                 bool               `yaml:"embed-lrc"`
-	EmbedCover               bool               `yaml:"embed-cover"`
-	SaveArtistCover          bool               `yaml:"save-artist-cover"`
-	CoverSize                string             `yaml:"cover-size"`
-	CoverFormat              string             `yaml:"cover-format"`
-	AlacSaveFolder           string             `yaml:"alac-save-folder"`
-	AtmosSaveFolder          string             `yaml:"atmos-save-folder"`
-	AacSaveFolder            string             `yaml:"aac-save-folder"`
-	MVSaveFolder             string             `yaml:"mv-save-folder"`
-	AlbumFolderFormat        string             `yaml:"album-folder-format"`
-	PlaylistFolderFormat     string             `yaml:"playlist-folder-format"`
-	ArtistFolderFormat       string             `yaml:"artist-folder-format"`
-	SongFileFormat           string             `yaml:"song-file-format"`
-	ExplicitChoice           string             `yaml:"explicit-choice"`
-	AppleMasterChoice        string             `yaml:"apple-master-choice"`
-	GetM3u8Mode              string             `yaml:"get-m3u8-mode"`
-	GetM3u8FromDevice        bool               `yaml:"get-m3u8-from-device"`
-	AacType                  string             `yaml:"aac-type"`
-	AlacMax                  int                `yaml:"alac-max"`
-	AtmosMax                 int                `yaml:"atmos-max"`
-	LimitMax                 int                `yaml:"limit-max"`
-	UseSongInfoForPlaylist   bool               `yaml:"use-songinfo-for-playlist"`
-	DlAlbumcoverForPlaylist  bool               `yaml:"dl-albumcover-for-playlist"`
-	MVAudioType              string             `yaml:"mv-audio-type"`
-	MVMax                    int                `yaml:"mv-max"`
-	MVMin                    int                `yaml:"mv-min"`
-	AacDownloadThreads       int                `yaml:"aac_downloadthreads"`
-	LosslessDownloadThreads  int                `yaml:"lossless_downloadthreads"`
-	HiresDownloadThreads     int                `yaml:"hires_downloadthreads"`
-	ChunkDownloadThreads     int                `yaml:"chunk_downloadthreads"`
-	BufferSizeKB             int                `yaml:"BufferSizeKB"`
-	NetworkReadBufferKB      int                `yaml:"NetworkReadBufferKB"`
-	MaxPathLength            int                `yaml:"max-path-length"`
-	DefaultLyricStorefront   string             `yaml:"default-lyric-storefront"`
-	DownloadVideos           bool               `yaml:"download-videos"`
-	FfmpegFix                bool               `yaml:"ffmpeg-fix"`
-	FfmpegCheckArgs          string             `yaml:"ffmpeg-check-args"`
-	FfmpegEncodeArgs         string             `yaml:"ffmpeg-encode-args"`
-	EnableCache              bool               `yaml:"enable-cache"`
-	CacheFolder              string             `yaml:"cache-folder"`
-	BatchSize                int                `yaml:"batch-size"`                  // 分批处理的批次大小，0表示不分批
-	WorkRestEnabled          bool               `yaml:"work-rest-enabled"`           // 启用工作-休息循环
-	WorkDurationMinutes      int                `yaml:"work-duration-minutes"`       // 工作时长（分钟）
-	RestDurationMinutes      int                `yaml:"rest-duration-minutes"`       // 休息时长（分钟）
-	Logging                  LoggingConfig      `yaml:"logging"`                     // 日志配置
-	EnableVirtualSingles     bool               `yaml:"enable-virtual-singles"`      // 是否启用虚拟Singles专辑
-	VirtualSinglesFolderName string                `yaml:"virtual-singles-folder-name"` // 虚拟单曲专辑的文件夹名称
-	FileValidation           FileValidationConfig  `yaml:"file-validation"`             // 文件校验配置
-	LocalWrapperOptimization LocalWrapperConfig    `yaml:"local-wrapper-optimization"`  // 本地 wrapper 服务优化配置
-}
-
-// FileValidationConfig 文件校验配置
-type FileValidationConfig struct {
-	SizeCheckEnabled       bool `yaml:"size-check-enabled"`        // 是否启用文件大小检查
-	ConcurrentCheckEnabled bool `yaml:"concurrent-check-enabled"`  // 是否启用并发校验
-	ConcurrentWorkers      int  `yaml:"concurrent-workers"`        // 并发worker数量
-}
-
-// LocalWrapperConfig 本地 wrapper 服务优化配置
-type LocalWrapperConfig struct {
-	Enabled              bool `yaml:"enabled"`                 // 是否启用本地优化模式
-	MaxIdleConns         int  `yaml:"max-idle-conns"`          // 最大空闲连接数
-	MaxIdleConnsPerHost  int  `yaml:"max-idle-conns-per-host"` // 每个主机的最大空闲连接数
-	MaxConnsPerHost      int  `yaml:"max-conns-per-host"`      // 每个主机的最大连接数
-	IdleConnTimeoutSec   int  `yaml:"idle-conn-timeout-sec"`   // 空闲连接超时（秒）
-	DialTimeoutMs        int  `yaml:"dial-timeout-ms"`         // 连接超时（毫秒）
-	KeepAlive            bool `yaml:"keep-alive"`              // 是否启用 TCP KeepAlive
-	DisableCompression   bool `yaml:"disable-compression"`     // 是否禁用压缩（本地通讯无需压缩）
-	ExpectContinueTimeMs int  `yaml:"expect-continue-time-ms"` // Expect: 100-continue 超时（毫秒）
-}
-
-// LoggingConfig 日志配置
-type LoggingConfig struct {
-	Level         string `yaml:"level"`          // 日志等级: debug/info/warn/error
-	Output        string `yaml:"output"`         // 输出目标: stdout/stderr/文件路径
-	ShowTimestamp bool   `yaml:"show_timestamp"` // 是否显示时间戳
-}
-
-// TrackBatch 表示一个曲目批次
-type TrackBatch struct {
-	Tracks       []int // 批次中的曲目编号列表
-	BatchNum     int   // 当前批次编号（从1开始）
-	TotalBatches int   // 总批次数
-	BatchSize    int   // 当前批次大小
-	IsLast       bool  // 是否最后一个批次
-}
-
-// BatchIterator 批次迭代器
-type BatchIterator struct {
-	tracks      []int
-	batchSize   int
-	currentIdx  int
-	totalTracks int
-}
-
-// NewBatchIterator 创建批次迭代器
-func NewBatchIterator(tracks []int, batchSize int) *BatchIterator {
-	if batchSize <= 0 {
-		batchSize = len(tracks)
-	}
-	return &BatchIterator{
-		tracks:      tracks,
-		batchSize:   batchSize,
-		currentIdx:  0,
-		totalTracks: len(tracks),
-	}
-}
-
-// Next 获取下一个批次，返回批次数据和是否还有更多批次
-func (b *BatchIterator) Next() (*TrackBatch, bool) {
-	if b.currentIdx >= b.totalTracks {
-		return nil, false
-	}
-
-	totalBatches := (b.totalTracks + b.batchSize - 1) / b.batchSize
-	batchNum := (b.currentIdx / b.batchSize) + 1
-
-	end := b.currentIdx + b.batchSize
-	if end > b.totalTracks {
-		end = b.totalTracks
-	}
-
-	batch := &TrackBatch{
-		Tracks:       b.tracks[b.currentIdx:end],
-		BatchNum:     batchNum,
-		TotalBatches: totalBatches,
-		BatchSize:    end - b.currentIdx,
-		IsLast:       end == b.totalTracks,
-	}
-
-	b.currentIdx = end
-	return batch, true
-}
-
-// HasNext 检查是否还有下一个批次
-func (b *BatchIterator) HasNext() bool {
-	return b.currentIdx < b.totalTracks
-}
-
-// Reset 重置迭代器
-func (b *BatchIterator) Reset() {
-	b.currentIdx = 0
-}
-
-type Counter struct {
-	Unavailable int
-	NotSong     int
-	Error       int
-	Success     int
-	Total       int
-}
-
-type ApiResult struct {
-	Data []SongData `json:"data"`
-}
-
-type SongAttributes struct {
-	ArtistName        string   `json:"artistName"`
-	DiscNumber        int      `json:"discNumber"`
-	GenreNames        []string `json:"genreNames"`
-	ExtendedAssetUrls struct {
-		EnhancedHls string `json:"enhancedHls"`
-	} `json:"extendedAssetUrls"`
-	IsMasteredForItunes  bool   `json:"isMasteredForItunes"`
-	IsAppleDigitalMaster bool   `json:"isAppleDigitalMaster"`
-	ContentRating        string `json:"contentRating"`
-	ReleaseDate          string `json:"releaseDate"`
-	Name                 string `json:"name"`
-	Isrc                 string `json:"isrc"`
-	AlbumName            string `json:"albumName"`
-	TrackNumber          int    `json:"trackNumber"`
-	ComposerName         string `json:"composerName"`
-}
-
-type AlbumAttributes struct {
-	ArtistName           string   `json:"artistName"`
-	IsSingle             bool     `json:"isSingle"`
-	IsComplete           bool     `json:"isComplete"`
-	GenreNames           []string `json:"genreNames"`
-	TrackCount           int      `json:"trackCount"`
-	IsMasteredForItunes  bool     `json:"isMasteredForItunes"`
-	IsAppleDigitalMaster bool     `json:"isAppleDigitalMaster"`
-	ContentRating        string   `json:"contentRating"`
-	ReleaseDate          string   `json:"releaseDate"`
-	Name                 string   `json:"name"`
-	RecordLabel          string   `json:"recordLabel"`
-	Upc                  string   `json:"upc"`
-	Copyright            string   `json:"copyright"`
-	IsCompilation        bool     `json:"isCompilation"`
-}
-
-type SongData struct {
-	ID            string         `json:"id"`
-	Attributes    SongAttributes `json:"attributes"`
-	Relationships struct {
-		Albums struct {
-			Data []struct {
-				ID         string          `json:"id"`
-				Type       string          `json:"type"`
-				Href       string          `json:"href"`
-				Attributes AlbumAttributes `json:"attributes"`
-			} `json:"data"`
-		} `json:"albums"`
-		Artists struct {
-			Href string `json:"href"`
-			Data []struct {
-				ID   string `json:"id"`
-				Type string `json:"type"`
-				Href string `json:"href"`
-			} `json:"data"`
-		} `json:"artists"`
-	} `json:"relationships"`
-}
-
-type SongResult struct {
-	Artwork struct {
-		Width                int    `json:"width"`
-		URL                  string `json:"url"`
-		Height               int    `json:"height"`
-		TextColor3           string `json:"textColor3"`
-		TextColor2           string `json:"textColor2"`
-		TextColor4           string `json:"textColor4"`
-		HasAlpha             bool   `json:"hasAlpha"`
-		TextColor1           string `json:"textColor1"`
-		BgColor              string `json:"bgColor"`
-		HasP3                bool   `json:"hasP3"`
-		SupportsLayeredImage bool   `json:"supportsLayeredImage"`
-	} `json:"artwork"`
-	ArtistName             string   `json:"artistName"`
-	CollectionID           string   `json:"collectionId"`
-	DiscNumber             int      `json:"discNumber"`
-	GenreNames             []string `json:"genreNames"`
-	ID                     string   `json:"id"`
-	DurationInMillis       int      `json:"durationInMillis"`
-	ReleaseDate            string   `json:"releaseDate"`
-	ContentRatingsBySystem struct {
-	} `json:"contentRatingsBySystem"`
-	Name     string `json:"name"`
-	Composer struct {
-		Name string `json:"name"`
-		URL  string `json:"url"`
-	} `json:"composer"`
-	EditorialArtwork struct {
-	} `json:"editorialArtwork"`
-	CollectionName string `json:"collectionName"`
-	AssetUrls      struct {
-		Plus             string `json:"plus"`
-		Lightweight      string `json:"lightweight"`
-		SuperLightweight string `json:"superLightweight"`
-		LightweightPlus  string `json:"lightweightPlus"`
-		EnhancedHls      string `json:"enhancedHls"`
-	} `json:"assetUrls"`
-	AudioTraits []string `json:"audioTraits"`
-	Kind        string   `json:"kind"`
-	Copyright   string   `json:"copyright"`
-	ArtistID    string   `json:"artistId"`
-	Genres      []struct {
-		GenreID   string `json:"genreId"`
-		Name      string `json:"name"`
-		URL       string `json:"url"`
-		MediaType string `json:"mediaType"`
-	} `json:"genres"`
-	TrackNumber int    `json:"trackNumber"`
-	AudioLocale string `json:"audioLocale"`
-	Offers      []struct {
-		ActionText struct {
-			Short       string `json:"short"`
-			Medium      string `json:"medium"`
-			Long        string `json:"long"`
-			Downloaded  string `json:"downloaded"`
-			Downloading string `json:"downloading"`
-		} `json:"actionText"`
-		Type           string  `json:"type"`
-		PriceFormatted string  `json:"priceFormatted"`
-		Price          float64 `json:"price"`
-		BuyParams      string  `json:"buyParams"`
-		Variant        string  `json:"variant,omitempty"`
-		Assets         []struct {
-			Flavor  string `json:"flavor"`
-			Preview struct {
-				Duration int    `json:"duration"`
-				URL      string `json:"url"`
-			} `json:"preview"`
-			Size     int `json:"size"`
-			Duration int `json:"duration"`
-		} `json:"assets"`
-	} `json:"offers"`
-}
-
-type TrackData struct {
-	ID         string `json:"id"`
-	Type       string `json:"type"`
-	Href       string `json:"href"`
-	Attributes struct {
-		Previews []struct {
-			URL string `json:"url"`
-		} `json:"previews"`
-		Artwork struct {
-			Width      int    `json:"width"`
-			Height     int    `json:"height"`
-			URL        string `json:"url"`
-			BgColor    string `json:"bgColor"`
-			TextColor1 string `json:"textColor1"`
-			TextColor2 string `json:"textColor2"`
-			TextColor3 string `json:"textColor3"`
-			TextColor4 string `json:"textColor4"`
-		} `json:"artwork"`
-		ArtistName           string   `json:"artistName"`
-		URL                  string   `json:"url"`
-		DiscNumber           int      `json:"discNumber"`
-		GenreNames           []string `json:"genreNames"`
-		HasTimeSyncedLyrics  bool     `json:"hasTimeSyncedLyrics"`
-		IsMasteredForItunes  bool     `json:"isMasteredForItunes"`
-		IsAppleDigitalMaster bool     `json:"isAppleDigitalMaster"`
-		ContentRating        string   `json:"contentRating"`
-		DurationInMillis     int      `json:"durationInMillis"`
-		ReleaseDate          string   `json:"releaseDate"`
-		Name                 string   `json:"name"`
-		Isrc                 string   `json:"isrc"`
-		AudioTraits          []string `json:"audioTraits"`
-		HasLyrics            bool     `json:"hasLyrics"`
-		AlbumName            string   `json:"albumName"`
-		PlayParams           struct {
-			ID   string `json:"id"`
-			Kind string `json:"kind"`
-		} `json:"playParams"`
-		TrackNumber  int    `json:"trackNumber"`
-		AudioLocale  string `json:"audioLocale"`
-		ComposerName string `json:"composerName"`
-	} `json:"attributes"`
-	Relationships struct {
-		Artists struct {
-			Href string `json:"href"`
-			Data []struct {
-				ID         string `json:"id"`
-				Type       string `json:"type"`
-				Href       string `json:"href"`
-				Attributes struct {
-					Name string `json:"name"`
-				} `json:"attributes"`
-			} `json:"data"`
-		} `json:"artists"`
-		Albums struct {
-			Href string      `json:"href"`
-			Data []AlbumData `json:"data"`
-		}
-	} `json:"relationships"`
-}
-
-type AlbumData struct {
-	ID         string `json:"id"`
-	Type       string `json:"type"`
-	Href       string `json:"href"`
-	Attributes struct {
-		ArtistName string `json:"artistName"`
-		Artwork    struct {
-			Width      int    `json:"width"`
-			Height     int    `json:"height"`
-			URL        string `json:"url"`
-			BgColor    string `json:"bgColor"`
-			TextColor1 string `json:"textColor1"`
-			TextColor2 string `json:"textColor2"`
-			TextColor3 string `json:"textColor3"`
-			TextColor4 string `json:"textColor4"`
-		} `json:"artwork"`
-		GenreNames          []string `json:"genreNames"`
-		IsCompilation       bool     `json:"isCompilation"`
-		IsComplete          bool     `json:"isComplete"`
-		IsMasteredForItunes bool     `json:"isMasteredForItunes"`
-		IsPrerelease        bool     `json:"isPrerelease"`
-		IsSingle            bool     `json:"isSingle"`
-		Name                string   `json:"name"`
-		PlayParams          struct {
-			ID   string `json:"id"`
-			Kind string `json:"kind"`
-		} `json:"playParams"`
-		ReleaseDate string `json:"releaseDate"`
-		TrackCount  int    `json:"trackCount"`
-		Upc         string `json:"upc"`
-		URL         string `json:"url"`
-	}
-}
-
-type AutoGenerated struct {
-	Data []struct {
-		ID         string `json:"id"`
-		Type       string `json:"type"`
-		Href       string `json:"href"`
-		Attributes struct {
-			Artwork struct {
-				Width      int    `json:"width"`
-				Height     int    `json:"height"`
-				URL        string `json:"url"`
-				BgColor    string `json:"bgColor"`
-				TextColor1 string `json:"textColor1"`
-				TextColor2 string `json:"textColor2"`
-				TextColor3 string `json:"textColor3"`
-				TextColor4 string `json:"textColor4"`
-			} `json:"artwork"`
-			ArtistName           string   `json:"artistName"`
-			IsSingle             bool     `json:"isSingle"`
-			URL                  string   `json:"url"`
-			IsComplete           bool     `json:"isComplete"`
-			GenreNames           []string `json:"genreNames"`
-			TrackCount           int      `json:"trackCount"`
-			IsMasteredForItunes  bool     `json:"isMasteredForItunes"`
-			IsAppleDigitalMaster bool     `json:"isAppleDigitalMaster"`
-			ContentRating        string   `json:"contentRating"`
-			ReleaseDate          string   `json:"releaseDate"`
-			Name                 string   `json:"name"`
-			RecordLabel          string   `json:"recordLabel"`
-			Upc                  string   `json:"upc"`
-			AudioTraits          []string `json:"audioTraits"`
-			Copyright            string   `json:"copyright"`
-			PlayParams           struct {
-				ID   string `json:"id"`
-				Kind string `json:"kind"`
-			} `json:"playParams"`
-			IsCompilation  bool `json:"isCompilation"`
-			EditorialVideo struct {
-				MotionTall struct {
-					Video string `json:"video"`
-				} `json:"motionTallVideo3x4"`
-				MotionSquare struct {
-					Video string `json:"video"`
-				} `json:"motionSquareVideo1x1"`
-				MotionDetailTall struct {
-					Video string `json:"video"`
-				} `json:"motionDetailTall"`
-				MotionDetailSquare struct {
-					Video string `json:"video"`
-				} `json:"motionDetailSquare"`
-			} `json:"editorialVideo"`
-			// [新增功能] 在此添加 EditorialNotes 字段
-			// Added EditorialNotes field here.
-			EditorialNotes *EditorialNotes `json:"editorialNotes"`
-		} `json:"attributes"`
-		Relationships struct {
-			RecordLabels struct {
-				Href string        `json:"href"`
-				Data []interface{} `json:"data"`
-			} `json:"record-labels"`
-			Artists struct {
-				Href string `json:"href"`
-				Data []struct {
-					ID         string `json:"id"`
-					Type       string `json:"type"`
-					Href       string `json:"href"`
-					Attributes struct {
-						Name    string `json:"name"`
-						Artwork struct {
-							Url string `json:"url"`
-						} `json:"artwork"`
-					} `json:"attributes"`
-				} `json:"data"`
-			} `json:"artists"`
-			Tracks struct {
-				Href string      `json:"href"`
-				Next string      `json:"next"`
-				Data []TrackData `json:"data"`
-			} `json:"tracks"`
-		} `json:"relationships"`
-	} `json:"data"`
-}
-
-type AutoGeneratedTrack struct {
-	Href string `json:"href"`
-	Next string `json:"next"`
-	Data []struct {
-		ID         string `json:"id"`
-		Type       string `json:"type"`
-		Href       string `json:"href"`
-		Attributes struct {
-			Previews []struct {
-				URL string `json:"url"`
-			} `json:"previews"`
-			Artwork struct {
-				Width      int    `json:"width"`
-				Height     int    `json:"height"`
-				URL        string `json:"url"`
-				BgColor    string `json:"bgColor"`
-				TextColor1 string `json:"textColor1"`
-				TextColor2 string `json:"textColor2"`
-				TextColor3 string `json:"textColor3"`
-				TextColor4 string `json:"textColor4"`
-			} `json:"artwork"`
-			ArtistName           string   `json:"artistName"`
-			URL                  string   `json:"url"`
-			DiscNumber           int      `json:"discNumber"`
-			GenreNames           []string `json:"genreNames"`
-			HasTimeSyncedLyrics  bool     `json:"hasTimeSyncedLyrics"`
-			IsMasteredForItunes  bool     `json:"isMasteredForItunes"`
-			IsAppleDigitalMaster bool     `json:"isAppleDigitalMaster"`
-			ContentRating        string   `json:"contentRating"`
-			DurationInMillis     int      `json:"durationInMillis"`
-			ReleaseDate          string   `json:"releaseDate"`
-			Name                 string   `json:"name"`
-			Isrc                 string   `json:"isrc"`
-			AudioTraits          []string `json:"audioTraits"`
-			HasLyrics            bool     `json:"hasLyrics"`
-			AlbumName            string   `json:"albumName"`
-			PlayParams           struct {
-				ID   string `json:"id"`
-				Kind string `json:"kind"`
-			} `json:"playParams"`
-			TrackNumber  int    `json:"trackNumber"`
-			AudioLocale  string `json:"audioLocale"`
-			ComposerName string `json:"composerName"`
-		} `json:"attributes"`
-		Relationships struct {
-			Artists struct {
-				Href string `json:"href"`
-				Data []struct {
-					ID         string `json:"id"`
-					Type       string `json:"type"`
-					Href       string `json:"href"`
-					Attributes struct {
-						Name string `json:"name"`
-					} `json:"attributes"`
-				} `json:"data"`
-			} `json:"artists"`
-			Albums struct {
-				Href string      `json:"href"`
-				Data []AlbumData `json:"data"`
-			}
-		} `json:"relationships"`
-	} `json:"data"`
-}
-
-type AutoGeneratedArtist struct {
-	Next string `json:"next"`
-	Data []struct {
-		ID         string `json:"id"`
-		Type       string `json:"type"`
-		Href       string `json:"href"`
-		Attributes struct {
-			Previews []struct {
-				URL string `json:"url"`
-			} `json:"previews"`
-			Artwork struct {
-				Width      int    `json:"width"`
-				Height     int    `json:"height"`
-				URL        string `json:"url"`
-				BgColor    string `json:"bgColor"`
-				TextColor1 string `json:"textColor1"`
-				TextColor2 string `json:"textColor2"`
-				TextColor3 string `json:"textColor3"`
-				TextColor4 string `json:"textColor4"`
-			} `json:"artwork"`
-			ArtistName           string   `json:"artistName"`
-			URL                  string   `json:"url"`
-			DiscNumber           int      `json:"discNumber"`
-			GenreNames           []string `json:"genreNames"`
-			HasTimeSyncedLyrics  bool     `json:"hasTimeSyncedLyrics"`
-			IsMasteredForItunes  bool     `json:"isMasteredForItunes"`
-			IsAppleDigitalMaster bool     `json:"isAppleDigitalMaster"`
-			IsSingle             bool     `json:"isSingle"` // 单曲标识
-			ContentRating        string   `json:"contentRating"`
-			DurationInMillis     int      `json:"durationInMillis"`
-			ReleaseDate          string   `json:"releaseDate"`
-			Name                 string   `json:"name"`
-			Isrc                 string   `json:"isrc"`
-			AudioTraits          []string `json:"audioTraits"`
-			HasLyrics            bool     `json:"hasLyrics"`
-			AlbumName            string   `json:"albumName"`
-			PlayParams           struct {
-				ID   string `json:"id"`
-				Kind string `json:"kind"`
-			} `json:"playParams"`
-			TrackNumber  int    `json:"trackNumber"`
-			AudioLocale  string `json:"audioLocale"`
-			ComposerName string `json:"composerName"`
-		} `json:"attributes"`
-	} `json:"data"`
-}
-
-type AutoGeneratedMusicVideo struct {
-	Data []struct {
-		ID         string `json:"id"`
-		Type       string `json:"type"`
-		Href       string `json:"href"`
-		Attributes struct {
-			Previews []struct {
-				URL string `json:"url"`
-			} `json:"previews"`
-			Artwork struct {
-				Width      int    `json:"width"`
-				Height     int    `json:"height"`
-				URL        string `json:"url"`
-				BgColor    string `json:"bgColor"`
-				TextColor1 string `json:"textColor1"`
-				TextColor2 string `json:"textColor2"`
-				TextColor3 string `json:"textColor3"`
-				TextColor4 string `json:"textColor4"`
-			} `json:"artwork"`
-			AlbumName        string   `json:"albumName"`
-			ArtistName       string   `json:"artistName"`
-			URL              string   `json:"url"`
-			GenreNames       []string `json:"genreNames"`
-			DurationInMillis int      `json:"durationInMillis"`
-			Isrc             string   `json:"isrc"`
-			TrackNumber      int      `json:"trackNumber"`
-			DiscNumber       int      `json:"discNumber"`
-			ContentRating    string   `json:"contentRating"`
-			ReleaseDate      string   `json:"releaseDate"`
-			Name             string   `json:"name"`
-			Has4K            bool     `json:"has4K"`
-			HasHDR           bool     `json:"hasHDR"`
-			PlayParams       struct {
-				ID   string `json:"id"`
-				Kind string `json:"kind"`
-			} `json:"playParams"`
-		} `json:"attributes"`
-	} `json:"data"`
-}
-
-type SongLyrics struct {
-	Data []struct {
-		Id         string `json:"id"`
-		Type       string `json:"type"`
-		Attributes struct {
-			Ttml       string `json:"ttml"`
-			PlayParams struct {
-				Id          string `json:"id"`
-				Kind        string `json:"kind"`
-				CatalogId   string `json:"catalogId"`
-				DisplayType int    `json:"displayType"`
-			} `json:"playParams"`
-		} `json:"attributes"`
-	} `json:"data"`
-}
+package structs
+
+type EditorialNotes struct {
+	Standard string `json:"standard"`
+}
+
+type Account struct {
+	Name               string `yaml:"name"`
+	Storefront         string `yaml:"storefront"`
+	MediaUserToken     string `yaml:"media-user-token"`
+	AuthorizationToken string `yaml:"authorization-token"`
+	DecryptM3u8Port    string `yaml:"decrypt-m3u8-port"`
+	GetM3u8Port        string `yaml:"get-m3u8-port"`
+}
+
+type ConfigSet struct {
+	Accounts                 []Account               `yaml:"accounts"`
+	Language                 string                  `yaml:"language"`
+	SaveLrcFile              bool                    `yaml:"save-lrc-file"`
+	LrcType                  string                  `yaml:"lrc-type"`
+	LrcFormat                string                  `yaml:"lrc-format"`
+	SaveAnimatedArtwork      bool                    `yaml:"save-animated-artwork"`
+	EmbyAnimatedArtwork      bool                    `yaml:"emby-animated-artwork"`
+	EmbedLrc                 bool                    `yaml:"embed-lrc"`
+	EmbedCover               bool                    `yaml:"embed-cover"`
+	EmbedCoverMaxSize        int                     `yaml:"embed-cover-max-size"` // 内嵌封面的最大边长（像素），0 表示保持原尺寸
+	SaveArtistCover          bool                    `yaml:"save-artist-cover"`
+	CoverSize                string                  `yaml:"cover-size"`
+	CoverFormat              string                  `yaml:"cover-format"`
+	AlacSaveFolder           string                  `yaml:"alac-save-folder"`
+	AtmosSaveFolder          string                  `yaml:"atmos-save-folder"`
+	AacSaveFolder            string                  `yaml:"aac-save-folder"`
+	MVSaveFolder             string                  `yaml:"mv-save-folder"`
+	AlbumFolderFormat        string                  `yaml:"album-folder-format"`
+	PlaylistFolderFormat     string                  `yaml:"playlist-folder-format"`
+	ArtistFolderFormat       string                  `yaml:"artist-folder-format"`
+	SongFileFormat           string                  `yaml:"song-file-format"`
+	ExplicitChoice           string                  `yaml:"explicit-choice"`
+	AppleMasterChoice        string                  `yaml:"apple-master-choice"`
+	GetM3u8Mode              string                  `yaml:"get-m3u8-mode"`
+	GetM3u8FromDevice        bool                    `yaml:"get-m3u8-from-device"`
+	AacType                  string                  `yaml:"aac-type"`
+	AlacMax                  int                     `yaml:"alac-max"`
+	AtmosMax                 int                     `yaml:"atmos-max"`
+	QualityPolicy            []string                `yaml:"quality-policy"`  // 音质策略：按顺序尝试的档位（如 hires<=96000、lossless、aac），留空时按下载模式选择
+	LibraryOutputs           []string                `yaml:"library-outputs"` // 多音乐库模式：每个专辑依次输出的档位（如 alac、atmos），每个档位保存到对应的音乐库
+	LimitMax                 int                     `yaml:"limit-max"`
+	UseSongInfoForPlaylist   bool                    `yaml:"use-songinfo-for-playlist"`
+	DlAlbumcoverForPlaylist  bool                    `yaml:"dl-albumcover-for-playlist"`
+	MVAudioType              string                  `yaml:"mv-audio-type"`
+	MVMax                    int                     `yaml:"mv-max"`
+	MVMin                    int                     `yaml:"mv-min"`
+	AacDownloadThreads       int                     `yaml:"aac_downloadthreads"`
+	LosslessDownloadThreads  int                     `yaml:"lossless_downloadthreads"`
+	HiresDownloadThreads     int                     `yaml:"hires_downloadthreads"`
+	ChunkDownloadThreads     int                     `yaml:"chunk_downloadthreads"`
+	BufferSizeKB             int                     `yaml:"BufferSizeKB"`
+	NetworkReadBufferKB      int                     `yaml:"NetworkReadBufferKB"`
+	MaxPathLength            int                     `yaml:"max-path-length"`
+	DefaultLyricStorefront   string                  `yaml:"default-lyric-storefront"`
+	DownloadVideos           bool                    `yaml:"download-videos"`
+	FfmpegFix                bool                    `yaml:"ffmpeg-fix"`
+	FfmpegCheckArgs          string                  `yaml:"ffmpeg-check-args"`
+	FfmpegEncodeArgs         string                  `yaml:"ffmpeg-encode-args"`
+	EnableCache              bool                    `yaml:"enable-cache"`
+	CacheFolder              string                  `yaml:"cache-folder"`
+	BatchSize                int                     `yaml:"batch-size"`                  // 分批处理的批次大小，0表示不分批
+	WorkRestEnabled          bool                    `yaml:"work-rest-enabled"`           // 启用工作-休息循环
+	WorkDurationMinutes      int                     `yaml:"work-duration-minutes"`       // 工作时长（分钟）
+	RestDurationMinutes      int                     `yaml:"rest-duration-minutes"`       // 休息时长（分钟）
+	AlbumConcurrency         int                     `yaml:"album-concurrency"`           // 批量模式下同时处理的专辑数，1表示串行
+	MaxConcurrentTracks      int                     `yaml:"max-concurrent-tracks"`       // 所有专辑合计同时下载的曲目数上限，0表示不限制
+	MaxBandwidth             string                  `yaml:"max-bandwidth"`               // 全局下载速率上限（如 8MB/s），留空表示不限速
+	BandwidthSchedule        []BandwidthWindowConfig `yaml:"bandwidth-schedule"`          // 按时段覆盖速率上限或暂停下载
+	MinFreeSpaceMB           int                     `yaml:"min-free-space-mb"`           // 缓存与目标目录需保留的最小剩余空间（MB），负数表示关闭空间检查
+	LowSpaceAction           string                  `yaml:"low-space-action"`            // 剩余空间不足时的处理方式: pause/abort
+	EnableTUI                bool                    `yaml:"enable-tui"`                  // 启用全屏终端界面（队列、曲目表、日志面板与快捷键）
+	MetricsListen            string                  `yaml:"metrics-listen"`              // Prometheus 指标监听地址（如 127.0.0.1:9108），留空不启用
+	Notifications            []NotificationConfig    `yaml:"notifications"`               // 任务事件通知（webhook / exec / notify-send）
+	PostHooks                []PostHookConfig        `yaml:"post-hooks"`                  // 下载后处理钩子（每首曲目 / 每个专辑完成后执行）
+	Loudness                 LoudnessConfig          `yaml:"loudness"`                    // 响度分析（EBU R128 / ReplayGain 2.0）
+	Checksums                ChecksumConfig          `yaml:"checksums"`                   // 专辑目录校验清单
+	RetagPreserve            []string                `yaml:"retag-preserve"`              // retag 命令保留文件中原值的字段（手动编辑过的标签）
+	Logging                  LoggingConfig           `yaml:"logging"`                     // 日志配置
+	EnableVirtualSingles     bool                    `yaml:"enable-virtual-singles"`      // 是否启用虚拟Singles专辑
+	VirtualSinglesFolderName string                  `yaml:"virtual-singles-folder-name"` // 虚拟单曲专辑的文件夹名称
+	ReleaseRules             ReleaseRulesConfig      `yaml:"release-rules"`               // 单曲 / EP 识别规则
+	MetadataLanguage         MetadataLanguageConfig  `yaml:"metadata-language"`           // 按地区 / 艺术家选择元数据语言，以及第二语言标签
+	ArtistExceptions         []string                `yaml:"artist-exceptions"`           // 名称中含有合作分隔符但不应拆分的艺术家（如 Simon & Garfunkel）
+	FileValidation           FileValidationConfig    `yaml:"file-validation"`             // 文件校验配置
+	LocalWrapperOptimization LocalWrapperConfig      `yaml:"local-wrapper-optimization"`  // 本地 wrapper 服务优化配置
+}
+
+// FileValidationConfig 文件校验配置
+type FileValidationConfig struct {
+	SizeCheckEnabled       bool `yaml:"size-check-enabled"`       // 是否启用文件大小检查
+	ConcurrentCheckEnabled bool `yaml:"concurrent-check-enabled"` // 是否启用并发校验
+	ConcurrentWorkers      int  `yaml:"concurrent-workers"`       // 并发worker数量
+}
+
+// BandwidthWindowConfig 限速时段配置
+type BandwidthWindowConfig struct {
+	Start string `yaml:"start"` // 开始时刻（HH:MM）
+	End   string `yaml:"end"`   // 结束时刻（HH:MM），早于开始时刻表示跨越午夜
+	Limit string `yaml:"limit"` // 时段内的速率上限（如 2MB/s）、unlimited 或 pause
+}
+
+// LoudnessConfig 响度分析配置
+type LoudnessConfig struct {
+	Enabled      bool `yaml:"enabled"`       // 专辑下载完成后分析响度并写入 ReplayGain 标签
+	Threads      int  `yaml:"threads"`       // 同时分析的曲目数，0 表示 CPU 核数
+	IncludeAtmos bool `yaml:"include-atmos"` // 是否分析杜比全景声（默认跳过）
+}
+
+// ChecksumConfig 专辑目录校验清单配置
+type ChecksumConfig struct {
+	Enabled   bool   `yaml:"enabled"`   // 专辑下载完成后在专辑目录生成 checksums.<算法> 清单
+	Algorithm string `yaml:"algorithm"` // 摘要算法: sha256/blake3/md5，默认 sha256
+}
+
+// ReleaseRulesConfig 单曲 / EP 识别规则（决定专辑是否归入虚拟 Singles / EPs）
+type ReleaseRulesConfig struct {
+	SinglePatterns   LanguagePatterns    `yaml:"single-patterns"`    // 专辑名包含任一文本时识别为单曲（按元数据语言），留空使用默认（"- Single"、" Single"、"单曲"）
+	EPPatterns       LanguagePatterns    `yaml:"ep-patterns"`        // 专辑名包含任一文本时识别为 EP（按元数据语言），留空使用默认（"- EP"）
+	SingleMaxTracks  int                 `yaml:"single-max-tracks"`  // 曲目数不超过该值时识别为单曲，0 表示默认 3，负数表示不按曲目数判断
+	SingleMaxMinutes int                 `yaml:"single-max-minutes"` // 按曲目数识别单曲时的总时长上限（分钟），0 表示不限制
+	EPMaxTracks      int                 `yaml:"ep-max-tracks"`      // 曲目数不超过该值时识别为 EP，0 表示不按曲目数判断
+	EPMaxMinutes     int                 `yaml:"ep-max-minutes"`     // 按曲目数识别 EP 时的总时长上限（分钟），0 表示不限制
+	EPMode           string              `yaml:"ep-mode"`            // EP 的处理方式: album（按普通专辑，默认）/single（并入虚拟 Singles）/collection（虚拟 "艺术家 - EPs"）
+	EPFolderName     string              `yaml:"ep-folder-name"`     // 虚拟 EPs 的合辑名称，默认 EPs
+	Artists          []ReleaseArtistRule `yaml:"artists"`            // 按艺术家覆盖
+}
+
+// PatternLanguageDefault LanguagePatterns 中用于未单独列出的语言的键
+const PatternLanguageDefault = "default"
+
+// LanguagePatterns 按元数据语言区分的名称规则：语言代码（如 ja、zh-TW）→ 规则文本
+// 未列出的语言使用 default 键；兼容旧格式，直接写列表时等同于只设置 default（空列表等同于未配置）
+type LanguagePatterns map[string][]string
+
+// UnmarshalYAML 同时接受列表与按语言的映射
+func (p *LanguagePatterns) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var list []string
+	if err := unmarshal(&list); err == nil {
+		*p = nil
+		if len(list) > 0 {
+			*p = LanguagePatterns{PatternLanguageDefault: list}
+		}
+		return nil
+	}
+	var m map[string][]string
+	if err := unmarshal(&m); err != nil {
+		return err
+	}
+	*p = m
+	return nil
+}
+
+// ReleaseArtistRule 单个艺术家的例外规则
+type ReleaseArtistRule struct {
+	Artist  string `yaml:"artist"`  // 艺术家名称（不区分大小写，匹配全名或主要艺术家）或艺术家 ID
+	Singles string `yaml:"singles"` // virtual（默认）/album：该艺术家的单曲按普通专辑保存
+	EPs     string `yaml:"eps"`     // 覆盖 ep-mode: album/single/collection
+}
+
+// MetadataLanguageConfig 元数据语言配置
+type MetadataLanguageConfig struct {
+	Rules           []LanguageRule `yaml:"rules"`            // 按顺序匹配，第一个命中的规则生效，未命中时使用 language
+	Secondary       string         `yaml:"secondary"`        // 第二语言（如 en-US）：额外获取一份该语言的元数据，留空关闭
+	SecondaryTarget string         `yaml:"secondary-target"` // 第二语言写入的位置: sort（排序字段，默认）/custom（自定义字段 ALT_TITLE 等）
+	Folder          string         `yaml:"folder"`           // 文件夹与文件名使用的语言: primary（默认）/secondary
+}
+
+// LanguageRule 元数据语言规则，同时设置地区与艺术家时两者都需匹配
+type LanguageRule struct {
+	Storefront string `yaml:"storefront"` // 链接地区（如 jp）
+	Artist     string `yaml:"artist"`     // 艺术家名称（不区分大小写，匹配全名或主要艺术家）或艺术家 ID
+	Language   string `yaml:"language"`   // 语言代码（如 ja、zh-TW）
+}
+
+// PostHookConfig 下载后处理钩子
+type PostHookConfig struct {
+	Name       string   `yaml:"name"`        // 钩子名称（用于日志）
+	Stage      string   `yaml:"stage"`       // 执行时机: track（每首曲目写入标签后）/album（专辑转移完成后）
+	Type       string   `yaml:"type"`        // 钩子类型: command/plugin，默认 command
+	Command    string   `yaml:"command"`     // command: 要执行的命令，标准输入为 JSON 描述
+	Args       []string `yaml:"args"`        // command: 命令参数，支持 {album_folder} {album_id} {track_path} {stage} 占位符
+	Plugin     string   `yaml:"plugin"`      // plugin: Go 插件（.so）路径，需导出 Hook 函数
+	TimeoutSec int      `yaml:"timeout-sec"` // 单次执行超时（秒），默认 300
+	OnFailure  string   `yaml:"on-failure"`  // 失败处理: ignore/warn/fail（fail 将专辑记为失败），默认 warn
+}
+
+// NotificationConfig 单个事件通知钩子
+type NotificationConfig struct {
+	Type       string            `yaml:"type"`        // 钩子类型: webhook/exec/notify-send
+	Events     []string          `yaml:"events"`      // 订阅的事件，留空表示全部事件
+	URL        string            `yaml:"url"`         // webhook: 接收 JSON 的地址（POST）
+	Headers    map[string]string `yaml:"headers"`     // webhook: 额外的请求头（如认证）
+	Command    string            `yaml:"command"`     // exec: 要执行的命令，事件信息通过环境变量和标准输入传递
+	Args       []string          `yaml:"args"`        // exec: 命令参数
+	Urgency    string            `yaml:"urgency"`     // notify-send: 紧急程度 low/normal/critical，留空按事件自动选择
+	TimeoutSec int               `yaml:"timeout-sec"` // 单次通知超时（秒），默认 10
+}
+
+// LocalWrapperConfig 本地 wrapper 服务优化配置
+type LocalWrapperConfig struct {
+	Enabled              bool `yaml:"enabled"`                 // 是否启用本地优化模式
+	MaxIdleConns         int  `yaml:"max-idle-conns"`          // 最大空闲连接数
+	MaxIdleConnsPerHost  int  `yaml:"max-idle-conns-per-host"` // 每个主机的最大空闲连接数
+	MaxConnsPerHost      int  `yaml:"max-conns-per-host"`      // 每个主机的最大连接数
+	IdleConnTimeoutSec   int  `yaml:"idle-conn-timeout-sec"`   // 空闲连接超时（秒）
+	DialTimeoutMs        int  `yaml:"dial-timeout-ms"`         // 连接超时（毫秒）
+	KeepAlive            bool `yaml:"keep-alive"`              // 是否启用 TCP KeepAlive
+	DisableCompression   bool `yaml:"disable-compression"`     // 是否禁用压缩（本地通讯无需压缩）
+	ExpectContinueTimeMs int  `yaml:"expect-continue-time-ms"` // Expect: 100-continue 超时（毫秒）
+}
+
+// LoggingConfig 日志配置
+type LoggingConfig struct {
+	Level         string        `yaml:"level"`          // 日志等级: debug/info/warn/error
+	Output        string        `yaml:"output"`         // 输出目标: stdout/stderr/文件路径
+	Format        string        `yaml:"format"`         // 控制台日志格式: text/json
+	ShowTimestamp bool          `yaml:"show_timestamp"` // 是否显示时间戳
+	ShowFields    bool          `yaml:"show_fields"`    // 控制台文本日志是否显示结构化字段
+	File          LogFileConfig `yaml:"file"`           // 独立的日志文件输出
+}
+
+// LogFileConfig 日志文件输出配置（独立于控制台的等级与格式，支持轮转）
+type LogFileConfig struct {
+	Path        string `yaml:"path"`         // 日志文件路径，为空时不写文件
+	Level       string `yaml:"level"`        // 文件日志等级，默认 debug
+	Format      string `yaml:"format"`       // 文件日志格式: json/text，默认 json
+	MaxSizeMB   int    `yaml:"max_size_mb"`  // 单个文件最大大小（MB），0 表示不按大小轮转
+	RotateEvery string `yaml:"rotate_every"` // 按时间轮转周期（如 24h），为空表示不按时间轮转
+	MaxBackups  int    `yaml:"max_backups"`  // 保留的备份文件数，0 表示不限制
+	MaxAgeDays  int    `yaml:"max_age_days"` // 备份保留天数，0 表示不限制
+}
+
+// TrackBatch 表示一个曲目批次
+type TrackBatch struct {
+	Tracks       []int // 批次中的曲目编号列表
+	BatchNum     int   // 当前批次编号（从1开始）
+	TotalBatches int   // 总批次数
+	BatchSize    int   // 当前批次大小
+	IsLast       bool  // 是否最后一个批次
+}
+
+// BatchIterator 批次迭代器
+type BatchIterator struct {
+	tracks      []int
+	batchSize   int
+	currentIdx  int
+	totalTracks int
+}
+
+// NewBatchIterator 创建批次迭代器
+func NewBatchIterator(tracks []int, batchSize int) *BatchIterator {
+	if batchSize <= 0 {
+		batchSize = len(tracks)
+	}
+	return &BatchIterator{
+		tracks:      tracks,
+		batchSize:   batchSize,
+		currentIdx:  0,
+		totalTracks: len(tracks),
+	}
+}
+
+// Next 获取下一个批次，返回批次数据和是否还有更多批次
+func (b *BatchIterator) Next() (*TrackBatch, bool) {
+	if b.currentIdx >= b.totalTracks {
+		return nil, false
+	}
+
+	totalBatches := (b.totalTracks + b.batchSize - 1) / b.batchSize
+	batchNum := (b.currentIdx / b.batchSize) + 1
+
+	end := b.currentIdx + b.batchSize
+	if end > b.totalTracks {
+		end = b.totalTracks
+	}
+
+	batch := &TrackBatch{
+		Tracks:       b.tracks[b.currentIdx:end],
+		BatchNum:     batchNum,
+		TotalBatches: totalBatches,
+		BatchSize:    end - b.currentIdx,
+		IsLast:       end == b.totalTracks,
+	}
+
+	b.currentIdx = end
+	return batch, true
+}
+
+// HasNext 检查是否还有下一个批次
+func (b *BatchIterator) HasNext() bool {
+	return b.currentIdx < b.totalTracks
+}
+
+// Reset 重置迭代器
+func (b *BatchIterator) Reset() {
+	b.currentIdx = 0
+}
+
+type Counter struct {
+	Unavailable int
+	NotSong     int
+	Error       int
+	Success     int
+	Total       int
+}
+
+type ApiResult struct {
+	Data []SongData `json:"data"`
+}
+
+type SongAttributes struct {
+	ArtistName        string   `json:"artistName"`
+	DiscNumber        int      `json:"discNumber"`
+	GenreNames        []string `json:"genreNames"`
+	ExtendedAssetUrls struct {
+		EnhancedHls string `json:"enhancedHls"`
+	} `json:"extendedAssetUrls"`
+	IsMasteredForItunes  bool   `json:"isMasteredForItunes"`
+	IsAppleDigitalMaster bool   `json:"isAppleDigitalMaster"`
+	ContentRating        string `json:"contentRating"`
+	ReleaseDate          string `json:"releaseDate"`
+	Name                 string `json:"name"`
+	Isrc                 string `json:"isrc"`
+	AlbumName            string `json:"albumName"`
+	TrackNumber          int    `json:"trackNumber"`
+	ComposerName         string `json:"composerName"`
+}
+
+type AlbumAttributes struct {
+	ArtistName           string   `json:"artistName"`
+	IsSingle             bool     `json:"isSingle"`
+	IsComplete           bool     `json:"isComplete"`
+	GenreNames           []string `json:"genreNames"`
+	TrackCount           int      `json:"trackCount"`
+	IsMasteredForItunes  bool     `json:"isMasteredForItunes"`
+	IsAppleDigitalMaster bool     `json:"isAppleDigitalMaster"`
+	ContentRating        string   `json:"contentRating"`
+	ReleaseDate          string   `json:"releaseDate"`
+	Name                 string   `json:"name"`
+	RecordLabel          string   `json:"recordLabel"`
+	Upc                  string   `json:"upc"`
+	Copyright            string   `json:"copyright"`
+	IsCompilation        bool     `json:"isCompilation"`
+}
+
+type SongData struct {
+	ID            string         `json:"id"`
+	Attributes    SongAttributes `json:"attributes"`
+	Relationships struct {
+		Albums struct {
+			Data []struct {
+				ID         string          `json:"id"`
+				Type       string          `json:"type"`
+				Href       string          `json:"href"`
+				Attributes AlbumAttributes `json:"attributes"`
+			} `json:"data"`
+		} `json:"albums"`
+		Artists struct {
+			Href string `json:"href"`
+			Data []struct {
+				ID   string `json:"id"`
+				Type string `json:"type"`
+				Href string `json:"href"`
+			} `json:"data"`
+		} `json:"artists"`
+	} `json:"relationships"`
+}
+
+type SongResult struct {
+	Artwork struct {
+		Width                int    `json:"width"`
+		URL                  string `json:"url"`
+		Height               int    `json:"height"`
+		TextColor3           string `json:"textColor3"`
+		TextColor2           string `json:"textColor2"`
+		TextColor4           string `json:"textColor4"`
+		HasAlpha             bool   `json:"hasAlpha"`
+		TextColor1           string `json:"textColor1"`
+		BgColor              string `json:"bgColor"`
+		HasP3                bool   `json:"hasP3"`
+		SupportsLayeredImage bool   `json:"supportsLayeredImage"`
+	} `json:"artwork"`
+	ArtistName             string   `json:"artistName"`
+	CollectionID           string   `json:"collectionId"`
+	DiscNumber             int      `json:"discNumber"`
+	GenreNames             []string `json:"genreNames"`
+	ID                     string   `json:"id"`
+	DurationInMillis       int      `json:"durationInMillis"`
+	ReleaseDate            string   `json:"releaseDate"`
+	ContentRatingsBySystem struct {
+	} `json:"contentRatingsBySystem"`
+	Name     string `json:"name"`
+	Composer struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"composer"`
+	EditorialArtwork struct {
+	} `json:"editorialArtwork"`
+	CollectionName string `json:"collectionName"`
+	AssetUrls      struct {
+		Plus             string `json:"plus"`
+		Lightweight      string `json:"lightweight"`
+		SuperLightweight string `json:"superLightweight"`
+		LightweightPlus  string `json:"lightweightPlus"`
+		EnhancedHls      string `json:"enhancedHls"`
+	} `json:"assetUrls"`
+	AudioTraits []string `json:"audioTraits"`
+	Kind        string   `json:"kind"`
+	Copyright   string   `json:"copyright"`
+	ArtistID    string   `json:"artistId"`
+	Genres      []struct {
+		GenreID   string `json:"genreId"`
+		Name      string `json:"name"`
+		URL       string `json:"url"`
+		MediaType string `json:"mediaType"`
+	} `json:"genres"`
+	TrackNumber int    `json:"trackNumber"`
+	AudioLocale string `json:"audioLocale"`
+	Offers      []struct {
+		ActionText struct {
+			Short       string `json:"short"`
+			Medium      string `json:"medium"`
+			Long        string `json:"long"`
+			Downloaded  string `json:"downloaded"`
+			Downloading string `json:"downloading"`
+		} `json:"actionText"`
+		Type           string  `json:"type"`
+		PriceFormatted string  `json:"priceFormatted"`
+		Price          float64 `json:"price"`
+		BuyParams      string  `json:"buyParams"`
+		Variant        string  `json:"variant,omitempty"`
+		Assets         []struct {
+			Flavor  string `json:"flavor"`
+			Preview struct {
+				Duration int    `json:"duration"`
+				URL      string `json:"url"`
+			} `json:"preview"`
+			Size     int `json:"size"`
+			Duration int `json:"duration"`
+		} `json:"assets"`
+	} `json:"offers"`
+}
+
+type TrackData struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	Href       string `json:"href"`
+	Attributes struct {
+		Previews []struct {
+			URL string `json:"url"`
+		} `json:"previews"`
+		Artwork struct {
+			Width      int    `json:"width"`
+			Height     int    `json:"height"`
+			URL        string `json:"url"`
+			BgColor    string `json:"bgColor"`
+			TextColor1 string `json:"textColor1"`
+			TextColor2 string `json:"textColor2"`
+			TextColor3 string `json:"textColor3"`
+			TextColor4 string `json:"textColor4"`
+		} `json:"artwork"`
+		ArtistName           string   `json:"artistName"`
+		URL                  string   `json:"url"`
+		DiscNumber           int      `json:"discNumber"`
+		GenreNames           []string `json:"genreNames"`
+		HasTimeSyncedLyrics  bool     `json:"hasTimeSyncedLyrics"`
+		IsMasteredForItunes  bool     `json:"isMasteredForItunes"`
+		IsAppleDigitalMaster bool     `json:"isAppleDigitalMaster"`
+		ContentRating        string   `json:"contentRating"`
+		DurationInMillis     int      `json:"durationInMillis"`
+		ReleaseDate          string   `json:"releaseDate"`
+		Name                 string   `json:"name"`
+		Isrc                 string   `json:"isrc"`
+		AudioTraits          []string `json:"audioTraits"`
+		HasLyrics            bool     `json:"hasLyrics"`
+		AlbumName            string   `json:"albumName"`
+		PlayParams           struct {
+			ID   string `json:"id"`
+			Kind string `json:"kind"`
+		} `json:"playParams"`
+		TrackNumber  int    `json:"trackNumber"`
+		AudioLocale  string `json:"audioLocale"`
+		ComposerName string `json:"composerName"`
+	} `json:"attributes"`
+	Relationships struct {
+		Artists struct {
+			Href string `json:"href"`
+			Data []struct {
+				ID         string `json:"id"`
+				Type       string `json:"type"`
+				Href       string `json:"href"`
+				Attributes struct {
+					Name string `json:"name"`
+				} `json:"attributes"`
+			} `json:"data"`
+		} `json:"artists"`
+		Albums struct {
+			Href string      `json:"href"`
+			Data []AlbumData `json:"data"`
+		}
+	} `json:"relationships"`
+}
+
+type AlbumData struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	Href       string `json:"href"`
+	Attributes struct {
+		ArtistName string `json:"artistName"`
+		Artwork    struct {
+			Width      int    `json:"width"`
+			Height     int    `json:"height"`
+			URL        string `json:"url"`
+			BgColor    string `json:"bgColor"`
+			TextColor1 string `json:"textColor1"`
+			TextColor2 string `json:"textColor2"`
+			TextColor3 string `json:"textColor3"`
+			TextColor4 string `json:"textColor4"`
+		} `json:"artwork"`
+		GenreNames          []string `json:"genreNames"`
+		IsCompilation       bool     `json:"isCompilation"`
+		IsComplete          bool     `json:"isComplete"`
+		IsMasteredForItunes bool     `json:"isMasteredForItunes"`
+		IsPrerelease        bool     `json:"isPrerelease"`
+		IsSingle            bool     `json:"isSingle"`
+		Name                string   `json:"name"`
+		PlayParams          struct {
+			ID   string `json:"id"`
+			Kind string `json:"kind"`
+		} `json:"playParams"`
+		ReleaseDate string `json:"releaseDate"`
+		TrackCount  int    `json:"trackCount"`
+		Upc         string `json:"upc"`
+		URL         string `json:"url"`
+	}
+}
+
+type AutoGenerated struct {
+	Data []struct {
+		ID         string `json:"id"`
+		Type       string `json:"type"`
+		Href       string `json:"href"`
+		Attributes struct {
+			Artwork struct {
+				Width      int    `json:"width"`
+				Height     int    `json:"height"`
+				URL        string `json:"url"`
+				BgColor    string `json:"bgColor"`
+				TextColor1 string `json:"textColor1"`
+				TextColor2 string `json:"textColor2"`
+				TextColor3 string `json:"textColor3"`
+				TextColor4 string `json:"textColor4"`
+			} `json:"artwork"`
+			ArtistName           string   `json:"artistName"`
+			IsSingle             bool     `json:"isSingle"`
+			URL                  string   `json:"url"`
+			IsComplete           bool     `json:"isComplete"`
+			GenreNames           []string `json:"genreNames"`
+			TrackCount           int      `json:"trackCount"`
+			IsMasteredForItunes  bool     `json:"isMasteredForItunes"`
+			IsAppleDigitalMaster bool     `json:"isAppleDigitalMaster"`
+			ContentRating        string   `json:"contentRating"`
+			ReleaseDate          string   `json:"releaseDate"`
+			Name                 string   `json:"name"`
+			RecordLabel          string   `json:"recordLabel"`
+			Upc                  string   `json:"upc"`
+			AudioTraits          []string `json:"audioTraits"`
+			Copyright            string   `json:"copyright"`
+			PlayParams           struct {
+				ID   string `json:"id"`
+				Kind string `json:"kind"`
+			} `json:"playParams"`
+			IsCompilation  bool `json:"isCompilation"`
+			EditorialVideo struct {
+				MotionTall struct {
+					Video string `json:"video"`
+				} `json:"motionTallVideo3x4"`
+				MotionSquare struct {
+					Video string `json:"video"`
+				} `json:"motionSquareVideo1x1"`
+				MotionDetailTall struct {
+					Video string `json:"video"`
+				} `json:"motionDetailTall"`
+				MotionDetailSquare struct {
+					Video string `json:"video"`
+				} `json:"motionDetailSquare"`
+			} `json:"editorialVideo"`
+			// [新增功能] 在此添加 EditorialNotes 字段
+			// Added EditorialNotes field here.
+			EditorialNotes *EditorialNotes `json:"editorialNotes"`
+		} `json:"attributes"`
+		Relationships struct {
+			RecordLabels struct {
+				Href string        `json:"href"`
+				Data []interface{} `json:"data"`
+			} `json:"record-labels"`
+			Artists struct {
+				Href string `json:"href"`
+				Data []struct {
+					ID         string `json:"id"`
+					Type       string `json:"type"`
+					Href       string `json:"href"`
+					Attributes struct {
+						Name    string `json:"name"`
+						Artwork struct {
+							Url string `json:"url"`
+						} `json:"artwork"`
+					} `json:"attributes"`
+				} `json:"data"`
+			} `json:"artists"`
+			Tracks struct {
+				Href string      `json:"href"`
+				Next string      `json:"next"`
+				Data []TrackData `json:"data"`
+			} `json:"tracks"`
+		} `json:"relationships"`
+	} `json:"data"`
+	// Language 获取元数据时使用的语言（l= 参数），未知时为空
+	Language string `json:"-"`
+	// Secondary 第二语言的元数据（metadata-language.secondary），未启用时为 nil
+	Secondary *AutoGenerated `json:"-"`
+}
+
+type AutoGeneratedTrack struct {
+	Href string `json:"href"`
+	Next string `json:"next"`
+	Data []struct {
+		ID         string `json:"id"`
+		Type       string `json:"type"`
+		Href       string `json:"href"`
+		Attributes struct {
+			Previews []struct {
+				URL string `json:"url"`
+			} `json:"previews"`
+			Artwork struct {
+				Width      int    `json:"width"`
+				Height     int    `json:"height"`
+				URL        string `json:"url"`
+				BgColor    string `json:"bgColor"`
+				TextColor1 string `json:"textColor1"`
+				TextColor2 string `json:"textColor2"`
+				TextColor3 string `json:"textColor3"`
+				TextColor4 string `json:"textColor4"`
+			} `json:"artwork"`
+			ArtistName           string   `json:"artistName"`
+			URL                  string   `json:"url"`
+			DiscNumber           int      `json:"discNumber"`
+			GenreNames           []string `json:"genreNames"`
+			HasTimeSyncedLyrics  bool     `json:"hasTimeSyncedLyrics"`
+			IsMasteredForItunes  bool     `json:"isMasteredForItunes"`
+			IsAppleDigitalMaster bool     `json:"isAppleDigitalMaster"`
+			ContentRating        string   `json:"contentRating"`
+			DurationInMillis     int      `json:"durationInMillis"`
+			ReleaseDate          string   `json:"releaseDate"`
+			Name                 string   `json:"name"`
+			Isrc                 string   `json:"isrc"`
+			AudioTraits          []string `json:"audioTraits"`
+			HasLyrics            bool     `json:"hasLyrics"`
+			AlbumName            string   `json:"albumName"`
+			PlayParams           struct {
+				ID   string `json:"id"`
+				Kind string `json:"kind"`
+			} `json:"playParams"`
+			TrackNumber  int    `json:"trackNumber"`
+			AudioLocale  string `json:"audioLocale"`
+			ComposerName string `json:"composerName"`
+		} `json:"attributes"`
+		Relationships struct {
+			Artists struct {
+				Href string `json:"href"`
+				Data []struct {
+					ID         string `json:"id"`
+					Type       string `json:"type"`
+					Href       string `json:"href"`
+					Attributes struct {
+						Name string `json:"name"`
+					} `json:"attributes"`
+				} `json:"data"`
+			} `json:"artists"`
+			Albums struct {
+				Href string      `json:"href"`
+				Data []AlbumData `json:"data"`
+			}
+		} `json:"relationships"`
+	} `json:"data"`
+}
+
+type AutoGeneratedArtist struct {
+	Next string `json:"next"`
+	Data []struct {
+		ID         string `json:"id"`
+		Type       string `json:"type"`
+		Href       string `json:"href"`
+		Attributes struct {
+			Previews []struct {
+				URL string `json:"url"`
+			} `json:"previews"`
+			Artwork struct {
+				Width      int    `json:"width"`
+				Height     int    `json:"height"`
+				URL        string `json:"url"`
+				BgColor    string `json:"bgColor"`
+				TextColor1 string `json:"textColor1"`
+				TextColor2 string `json:"textColor2"`
+				TextColor3 string `json:"textColor3"`
+				TextColor4 string `json:"textColor4"`
+			} `json:"artwork"`
+			ArtistName           string   `json:"artistName"`
+			URL                  string   `json:"url"`
+			DiscNumber           int      `json:"discNumber"`
+			GenreNames           []string `json:"genreNames"`
+			HasTimeSyncedLyrics  bool     `json:"hasTimeSyncedLyrics"`
+			IsMasteredForItunes  bool     `json:"isMasteredForItunes"`
+			IsAppleDigitalMaster bool     `json:"isAppleDigitalMaster"`
+			IsSingle             bool     `json:"isSingle"` // 单曲标识
+			ContentRating        string   `json:"contentRating"`
+			DurationInMillis     int      `json:"durationInMillis"`
+			ReleaseDate          string   `json:"releaseDate"`
+			Name                 string   `json:"name"`
+			Isrc                 string   `json:"isrc"`
+			AudioTraits          []string `json:"audioTraits"`
+			HasLyrics            bool     `json:"hasLyrics"`
+			AlbumName            string   `json:"albumName"`
+			PlayParams           struct {
+				ID   string `json:"id"`
+				Kind string `json:"kind"`
+			} `json:"playParams"`
+			TrackNumber  int    `json:"trackNumber"`
+			AudioLocale  string `json:"audioLocale"`
+			ComposerName string `json:"composerName"`
+		} `json:"attributes"`
+		Relationships struct {
+			Artists struct {
+				Data []struct {
+					ID         string `json:"id"`
+					Attributes struct {
+						Name string `json:"name"`
+					} `json:"attributes"`
+				} `json:"data"`
+			} `json:"artists"`
+		} `json:"relationships"` // 请求时 include=artists 才有数据
+	} `json:"data"`
+}
+
+type AutoGeneratedMusicVideo struct {
+	Data []struct {
+		ID         string `json:"id"`
+		Type       string `json:"type"`
+		Href       string `json:"href"`
+		Attributes struct {
+			Previews []struct {
+				URL string `json:"url"`
+			} `json:"previews"`
+			Artwork struct {
+				Width      int    `json:"width"`
+				Height     int    `json:"height"`
+				URL        string `json:"url"`
+				BgColor    string `json:"bgColor"`
+				TextColor1 string `json:"textColor1"`
+				TextColor2 string `json:"textColor2"`
+				TextColor3 string `json:"textColor3"`
+				TextColor4 string `json:"textColor4"`
+			} `json:"artwork"`
+			AlbumName        string   `json:"albumName"`
+			ArtistName       string   `json:"artistName"`
+			URL              string   `json:"url"`
+			GenreNames       []string `json:"genreNames"`
+			DurationInMillis int      `json:"durationInMillis"`
+			Isrc             string   `json:"isrc"`
+			TrackNumber      int      `json:"trackNumber"`
+			DiscNumber       int      `json:"discNumber"`
+			ContentRating    string   `json:"contentRating"`
+			ReleaseDate      string   `json:"releaseDate"`
+			Name             string   `json:"name"`
+			Has4K            bool     `json:"has4K"`
+			HasHDR           bool     `json:"hasHDR"`
+			PlayParams       struct {
+				ID   string `json:"id"`
+				Kind string `json:"kind"`
+			} `json:"playParams"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+type SongLyrics struct {
+	Data []struct {
+		Id         string `json:"id"`
+		Type       string `json:"type"`
+		Attributes struct {
+			Ttml       string `json:"ttml"`
+			PlayParams struct {
+				Id          string `json:"id"`
+				Kind        string `json:"kind"`
+				CatalogId   string `json:"catalogId"`
+				DisplayType int    `json:"displayType"`
+			} `json:"playParams"`
+		} `json:"attributes"`
+	} `json:"data"`
+}